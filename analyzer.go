@@ -3,197 +3,67 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
-	"time"
 
 	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // analyzeURLInternal analyzes a YouTube URL and returns video information
 func (a *App) analyzeURLInternal(url string) (string, error) {
-	ytDlpPath := filepath.Join("./bin", a.getYtDlpBinaryName())
-
 	// Build command arguments based on settings - ensure we only get info, not download
 	// Updated to get all available formats without restrictions
 	// Note: Removed --extractor-args as it conflicts with --js-runtimes and prevents high quality formats
-	args := []string{"--print-json", "--simulate", url, "--no-warnings"}
-
-	// Add JS runtime if enabled or if it's a YouTube URL (which requires it)
-	if a.settings.UseJSRuntime || a.isYouTubeURL(url) {
-		if a.isDenoAvailable() {
-			args = append(args, "--js-runtimes", "deno:"+filepath.Join("./bin", a.getDenoBinaryName()))
-		} else {
-			// If deno is not available but JS runtime is required, try to download it
-			wailsRuntime.LogInfof(a.ctx, "Deno not found, attempting to download...")
-			err := a.downloadDenoWithProgress()
-			if err != nil {
-				wailsRuntime.LogErrorf(a.ctx, "Failed to download deno: %v", err)
-				// Continue without JS runtime
-			} else {
-				args = append(args, "--js-runtimes", "deno:"+filepath.Join("./bin", a.getDenoBinaryName()))
-			}
-		}
-	}
-
-	// Add proxy settings if enabled
-	if a.settings.ProxyMode == "manual" && a.settings.ProxyAddress != "" {
-		args = append(args, "--proxy", a.settings.ProxyAddress)
-	}
-	// Note: When ProxyMode is "system", yt-dlp automatically uses system proxy settings
-	// We don't need to add --proxy argument for system mode
-
-	// Add cookies settings if enabled
-	if a.settings.CookiesMode == "browser" {
-		cookiesArg := fmt.Sprintf("--cookies-from-browser=%s", a.settings.CookiesBrowser)
-		args = append(args, cookiesArg)
-	} else if a.settings.CookiesMode == "file" && a.settings.CookiesFile != "" {
-		// Check if the cookies file exists before using it
-		if _, err := os.Stat(a.settings.CookiesFile); err == nil {
-			args = append(args, "--cookies", a.settings.CookiesFile)
-		} else {
-			wailsRuntime.LogInfof(a.ctx, "Cookies file does not exist: %s, proceeding without cookies", a.settings.CookiesFile)
-		}
-	}
-
-	// Hide console window on Windows
-	cmd := exec.Command(ytDlpPath, args...)
-	setHideWindow(cmd)
+	inv := a.newYtDlpInvocation(url)
+	inv.Flags = []string{"--print-json", "--simulate", "--no-warnings"}
 
-	output, err := cmd.Output()
+	output, err := a.ytDlpCommand(inv).Output()
 	if err != nil {
 		// Log stderr output if available
 		if exitError, ok := err.(*exec.ExitError); ok {
 			stderrStr := string(exitError.Stderr)
-			wailsRuntime.LogInfof(a.ctx, "First attempt failed with stderr: %s", stderrStr)
+			a.logger.Infof("First attempt failed with stderr: %s", stderrStr)
 
 			// Check if the error is related to cookies or format availability
-			isCookiesError := strings.Contains(stderrStr, "cookies") ||
-				strings.Contains(stderrStr, "Sign in to confirm") ||
-				strings.Contains(stderrStr, "authentication")
 			isFormatError := strings.Contains(stderrStr, "Requested format is not available")
 
-			// If cookies are enabled and we get a format error, try without cookies
-			if (isCookiesError || isFormatError) && (a.settings.CookiesMode == "browser" || a.settings.CookiesMode == "file") {
-				wailsRuntime.LogInfof(a.ctx, "Cookies-related error detected, trying without cookies...")
+			// If cookies are enabled and we get a cookies or format error, try without cookies
+			if isCookiesRelatedError(stderrStr) && inv.UsesCookies() {
+				a.logger.Infof("Cookies-related error detected, trying without cookies...")
 
-				// Try without cookies
-				argsWithoutCookies := []string{"--print-json", "--simulate", url, "--no-warnings"}
-
-				// Add proxy settings if enabled (but no cookies)
-				if a.settings.ProxyMode == "manual" && a.settings.ProxyAddress != "" {
-					argsWithoutCookies = append(argsWithoutCookies, "--proxy", a.settings.ProxyAddress)
-				}
-				// Note: When ProxyMode is "system", yt-dlp automatically uses system proxy settings
-
-				cmdWithoutCookies := exec.Command(ytDlpPath, argsWithoutCookies...)
-				setHideWindow(cmdWithoutCookies)
-
-				output, err = cmdWithoutCookies.Output()
+				output, err = a.ytDlpCommand(inv.NoCookies()).Output()
 				if err == nil {
-					wailsRuntime.LogInfof(a.ctx, "Successfully retrieved info without cookies")
+					a.logger.Infof("Successfully retrieved info without cookies")
 				} else {
-					wailsRuntime.LogInfof(a.ctx, "Attempt without cookies also failed")
+					a.logger.Infof("Attempt without cookies also failed")
 				}
 			}
 
 			// If still have error, check if it's format availability issue
 			if err != nil && isFormatError {
-				// If format is not available, try without specifying format restrictions
-				fallbackArgs := []string{"--print-json", "--simulate", url, "--no-warnings"}
-
-				// Add proxy settings if enabled
-				if a.settings.ProxyMode == "manual" && a.settings.ProxyAddress != "" {
-					fallbackArgs = append(fallbackArgs, "--proxy", a.settings.ProxyAddress)
-				}
-				// Note: When ProxyMode is "system", yt-dlp automatically uses system proxy settings
-
-				// Add cookies settings if enabled
-				if a.settings.CookiesMode == "browser" {
-					cookiesArg := fmt.Sprintf("--cookies-from-browser=%s", a.settings.CookiesBrowser)
-					fallbackArgs = append(fallbackArgs, cookiesArg)
-				} else if a.settings.CookiesMode == "file" && a.settings.CookiesFile != "" {
-					// Check if the cookies file exists before using it
-					if _, err := os.Stat(a.settings.CookiesFile); err == nil {
-						fallbackArgs = append(fallbackArgs, "--cookies", a.settings.CookiesFile)
-					} else {
-						wailsRuntime.LogInfof(a.ctx, "Cookies file does not exist: %s, proceeding without cookies", a.settings.CookiesFile)
-					}
-				}
+				// Try with minimal arguments to get basic info
+				minimal := inv
+				minimal.Flags = []string{"--print-json", "--simulate"}
 
-				fallbackCmd := exec.Command(ytDlpPath, fallbackArgs...)
-				setHideWindow(fallbackCmd)
-
-				output, err = fallbackCmd.Output()
+				output, err = a.ytDlpCommand(minimal).Output()
 				if err != nil {
-					// If fallback also fails, log the error but try one more approach
-					wailsRuntime.LogInfof(a.ctx, "Fallback attempt also failed with stderr: %s", string(exitError.Stderr))
-
-					// Try with minimal arguments to get basic info
-					minimalArgs := []string{"--print-json", "--simulate", url}
+					a.logger.Infof("Minimal attempt also failed with stderr: %s", stderrStr)
 
-					// Add proxy settings if enabled
-					if a.settings.ProxyMode == "manual" && a.settings.ProxyAddress != "" {
-						minimalArgs = append(minimalArgs, "--proxy", a.settings.ProxyAddress)
-					}
-					// Note: When ProxyMode is "system", yt-dlp automatically uses system proxy settings
-
-					// Add cookies settings if enabled
-					if a.settings.CookiesMode == "browser" {
-						cookiesArg := fmt.Sprintf("--cookies-from-browser=%s", a.settings.CookiesBrowser)
-						minimalArgs = append(minimalArgs, cookiesArg)
-					} else if a.settings.CookiesMode == "file" && a.settings.CookiesFile != "" {
-						// Check if the cookies file exists before using it
-						if _, err := os.Stat(a.settings.CookiesFile); err == nil {
-							minimalArgs = append(minimalArgs, "--cookies", a.settings.CookiesFile)
-						} else {
-							wailsRuntime.LogInfof(a.ctx, "Cookies file does not exist: %s, proceeding without cookies", a.settings.CookiesFile)
-						}
-					}
+					// If even minimal attempt fails, list the available formats for the log
+					list := inv
+					list.Flags = []string{"--list-formats", "--simulate", "--no-warnings"}
 
-					minimalCmd := exec.Command(ytDlpPath, minimalArgs...)
-					setHideWindow(minimalCmd)
-
-					output, err = minimalCmd.Output()
-					if err != nil {
-						// If even minimal attempt fails, try with --list-formats to see what's available
-						// and then try to get basic info without format restrictions
-						listArgs := []string{"--list-formats", "--simulate", url, "--no-warnings"}
-
-						// Add proxy settings if enabled
-						if a.settings.ProxyMode == "manual" && a.settings.ProxyAddress != "" {
-							listArgs = append(listArgs, "--proxy", a.settings.ProxyAddress)
-						}
-						// Note: When ProxyMode is "system", yt-dlp automatically uses system proxy settings
-
-						// Add cookies settings if enabled
-						if a.settings.CookiesMode == "browser" {
-							cookiesArg := fmt.Sprintf("--cookies-from-browser=%s", a.settings.CookiesBrowser)
-							listArgs = append(listArgs, cookiesArg)
-						} else if a.settings.CookiesMode == "file" && a.settings.CookiesFile != "" {
-							// Check if the cookies file exists before using it
-							if _, err := os.Stat(a.settings.CookiesFile); err == nil {
-								listArgs = append(listArgs, "--cookies", a.settings.CookiesFile)
-							} else {
-								wailsRuntime.LogInfof(a.ctx, "Cookies file does not exist: %s, proceeding without cookies", a.settings.CookiesFile)
-							}
-						}
-
-						listCmd := exec.Command(ytDlpPath, listArgs...)
-						setHideWindow(listCmd)
-
-						listOutput, listErr := listCmd.CombinedOutput()
-						if listErr != nil {
-							wailsRuntime.LogInfof(a.ctx, "Format listing failed: %v, output: %s", listErr, string(listOutput))
-						} else {
-							wailsRuntime.LogInfof(a.ctx, "Available formats: %s", string(listOutput))
-						}
+					listOutput, listErr := a.ytDlpCommand(list).CombinedOutput()
+					if listErr != nil {
+						a.logger.Infof("Format listing failed: %v, output: %s", listErr, string(listOutput))
+					} else {
+						a.logger.Infof("Available formats: %s", string(listOutput))
 					}
 				}
 			} else if err != nil && !isFormatError {
@@ -205,31 +75,10 @@ func (a *App) analyzeURLInternal(url string) (string, error) {
 
 		if err != nil {
 			// Try with the older flag if --print-json fails
-			args = []string{"--dump-single-json", "--simulate", url, "--no-warnings"}
-
-			// Add proxy settings if enabled
-			if a.settings.ProxyMode == "manual" && a.settings.ProxyAddress != "" {
-				args = append(args, "--proxy", a.settings.ProxyAddress)
-			}
-			// Note: When ProxyMode is "system", yt-dlp automatically uses system proxy settings
-
-			// Add cookies settings if enabled
-			if a.settings.CookiesMode == "browser" {
-				cookiesArg := fmt.Sprintf("--cookies-from-browser=%s", a.settings.CookiesBrowser)
-				args = append(args, cookiesArg)
-			} else if a.settings.CookiesMode == "file" && a.settings.CookiesFile != "" {
-				// Check if the cookies file exists before using it
-				if _, err := os.Stat(a.settings.CookiesFile); err == nil {
-					args = append(args, "--cookies", a.settings.CookiesFile)
-				} else {
-					wailsRuntime.LogInfof(a.ctx, "Cookies file does not exist: %s, proceeding without cookies", a.settings.CookiesFile)
-				}
-			}
-
-			cmd = exec.Command(ytDlpPath, args...)
-			setHideWindow(cmd)
+			single := inv
+			single.Flags = []string{"--dump-single-json", "--simulate", "--no-warnings"}
 
-			output, err = cmd.Output()
+			output, err = a.ytDlpCommand(single).Output()
 			if err != nil {
 				if exitError, ok := err.(*exec.ExitError); ok {
 					a.logDetailedError("AnalyzeURL", url, "", fmt.Errorf("failed to analyze URL: exit status: %v, stderr: %s", exitError.ExitCode(), string(exitError.Stderr)))
@@ -251,64 +100,108 @@ func (a *App) analyzeURLInternal(url string) (string, error) {
 
 	// Skip format enrichment to speed up analysis
 	// The basic info from yt-dlp is sufficient for most use cases
-	// videoInfo.Formats = a.enrichFormatInfo(ytDlpPath, url, videoInfo.Formats)
+	// videoInfo.Formats = a.enrichFormatInfo(url, videoInfo.Formats)
+
+	withSubtitles, err := addAvailableSubtitles(output, videoInfo)
+	if err != nil {
+		a.logger.Infof("Failed to summarize subtitles for %s: %v", url, err)
+		return string(output), nil
+	}
 
 	// Return the JSON as string
-	return string(output), nil
+	return string(withSubtitles), nil
+}
+
+// subtitleLanguages lists the subtitle languages of a video, regular subtitles
+// first, each group sorted by language code
+func subtitleLanguages(info VideoInfo) []SubtitleLanguage {
+	var languages []SubtitleLanguage
+	for _, group := range []struct {
+		tracks map[string][]SubtitleTrack
+		auto   bool
+	}{{info.Subtitles, false}, {info.AutomaticCaptions, true}} {
+		codes := make([]string, 0, len(group.tracks))
+		for code := range group.tracks {
+			// yt-dlp lists live chat replays as a subtitle track
+			if code != "live_chat" {
+				codes = append(codes, code)
+			}
+		}
+		sort.Strings(codes)
+
+		for _, code := range codes {
+			language := SubtitleLanguage{Code: code, Auto: group.auto, Formats: []string{}}
+			for _, track := range group.tracks[code] {
+				if language.Name == "" {
+					language.Name = track.Name
+				}
+				language.Formats = append(language.Formats, track.Ext)
+			}
+			languages = append(languages, language)
+		}
+	}
+	return languages
+}
+
+// addAvailableSubtitles adds the available_subtitles summary to the raw yt-dlp
+// JSON, keeping every other field as yt-dlp wrote it
+func addAvailableSubtitles(output []byte, info VideoInfo) ([]byte, error) {
+	languages := subtitleLanguages(info)
+	if len(languages) == 0 {
+		return output, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(output, &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse video info: %w", err)
+	}
+	summary, err := json.Marshal(languages)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode subtitles: %w", err)
+	}
+	fields["available_subtitles"] = summary
+	return json.Marshal(fields)
 }
 
 // enrichFormatInfo attempts to get more detailed information for formats, especially file sizes
-func (a *App) enrichFormatInfo(ytDlpPath, url string, formats []Format) []Format {
+func (a *App) enrichFormatInfo(url string, formats []Format) []Format {
 	enrichedFormats := make([]Format, len(formats))
 	copy(enrichedFormats, formats)
 
+	base := a.newYtDlpInvocation(url)
+	base.Flags = []string{"--print-json", "--simulate", "--no-warnings"}
+
 	// For each format, try to get more detailed info if filesize is missing
 	for i, format := range enrichedFormats {
 		// Check if filesize is missing or invalid
 		if format.FileSize == nil || format.FileSize == 0 {
 			// Try to get detailed info for this specific format
-			args := []string{"--print-json", "--simulate", "-f", format.FormatID, url, "--no-warnings"}
+			inv := base
+			inv.FormatID = format.FormatID
 
-			// Add JS runtime if enabled or if it's a YouTube URL (which requires it)
-			if a.settings.UseJSRuntime || a.isYouTubeURL(url) {
-				if a.isDenoAvailable() {
-					args = append(args, "--js-runtimes", "deno:"+filepath.Join("./bin", a.getDenoBinaryName()))
-				} else {
-					// If deno is not available but JS runtime is required, try to download it
-					wailsRuntime.LogInfof(a.ctx, "Deno not found, attempting to download...")
-					err := a.downloadDenoWithProgress()
-					if err != nil {
-						wailsRuntime.LogErrorf(a.ctx, "Failed to download deno: %v", err)
-						// Continue without JS runtime
+			output, err := a.ytDlpCommand(inv).Output()
+			if err != nil {
+				// Check if the error is related to cookies
+				if exitError, ok := err.(*exec.ExitError); ok {
+					stderrStr := string(exitError.Stderr)
+
+					// If cookies are enabled and we get a cookies or format error, try without cookies
+					if isCookiesRelatedError(stderrStr) && inv.UsesCookies() {
+						output, err = a.ytDlpCommand(inv.NoCookies()).Output()
+						if err != nil {
+							// Log the error but continue processing other formats
+							a.logger.Infof("Failed to enrich format %s (with and without cookies): %s", format.FormatID, stderrStr)
+						}
 					} else {
-						args = append(args, "--js-runtimes", "deno:"+filepath.Join("./bin", a.getDenoBinaryName()))
+						// Log the error but continue processing other formats
+						a.logger.Infof("Failed to enrich format %s: %s", format.FormatID, stderrStr)
 					}
-				}
-			}
-
-			// Add proxy settings if enabled
-			if a.settings.ProxyMode == "manual" && a.settings.ProxyAddress != "" {
-				args = append(args, "--proxy", a.settings.ProxyAddress)
-			}
-			// Note: When ProxyMode is "system", yt-dlp automatically uses system proxy settings
-
-			// Add cookies settings if enabled
-			if a.settings.CookiesMode == "browser" {
-				cookiesArg := fmt.Sprintf("--cookies-from-browser=%s", a.settings.CookiesBrowser)
-				args = append(args, cookiesArg)
-			} else if a.settings.CookiesMode == "file" && a.settings.CookiesFile != "" {
-				// Check if the cookies file exists before using it
-				if _, err := os.Stat(a.settings.CookiesFile); err == nil {
-					args = append(args, "--cookies", a.settings.CookiesFile)
 				} else {
-					wailsRuntime.LogInfof(a.ctx, "Cookies file does not exist: %s, proceeding without cookies", a.settings.CookiesFile)
+					// Log the error but continue processing other formats
+					a.logger.Infof("Failed to enrich format %s: %v", format.FormatID, err)
 				}
 			}
 
-			cmd := exec.Command(ytDlpPath, args...)
-			setHideWindow(cmd)
-
-			output, err := cmd.Output()
 			if err == nil {
 				// Parse the detailed info
 				var detailedInfo VideoInfo
@@ -323,73 +216,6 @@ func (a *App) enrichFormatInfo(ytDlpPath, url string, formats []Format) []Format
 						enrichedFormats[i].FileSizeApprox = detailedFormat.FileSizeApprox
 					}
 				}
-			} else {
-				// Check if the error is related to cookies
-				if exitError, ok := err.(*exec.ExitError); ok {
-					stderrStr := string(exitError.Stderr)
-					isCookiesError := strings.Contains(stderrStr, "cookies") ||
-						strings.Contains(stderrStr, "Sign in to confirm") ||
-						strings.Contains(stderrStr, "authentication")
-					isFormatError := strings.Contains(stderrStr, "Requested format is not available")
-
-					// If cookies are enabled and we get a format error, try without cookies
-					if (isCookiesError || isFormatError) && (a.settings.CookiesMode == "browser" || a.settings.CookiesMode == "file") {
-						// Try without cookies
-						argsWithoutCookies := []string{"--print-json", "--simulate", "-f", format.FormatID, url, "--no-warnings"}
-
-						// Add JS runtime if enabled or if it's a YouTube URL (which requires it)
-						if a.settings.UseJSRuntime || a.isYouTubeURL(url) {
-							if a.isDenoAvailable() {
-								argsWithoutCookies = append(argsWithoutCookies, "--js-runtimes", "deno:"+filepath.Join("./bin", a.getDenoBinaryName()))
-							} else {
-								// If deno is not available but JS runtime is required, try to download it
-								wailsRuntime.LogInfof(a.ctx, "Deno not found, attempting to download...")
-								err := a.downloadDenoWithProgress()
-								if err != nil {
-									wailsRuntime.LogErrorf(a.ctx, "Failed to download deno: %v", err)
-									// Continue without JS runtime
-								} else {
-									argsWithoutCookies = append(argsWithoutCookies, "--js-runtimes", "deno:"+filepath.Join("./bin", a.getDenoBinaryName()))
-								}
-							}
-						}
-
-						// Add proxy settings if enabled (but no cookies)
-						if a.settings.ProxyMode == "manual" && a.settings.ProxyAddress != "" {
-							argsWithoutCookies = append(argsWithoutCookies, "--proxy", a.settings.ProxyAddress)
-						}
-						// Note: When ProxyMode is "system", yt-dlp automatically uses system proxy settings
-
-						cmdWithoutCookies := exec.Command(ytDlpPath, argsWithoutCookies...)
-						setHideWindow(cmdWithoutCookies)
-
-						outputWithoutCookies, errWithoutCookies := cmdWithoutCookies.Output()
-						if errWithoutCookies == nil {
-							// Parse the detailed info
-							var detailedInfo VideoInfo
-							if err := json.Unmarshal(outputWithoutCookies, &detailedInfo); err == nil && len(detailedInfo.Formats) > 0 {
-								detailedFormat := detailedInfo.Formats[0]
-
-								// Update the format with detailed info if available
-								if detailedFormat.FileSize != nil && detailedFormat.FileSize != 0 {
-									enrichedFormats[i].FileSize = detailedFormat.FileSize
-								}
-								if detailedFormat.FileSizeApprox != nil {
-									enrichedFormats[i].FileSizeApprox = detailedFormat.FileSizeApprox
-								}
-							}
-						} else {
-							// Log the error but continue processing other formats
-							wailsRuntime.LogInfof(a.ctx, "Failed to enrich format %s (with and without cookies): %s", format.FormatID, stderrStr)
-						}
-					} else {
-						// Log the error but continue processing other formats
-						wailsRuntime.LogInfof(a.ctx, "Failed to enrich format %s: %s", format.FormatID, stderrStr)
-					}
-				} else {
-					// Log the error but continue processing other formats
-					wailsRuntime.LogInfof(a.ctx, "Failed to enrich format %s: %v", format.FormatID, err)
-				}
 			}
 		}
 
@@ -416,65 +242,19 @@ func (a *App) enrichFormatInfo(ytDlpPath, url string, formats []Format) []Format
 
 // analyzePlaylistInternal analyzes a playlist URL and returns playlist information
 func (a *App) analyzePlaylistInternal(url string) (string, error) {
-	ytDlpPath := filepath.Join("./bin", a.getYtDlpBinaryName())
-
 	// Build command arguments for playlist info - use --dump-single-json to get all info in one JSON object
-	args := []string{"--dump-single-json", "--flat-playlist", "--simulate", url, "--no-warnings"}
+	inv := a.newYtDlpInvocation(url)
+	inv.Flags = []string{"--dump-single-json", "--flat-playlist", "--simulate", "--no-warnings"}
 
-	// Add JS runtime if enabled or if it's a YouTube URL (which requires it)
-	if a.settings.UseJSRuntime || a.isYouTubeURL(url) {
-		if a.isDenoAvailable() {
-			args = append(args, "--js-runtimes", "deno:"+filepath.Join("./bin", a.getDenoBinaryName()))
-		} else {
-			// If deno is not available but JS runtime is required, try to download it
-			wailsRuntime.LogInfof(a.ctx, "Deno not found, attempting to download...")
-			err := a.downloadDenoWithProgress()
-			if err != nil {
-				wailsRuntime.LogErrorf(a.ctx, "Failed to download deno: %v", err)
-				// Continue without JS runtime
-			} else {
-				args = append(args, "--js-runtimes", "deno:"+filepath.Join("./bin", a.getDenoBinaryName()))
-			}
-		}
-	}
-
-	// Add proxy settings if enabled
-	if a.settings.ProxyMode == "manual" && a.settings.ProxyAddress != "" {
-		args = append(args, "--proxy", a.settings.ProxyAddress)
-	}
-
-	// Add cookies settings if enabled
-	if a.settings.CookiesMode == "browser" {
-		cookiesArg := fmt.Sprintf("--cookies-from-browser=%s", a.settings.CookiesBrowser)
-		args = append(args, cookiesArg)
-	} else if a.settings.CookiesMode == "file" && a.settings.CookiesFile != "" {
-		if _, err := os.Stat(a.settings.CookiesFile); err == nil {
-			args = append(args, "--cookies", a.settings.CookiesFile)
-		}
-	}
-
-	// Hide console window on Windows
-	cmd := exec.Command(ytDlpPath, args...)
-	setHideWindow(cmd)
-
-	output, err := cmd.Output()
+	output, err := a.ytDlpCommand(inv).Output()
 	if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
 			stderrStr := string(exitError.Stderr)
-			wailsRuntime.LogInfof(a.ctx, "Playlist analysis failed: %s", stderrStr)
+			a.logger.Infof("Playlist analysis failed: %s", stderrStr)
 
 			// Try fallback without cookies if needed
-			if strings.Contains(stderrStr, "cookies") || strings.Contains(stderrStr, "Sign in") {
-				fallbackArgs := []string{"--dump-single-json", "--flat-playlist", "--simulate", url, "--no-warnings"}
-
-				if a.settings.ProxyMode == "manual" && a.settings.ProxyAddress != "" {
-					fallbackArgs = append(fallbackArgs, "--proxy", a.settings.ProxyAddress)
-				}
-
-				cmd = exec.Command(ytDlpPath, fallbackArgs...)
-				setHideWindow(cmd)
-
-				output, err = cmd.Output()
+			if isCookiesRelatedError(stderrStr) && inv.UsesCookies() {
+				output, err = a.ytDlpCommand(inv.NoCookies()).Output()
 			}
 		}
 
@@ -502,17 +282,11 @@ func (a *App) analyzePlaylistInternal(url string) (string, error) {
 	if entries, ok := playlistData["entries"].([]interface{}); ok {
 		for _, entry := range entries {
 			if entryMap, ok := entry.(map[string]interface{}); ok {
-				playlistEntry := PlaylistEntry{
-					ID:        getStringValue(entryMap["id"]),
-					Title:     getStringValue(entryMap["title"]),
-					Thumbnail: getStringValue(entryMap["thumbnail"]),
-					URL:       getStringValue(entryMap["url"]),
-					Duration:  getFloatValue(entryMap["duration"]),
-				}
-				playlistInfo.Entries = append(playlistInfo.Entries, playlistEntry)
+				playlistInfo.Entries = append(playlistInfo.Entries, playlistEntryFromInfo(entryMap))
 			}
 		}
 	}
+	a.markArchivedEntries(playlistInfo.Entries, filepath.Join(defaultDownloadDir, a.currentSettings().outputTemplate(true)))
 
 	// Convert back to JSON for return
 	result, err := json.Marshal(playlistInfo)
@@ -525,66 +299,20 @@ func (a *App) analyzePlaylistInternal(url string) (string, error) {
 
 // getPlaylistItemsInternal returns a list of video entries from a playlist
 func (a *App) getPlaylistItemsInternal(url string) (string, error) {
-	ytDlpPath := filepath.Join("./bin", a.getYtDlpBinaryName())
-
 	// Use --dump-json with --flat-playlist to get just the entries without full video info
-	args := []string{"--dump-json", "--flat-playlist", "--simulate", url, "--no-warnings"}
-
-	// Add JS runtime if enabled or if it's a YouTube URL (which requires it)
-	if a.settings.UseJSRuntime || a.isYouTubeURL(url) {
-		if a.isDenoAvailable() {
-			args = append(args, "--js-runtimes", "deno:"+filepath.Join("./bin", a.getDenoBinaryName()))
-		} else {
-			// If deno is not available but JS runtime is required, try to download it
-			wailsRuntime.LogInfof(a.ctx, "Deno not found, attempting to download...")
-			err := a.downloadDenoWithProgress()
-			if err != nil {
-				wailsRuntime.LogErrorf(a.ctx, "Failed to download deno: %v", err)
-				// Continue without JS runtime
-			} else {
-				args = append(args, "--js-runtimes", "deno:"+filepath.Join("./bin", a.getDenoBinaryName()))
-			}
-		}
-	}
-
-	// Add proxy settings if enabled
-	if a.settings.ProxyMode == "manual" && a.settings.ProxyAddress != "" {
-		args = append(args, "--proxy", a.settings.ProxyAddress)
-	}
-
-	// Add cookies settings if enabled
-	if a.settings.CookiesMode == "browser" {
-		cookiesArg := fmt.Sprintf("--cookies-from-browser=%s", a.settings.CookiesBrowser)
-		args = append(args, cookiesArg)
-	} else if a.settings.CookiesMode == "file" && a.settings.CookiesFile != "" {
-		if _, err := os.Stat(a.settings.CookiesFile); err == nil {
-			args = append(args, "--cookies", a.settings.CookiesFile)
-		}
-	}
-
-	// Hide console window on Windows
-	cmd := exec.Command(ytDlpPath, args...)
-	setHideWindow(cmd)
+	inv := a.newYtDlpInvocation(url)
+	inv.Flags = []string{"--dump-json", "--flat-playlist", "--simulate", "--no-warnings"}
 
-	output, err := cmd.Output()
+	output, err := a.ytDlpCommand(inv).Output()
 	if err != nil {
 		// Log the error but try to continue with fallback
 		if exitError, ok := err.(*exec.ExitError); ok {
 			stderrStr := string(exitError.Stderr)
-			wailsRuntime.LogInfof(a.ctx, "GetPlaylistItems failed: %s", stderrStr)
+			a.logger.Infof("GetPlaylistItems failed: %s", stderrStr)
 
 			// Try fallback without cookies if needed
-			if strings.Contains(stderrStr, "cookies") || strings.Contains(stderrStr, "Sign in") {
-				fallbackArgs := []string{"--dump-json", "--flat-playlist", "--simulate", url, "--no-warnings"}
-
-				if a.settings.ProxyMode == "manual" && a.settings.ProxyAddress != "" {
-					fallbackArgs = append(fallbackArgs, "--proxy", a.settings.ProxyAddress)
-				}
-
-				cmd = exec.Command(ytDlpPath, fallbackArgs...)
-				setHideWindow(cmd)
-
-				output, err = cmd.Output()
+			if isCookiesRelatedError(stderrStr) && inv.UsesCookies() {
+				output, err = a.ytDlpCommand(inv.NoCookies()).Output()
 			}
 		}
 
@@ -608,15 +336,9 @@ func (a *App) getPlaylistItemsInternal(url string) (string, error) {
 			continue // Skip invalid lines
 		}
 
-		entry := PlaylistEntry{
-			ID:        getStringValue(entryData["id"]),
-			Title:     getStringValue(entryData["title"]),
-			Thumbnail: getStringValue(entryData["thumbnail"]),
-			URL:       getStringValue(entryData["url"]),
-			Duration:  getFloatValue(entryData["duration"]),
-		}
-		entries = append(entries, entry)
+		entries = append(entries, playlistEntryFromInfo(entryData))
 	}
+	a.markArchivedEntries(entries, filepath.Join(defaultDownloadDir, a.currentSettings().outputTemplate(true)))
 
 	// Create a PlaylistInfo-like structure with just the entries
 	playlistItems := struct {
@@ -659,63 +381,34 @@ func getFloatValue(value interface{}) float64 {
 	return 0.0
 }
 
-// downloadPlaylistInternal downloads an entire playlist
-func (a *App) downloadPlaylistInternal(url, formatID, outputPath string, startItem, endItem int) error {
-	ytDlpPath := filepath.Join("./bin", a.getYtDlpBinaryName())
-
-	// Build command arguments for playlist download
-	args := []string{url, "-f", formatID, "-o", outputPath, "--newline", "--progress", "--continue", "--part", "--ignore-errors"}
-
-	// Add JS runtime if enabled or if it's a YouTube URL (which requires it)
-	if a.settings.UseJSRuntime || a.isYouTubeURL(url) {
-		if a.isDenoAvailable() {
-			args = append(args, "--js-runtimes", "deno:"+filepath.Join("./bin", a.getDenoBinaryName()))
-		} else {
-			// If deno is not available but JS runtime is required, try to download it
-			wailsRuntime.LogInfof(a.ctx, "Deno not found, attempting to download...")
-			err := a.downloadDenoWithProgress()
-			if err != nil {
-				wailsRuntime.LogErrorf(a.ctx, "Failed to download deno: %v", err)
-				// Continue without JS runtime
-			} else {
-				args = append(args, "--js-runtimes", "deno:"+filepath.Join("./bin", a.getDenoBinaryName()))
-			}
-		}
-	}
-
-	// Add playlist range if specified
-	if startItem > 0 {
-		if endItem > 0 {
-			args = append(args, "--playlist-items", fmt.Sprintf("%d-%d", startItem, endItem))
-		} else {
-			args = append(args, "--playlist-items", fmt.Sprintf("%d-", startItem))
-		}
-	}
+// enqueuePlaylistInternal queues a playlist download and returns its job ID
+func (a *App) enqueuePlaylistInternal(url, formatID, outputPath string, startItem, endItem int, optionsJSON string) (string, error) {
+	options, err := parseDownloadOptions(optionsJSON)
+	if err != nil {
+		return "", err
+	}
+
+	return a.enqueueJob(&DownloadJob{
+		URL:        url,
+		FormatID:   formatID,
+		OutputPath: outputPath,
+		Playlist:   true,
+		StartItem:  startItem,
+		EndItem:    endItem,
+		Options:    options,
+	}), nil
+}
 
-	// Add proxy settings if enabled
-	if a.settings.ProxyMode == "manual" && a.settings.ProxyAddress != "" {
-		args = append(args, "--proxy", a.settings.ProxyAddress)
-	}
+// startPlaylistDownload starts the yt-dlp process for a playlist job
+func (a *App) startPlaylistDownload(job *DownloadJob) error {
+	inv := a.downloadInvocation(job)
+	cmd := a.ytDlpCommand(inv)
 
-	// Add cookies settings if enabled
-	if a.settings.CookiesMode == "browser" {
-		cookiesArg := fmt.Sprintf("--cookies-from-browser=%s", a.settings.CookiesBrowser)
-		args = append(args, cookiesArg)
-	} else if a.settings.CookiesMode == "file" && a.settings.CookiesFile != "" {
-		if _, err := os.Stat(a.settings.CookiesFile); err == nil {
-			args = append(args, "--cookies", a.settings.CookiesFile)
-		}
+	// Store the download command on the job for cancellation
+	if !a.setJobCommand(job, cmd) {
+		return nil // Job was stopped before the process started
 	}
 
-	// Hide console window on Windows
-	cmd := exec.Command(ytDlpPath, args...)
-	setHideWindow(cmd)
-
-	// Store the current download command for cancellation
-	downloadMutex.Lock()
-	currentDownloadCmd = cmd
-	downloadMutex.Unlock()
-
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return fmt.Errorf("failed to create stdout pipe: %w", err)
@@ -730,366 +423,184 @@ func (a *App) downloadPlaylistInternal(url, formatID, outputPath string, startIt
 		return fmt.Errorf("failed to start download: %w", err)
 	}
 
-	wailsRuntime.LogInfof(a.ctx, "Playlist download started for URL: %s", url)
-
-	// Use a shared progress tracker with optimized parsing
-	// Pre-compile regex patterns for better performance
-	progressTracker := struct {
-		mu               sync.Mutex
-		previousProgress int
-		maxProgress      int
-		lastUpdateTime   time.Time
-	}{
-		previousProgress: -1,
-		maxProgress:      -1,
-		lastUpdateTime:   time.Time{},
+	// The job may have been stopped while the process was starting
+	if !a.jobCommandActive(job, cmd) {
+		cmd.Process.Kill()
 	}
 
-	// Pre-compiled regex patterns for playlist progress
-	reProgressMain := regexp.MustCompile(`\[download\]\s+([0-9]{1,3}(?:\.[0-9]+)?)%\s+of\s+(~?[0-9.]+\s*[KMGT]?i?B)\s+at\s+([0-9.]+\s*[KMGT]?i?B/s|unknown)\s+ETA\s+([0-9:]+|unknown)`)
-	reProgressFlexible := regexp.MustCompile(`\[download\]\s+([0-9]{1,3}(?:\.[0-9]+)?)%.*?of\s+([0-9.]+\s*[KMGT]?i?B).*?at\s+([0-9.]+\s*[KMGT]?i?B/s).*?ETA\s+([0-9:]+)`)
-	reProgressSimple := regexp.MustCompile(`\[download\][^0-9]*([0-9]{1,3})\.?[0-9]*%`)
+	a.logger.Infof("Playlist download job %s started for URL: %s", job.ID, job.URL)
 
-	// Read stdout and stderr in real-time to get progress and errors
-	go func() {
-		buffer := make([]byte, 1024)
-
-		for {
-			n, err := stdout.Read(buffer)
-			if n > 0 {
-				output := string(buffer[:n])
+	progress := newProgressReporter(a, job)
 
-				// Log the raw output for debugging
-				if strings.Contains(output, "[download]") {
-					wailsRuntime.LogInfof(a.ctx, "Raw playlist download output: %s", output)
-				}
-
-				// Look for progress indicators in the output
-				matches := reProgressMain.FindStringSubmatch(output)
-
-				// If the main pattern doesn't match, try a more flexible one
-				if len(matches) < 5 {
-					matches = reProgressFlexible.FindStringSubmatch(output)
-				}
-
-				if len(matches) >= 5 {
-					progress, convErr := strconv.ParseFloat(matches[1], 64)
-					if convErr == nil && progress >= 0 && progress <= 100 {
-						// Only emit progress if it's different from the previous value
-						// Also prevent progress from going backwards
-						progressTracker.mu.Lock()
-						if int(progress) > progressTracker.maxProgress {
-							progressTracker.maxProgress = int(progress)
-						}
-						// Throttle progress updates to once every 100ms
-						shouldEmit := int(progress) != progressTracker.previousProgress &&
-							int(progress) >= progressTracker.maxProgress &&
-							time.Since(progressTracker.lastUpdateTime) > 100*time.Millisecond
-
-						if shouldEmit {
-							progressTracker.previousProgress = int(progress)
-							progressTracker.lastUpdateTime = time.Now()
-							progressTracker.mu.Unlock()
-
-							// Handle "unknown" values
-							size := matches[2]
-							speed := matches[3]
-							eta := matches[4]
-
-							if speed == "unknown" {
-								speed = "Calculating..."
-							}
-							if eta == "unknown" {
-								eta = "Calculating..."
-							}
-
-							// Emit progress event with additional information
-							wailsRuntime.EventsEmit(a.ctx, "download-progress", map[string]interface{}{
-								"progress": int(progress),
-								"size":     size,
-								"speed":    speed,
-								"eta":      eta,
-							})
-						} else {
-							progressTracker.mu.Unlock()
-						}
-					}
-				} else {
-					// Fallback to simpler pattern: [download]  XX.X%
-					matchesSimple := reProgressSimple.FindStringSubmatch(output)
-					if len(matchesSimple) > 1 {
-						progress, convErr := strconv.Atoi(matchesSimple[1])
-						if convErr == nil && progress >= 0 && progress <= 100 {
-							// Only emit progress if it's different from the previous value
-							progressTracker.mu.Lock()
-							if progress != progressTracker.previousProgress {
-								progressTracker.previousProgress = progress
-								progressTracker.mu.Unlock()
-
-								// Emit progress event with basic information
-								wailsRuntime.EventsEmit(a.ctx, "download-progress", map[string]interface{}{
-									"progress": progress,
-									"size":     "Calculating...",
-									"speed":    "Calculating...",
-									"eta":      "Calculating...",
-								})
-							} else {
-								progressTracker.mu.Unlock()
-							}
-						}
-					}
-				}
-
-			}
+	// Drain both pipes before waiting, as in startVideoDownload
+	var readers sync.WaitGroup
+	readers.Add(2)
 
-			if err == io.EOF {
-				break
-			}
-
-			if err != nil {
-				wailsRuntime.LogErrorf(a.ctx, "Error reading stdout: %v", err)
-				break
-			}
+	// Read stdout in real-time to get progress
+	go func() {
+		defer readers.Done()
+		if err := scanOutputLines(stdout, func(line string) { progress.handleLine(line) }); err != nil {
+			a.logger.Errorf("Error reading stdout: %v", err)
 		}
 	}()
 
 	// Read stderr to check for cookies-related errors
+	var onCookiesError func(string)
+	if inv.UsesCookies() {
+		onCookiesError = func(stderrStr string) {
+			a.logger.Infof("Cookies-related error detected during playlist download: %s", stderrStr)
+			a.retryPlaylistDownloadWithoutCookies(job, inv, cmd, progress)
+		}
+	}
+	var lastError string
 	go func() {
-		buffer := make([]byte, 4096)
-		var stderrContent strings.Builder
-
-		for {
-			n, err := stderr.Read(buffer)
-			if n > 0 {
-				stderrContent.Write(buffer[:n])
-				stderrStr := stderrContent.String()
-
-				// Check for cookies-related errors
-				if strings.Contains(stderrStr, "cookies") ||
-					strings.Contains(stderrStr, "Sign in to confirm") ||
-					strings.Contains(stderrStr, "authentication") ||
-					strings.Contains(stderrStr, "Requested format is not available") {
-
-					wailsRuntime.LogInfof(a.ctx, "Cookies-related error detected during playlist download: %s", stderrStr)
-
-					// Kill the current process and try without cookies
-					if cmd.Process != nil {
-						wailsRuntime.LogInfof(a.ctx, "Killing current playlist download process due to cookies error")
-						cmd.Process.Kill()
-					}
-
-					// Clear the current download command
-					downloadMutex.Lock()
-					currentDownloadCmd = nil
-					downloadMutex.Unlock()
+		defer readers.Done()
+		lastError = a.scanDownloadStderr(stderr, progress, onCookiesError)
+	}()
 
-					// Try downloading without cookies
-					argsWithoutCookies := []string{url, "-f", formatID, "-o", outputPath, "--newline", "--progress", "--continue", "--part", "--ignore-errors"}
+	// Wait for the command to finish
+	go func() {
+		readers.Wait()
+		waitErr := withStderrError(cmd.Wait(), lastError)
+		if !a.jobCommandActive(job, cmd) {
+			return // Job was stopped or its process was replaced by a retry
+		}
+		a.finishPlaylistDownload(job, progress, waitErr, "")
+	}()
 
-					// Add playlist range if specified
-					if startItem > 0 {
-						if endItem > 0 {
-							argsWithoutCookies = append(argsWithoutCookies, "--playlist-items", fmt.Sprintf("%d-%d", startItem, endItem))
-						} else {
-							argsWithoutCookies = append(argsWithoutCookies, "--playlist-items", fmt.Sprintf("%d-", startItem))
-						}
-					}
+	return nil
+}
 
-					// Add proxy settings if enabled (but no cookies)
-					if a.settings.ProxyMode == "manual" && a.settings.ProxyAddress != "" {
-						argsWithoutCookies = append(argsWithoutCookies, "--proxy", a.settings.ProxyAddress)
-					} else if a.settings.ProxyMode == "system" {
-						argsWithoutCookies = append(argsWithoutCookies, "--proxy", "system")
-					}
+// retryPlaylistDownloadWithoutCookies replaces a playlist download process that
+// failed because of cookies with a new one that runs without them
+func (a *App) retryPlaylistDownloadWithoutCookies(job *DownloadJob, inv YtDlpInvocation, cmd *exec.Cmd, progress *progressReporter) {
+	// Detach the current process from the job so its exit is not reported
+	if !a.setJobCommand(job, nil) {
+		return // Job was stopped by the user
+	}
 
-					cmdWithoutCookies := exec.Command(ytDlpPath, argsWithoutCookies...)
-					setHideWindow(cmdWithoutCookies)
+	// Kill the current process and try without cookies
+	if cmd.Process != nil {
+		a.logger.Infof("Killing current playlist download process due to cookies error")
+		cmd.Process.Kill()
+	}
 
-					// Store the retry download command
-					downloadMutex.Lock()
-					currentDownloadCmd = cmdWithoutCookies
-					downloadMutex.Unlock()
+	cmdWithoutCookies := a.ytDlpCommand(inv.NoCookies())
+	progress.resetPlaylist()
 
-					stdoutWithoutCookies, err := cmdWithoutCookies.StdoutPipe()
-					if err != nil {
-						wailsRuntime.LogErrorf(a.ctx, "Failed to create stdout pipe for retry: %v", err)
-						return
-					}
+	// Store the retry download command
+	if !a.setJobCommand(job, cmdWithoutCookies) {
+		return // Job was stopped by the user
+	}
 
-					if err := cmdWithoutCookies.Start(); err != nil {
-						wailsRuntime.LogErrorf(a.ctx, "Failed to start retry download: %v", err)
-						return
-					}
+	stdoutWithoutCookies, err := cmdWithoutCookies.StdoutPipe()
+	if err != nil {
+		a.logger.Errorf("Failed to create stdout pipe for retry: %v", err)
+		a.emitDownloadEvent(job, "download-error", map[string]interface{}{
+			"error": fmt.Sprintf("Playlist download failed (retry): %v", err),
+		})
+		return
+	}
 
-					wailsRuntime.LogInfof(a.ctx, "Retry playlist download started without cookies")
-
-					// Read progress from the retry command
-					go func() {
-						buffer := make([]byte, 1024)
-
-						for {
-							n, err := stdoutWithoutCookies.Read(buffer)
-							if n > 0 {
-								output := string(buffer[:n])
-
-								// Log the raw output for debugging
-								if strings.Contains(output, "[download]") {
-									wailsRuntime.LogInfof(a.ctx, "Raw retry playlist download output: %s", output)
-								}
-
-								// Look for progress indicators in the output
-								// More flexible pattern to handle various yt-dlp output formats
-								re := regexp.MustCompile(`\[download\]\s+([0-9]{1,3}(?:\.[0-9]+)?)%\s+of\s+(~?[0-9.]+\s*[KMGT]?i?B)\s+at\s+([0-9.]+\s*[KMGT]?i?B/s|unknown)\s+ETA\s+([0-9:]+|unknown)`)
-								matches := re.FindStringSubmatch(output)
-
-								// If the main pattern doesn't match, try a more flexible one
-								if len(matches) < 5 {
-									reFlexible := regexp.MustCompile(`\[download\]\s+([0-9]{1,3}(?:\.[0-9]+)?)%.*?of\s+([0-9.]+\s*[KMGT]?i?B).*?at\s+([0-9.]+\s*[KMGT]?i?B/s).*?ETA\s+([0-9:]+)`)
-									matches = reFlexible.FindStringSubmatch(output)
-								}
-
-								if len(matches) >= 5 {
-									progress, convErr := strconv.ParseFloat(matches[1], 64)
-									if convErr == nil && progress >= 0 && progress <= 100 {
-										// Only emit progress if it's different from the previous value
-										// Also prevent progress from going backwards
-										progressTracker.mu.Lock()
-										if int(progress) > progressTracker.maxProgress {
-											progressTracker.maxProgress = int(progress)
-										}
-										if int(progress) != progressTracker.previousProgress && int(progress) >= progressTracker.maxProgress {
-											progressTracker.previousProgress = int(progress)
-											progressTracker.mu.Unlock()
-
-											// Handle "unknown" values
-											size := matches[2]
-											speed := matches[3]
-											eta := matches[4]
-
-											if speed == "unknown" {
-												speed = "Calculating..."
-											}
-											if eta == "unknown" {
-												eta = "Calculating..."
-											}
-
-											wailsRuntime.EventsEmit(a.ctx, "download-progress", map[string]interface{}{
-												"progress": int(progress),
-												"size":     size,
-												"speed":    speed,
-												"eta":      eta,
-											})
-										} else {
-											progressTracker.mu.Unlock()
-										}
-									}
-								} else {
-									reSimple := regexp.MustCompile(`\[download\][^0-9]*([0-9]{1,3})\.?[0-9]*%`)
-									matchesSimple := reSimple.FindStringSubmatch(output)
-									if len(matchesSimple) > 1 {
-										progress, convErr := strconv.Atoi(matchesSimple[1])
-										if convErr == nil && progress >= 0 && progress <= 100 {
-											progressTracker.mu.Lock()
-											if progress != progressTracker.previousProgress {
-												progressTracker.previousProgress = progress
-												progressTracker.mu.Unlock()
-												wailsRuntime.EventsEmit(a.ctx, "download-progress", map[string]interface{}{
-													"progress": progress,
-													"size":     "Calculating...",
-													"speed":    "Calculating...",
-													"eta":      "Calculating...",
-												})
-											} else {
-												progressTracker.mu.Unlock()
-											}
-										}
-									}
-								}
-
-							}
-
-							if err == io.EOF {
-								break
-							}
-
-							if err != nil {
-								wailsRuntime.LogErrorf(a.ctx, "Error reading stdout (retry): %v", err)
-								break
-							}
-						}
+	stderrWithoutCookies, err := cmdWithoutCookies.StderrPipe()
+	if err != nil {
+		a.logger.Errorf("Failed to create stderr pipe for retry: %v", err)
+		a.emitDownloadEvent(job, "download-error", map[string]interface{}{
+			"error": fmt.Sprintf("Playlist download failed (retry): %v", err),
+		})
+		return
+	}
 
-						waitErr := cmdWithoutCookies.Wait()
-						// Clear the current download command
-						downloadMutex.Lock()
-						currentDownloadCmd = nil
-						downloadMutex.Unlock()
-
-						if waitErr != nil {
-							wailsRuntime.LogErrorf(a.ctx, "Playlist download failed (retry): %v", waitErr)
-							errorMsg := fmt.Sprintf("Playlist download failed (retry): %v", waitErr)
-							a.logDetailedError("DownloadPlaylist", url, formatID, waitErr)
-							emitDownloadEvent(a.ctx, "download-error", errorMsg)
-						} else {
-							wailsRuntime.LogInfof(a.ctx, "Playlist download completed successfully (retry) for URL: %s, Format: %s", url, formatID)
-							progressTracker.mu.Lock()
-							if progressTracker.previousProgress != 100 {
-								wailsRuntime.EventsEmit(a.ctx, "download-progress", map[string]interface{}{
-									"progress": 100,
-									"size":     "Complete",
-									"speed":    "0",
-									"eta":      "00:00",
-								})
-							}
-							progressTracker.mu.Unlock()
-							emitDownloadEvent(a.ctx, "download-complete")
-						}
-					}()
+	if err := cmdWithoutCookies.Start(); err != nil {
+		a.logger.Errorf("Failed to start retry download: %v", err)
+		a.emitDownloadEvent(job, "download-error", map[string]interface{}{
+			"error": fmt.Sprintf("Playlist download failed (retry): %v", err),
+		})
+		return
+	}
 
-					return
-				}
-			}
+	a.logger.Infof("Retry playlist download started without cookies")
 
-			if err == io.EOF {
-				break
-			}
+	var readers sync.WaitGroup
+	readers.Add(2)
 
-			if err != nil {
-				wailsRuntime.LogErrorf(a.ctx, "Error reading stderr: %v", err)
-				break
-			}
+	// Read progress from the retry command
+	go func() {
+		defer readers.Done()
+		if err := scanOutputLines(stdoutWithoutCookies, func(line string) { progress.handleLine(line) }); err != nil {
+			a.logger.Errorf("Error reading stdout (retry): %v", err)
 		}
 	}()
 
+	// Read stderr for errors, as for the first attempt but without another retry
+	var lastError string
+	go func() {
+		defer readers.Done()
+		lastError = a.scanDownloadStderr(stderrWithoutCookies, progress, nil)
+	}()
+
 	// Wait for the command to finish
 	go func() {
-		waitErr := cmd.Wait()
-		// Clear the current download command
-		downloadMutex.Lock()
-		currentDownloadCmd = nil
-		downloadMutex.Unlock()
-
-		if waitErr != nil {
-			wailsRuntime.LogErrorf(a.ctx, "Playlist download failed: %v", waitErr)
-			a.logDetailedError("DownloadPlaylist", url, formatID, waitErr)
-			emitDownloadEvent(a.ctx, "download-error", fmt.Sprintf("Playlist download failed: %v", waitErr))
-		} else {
-			wailsRuntime.LogInfof(a.ctx, "Playlist download completed successfully for URL: %s, Format: %s", url, formatID)
-			// Ensure we emit 100% progress when download completes
-			progressTracker.mu.Lock()
-			if progressTracker.previousProgress != 100 {
-				wailsRuntime.EventsEmit(a.ctx, "download-progress", map[string]interface{}{
-					"progress": 100,
-					"size":     "Complete",
-					"speed":    "0",
-					"eta":      "00:00",
-				})
-			}
-			progressTracker.mu.Unlock()
-			emitDownloadEvent(a.ctx, "download-complete")
+		readers.Wait()
+		waitErr := withStderrError(cmdWithoutCookies.Wait(), lastError)
+		if !a.jobCommandActive(job, cmdWithoutCookies) {
+			return // Job was stopped by the user
 		}
+		a.finishPlaylistDownload(job, progress, waitErr, "(retry)")
 	}()
+}
 
-	return nil
+// finishPlaylistDownload emits the terminal event for a finished playlist process.
+// attempt is appended to log messages, e.g. "(retry)".
+func (a *App) finishPlaylistDownload(job *DownloadJob, progress *progressReporter, waitErr error, attempt string) {
+	logSuffix := ""
+	if attempt != "" {
+		logSuffix = " " + attempt
+	}
+
+	summary := progress.playlistSummary()
+	for _, itemErr := range summary.Errors {
+		a.logger.Warningf("Playlist item %d (%s) failed%s: %s", itemErr.ItemIndex, itemErr.VideoID, logSuffix, itemErr.Error)
+	}
+
+	if maxDownloadsReached(job, waitErr) {
+		a.logger.Infof("Playlist download stopped after %d downloads%s", job.Filter.MaxDownloads, logSuffix)
+		waitErr = nil
+	}
+	if partialPlaylistFailure(summary, waitErr) {
+		a.logger.Warningf("Playlist download finished with %d failed items%s", summary.Failed, logSuffix)
+		waitErr = nil
+	}
+	a.emitDownloadEvent(job, "playlist-download-summary", map[string]interface{}{
+		"total":     summary.Total,
+		"succeeded": summary.Succeeded,
+		"failed":    summary.Failed,
+		"skipped":   summary.Skipped,
+		"errors":    summary.Errors,
+	})
+
+	if waitErr != nil {
+		a.logger.Errorf("Playlist download failed%s: %v", logSuffix, waitErr)
+		a.logDetailedError("DownloadPlaylist", job.URL, job.FormatID, waitErr)
+		a.emitDownloadEvent(job, "download-error", map[string]interface{}{
+			"error": fmt.Sprintf("Playlist download failed%s: %v", logSuffix, waitErr),
+		})
+		return
+	}
+
+	files := progress.outputFiles()
+	a.logger.Infof("Playlist download completed%s for URL: %s, Format: %s, files: %d, succeeded: %d, failed: %d, skipped: %d",
+		logSuffix, job.URL, job.FormatID, len(files), summary.Succeeded, summary.Failed, summary.Skipped)
+	a.setJobFilePath(job, playlistFolder(files, job.OutputPath))
+	// Ensure we emit 100% progress when download completes
+	progress.finish()
+	a.emitDownloadEvent(job, "download-complete", map[string]interface{}{
+		"file_path": job.FilePath,
+		"files":     files,
+		"succeeded": summary.Succeeded,
+		"failed":    summary.Failed,
+		"skipped":   summary.Skipped,
+	})
 }
 
 // getClipboardTextInternal returns the current text from clipboard
@@ -1170,7 +681,7 @@ func (a *App) processDroppedFilesInternal(filePaths []string) (string, error) {
 
 		// Check if file exists
 		if _, err := os.Stat(path); os.IsNotExist(err) {
-			wailsRuntime.LogInfof(a.ctx, "Dropped file does not exist: %s", path)
+			a.logger.Infof("Dropped file does not exist: %s", path)
 			continue
 		}
 
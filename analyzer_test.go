@@ -1,6 +1,9 @@
 package main
 
 import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -31,48 +34,28 @@ func TestFormatFileSize(t *testing.T) {
 func TestGetDownloadPath(t *testing.T) {
 	app := &App{}
 
-	// Test basic title sanitization
-	title := "Test Video: The Movie"
-	path := app.GetDownloadPath(title)
-
-	if path == "" {
-		t.Error("GetDownloadPath returned empty string")
+	// Without settings the default templates are used
+	path := app.GetDownloadPath(false)
+	if path != filepath.Join(defaultDownloadDir, defaultOutputTemplate) {
+		t.Errorf("GetDownloadPath(false) = %s, want the default template", path)
 	}
 
-	// Verify download directory is used (cross-platform check)
-	// Note: Title has spaces and colon, both replaced with _
-	// "Test Video: The Movie" -> "Test_Video__The_Movie"
-	if !contains(path, "Test_Video__The_Movie") {
-		t.Errorf("Path should contain sanitized title, got: %s", path)
+	playlistPath := app.GetDownloadPath(true)
+	if !contains(playlistPath, "%(playlist_index)s") {
+		t.Errorf("Playlist path should number the items, got: %s", playlistPath)
 	}
 }
 
-func TestGetDownloadPathSpecialChars(t *testing.T) {
+func TestGetDownloadPathUsesSettings(t *testing.T) {
 	app := &App{}
+	app.settings.OutputTemplate = "%(uploader)s/%(title)s [%(id)s].%(ext)s"
 
-	// Test special characters that should be sanitized
-	title := "Test/Video\\File:Name*?\"<>|"
-	path := app.GetDownloadPath(title)
-
-	// Extract just the filename from the path to check sanitization
-	// The path separator (/ or \) is valid in the directory part
-	filename := path
-	if lastSlash := strings.LastIndex(path, "/"); lastSlash >= 0 {
-		filename = path[lastSlash+1:]
-	}
-	if lastBackslash := strings.LastIndex(filename, "\\"); lastBackslash >= 0 {
-		filename = filename[lastBackslash+1:]
-	}
+	path := app.GetDownloadPath(false)
 
-	// All special characters in the filename should be replaced with _
-	if contains(filename, "/") || contains(filename, ":") ||
-		contains(filename, "*") || contains(filename, "?") || contains(filename, "\"") ||
-		contains(filename, "<") || contains(filename, ">") || contains(filename, "|") ||
-		contains(filename, "\\") {
-		t.Errorf("Filename should not contain special characters, got: %s", filename)
+	// The template is passed to yt-dlp unchanged, inside the download directory
+	if !strings.HasSuffix(filepath.ToSlash(path), "%(uploader)s/%(title)s [%(id)s].%(ext)s") {
+		t.Errorf("Path should end with the configured template, got: %s", path)
 	}
-
-	// Verify the path uses the correct directory
 	if !contains(path, "downloads") {
 		t.Errorf("Path should contain downloads directory, got: %s", path)
 	}
@@ -123,3 +106,59 @@ func containsAt(s, substr string, start int) bool {
 	}
 	return false
 }
+
+func TestSubtitleLanguages(t *testing.T) {
+	info := VideoInfo{
+		Subtitles: map[string][]SubtitleTrack{
+			"en":        {{Ext: "vtt", Name: "English"}, {Ext: "srv3", Name: "English"}},
+			"de":        {{Ext: "vtt", Name: "German"}},
+			"live_chat": {{Ext: "json"}},
+		},
+		AutomaticCaptions: map[string][]SubtitleTrack{
+			"fr": {{Ext: "vtt"}},
+		},
+	}
+
+	expected := []SubtitleLanguage{
+		{Code: "de", Name: "German", Formats: []string{"vtt"}},
+		{Code: "en", Name: "English", Formats: []string{"vtt", "srv3"}},
+		{Code: "fr", Formats: []string{"vtt"}, Auto: true},
+	}
+	if got := subtitleLanguages(info); !reflect.DeepEqual(got, expected) {
+		t.Errorf("subtitleLanguages() = %+v, want %+v", got, expected)
+	}
+}
+
+func TestAddAvailableSubtitles(t *testing.T) {
+	output := []byte(`{"id":"abc","title":"Clip","subtitles":{"en":[{"ext":"vtt","url":"https://example.com/en.vtt","name":"English"}]}}`)
+
+	var info VideoInfo
+	if err := json.Unmarshal(output, &info); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	result, err := addAvailableSubtitles(output, info)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var got struct {
+		Title              string                     `json:"title"`
+		Subtitles          map[string]json.RawMessage `json:"subtitles"`
+		AvailableSubtitles []SubtitleLanguage         `json:"available_subtitles"`
+	}
+	if err := json.Unmarshal(result, &got); err != nil {
+		t.Fatalf("result is not valid JSON: %v", err)
+	}
+	if got.Title != "Clip" || got.Subtitles["en"] == nil {
+		t.Errorf("original fields were not kept: %s", result)
+	}
+	expected := []SubtitleLanguage{{Code: "en", Name: "English", Formats: []string{"vtt"}}}
+	if !reflect.DeepEqual(got.AvailableSubtitles, expected) {
+		t.Errorf("available_subtitles = %+v, want %+v", got.AvailableSubtitles, expected)
+	}
+
+	noSubtitles := []byte(`{"id":"abc"}`)
+	if result, err := addAvailableSubtitles(noSubtitles, VideoInfo{}); err != nil || string(result) != string(noSubtitles) {
+		t.Errorf("output without subtitles should be returned unchanged, got %s, %v", result, err)
+	}
+}
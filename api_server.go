@@ -0,0 +1,463 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultAPIPort is the loopback port of the local API when none is configured
+const defaultAPIPort = 9817
+
+// apiKeepAliveInterval is how often an idle event stream gets a comment line,
+// so proxies and clients do not time it out
+const apiKeepAliveInterval = 30 * time.Second
+
+// apiEvent is an event as sent to event stream subscribers
+type apiEvent struct {
+	Name string
+	Data []byte
+}
+
+// eventBroker is an EventSink that forwards events to the clients of the
+// local API's event stream
+type eventBroker struct {
+	mu          sync.Mutex
+	subscribers map[chan apiEvent]struct{}
+}
+
+// newEventBroker creates a broker without subscribers
+func newEventBroker() *eventBroker {
+	return &eventBroker{subscribers: make(map[chan apiEvent]struct{})}
+}
+
+// Emit sends the event to every subscriber. Subscribers that fall behind
+// miss events rather than blocking the download that emitted them.
+func (b *eventBroker) Emit(name string, data ...interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.subscribers) == 0 {
+		return
+	}
+
+	var payload interface{}
+	switch len(data) {
+	case 0:
+	case 1:
+		payload = data[0]
+	default:
+		payload = data
+	}
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- apiEvent{Name: name, Data: encoded}:
+		default:
+		}
+	}
+}
+
+// subscribe registers a new subscriber. The returned function unregisters it.
+func (b *eventBroker) subscribe() (<-chan apiEvent, func()) {
+	ch := make(chan apiEvent, 64)
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subscribers, ch)
+		b.mu.Unlock()
+	}
+}
+
+// apiJobRequest is the body of POST /jobs
+type apiJobRequest struct {
+	URL        string          `json:"url"`
+	FormatID   string          `json:"format_id"`   // Empty for yt-dlp's default format
+	OutputPath string          `json:"output_path"` // Template relative to the download directory, empty for the configured one
+	Options    json.RawMessage `json:"options"`     // DownloadOptions
+	Playlist   bool            `json:"playlist"`
+	StartItem  int             `json:"start_item"`
+	EndItem    int             `json:"end_item"`
+	Items      []int           `json:"items"`  // Playlist indices instead of the range
+	IDs        []string        `json:"ids"`    // Playlist entry IDs instead of the range
+	Filter     *PlaylistFilter `json:"filter"` // Playlist filters
+}
+
+// apiOriginPrefixes are the browser origins allowed to call the local API.
+// Browser extensions are; web pages are not.
+var apiOriginPrefixes = []string{"chrome-extension://", "moz-extension://", "safari-web-extension://"}
+
+// apiURLRequest is the body of POST /analyze
+type apiURLRequest struct {
+	URL string `json:"url"`
+}
+
+// apiServer is the running local HTTP API
+type apiServer struct {
+	server   *http.Server
+	listener net.Listener
+}
+
+// apiPort returns the configured port of the local API
+func (s Settings) apiPort() int {
+	if s.APIPort < 1 || s.APIPort > 65535 {
+		return defaultAPIPort
+	}
+	return s.APIPort
+}
+
+// newAPIToken generates a random token for the local API
+func newAPIToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate API token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// startAPIServer starts the local API on the loopback interface, replacing a
+// server that is already running
+func (a *App) startAPIServer() error {
+	a.apiMu.Lock()
+	defer a.apiMu.Unlock()
+
+	a.stopAPIServerLocked()
+
+	settings := a.currentSettings()
+	if settings.APIToken == "" {
+		return fmt.Errorf("local API requires a token")
+	}
+
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(settings.apiPort()))
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("failed to start local API on %s: %w", address, err)
+	}
+
+	server := &http.Server{
+		Handler:           a.apiHandler(settings.APIToken),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	a.api = &apiServer{server: server, listener: listener}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.logger.Errorf("Local API stopped: %v", err)
+		}
+	}()
+
+	a.logger.Infof("Local API listening on http://%s", listener.Addr())
+	return nil
+}
+
+// stopAPIServer stops the local API if it is running
+func (a *App) stopAPIServer() {
+	a.apiMu.Lock()
+	defer a.apiMu.Unlock()
+	a.stopAPIServerLocked()
+}
+
+// stopAPIServerLocked stops the local API. The caller holds apiMu.
+func (a *App) stopAPIServerLocked() {
+	if a.api == nil {
+		return
+	}
+
+	// Close rather than Shutdown: event streams never finish by themselves
+	a.api.server.Close()
+	a.logger.Infof("Local API stopped")
+	a.api = nil
+}
+
+// updateAPISettingsInternal enables or disables the local API. A token is
+// generated the first time the API is enabled.
+func (a *App) updateAPISettingsInternal(enabled bool, port int) error {
+	if port != 0 && (port < 1024 || port > 65535) {
+		return fmt.Errorf("invalid API port %d, expected 1024-65535", port)
+	}
+
+	var token string
+	if enabled && a.currentSettings().APIToken == "" {
+		var err error
+		if token, err = newAPIToken(); err != nil {
+			return err
+		}
+	}
+
+	err := a.updateSettings(func(settings *Settings) {
+		settings.APIEnabled = enabled
+		settings.APIPort = port
+		if enabled && settings.APIToken == "" {
+			settings.APIToken = token
+		}
+	})
+	if err != nil {
+		a.logger.Errorf("Failed to save API settings: %v", err)
+		return err
+	}
+
+	if !enabled {
+		a.stopAPIServer()
+		return nil
+	}
+	return a.startAPIServer()
+}
+
+// regenerateAPITokenInternal replaces the local API token, invalidating the old one
+func (a *App) regenerateAPITokenInternal() (string, error) {
+	token, err := newAPIToken()
+	if err != nil {
+		return "", err
+	}
+	var enabled bool
+	err = a.updateSettings(func(settings *Settings) {
+		settings.APIToken = token
+		enabled = settings.APIEnabled
+	})
+	if err != nil {
+		a.logger.Errorf("Failed to save API token: %v", err)
+		return "", err
+	}
+
+	if enabled {
+		if err := a.startAPIServer(); err != nil {
+			return "", err
+		}
+	}
+	return token, nil
+}
+
+// apiHandler routes the local API. Every request except CORS preflights must
+// carry the token.
+func (a *App) apiHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /analyze", a.handleAPIAnalyze)
+	mux.HandleFunc("GET /jobs", a.handleAPIListJobs)
+	mux.HandleFunc("POST /jobs", a.handleAPICreateJob)
+	mux.HandleFunc("DELETE /jobs/{id}", a.handleAPICancelJob)
+	mux.HandleFunc("GET /events", a.handleAPIEvents)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Browser extensions call the API from their own origin. Scripts send
+		// no Origin, and web pages are turned away.
+		if origin := r.Header.Get("Origin"); origin != "" {
+			if !allowedAPIOrigin(origin) {
+				writeAPIError(w, http.StatusForbidden, fmt.Errorf("origin not allowed: %s", origin))
+				return
+			}
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+			w.Header().Set("Vary", "Origin")
+		}
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		if !validAPIToken(r, token) {
+			writeAPIError(w, http.StatusUnauthorized, fmt.Errorf("missing or invalid token"))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// allowedAPIOrigin reports whether a browser origin may call the local API
+func allowedAPIOrigin(origin string) bool {
+	for _, prefix := range apiOriginPrefixes {
+		if strings.HasPrefix(origin, prefix) && len(origin) > len(prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// validAPIToken checks the bearer token of a request. EventSource cannot set
+// headers, so the event stream also takes the token as the token query
+// parameter; other endpoints only accept the header, which does not end up
+// in logs and browser history.
+func validAPIToken(r *http.Request, token string) bool {
+	given := ""
+	if r.Method == http.MethodGet && r.URL.Path == "/events" {
+		given = r.URL.Query().Get("token")
+	}
+	if header := r.Header.Get("Authorization"); header != "" {
+		given = strings.TrimPrefix(header, "Bearer ")
+	}
+	return given != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// handleAPIAnalyze analyzes a URL like the AnalyzeURL binding
+func (a *App) handleAPIAnalyze(w http.ResponseWriter, r *http.Request) {
+	var req apiURLRequest
+	if err := decodeAPIRequest(w, r, &req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	if strings.TrimSpace(req.URL) == "" {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("url cannot be empty"))
+		return
+	}
+
+	info, err := a.AnalyzeURL(req.URL)
+	if err != nil {
+		writeAPIError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, []byte(info))
+}
+
+// handleAPIListJobs lists the download queue like the ListJobs binding
+func (a *App) handleAPIListJobs(w http.ResponseWriter, r *http.Request) {
+	jobs, err := a.ListJobs()
+	if err != nil {
+		writeAPIError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, []byte(jobs))
+}
+
+// handleAPICreateJob queues a video or playlist download
+func (a *App) handleAPICreateJob(w http.ResponseWriter, r *http.Request) {
+	var req apiJobRequest
+	if err := decodeAPIRequest(w, r, &req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	if strings.TrimSpace(req.URL) == "" {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("url cannot be empty"))
+		return
+	}
+
+	outputPath, err := a.apiOutputPath(req.OutputPath, req.Playlist)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	optionsJSON := ""
+	if len(req.Options) > 0 && string(req.Options) != "null" {
+		optionsJSON = string(req.Options)
+	}
+
+	var id string
+	if req.Playlist {
+		selection, _ := json.Marshal(PlaylistSelection{
+			Items:     req.Items,
+			IDs:       req.IDs,
+			StartItem: req.StartItem,
+			EndItem:   req.EndItem,
+			Filter:    req.Filter,
+		})
+		id, err = a.downloadPlaylistSelectionInternal(req.URL, req.FormatID, outputPath, string(selection), optionsJSON)
+	} else {
+		id, err = a.EnqueueDownload(req.URL, req.FormatID, outputPath, optionsJSON)
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	body, _ := json.Marshal(map[string]string{"id": id})
+	writeAPIJSON(w, http.StatusAccepted, body)
+}
+
+// apiOutputPath resolves the output_path of an API job against the download
+// directory. Like the configured templates it must stay inside that directory.
+func (a *App) apiOutputPath(outputPath string, playlist bool) (string, error) {
+	if outputPath == "" {
+		return a.GetDownloadPath(playlist), nil
+	}
+	if err := validateOutputTemplate(outputPath); err != nil {
+		return "", err
+	}
+	return filepath.Join(defaultDownloadDir, outputPath), nil
+}
+
+// handleAPICancelJob cancels a download job like the CancelJob binding, or
+// like DiscardJob with ?discard=true
+func (a *App) handleAPICancelJob(w http.ResponseWriter, r *http.Request) {
+	cancel := a.CancelJob
+	if discard, _ := strconv.ParseBool(r.URL.Query().Get("discard")); discard {
+		cancel = a.DiscardJob
+	}
+	if err := cancel(r.PathValue("id")); err != nil {
+		status := http.StatusConflict
+		if errors.Is(err, errJobNotFound) {
+			status = http.StatusNotFound
+		}
+		writeAPIError(w, status, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleAPIEvents streams the download, conversion and setup events as
+// server-sent events until the client disconnects
+func (a *App) handleAPIEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	events, unsubscribe := a.apiEvents.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(apiKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-events:
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Name, event.Data)
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+// decodeAPIRequest decodes a JSON request body of at most 1 MiB
+func decodeAPIRequest(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(v); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+// writeAPIJSON writes an already encoded JSON response
+func writeAPIJSON(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// writeAPIError writes an error response as {"error": "..."}
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	body, _ := json.Marshal(map[string]string{"error": err.Error()})
+	writeAPIJSON(w, status, body)
+}
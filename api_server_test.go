@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAPIToken = "secret"
+
+// newTestAPI serves the local API of a test app that records its events
+func newTestAPI(t *testing.T) (*App, *httptest.Server) {
+	t.Helper()
+	app, sink := newTestApp(t)
+	app.apiEvents = newEventBroker()
+	app.events = multiEventSink{sink, app.apiEvents}
+
+	server := httptest.NewServer(app.apiHandler(testAPIToken))
+	t.Cleanup(server.Close)
+	return app, server
+}
+
+// apiRequest sends an authorized request to the test API
+func apiRequest(t *testing.T, server *httptest.Server, method, path, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testAPIToken)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+func TestAPIRequiresToken(t *testing.T) {
+	_, server := newTestAPI(t)
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{"missing", "/jobs", "", http.StatusUnauthorized},
+		{"wrong", "/jobs", "Bearer nope", http.StatusUnauthorized},
+		{"header", "/jobs", "Bearer " + testAPIToken, http.StatusOK},
+		{"query", "/jobs?token=" + testAPIToken, "", http.StatusUnauthorized},
+		{"query for events", "/events?token=" + testAPIToken, "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, server.URL+tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPICORS(t *testing.T) {
+	_, server := newTestAPI(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		origin     string
+		auth       bool
+		want       int
+		wantOrigin string
+	}{
+		{name: "ExtensionPreflight", method: http.MethodOptions, origin: "chrome-extension://abcdef", want: http.StatusNoContent, wantOrigin: "chrome-extension://abcdef"},
+		{name: "ExtensionRequest", method: http.MethodGet, origin: "moz-extension://1234", auth: true, want: http.StatusOK, wantOrigin: "moz-extension://1234"},
+		{name: "WebPagePreflight", method: http.MethodOptions, origin: "https://example.com", want: http.StatusForbidden},
+		{name: "WebPageRequest", method: http.MethodGet, origin: "https://example.com", auth: true, want: http.StatusForbidden},
+		{name: "ScriptWithoutOrigin", method: http.MethodGet, auth: true, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, server.URL+"/jobs", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.auth {
+				req.Header.Set("Authorization", "Bearer "+testAPIToken)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.want || resp.Header.Get("Access-Control-Allow-Origin") != tt.wantOrigin {
+				t.Errorf("unexpected response: %d %v", resp.StatusCode, resp.Header)
+			}
+		})
+	}
+}
+
+func TestAPIAnalyze(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, server := newTestAPI(t)
+	tools.install(app.getYtDlpBinaryName(), fakeRun{Stdout: []string{fakeVideoJSON}})
+
+	status, body := apiRequest(t, server, http.MethodPost, "/analyze", `{"url":"`+fakeVideoURL+`"}`)
+	if status != http.StatusOK || !strings.Contains(body, `"title":"Clip"`) {
+		t.Errorf("POST /analyze = %d %s", status, body)
+	}
+
+	if status, _ := apiRequest(t, server, http.MethodPost, "/analyze", `{"url":""}`); status != http.StatusBadRequest {
+		t.Errorf("empty url: status = %d, want %d", status, http.StatusBadRequest)
+	}
+}
+
+func TestAPIJobs(t *testing.T) {
+	app, server := newTestAPI(t)
+	app.settings.MaxConcurrentDownloads = 1
+	// Occupy the only slot so new jobs stay queued
+	app.queue.running = 1
+
+	status, body := apiRequest(t, server, http.MethodPost, "/jobs", `{"url":"`+fakeVideoURL+`","options":{"audio":{"codec":"mp3"}}}`)
+	if status != http.StatusAccepted {
+		t.Fatalf("POST /jobs = %d %s", status, body)
+	}
+	var created map[string]string
+	if err := json.Unmarshal([]byte(body), &created); err != nil || created["id"] == "" {
+		t.Fatalf("unexpected response %s: %v", body, err)
+	}
+
+	status, body = apiRequest(t, server, http.MethodGet, "/jobs", "")
+	var jobs []DownloadJob
+	if err := json.Unmarshal([]byte(body), &jobs); err != nil || status != http.StatusOK {
+		t.Fatalf("GET /jobs = %d %s", status, body)
+	}
+	if len(jobs) != 1 || jobs[0].ID != created["id"] || jobs[0].Status != JobStatusQueued || jobs[0].Options.Audio.Codec != "mp3" {
+		t.Errorf("unexpected jobs: %+v", jobs)
+	}
+
+	if status, body := apiRequest(t, server, http.MethodPost, "/jobs", `{"url":"`+fakeVideoURL+`","options":{"audio":{"codec":"xyz"}}}`); status != http.StatusBadRequest {
+		t.Errorf("invalid options: %d %s", status, body)
+	}
+
+	// Output paths are templates inside the download directory
+	for _, outputPath := range []string{"/etc/%(title)s.%(ext)s", "../%(title)s.%(ext)s", "music/../../%(title)s.%(ext)s"} {
+		if status, body := apiRequest(t, server, http.MethodPost, "/jobs", `{"url":"`+fakeVideoURL+`","output_path":"`+outputPath+`"}`); status != http.StatusBadRequest {
+			t.Errorf("output_path %s: %d %s", outputPath, status, body)
+		}
+	}
+	status, body = apiRequest(t, server, http.MethodPost, "/jobs", `{"url":"`+fakeVideoURL+`","output_path":"music/%(title)s.%(ext)s"}`)
+	var inside map[string]string
+	if err := json.Unmarshal([]byte(body), &inside); err != nil || status != http.StatusAccepted {
+		t.Fatalf("POST /jobs with an output path = %d %s", status, body)
+	}
+	if got, want := app.queue.jobs[inside["id"]].OutputPath, filepath.Join(defaultDownloadDir, "music", "%(title)s.%(ext)s"); got != want {
+		t.Errorf("output path = %s, want %s", got, want)
+	}
+
+	status, body = apiRequest(t, server, http.MethodPost, "/jobs", `{"url":"https://example.com/playlist","playlist":true,"items":[3,1],"filter":{"max_downloads":2}}`)
+	if status != http.StatusAccepted {
+		t.Fatalf("POST /jobs for a playlist = %d %s", status, body)
+	}
+	var playlist map[string]string
+	json.Unmarshal([]byte(body), &playlist)
+	if job := app.queue.jobs[playlist["id"]]; !job.Playlist || !reflect.DeepEqual(job.Items, []int{3, 1}) || job.Filter == nil || job.Filter.MaxDownloads != 2 {
+		t.Errorf("unexpected playlist job: %+v", job)
+	}
+
+	if status, body := apiRequest(t, server, http.MethodDelete, "/jobs/"+created["id"], ""); status != http.StatusNoContent {
+		t.Errorf("DELETE /jobs/{id} = %d %s", status, body)
+	}
+	if status, _ := apiRequest(t, server, http.MethodDelete, "/jobs/"+created["id"], ""); status != http.StatusConflict {
+		t.Errorf("deleting a cancelled job: status = %d, want %d", status, http.StatusConflict)
+	}
+	if status, _ := apiRequest(t, server, http.MethodDelete, "/jobs/unknown", ""); status != http.StatusNotFound {
+		t.Errorf("deleting an unknown job: status = %d, want %d", status, http.StatusNotFound)
+	}
+}
+
+func TestAPIEventStream(t *testing.T) {
+	app, server := newTestAPI(t)
+
+	resp, err := http.Get(server.URL + "/events?token=" + testAPIToken)
+	if err != nil {
+		t.Fatalf("GET /events failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", resp.Header.Get("Content-Type"))
+	}
+
+	// The subscription is registered before the headers are sent
+	app.events.Emit("conversion-progress", map[string]interface{}{"progress": 42})
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	var got []string
+	timeout := time.After(5 * time.Second)
+	for len(got) < 2 {
+		select {
+		case line := <-lines:
+			if line != "" {
+				got = append(got, line)
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for the event, got %q", got)
+		}
+	}
+	want := []string{"event: conversion-progress", `data: {"progress":42}`}
+	if got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("stream = %q, want %q", got, want)
+	}
+}
+
+func TestUpdateAPISettingsRejectsInvalidPort(t *testing.T) {
+	app, _ := newTestApp(t)
+	for _, port := range []int{-1, 80, 70000} {
+		if err := app.updateAPISettingsInternal(true, port); err == nil {
+			t.Errorf("port %d should be rejected", port)
+		}
+	}
+	if app.settings.APIEnabled {
+		t.Error("settings should not change for an invalid port")
+	}
+}
+
+func TestUpdateAPISettingsStartsAndStopsServer(t *testing.T) {
+	t.Chdir(t.TempDir())
+	app, _ := newTestApp(t)
+	app.apiEvents = newEventBroker()
+
+	// Find a free loopback port
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a free port: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	if err := app.updateAPISettingsInternal(true, port); err != nil {
+		t.Fatalf("updateAPISettingsInternal() error = %v", err)
+	}
+	t.Cleanup(app.stopAPIServer)
+	if app.settings.APIToken == "" {
+		t.Fatal("enabling the API should generate a token")
+	}
+
+	url := fmt.Sprintf("http://127.0.0.1:%d/events?token=%s", port, app.settings.APIToken)
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET /jobs failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	if err := app.updateAPISettingsInternal(false, port); err != nil {
+		t.Fatalf("updateAPISettingsInternal() error = %v", err)
+	}
+	if _, err := http.Get(url); err == nil {
+		t.Error("the API should be stopped after disabling it")
+	}
+}
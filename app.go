@@ -2,18 +2,59 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"sync"
 	"time"
 )
 
 // App struct
 type App struct {
-	ctx      context.Context
-	settings Settings
+	ctx     context.Context
+	events  EventSink // Where download, conversion and setup events go
+	logger  Logger
+	queue   *downloadQueue
+	history *historyStore
+
+	settingsMu sync.RWMutex // Guards settings, which background workers read too
+	settings   Settings
+
+	conversions *conversionQueue
+
+	subscriptions *subscriptionStore
+
+	apiEvents *eventBroker // Forwards events to clients of the local API
+	apiMu     sync.Mutex   // Guards api
+	api       *apiServer   // Running local API, nil when disabled
+
+	clipboardMu sync.Mutex        // Guards clipboard
+	clipboard   *clipboardWatcher // Running clipboard watcher, nil when disabled
+
+	deepAnalysisMu   sync.Mutex    // Guards deepAnalysisStop
+	deepAnalysisStop chan struct{} // Closed to stop the running deep playlist analysis
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	app := &App{}
+	// Events and logs go to Wails once OnStartup provides its context
+	app := newApp(context.Background(), nopSink{}, nopSink{})
+	app.queue.statePath = queueStateFile
+	return app
+}
+
+// newApp creates an App that reports events and log messages to the given sinks
+func newApp(ctx context.Context, events EventSink, logger Logger) *App {
+	broker := newEventBroker()
+	app := &App{
+		ctx:       ctx,
+		events:    multiEventSink{events, broker},
+		logger:    logger,
+		queue:     newDownloadQueue(),
+		history:   newHistoryStore(historyFile),
+		apiEvents: broker,
+
+		conversions:   newConversionQueue(),
+		subscriptions: newSubscriptionStore(subscriptionsFile),
+	}
 	app.loadSettings() // Load settings on initialization
 	return app
 }
@@ -22,10 +63,21 @@ func NewApp() *App {
 // so we can call the runtime methods
 func (a *App) OnStartup(ctx context.Context) {
 	a.ctx = ctx
+	a.events = multiEventSink{wailsSink{ctx: ctx}, a.apiEvents}
+	a.logger = wailsSink{ctx: ctx}
 
 	// Загружаем настройки после инициализации контекста
 	a.loadSettingsWithLogging()
 
+	if a.currentSettings().APIEnabled {
+		if err := a.startAPIServer(); err != nil {
+			a.logger.Errorf("Failed to start local API: %v", err)
+		}
+	}
+
+	// Restore unfinished downloads from the previous session
+	restoredJobs := a.restoreQueueState()
+
 	// Запускаем в отдельной горутине с небольшой задержкой
 	// чтобы Wails runtime успел загрузиться
 	go func() {
@@ -34,6 +86,21 @@ func (a *App) OnStartup(ctx context.Context) {
 
 		// Check and setup dependencies
 		a.SetupDependencies()
+
+		// The watcher asks yt-dlp for its extractors, so it starts after setup
+		if a.currentSettings().ClipboardWatch {
+			a.startClipboardWatcher()
+		}
+
+		// Look for new items of subscribed channels and playlists
+		go a.runSubscriptionScheduler()
+
+		// Offer to resume downloads that were paused or interrupted on exit
+		if restoredJobs > 0 {
+			a.events.Emit("download-queue-restored", map[string]interface{}{
+				"count": restoredJobs,
+			})
+		}
 	}()
 }
 
@@ -53,6 +120,21 @@ func (a *App) AnalyzePlaylist(url string) (string, error) {
 	return a.analyzePlaylistInternal(url)
 }
 
+// AnalyzePlaylistDeep analyzes a playlist URL like AnalyzePlaylist and then streams
+// the full metadata of every entry as playlist-entry-analyzed events
+//
+//export AnalyzePlaylistDeep
+func (a *App) AnalyzePlaylistDeep(url string) (string, error) {
+	return a.analyzePlaylistDeepInternal(url)
+}
+
+// CancelPlaylistAnalysis stops the running deep playlist analysis
+//
+//export CancelPlaylistAnalysis
+func (a *App) CancelPlaylistAnalysis() {
+	a.cancelPlaylistAnalysisInternal()
+}
+
 // GetPlaylistItems returns a list of video entries from a playlist
 //
 //export GetPlaylistItems
@@ -60,39 +142,147 @@ func (a *App) GetPlaylistItems(url string) (string, error) {
 	return a.getPlaylistItemsInternal(url)
 }
 
-// DownloadVideo downloads a video using the selected format ID
+// DownloadVideo queues a video download using the selected format ID and returns
+// its job ID. optionsJSON holds DownloadOptions such as an audio extraction profile and may be empty.
 //
 //export DownloadVideo
-func (a *App) DownloadVideo(url, formatID, outputPath string) error {
-	return a.downloadVideoInternal(url, formatID, outputPath)
+func (a *App) DownloadVideo(url, formatID, outputPath, optionsJSON string) (string, error) {
+	return a.downloadVideoInternal(url, formatID, outputPath, optionsJSON)
 }
 
-// DownloadPlaylist downloads an entire playlist
+// DownloadPlaylist queues a download of an entire playlist and returns its job ID
 //
 //export DownloadPlaylist
-func (a *App) DownloadPlaylist(url, formatID, outputPath string, startItem, endItem int) error {
-	return a.downloadPlaylistInternal(url, formatID, outputPath, startItem, endItem)
+func (a *App) DownloadPlaylist(url, formatID, outputPath string, startItem, endItem int, optionsJSON string) (string, error) {
+	return a.enqueuePlaylistInternal(url, formatID, outputPath, startItem, endItem, optionsJSON)
+}
+
+// DownloadPlaylistSelection queues a download of selected playlist entries and
+// returns its job ID. selectionJSON holds a PlaylistSelection with item indices,
+// entry IDs or a range plus filters; optionsJSON holds DownloadOptions. Both may be empty.
+//
+//export DownloadPlaylistSelection
+func (a *App) DownloadPlaylistSelection(url, formatID, outputPath, selectionJSON, optionsJSON string) (string, error) {
+	return a.downloadPlaylistSelectionInternal(url, formatID, outputPath, selectionJSON, optionsJSON)
+}
+
+// EnqueueDownload adds a video download to the queue and returns its job ID.
+// optionsJSON holds DownloadOptions and may be empty.
+//
+//export EnqueueDownload
+func (a *App) EnqueueDownload(url, formatID, outputPath, optionsJSON string) (string, error) {
+	if url == "" {
+		return "", fmt.Errorf("url cannot be empty")
+	}
+	options, err := parseDownloadOptions(optionsJSON)
+	if err != nil {
+		return "", err
+	}
+	return a.enqueueJob(&DownloadJob{
+		URL:        url,
+		FormatID:   formatID,
+		OutputPath: outputPath,
+		Options:    options,
+	}), nil
+}
+
+// ListJobs returns all download jobs as JSON
+//
+//export ListJobs
+func (a *App) ListJobs() (string, error) {
+	return a.listJobsInternal()
+}
+
+// CancelJob cancels a queued, running or paused download job
+//
+//export CancelJob
+func (a *App) CancelJob(id string) error {
+	return a.stopJobInternal(id, "cancel")
+}
+
+// DiscardJob cancels a download job and deletes the partial files it left behind
+//
+//export DiscardJob
+func (a *App) DiscardJob(id string) error {
+	return a.discardJobInternal(id)
+}
+
+// PauseJob pauses a queued or running download job
+//
+//export PauseJob
+func (a *App) PauseJob(id string) error {
+	return a.stopJobInternal(id, "pause")
+}
+
+// ResumeJob puts a paused or interrupted download job back into the queue
+//
+//export ResumeJob
+func (a *App) ResumeJob(id string) error {
+	return a.resumeJobInternal(id)
+}
+
+// ResumeAllJobs puts every paused or interrupted download job back into the queue
+//
+//export ResumeAllJobs
+func (a *App) ResumeAllJobs() error {
+	return a.resumeAllJobsInternal()
+}
+
+// QueryHistory returns download history items matching a JSON-encoded filter
+//
+//export QueryHistory
+func (a *App) QueryHistory(filterJSON string) (string, error) {
+	return a.queryHistoryInternal(filterJSON)
+}
+
+// DeleteHistoryItem removes an item from the download history
+//
+//export DeleteHistoryItem
+func (a *App) DeleteHistoryItem(id string) error {
+	return a.history.delete(id)
+}
+
+// ExportHistory exports the download history as "csv" or "json" and returns the file path
+//
+//export ExportHistory
+func (a *App) ExportHistory(format, filePath string) (string, error) {
+	return a.exportHistoryInternal(format, filePath)
 }
 
-// CancelDownload cancels the current download gracefully
+// CancelDownload cancels all running downloads gracefully
 //
 //export CancelDownload
 func (a *App) CancelDownload() error {
 	return a.cancelDownloadInternal()
 }
 
-// PauseDownload pauses the current download gracefully
+// PauseDownload pauses all running downloads gracefully
 //
 //export PauseDownload
 func (a *App) PauseDownload() error {
 	return a.pauseDownloadInternal()
 }
 
-// GetDownloadPath returns a suggested download path
+// ResumeDownload resumes a paused download job from its partial files, or every
+// paused job when jobID is empty
+//
+//export ResumeDownload
+func (a *App) ResumeDownload(jobID string) error {
+	return a.resumeDownloadInternal(jobID)
+}
+
+// GetDownloadPath returns the output template for a new video or playlist download
 //
 //export GetDownloadPath
-func (a *App) GetDownloadPath(title string) string {
-	return a.getDownloadPathInternal(title)
+func (a *App) GetDownloadPath(playlist bool) string {
+	return a.getDownloadPathInternal(playlist)
+}
+
+// PreviewOutputTemplate renders an output template against analyzed video info
+//
+//export PreviewOutputTemplate
+func (a *App) PreviewOutputTemplate(template, videoInfoJSON string) (string, error) {
+	return a.previewOutputTemplateInternal(template, videoInfoJSON)
 }
 
 // SelectDownloadDirectory opens a dialog to select the download directory
@@ -134,16 +324,70 @@ func (a *App) UpdateSettingsWithCookiesFile(proxyMode, proxyAddress, cookiesMode
 //
 //export UpdateAutoRedirectToQueue
 func (a *App) UpdateAutoRedirectToQueue(autoRedirect bool) error {
-	a.settings.AutoRedirectToQueue = autoRedirect
+	err := a.updateSettings(func(settings *Settings) {
+		settings.AutoRedirectToQueue = autoRedirect
+	})
+	if err != nil {
+		return err
+	}
 
-	err := a.saveSettings()
+	return nil
+}
+
+// UpdateMaxConcurrentDownloads updates how many queued downloads may run at once
+//
+//export UpdateMaxConcurrentDownloads
+func (a *App) UpdateMaxConcurrentDownloads(maxDownloads int) error {
+	if maxDownloads < 1 {
+		return fmt.Errorf("max concurrent downloads must be at least 1")
+	}
+	err := a.updateSettings(func(settings *Settings) {
+		settings.MaxConcurrentDownloads = maxDownloads
+	})
 	if err != nil {
 		return err
 	}
 
+	// Start queued jobs if the limit was raised
+	a.scheduleDownloads()
 	return nil
 }
 
+// UpdateEmbedSettings updates the default metadata, thumbnail, chapter and info JSON embedding
+//
+//export UpdateEmbedSettings
+func (a *App) UpdateEmbedSettings(metadata, thumbnail, chapters, infoJSON bool) error {
+	return a.updateEmbedSettingsInternal(metadata, thumbnail, chapters, infoJSON)
+}
+
+// UpdateOutputTemplates updates the output templates and filename sanitization
+//
+//export UpdateOutputTemplates
+func (a *App) UpdateOutputTemplates(outputTemplate, playlistOutputTemplate string, restrictFilenames, windowsFilenames bool) error {
+	return a.updateOutputTemplatesInternal(outputTemplate, playlistOutputTemplate, restrictFilenames, windowsFilenames)
+}
+
+// UpdateDownloadArchiveSettings sets the download archive mode ("off", "global" or "folder") and whether to re-download archived videos
+//
+//export UpdateDownloadArchiveSettings
+func (a *App) UpdateDownloadArchiveSettings(mode string, forceRedownload bool) error {
+	return a.updateDownloadArchiveSettingsInternal(mode, forceRedownload)
+}
+
+// UpdateAPISettings enables or disables the local HTTP API on the given port (0 for the default)
+//
+//export UpdateAPISettings
+func (a *App) UpdateAPISettings(enabled bool, port int) error {
+	return a.updateAPISettingsInternal(enabled, port)
+}
+
+// RegenerateAPIToken replaces the local HTTP API token and returns the new one
+//
+//export RegenerateAPIToken
+func (a *App) RegenerateAPIToken() (string, error) {
+	return a.regenerateAPITokenInternal()
+}
+
 // GetYtDlpVersion returns the current yt-dlp version
 //
 //export GetYtDlpVersion
@@ -179,13 +423,93 @@ func (a *App) OpenInExplorer(path string) error {
 	return a.openInExplorerInternal(path)
 }
 
-// ConvertVideo converts a video file to a different format using FFmpeg
+// ConvertVideo queues the conversion of a video file to a different format
+// using FFmpeg and returns the conversion job ID
 //
 //export ConvertVideo
-func (a *App) ConvertVideo(sourcePath, targetFormat string) error {
+func (a *App) ConvertVideo(sourcePath, targetFormat string) (string, error) {
 	return a.convertVideoInternal(sourcePath, targetFormat)
 }
 
+// ProbeMedia returns the streams, codecs, bitrates, duration, chapters and tags
+// of a media file as JSON
+//
+//export ProbeMedia
+func (a *App) ProbeMedia(path string) (string, error) {
+	return a.probeMediaInternal(path)
+}
+
+// ListConversionPresets returns the built-in and user-defined conversion presets as JSON
+//
+//export ListConversionPresets
+func (a *App) ListConversionPresets() (string, error) {
+	return a.listConversionPresetsInternal()
+}
+
+// SaveConversionPreset adds or replaces a user-defined conversion preset given as JSON
+//
+//export SaveConversionPreset
+func (a *App) SaveConversionPreset(presetJSON string) error {
+	return a.saveConversionPresetInternal(presetJSON)
+}
+
+// DeleteConversionPreset removes a user-defined conversion preset
+//
+//export DeleteConversionPreset
+func (a *App) DeleteConversionPreset(name string) error {
+	return a.deleteConversionPresetInternal(name)
+}
+
+// ConvertWithPreset queues the conversion of a file using a named conversion
+// preset and returns the conversion job ID
+//
+//export ConvertWithPreset
+func (a *App) ConvertWithPreset(sourcePath, presetName string) (string, error) {
+	return a.convertWithPresetInternal(sourcePath, presetName)
+}
+
+// ConvertBatch queues the conversion of the selected files and of the media
+// files in the selected folders with a preset. pathsJSON holds an array of
+// paths; the job IDs are returned as a JSON array.
+//
+//export ConvertBatch
+func (a *App) ConvertBatch(pathsJSON, presetName string) (string, error) {
+	return a.convertBatchInternal(pathsJSON, presetName)
+}
+
+// ListConversionJobs returns all conversion jobs as JSON
+//
+//export ListConversionJobs
+func (a *App) ListConversionJobs() (string, error) {
+	return a.listConversionJobsInternal()
+}
+
+// CancelConversionJob cancels a queued or running conversion job
+//
+//export CancelConversionJob
+func (a *App) CancelConversionJob(id string) error {
+	return a.cancelConversionJobInternal(id)
+}
+
+// UpdateMaxConcurrentConversions updates how many queued conversions may run at once
+//
+//export UpdateMaxConcurrentConversions
+func (a *App) UpdateMaxConcurrentConversions(maxConversions int) error {
+	if maxConversions < 1 {
+		return fmt.Errorf("max concurrent conversions must be at least 1")
+	}
+	err := a.updateSettings(func(settings *Settings) {
+		settings.MaxConcurrentConversions = maxConversions
+	})
+	if err != nil {
+		return err
+	}
+
+	// Start queued conversions if the limit was raised
+	a.scheduleConversions()
+	return nil
+}
+
 // GetActualDownloadPath returns the actual path of the downloaded file
 //
 //export GetActualDownloadPath
@@ -246,9 +570,9 @@ func (a *App) ApplyAppUpdate() error {
 //
 //export UpdateJSRuntimeSetting
 func (a *App) UpdateJSRuntimeSetting(useJSRuntime bool) error {
-	a.settings.UseJSRuntime = useJSRuntime
-
-	err := a.saveSettings()
+	err := a.updateSettings(func(settings *Settings) {
+		settings.UseJSRuntime = useJSRuntime
+	})
 	if err != nil {
 		return err
 	}
@@ -337,15 +661,16 @@ func (a *App) UpdateNode() error {
 //
 //export GetJSRuntimeType
 func (a *App) GetJSRuntimeType() string {
-	return a.settings.JSRuntimeType
+	return a.currentSettings().JSRuntimeType
 }
 
 // UpdateJSRuntimeType updates the JS runtime type setting
 //
 //export UpdateJSRuntimeType
 func (a *App) UpdateJSRuntimeType(runtimeType string) error {
-	a.settings.JSRuntimeType = runtimeType
-	err := a.saveSettings()
+	err := a.updateSettings(func(settings *Settings) {
+		settings.JSRuntimeType = runtimeType
+	})
 	if err != nil {
 		return err
 	}
@@ -366,6 +691,61 @@ func (a *App) GetClipboardText() (string, error) {
 	return a.getClipboardTextInternal()
 }
 
+// SetClipboardWatch turns on or off the background watcher that emits
+// clipboard-url-detected for supported URLs copied to the clipboard
+//
+//export SetClipboardWatch
+func (a *App) SetClipboardWatch(enabled bool) error {
+	return a.setClipboardWatchInternal(enabled)
+}
+
+// AddSubscription subscribes to a channel or playlist whose new entries are
+// downloaded automatically and returns the subscription as JSON.
+// optionsJSON holds DownloadOptions and may be empty. With skipExisting the
+// entries already published are not downloaded.
+//
+//export AddSubscription
+func (a *App) AddSubscription(url, formatID, outputDir, optionsJSON string, skipExisting bool) (string, error) {
+	return a.addSubscriptionInternal(url, formatID, outputDir, optionsJSON, skipExisting)
+}
+
+// ListSubscriptions returns all subscriptions as JSON
+//
+//export ListSubscriptions
+func (a *App) ListSubscriptions() (string, error) {
+	return a.listSubscriptionsInternal()
+}
+
+// RemoveSubscription unsubscribes from a channel or playlist
+//
+//export RemoveSubscription
+func (a *App) RemoveSubscription(id string) error {
+	return a.removeSubscriptionInternal(id)
+}
+
+// SetSubscriptionEnabled pauses or resumes scheduled syncing of a subscription
+//
+//export SetSubscriptionEnabled
+func (a *App) SetSubscriptionEnabled(id string, enabled bool) error {
+	return a.setSubscriptionEnabledInternal(id, enabled)
+}
+
+// SyncSubscription checks a subscription for new entries now, queues them and
+// returns how many were queued
+//
+//export SyncSubscription
+func (a *App) SyncSubscription(id string) (int, error) {
+	jobIDs, err := a.syncSubscriptionInternal(id)
+	return len(jobIDs), err
+}
+
+// UpdateSubscriptionSyncInterval sets how often subscriptions are synced, in minutes
+//
+//export UpdateSubscriptionSyncInterval
+func (a *App) UpdateSubscriptionSyncInterval(minutes int) error {
+	return a.updateSubscriptionSyncIntervalInternal(minutes)
+}
+
 // ReadLinksFromFile reads all URLs from a text file (one per line)
 //
 //export ReadLinksFromFile
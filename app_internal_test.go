@@ -4,6 +4,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -47,28 +48,70 @@ func TestBuildOpenInExplorerCommandForOS_UnsupportedOS(t *testing.T) {
 	}
 }
 
-func TestGetDownloadPathInternal_SanitizesTitleAndCaches(t *testing.T) {
+func TestPreviewOutputTemplateInternal_RendersInsideDownloadDir(t *testing.T) {
 	tmpDir := t.TempDir()
 	oldDir := defaultDownloadDir
 	defaultDownloadDir = tmpDir
 	t.Cleanup(func() { defaultDownloadDir = oldDir })
 
 	app := &App{}
-	title := `Bad:/\*?"<>| Title`
+	app.settings.OutputTemplate = "%(uploader)s/%(title)s.%(ext)s"
+	info := `{"id":"abc","title":"Bad:/\\*?\"<>| Title","uploader":"Someone","ext":"webm"}`
 
-	first := app.getDownloadPathInternal(title)
-	second := app.getDownloadPathInternal(title)
-
-	if first != second {
-		t.Fatalf("expected cached path to match, first=%q second=%q", first, second)
+	preview, err := app.previewOutputTemplateInternal("", info)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if filepath.Dir(filepath.Dir(preview)) != tmpDir || filepath.Base(filepath.Dir(preview)) != "Someone" {
+		t.Fatalf("expected preview inside the download directory, got %q", preview)
 	}
 
-	fileName := filepath.Base(first)
+	fileName := filepath.Base(preview)
 	for _, bad := range []string{":", "/", "\\", "*", "?", "\"", "<", ">", "|"} {
 		if strings.Contains(fileName, bad) {
 			t.Fatalf("expected sanitized filename, found %q in %q", bad, fileName)
 		}
 	}
+
+	if _, err := app.previewOutputTemplateInternal("../%(title)s.%(ext)s", info); err == nil {
+		t.Fatal("expected an error for a template outside the download directory")
+	}
+}
+
+func TestSettingsConcurrentAccess(t *testing.T) {
+	t.Chdir(t.TempDir())
+	app, _ := newTestApp(t)
+	holdQueue(app)
+
+	// Bindings change the settings while queue workers and the scheduler read them
+	var wg sync.WaitGroup
+	for i := 1; i <= 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := app.UpdateMaxConcurrentDownloads(i); err != nil {
+				t.Errorf("UpdateMaxConcurrentDownloads() error = %v", err)
+			}
+			if err := app.updateSubscriptionSyncIntervalInternal(i); err != nil {
+				t.Errorf("updateSubscriptionSyncIntervalInternal() error = %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			app.maxConcurrentDownloads()
+			app.subscriptionSyncInterval()
+			app.jobArchivePath(&DownloadJob{OutputPath: "downloads/a.mp4"})
+		}()
+	}
+	wg.Wait()
+
+	if err := app.updateSubscriptionSyncIntervalInternal(15); err != nil {
+		t.Fatalf("updateSubscriptionSyncIntervalInternal() error = %v", err)
+	}
+	app.loadSettings()
+	if got := app.currentSettings().SubscriptionSyncMinutes; got != 15 {
+		t.Errorf("saved sync interval = %d, want 15", got)
+	}
 }
 
 func TestParseFFmpegTime(t *testing.T) {
@@ -48,7 +48,7 @@ func (a *App) applyAppUpdate() error {
 		return err
 	}
 
-	wailsRuntime.EventsEmit(a.ctx, "app-update-start", nil)
+	a.events.Emit("app-update-start", nil)
 
 	tmpDir, err := os.MkdirTemp("", "go-dlp-update-*")
 	if err != nil {
@@ -80,7 +80,7 @@ func (a *App) applyAppUpdate() error {
 			a.emitAppUpdateError(err)
 			return err
 		}
-		wailsRuntime.EventsEmit(a.ctx, "app-update-complete", appUpdateComplete{
+		a.events.Emit("app-update-complete", appUpdateComplete{
 			Message: "Update installed. Restarting app...",
 		})
 		wailsRuntime.Quit(a.ctx)
@@ -90,7 +90,7 @@ func (a *App) applyAppUpdate() error {
 			a.emitAppUpdateError(err)
 			return err
 		}
-		wailsRuntime.EventsEmit(a.ctx, "app-update-complete", appUpdateComplete{
+		a.events.Emit("app-update-complete", appUpdateComplete{
 			Message: "Update installed successfully. Restart the app to use the new version.",
 		})
 		return nil
@@ -140,7 +140,7 @@ func (a *App) downloadUpdateFile(downloadURL, destinationPath string) error {
 			if total > 0 {
 				percentage = (float64(downloaded) / float64(total)) * 100
 			}
-			wailsRuntime.EventsEmit(a.ctx, "app-update-progress", appUpdateProgress{
+			a.events.Emit("app-update-progress", appUpdateProgress{
 				Downloaded: downloaded,
 				Total:      total,
 				Percentage: percentage,
@@ -156,7 +156,7 @@ func (a *App) downloadUpdateFile(downloadURL, destinationPath string) error {
 		}
 	}
 
-	wailsRuntime.EventsEmit(a.ctx, "app-update-progress", appUpdateProgress{
+	a.events.Emit("app-update-progress", appUpdateProgress{
 		Downloaded: downloaded,
 		Total:      total,
 		Percentage: 100,
@@ -167,7 +167,7 @@ func (a *App) downloadUpdateFile(downloadURL, destinationPath string) error {
 }
 
 func (a *App) emitAppUpdateError(err error) {
-	wailsRuntime.EventsEmit(a.ctx, "app-update-error", err.Error())
+	a.events.Emit("app-update-error", err.Error())
 }
 
 func getUpdateAssetForPlatform(release *ReleaseInfo) (string, string, error) {
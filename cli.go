@@ -0,0 +1,528 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+)
+
+const cliUsage = `Usage: go-dlp cli <command> [options]
+
+Commands:
+  analyze   [--json] URL                  Show video information and formats
+  download  [options] URL                 Download a single video
+  playlist  [options] [--start N] [--end N] URL
+                                          Download a playlist
+  convert   [--json] SOURCE FORMAT        Convert a file with FFmpeg, e.g. mp4 or mp3
+  deps      [--json]                      Download yt-dlp and check for FFmpeg
+
+Run "go-dlp cli <command> -h" for the options of a command.
+`
+
+// cliTerminalEvents end a CLI command. The value tells whether the command succeeded.
+var cliTerminalEvents = map[string]bool{
+	"download-complete":    true,
+	"download-error":       false,
+	"download-cancelled":   false,
+	"conversion-complete":  true,
+	"conversion-error":     false,
+	"conversion-cancelled": false,
+	"setup-complete":       true,
+	"setup-error":          false,
+}
+
+// cliEvent is an event as written in --json mode
+type cliEvent struct {
+	Event string      `json:"event"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+// cliSink prints events and log messages to the terminal and reports when the
+// running command has finished
+type cliSink struct {
+	mu      sync.Mutex
+	out     io.Writer
+	errOut  io.Writer
+	json    bool
+	verbose bool
+	inLine  bool // A progress line without a trailing newline is on screen
+
+	done chan bool // Receives the result of the first terminal event
+}
+
+// newCLISink creates a sink writing events to out and errors and logs to errOut
+func newCLISink(out, errOut io.Writer, jsonOutput, verbose bool) *cliSink {
+	return &cliSink{out: out, errOut: errOut, json: jsonOutput, verbose: verbose, done: make(chan bool, 1)}
+}
+
+// Emit prints an event, as a JSON line in --json mode and as text otherwise
+func (s *cliSink) Emit(name string, data ...interface{}) {
+	var payload interface{}
+	if len(data) == 1 {
+		payload = data[0]
+	} else if len(data) > 1 {
+		payload = data
+	}
+
+	s.mu.Lock()
+	if s.json {
+		line, err := json.Marshal(cliEvent{Event: name, Data: payload})
+		if err == nil {
+			fmt.Fprintln(s.out, string(line))
+		}
+	} else {
+		s.printEvent(name, payload)
+	}
+	s.mu.Unlock()
+
+	if ok, terminal := cliTerminalEvents[name]; terminal {
+		select {
+		case s.done <- ok:
+		default:
+		}
+	}
+}
+
+// printEvent writes the human readable form of an event. The caller holds s.mu.
+func (s *cliSink) printEvent(name string, payload interface{}) {
+	fields, _ := payload.(map[string]interface{})
+
+	switch name {
+	case "download-progress":
+		prefix := ""
+		if index, _ := fields["playlist_index"].(int); index > 0 {
+			prefix = fmt.Sprintf("(%d/%v) ", index, fields["playlist_count"])
+		}
+		if fields["phase"] == progressPhasePostprocess {
+			s.printProgress("%s[%3v%%] %v...", prefix, fields["progress"], fields["postprocessor"])
+		} else {
+			s.printProgress("%s[%3v%%] %v at %v, ETA %v", prefix, fields["progress"], fields["size"], fields["speed"], fields["eta"])
+		}
+	case "conversion-progress":
+		s.printProgress("Converting... %3v%% at %vx, ETA %v", fields["progress"], fields["speed"], fields["eta"])
+	case "setup-progress":
+		s.printProgress("Downloading... %3v%%", fields["percentage"])
+	case "download-complete":
+		filePath, _ := fields["file_path"].(string)
+		if already, _ := fields["already_downloaded"].(bool); already || filePath == "" {
+			s.println(s.out, "Already downloaded, skipped by the download archive")
+		} else if files, ok := fields["files"].([]string); ok {
+			s.println(s.out, "Saved %d files to %v", len(files), fields["file_path"])
+		} else {
+			s.println(s.out, "Saved to %v", fields["file_path"])
+		}
+	case "conversion-complete":
+		s.println(s.out, "Converted to %v", fields["targetPath"])
+	case "setup-started":
+		s.println(s.out, "Checking dependencies...")
+	case "setup-complete":
+		s.println(s.out, "Dependencies are ready")
+	case "download-cancelled", "conversion-cancelled":
+		s.println(s.errOut, "Cancelled")
+	case "download-error", "conversion-error":
+		s.println(s.errOut, "Error: %v", fields["error"])
+	case "setup-error", "ffmpeg-warning":
+		s.println(s.errOut, "%v", payload)
+	}
+}
+
+// printProgress replaces the current progress line
+func (s *cliSink) printProgress(format string, args ...interface{}) {
+	fmt.Fprintf(s.out, "\r"+format+"\033[K", args...)
+	s.inLine = true
+}
+
+// println ends any progress line and prints a message on its own line
+func (s *cliSink) println(w io.Writer, format string, args ...interface{}) {
+	if s.inLine {
+		fmt.Fprintln(s.out)
+		s.inLine = false
+	}
+	fmt.Fprintf(w, format+"\n", args...)
+}
+
+// log writes a log message to errOut in verbose mode
+func (s *cliSink) log(level, format string, args ...interface{}) {
+	if !s.verbose {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.println(s.errOut, level+": "+format, args...)
+}
+
+// Info logs a message
+func (s *cliSink) Info(message string) { s.log("INF", "%s", message) }
+
+// Infof logs a formatted message
+func (s *cliSink) Infof(format string, args ...interface{}) { s.log("INF", format, args...) }
+
+// Warningf logs a formatted warning
+func (s *cliSink) Warningf(format string, args ...interface{}) { s.log("WRN", format, args...) }
+
+// Errorf logs a formatted error
+func (s *cliSink) Errorf(format string, args ...interface{}) { s.log("ERR", format, args...) }
+
+// wait blocks until the running command has finished and returns whether it succeeded
+func (s *cliSink) wait() bool {
+	return <-s.done
+}
+
+// cliCommonFlags are the options every command accepts
+type cliCommonFlags struct {
+	json    bool
+	verbose bool
+}
+
+// register adds the common options to a flag set
+func (c *cliCommonFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&c.json, "json", false, "Print events as newline-delimited JSON")
+	fs.BoolVar(&c.verbose, "v", false, "Print log messages to stderr")
+}
+
+// cliDownloadFlags are the options of the download and playlist commands
+type cliDownloadFlags struct {
+	format    string
+	dir       string
+	template  string
+	audio     string
+	quality   string
+	subs      string
+	autoSubs  bool
+	subFormat string
+	embedSubs bool
+}
+
+// register adds the download options to a flag set
+func (d *cliDownloadFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&d.format, "f", "", "yt-dlp format selector, empty for yt-dlp's default")
+	fs.StringVar(&d.dir, "dir", "", "Download directory, defaults to the app's download directory")
+	fs.StringVar(&d.template, "o", "", "Output template relative to the download directory, defaults to the configured one")
+	fs.StringVar(&d.audio, "audio", "", "Extract audio only with this codec, e.g. mp3, m4a or best")
+	fs.StringVar(&d.quality, "audio-quality", "", "Audio quality, 0-10 or a bitrate like 192K")
+	fs.StringVar(&d.subs, "subs", "", "Comma separated subtitle languages to download, e.g. en,de")
+	fs.BoolVar(&d.autoSubs, "auto-subs", false, "Also use auto-generated captions")
+	fs.StringVar(&d.subFormat, "sub-format", "", "Convert subtitles to srt, vtt or ass")
+	fs.BoolVar(&d.embedSubs, "embed-subs", false, "Embed subtitles instead of writing sidecar files")
+}
+
+// options builds the JSON-encoded DownloadOptions for the flags
+func (d *cliDownloadFlags) options() (string, error) {
+	var options DownloadOptions
+	if d.audio != "" {
+		options.Audio = &AudioExtraction{Codec: d.audio, Quality: d.quality}
+	}
+	if d.subs != "" || d.autoSubs {
+		options.Subtitles = &SubtitleSelection{
+			Languages: strings.Split(d.subs, ","),
+			Auto:      d.autoSubs,
+			Format:    d.subFormat,
+			Embed:     d.embedSubs,
+		}
+	}
+	if err := options.validate(); err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(options)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode download options: %w", err)
+	}
+	return string(data), nil
+}
+
+// outputPath returns the output template for the download
+func (d *cliDownloadFlags) outputPath(a *App, playlist bool) (string, error) {
+	if d.dir != "" {
+		if err := a.setDownloadDirectoryInternal(d.dir); err != nil {
+			return "", err
+		}
+	}
+	if d.template == "" {
+		return a.getDownloadPathInternal(playlist), nil
+	}
+	if err := validateOutputTemplate(d.template); err != nil {
+		return "", err
+	}
+	return filepath.Join(a.getDownloadDirectoryInternal(), d.template), nil
+}
+
+// errCLIUsage reports invalid command line arguments
+var errCLIUsage = errors.New("invalid arguments")
+
+// errCLIFailed reports a command whose failure was already printed as an event
+var errCLIFailed = errors.New("command failed")
+
+// cliCommand runs one CLI command against app
+type cliCommand func(app *App, args []string, stdout, stderr io.Writer) error
+
+// runCLI runs a headless command with the same App core as the desktop app and
+// returns the process exit code
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		fmt.Fprint(stderr, cliUsage)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+
+	commands := map[string]cliCommand{
+		"analyze":  cliAnalyze,
+		"download": cliDownload,
+		"playlist": cliPlaylist,
+		"convert":  cliConvert,
+		"deps":     cliDeps,
+	}
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "Unknown command %q\n\n%s", args[0], cliUsage)
+		return 2
+	}
+
+	// The queue is kept in memory only, so the CLI never touches the desktop
+	// app's unfinished downloads. Settings and history are shared.
+	err := command(newApp(context.Background(), nopSink{}, nopSink{}), args[1:], stdout, stderr)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errCLIUsage):
+		return 2
+	case errors.Is(err, errCLIFailed):
+		return 1 // The sink already printed the error event
+	default:
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+}
+
+// parseCLIFlags parses the arguments of a command, checks the number of
+// positional arguments and connects a terminal sink to the app
+func parseCLIFlags(app *App, fs *flag.FlagSet, common *cliCommonFlags, args []string, positional int, stdout, stderr io.Writer) (*cliSink, error) {
+	common.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != positional {
+		fs.Usage()
+		return nil, errCLIUsage
+	}
+
+	sink := newCLISink(stdout, stderr, common.json, common.verbose)
+	app.events = sink
+	app.logger = sink
+	return sink, nil
+}
+
+// newCLIFlagSet creates the flag set of a command
+func newCLIFlagSet(name, arguments string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: go-dlp cli %s [options] %s\n\nOptions:\n", name, arguments)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// cancelOnInterrupt calls cancel when the user presses Ctrl+C. The returned
+// function stops listening.
+func cancelOnInterrupt(cancel func()) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-stop:
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(stop)
+	}
+}
+
+// cliAnalyze prints the information yt-dlp reports for a URL
+func cliAnalyze(app *App, args []string, stdout, stderr io.Writer) error {
+	var common cliCommonFlags
+	fs := newCLIFlagSet("analyze", "URL", stderr)
+	if _, err := parseCLIFlags(app, fs, &common, args, 1, stdout, stderr); err != nil {
+		return err
+	}
+
+	result, err := app.analyzeURLInternal(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if common.json {
+		fmt.Fprintln(stdout, result)
+		return nil
+	}
+
+	var info VideoInfo
+	if err := json.Unmarshal([]byte(result), &info); err != nil {
+		return fmt.Errorf("failed to parse video info: %w", err)
+	}
+	printVideoInfo(stdout, info)
+	return nil
+}
+
+// printVideoInfo writes a summary of the video and a table of its formats
+func printVideoInfo(w io.Writer, info VideoInfo) {
+	fmt.Fprintf(w, "Title:     %s\n", info.Title)
+	if info.Uploader != "" {
+		fmt.Fprintf(w, "Uploader:  %s\n", info.Uploader)
+	}
+	if info.Duration > 0 {
+		fmt.Fprintf(w, "Duration:  %s\n", formatDuration(info.Duration))
+	}
+	if info.UploadDate != "" {
+		fmt.Fprintf(w, "Uploaded:  %s\n", info.UploadDate)
+	}
+	if len(info.AvailableSubtitles) > 0 {
+		codes := make([]string, 0, len(info.AvailableSubtitles))
+		for _, language := range info.AvailableSubtitles {
+			if !language.Auto {
+				codes = append(codes, language.Code)
+			}
+		}
+		if len(codes) > 0 {
+			fmt.Fprintf(w, "Subtitles: %s\n", strings.Join(codes, ", "))
+		}
+	}
+
+	fmt.Fprintln(w)
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "ID\tEXT\tRESOLUTION\tSIZE\tVCODEC\tACODEC\tNOTE")
+	for _, format := range info.Formats {
+		size := format.FileSize
+		if size == nil {
+			size = format.FileSizeApprox
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			format.FormatID, format.Ext, format.Resolution, formatFileSize(size), format.VCodec, format.ACodec, format.FormatNote)
+	}
+	table.Flush()
+}
+
+// cliDownload downloads a single video and waits for it to finish
+func cliDownload(app *App, args []string, stdout, stderr io.Writer) error {
+	var common cliCommonFlags
+	var download cliDownloadFlags
+	fs := newCLIFlagSet("download", "URL", stderr)
+	download.register(fs)
+	sink, err := parseCLIFlags(app, fs, &common, args, 1, stdout, stderr)
+	if err != nil {
+		return err
+	}
+
+	optionsJSON, err := download.options()
+	if err != nil {
+		return err
+	}
+	outputPath, err := download.outputPath(app, false)
+	if err != nil {
+		return err
+	}
+
+	stop := cancelOnInterrupt(func() { app.cancelDownloadInternal() })
+	defer stop()
+	if _, err := app.downloadVideoInternal(fs.Arg(0), download.format, outputPath, optionsJSON); err != nil {
+		return err
+	}
+	if !sink.wait() {
+		return errCLIFailed
+	}
+	return nil
+}
+
+// cliPlaylist downloads a playlist and waits for it to finish
+func cliPlaylist(app *App, args []string, stdout, stderr io.Writer) error {
+	var common cliCommonFlags
+	var download cliDownloadFlags
+	var startItem, endItem int
+	fs := newCLIFlagSet("playlist", "URL", stderr)
+	download.register(fs)
+	fs.IntVar(&startItem, "start", 0, "First playlist item to download (1-based), 0 for all")
+	fs.IntVar(&endItem, "end", 0, "Last playlist item to download, 0 for the end of the playlist")
+	sink, err := parseCLIFlags(app, fs, &common, args, 1, stdout, stderr)
+	if err != nil {
+		return err
+	}
+
+	optionsJSON, err := download.options()
+	if err != nil {
+		return err
+	}
+	outputPath, err := download.outputPath(app, true)
+	if err != nil {
+		return err
+	}
+
+	stop := cancelOnInterrupt(func() { app.cancelDownloadInternal() })
+	defer stop()
+	if _, err := app.enqueuePlaylistInternal(fs.Arg(0), download.format, outputPath, startItem, endItem, optionsJSON); err != nil {
+		return err
+	}
+	if !sink.wait() {
+		return errCLIFailed
+	}
+	return nil
+}
+
+// cliConvert converts a file with FFmpeg and waits for it to finish
+func cliConvert(app *App, args []string, stdout, stderr io.Writer) error {
+	var common cliCommonFlags
+	fs := newCLIFlagSet("convert", "SOURCE FORMAT", stderr)
+	sink, err := parseCLIFlags(app, fs, &common, args, 2, stdout, stderr)
+	if err != nil {
+		return err
+	}
+
+	stop := cancelOnInterrupt(func() { app.CancelConversion() })
+	defer stop()
+	if _, err := app.convertVideoInternal(fs.Arg(0), strings.TrimPrefix(fs.Arg(1), ".")); err != nil {
+		return err
+	}
+	if !sink.wait() {
+		return errCLIFailed
+	}
+	return nil
+}
+
+// cliDeps downloads missing dependencies and prints the yt-dlp version
+func cliDeps(app *App, args []string, stdout, stderr io.Writer) error {
+	var common cliCommonFlags
+	fs := newCLIFlagSet("deps", "", stderr)
+	sink, err := parseCLIFlags(app, fs, &common, args, 0, stdout, stderr)
+	if err != nil {
+		return err
+	}
+
+	app.SetupDependencies()
+	if !sink.wait() {
+		return errCLIFailed
+	}
+
+	version, err := app.getYtDlpVersionInternal()
+	if err != nil {
+		return err
+	}
+	if !common.json {
+		fmt.Fprintf(stdout, "yt-dlp %s\n", version)
+	}
+	return nil
+}
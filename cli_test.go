@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCLISinkJSONMode(t *testing.T) {
+	var out, errOut bytes.Buffer
+	sink := newCLISink(&out, &errOut, true, false)
+
+	sink.Emit("download-progress", map[string]interface{}{"id": "job", "progress": 42})
+	sink.Emit("setup-started")
+	sink.Emit("download-complete", map[string]interface{}{"id": "job", "file_path": "/tmp/clip.mp4"})
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected one line per event, got %q", out.String())
+	}
+
+	var event struct {
+		Event string                 `json:"event"`
+		Data  map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &event); err != nil {
+		t.Fatalf("line is not valid JSON: %v", err)
+	}
+	if event.Event != "download-progress" || event.Data["progress"] != float64(42) || event.Data["id"] != "job" {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if lines[1] != `{"event":"setup-started"}` {
+		t.Errorf("event without payload = %s", lines[1])
+	}
+
+	if !sink.wait() {
+		t.Error("download-complete should finish the command successfully")
+	}
+}
+
+func TestCLISinkTextMode(t *testing.T) {
+	var out, errOut bytes.Buffer
+	sink := newCLISink(&out, &errOut, false, false)
+
+	sink.Emit("download-progress", progressUpdate{Phase: progressPhaseDownload, Percent: 50, TotalBytes: 2048, SpeedBps: 1024, EtaSeconds: 1}.eventData())
+	sink.Emit("download-progress", map[string]interface{}{"progress": 99, "phase": progressPhasePostprocess, "postprocessor": "ExtractAudio"})
+	sink.Infof("not shown without -v")
+	sink.Emit("download-error", map[string]interface{}{"error": "boom"})
+
+	if !strings.Contains(out.String(), "\r[ 50%] 2.00 KB at 1.00 KB/s, ETA 0:01") {
+		t.Errorf("missing download progress line: %q", out.String())
+	}
+	if !strings.Contains(out.String(), "\r[ 99%] ExtractAudio...") {
+		t.Errorf("missing post-processing line: %q", out.String())
+	}
+	if !strings.HasSuffix(out.String(), "\n") {
+		t.Errorf("the progress line should be ended before the error is printed: %q", out.String())
+	}
+	if errOut.String() != "Error: boom\n" {
+		t.Errorf("stderr = %q, want only the error", errOut.String())
+	}
+
+	if sink.wait() {
+		t.Error("download-error should fail the command")
+	}
+}
+
+func TestCLISinkAlreadyDownloaded(t *testing.T) {
+	var out, errOut bytes.Buffer
+	sink := newCLISink(&out, &errOut, false, false)
+
+	sink.Emit("download-complete", map[string]interface{}{"id": "job-1", "already_downloaded": true})
+
+	if out.String() != "Already downloaded, skipped by the download archive\n" {
+		t.Errorf("stdout = %q, want the already downloaded line", out.String())
+	}
+	if !sink.wait() {
+		t.Error("a download skipped by the archive should not fail the command")
+	}
+}
+
+func TestCLIDownloadFlagsOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		flags    cliDownloadFlags
+		expected string
+		wantErr  bool
+	}{
+		{"Defaults", cliDownloadFlags{}, `{}`, false},
+		{"Audio", cliDownloadFlags{audio: "MP3", quality: "192K"},
+			`{"audio":{"codec":"mp3","quality":"192K","keep_original":false}}`, false},
+		{"Subtitles", cliDownloadFlags{subs: "en,de", subFormat: "srt"},
+			`{"subtitles":{"languages":["en","de"],"auto":false,"format":"srt","embed":false}}`, false},
+		{"InvalidCodec", cliDownloadFlags{audio: "xyz"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.flags.options()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("options() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.expected {
+				t.Errorf("options() = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRunCLIUsage(t *testing.T) {
+	var out, errOut bytes.Buffer
+	if code := runCLI(nil, &out, &errOut); code != 2 {
+		t.Errorf("runCLI() without a command = %d, want 2", code)
+	}
+	if code := runCLI([]string{"bogus"}, &out, &errOut); code != 2 {
+		t.Errorf("runCLI() with an unknown command = %d, want 2", code)
+	}
+	if !strings.Contains(errOut.String(), "Unknown command \"bogus\"") {
+		t.Errorf("missing unknown command message: %q", errOut.String())
+	}
+}
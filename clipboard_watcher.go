@@ -0,0 +1,230 @@
+package main
+
+import (
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// clipboardPollInterval is how often the clipboard watcher reads the clipboard
+const clipboardPollInterval = time.Second
+
+// clipboardSeenLimit bounds how many detected URLs the watcher remembers
+const clipboardSeenLimit = 256
+
+// clipboardURLPattern finds http(s) URLs in copied text
+var clipboardURLPattern = regexp.MustCompile(`https?://[^\s"'<>]+`)
+
+// extractorSiteAliases maps domains whose name differs from their extractor's
+var extractorSiteAliases = map[string]string{
+	"youtu.be": "youtube",
+	"x.com":    "twitter",
+	"redd.it":  "reddit",
+	"fb.watch": "facebook",
+}
+
+// extractorSites is the set of site names yt-dlp has extractors for, such as
+// "youtube" or "vimeo"
+type extractorSites map[string]bool
+
+// normalizeSiteName lowercases a name and drops everything but letters and digits
+func normalizeSiteName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// parseExtractorList derives site names from the output of yt-dlp
+// --list-extractors, e.g. "youtube:playlist" and "Vimeo" become "youtube" and "vimeo"
+func parseExtractorList(output string) extractorSites {
+	sites := make(extractorSites)
+	for _, line := range strings.Split(output, "\n") {
+		name := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(line), "(CURRENTLY BROKEN)"))
+		if idx := strings.Index(name, ":"); idx >= 0 {
+			name = name[:idx]
+		}
+		name = normalizeSiteName(name)
+		// The generic extractor accepts any URL, which is not a useful signal
+		if len(name) < 2 || name == "generic" {
+			continue
+		}
+		sites[name] = true
+	}
+	return sites
+}
+
+// siteFor returns the site a URL belongs to, or "" if no extractor handles it.
+// A URL matches when one of its host labels, other than the top-level domain,
+// names an extractor.
+func (s extractorSites) siteFor(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(parsed.Hostname()), "www.")
+	if host == "" {
+		return ""
+	}
+
+	if site, ok := extractorSiteAliases[host]; ok && s[site] {
+		return site
+	}
+	labels := strings.Split(host, ".")
+	for _, label := range labels[:len(labels)-1] {
+		if site := normalizeSiteName(label); s[site] {
+			return site
+		}
+	}
+	return ""
+}
+
+// loadExtractorSites asks yt-dlp which sites it supports
+func (a *App) loadExtractorSites() (extractorSites, error) {
+	ytDlpPath := filepath.Join("./bin", a.getYtDlpBinaryName())
+
+	cmd := exec.Command(ytDlpPath, "--list-extractors")
+	setHideWindow(cmd)
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseExtractorList(string(output)), nil
+}
+
+// clipboardWatcher polls the clipboard and reports newly copied URLs that
+// yt-dlp supports
+type clipboardWatcher struct {
+	read     func() (string, error)
+	sites    extractorSites
+	interval time.Duration
+	stop     chan struct{}
+	done     chan struct{}
+
+	last  string          // Clipboard text of the previous poll
+	seen  map[string]bool // URLs already reported
+	order []string        // Reported URLs, oldest first
+}
+
+// newClipboardWatcher creates a watcher reading the clipboard with read
+func newClipboardWatcher(read func() (string, error), sites extractorSites) *clipboardWatcher {
+	return &clipboardWatcher{
+		read:     read,
+		sites:    sites,
+		interval: clipboardPollInterval,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+		seen:     make(map[string]bool),
+	}
+}
+
+// detect returns the supported URLs in text that have not been reported yet
+// and remembers them
+func (w *clipboardWatcher) detect(text string) []string {
+	var found []string
+	for _, match := range clipboardURLPattern.FindAllString(text, -1) {
+		match = strings.TrimRight(match, ".,;:!?)]}")
+		if w.seen[match] || w.sites.siteFor(match) == "" {
+			continue
+		}
+
+		w.seen[match] = true
+		w.order = append(w.order, match)
+		if len(w.order) > clipboardSeenLimit {
+			delete(w.seen, w.order[0])
+			w.order = w.order[1:]
+		}
+		found = append(found, match)
+	}
+	return found
+}
+
+// run polls the clipboard until stopped. Whatever is on the clipboard when the
+// watcher starts is not reported; only text copied afterwards is.
+func (w *clipboardWatcher) run(events EventSink) {
+	defer close(w.done)
+
+	w.last, _ = w.read()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+
+		text, err := w.read()
+		if err != nil || text == w.last {
+			continue
+		}
+		w.last = text
+
+		for _, found := range w.detect(text) {
+			events.Emit("clipboard-url-detected", map[string]interface{}{
+				"url":  found,
+				"site": w.sites.siteFor(found),
+			})
+		}
+	}
+}
+
+// startClipboardWatcher starts watching the clipboard if it is not watched yet
+func (a *App) startClipboardWatcher() {
+	a.clipboardMu.Lock()
+	defer a.clipboardMu.Unlock()
+	if a.clipboard != nil {
+		return
+	}
+
+	sites, err := a.loadExtractorSites()
+	if err != nil {
+		a.logger.Warningf("Failed to list yt-dlp extractors, only YouTube links will be detected: %v", err)
+		sites = extractorSites{"youtube": true}
+	}
+
+	a.clipboard = newClipboardWatcher(a.getClipboardTextInternal, sites)
+	go a.clipboard.run(a.events)
+	a.logger.Infof("Clipboard watcher started (%d supported sites)", len(sites))
+}
+
+// stopClipboardWatcher stops watching the clipboard
+func (a *App) stopClipboardWatcher() {
+	a.clipboardMu.Lock()
+	defer a.clipboardMu.Unlock()
+	if a.clipboard == nil {
+		return
+	}
+
+	close(a.clipboard.stop)
+	<-a.clipboard.done
+	a.clipboard = nil
+	a.logger.Infof("Clipboard watcher stopped")
+}
+
+// setClipboardWatchInternal turns the clipboard watcher on or off and saves the choice
+func (a *App) setClipboardWatchInternal(enabled bool) error {
+	err := a.updateSettings(func(settings *Settings) {
+		settings.ClipboardWatch = enabled
+	})
+	if err != nil {
+		a.logger.Errorf("Failed to save clipboard watch setting: %v", err)
+		return err
+	}
+
+	if enabled {
+		a.startClipboardWatcher()
+	} else {
+		a.stopClipboardWatcher()
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testExtractorList = `youtube
+youtube:playlist
+youtube:tab
+Vimeo
+vimeo:album
+BBC
+twitter:broadcast
+TwitchVod (CURRENTLY BROKEN)
+generic
+`
+
+func TestParseExtractorList(t *testing.T) {
+	got := parseExtractorList(testExtractorList)
+	want := extractorSites{"youtube": true, "vimeo": true, "bbc": true, "twitter": true, "twitchvod": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseExtractorList() = %v, want %v", got, want)
+	}
+}
+
+func TestExtractorSitesSiteFor(t *testing.T) {
+	sites := parseExtractorList(testExtractorList)
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.youtube.com/watch?v=abc", "youtube"},
+		{"https://m.youtube.com/watch?v=abc", "youtube"},
+		{"https://youtu.be/abc", "youtube"},
+		{"https://vimeo.com/123", "vimeo"},
+		{"https://www.bbc.co.uk/iplayer/episode/abc", "bbc"},
+		{"https://x.com/user/status/1", "twitter"},
+		{"https://example.com/video.mp4", ""},
+		{"https://notyoutube.example.com/", ""},
+		{"ftp://vimeo.com/123", ""},
+		{"not a url", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := sites.siteFor(tt.url); got != tt.want {
+				t.Errorf("siteFor(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClipboardWatcherDetect(t *testing.T) {
+	w := newClipboardWatcher(nil, parseExtractorList(testExtractorList))
+
+	got := w.detect("see https://vimeo.com/1, https://example.com/x and (https://youtu.be/abc)")
+	if want := []string{"https://vimeo.com/1", "https://youtu.be/abc"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("detect() = %q, want %q", got, want)
+	}
+	if got := w.detect("https://vimeo.com/1"); len(got) != 0 {
+		t.Errorf("already reported URLs should be skipped, got %q", got)
+	}
+}
+
+func TestClipboardWatcherRun(t *testing.T) {
+	var mu sync.Mutex
+	clipboard := "https://vimeo.com/old"
+	read := func() (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return clipboard, nil
+	}
+	copyText := func(text string) {
+		mu.Lock()
+		clipboard = text
+		mu.Unlock()
+	}
+
+	sink := &recordingSink{}
+	w := newClipboardWatcher(read, parseExtractorList(testExtractorList))
+	w.interval = 5 * time.Millisecond
+	go w.run(sink)
+	defer func() {
+		close(w.stop)
+		<-w.done
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	copyText("https://example.com/page")
+	time.Sleep(20 * time.Millisecond)
+	copyText("https://www.youtube.com/watch?v=abc")
+	event := sink.waitFor(t, "clipboard-url-detected")
+
+	data := event.Data[0].(map[string]interface{})
+	if data["url"] != "https://www.youtube.com/watch?v=abc" || data["site"] != "youtube" {
+		t.Errorf("unexpected event data: %v", data)
+	}
+	// Copying the same URL again is not reported twice
+	copyText("something else")
+	time.Sleep(20 * time.Millisecond)
+	copyText("https://www.youtube.com/watch?v=abc")
+	time.Sleep(20 * time.Millisecond)
+	if names := sink.Names(); len(names) != 1 {
+		t.Errorf("expected exactly one event, got %v", names)
+	}
+}
+
+func TestLoadExtractorSites(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, _ := newTestApp(t)
+	tools.install(app.getYtDlpBinaryName(), fakeRun{When: []string{"--list-extractors"}, Stdout: []string{"Vimeo", "generic"}})
+
+	sites, err := app.loadExtractorSites()
+	if err != nil {
+		t.Fatalf("loadExtractorSites() error = %v", err)
+	}
+	if !reflect.DeepEqual(sites, extractorSites{"vimeo": true}) {
+		t.Errorf("sites = %v", sites)
+	}
+}
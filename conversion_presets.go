@@ -0,0 +1,372 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// targetSizeOverhead leaves room for the container when a preset aims for a file size
+const targetSizeOverhead = 0.95
+
+// minTargetVideoKbps is the lowest video bitrate a target size may force.
+// Sources too long to fit the target at this bitrate are rejected.
+const minTargetVideoKbps = 100
+
+// containerPattern matches the file extensions a preset may produce
+var containerPattern = regexp.MustCompile(`^[a-z0-9]+$`)
+
+// bitratePattern matches FFmpeg bitrates such as "128k", "2.5M" or "800000"
+var bitratePattern = regexp.MustCompile(`^\d+(\.\d+)?[kKmM]?$`)
+
+// builtInConversionPresets ship with the app and cannot be overwritten
+var builtInConversionPresets = []ConversionPreset{
+	{
+		Name: "Phone 720p", Container: "mp4",
+		VideoCodec: "libx264", CRF: 23, Preset: "medium", MaxHeight: 720, FPS: 30,
+		AudioCodec: "aac", AudioBitrate: "128k", SampleRate: 44100, Channels: 2,
+	},
+	{
+		Name: "Web 1080p", Container: "mp4",
+		VideoCodec: "libx264", CRF: 21, Preset: "slow", MaxHeight: 1080,
+		AudioCodec: "aac", AudioBitrate: "192k",
+	},
+	{
+		Name: "Discord <25MB", Container: "mp4",
+		VideoCodec: "libx264", Preset: "medium", MaxHeight: 720, FPS: 30,
+		AudioCodec: "aac", AudioBitrate: "96k", Channels: 2, TargetSizeMB: 24,
+	},
+	{
+		Name: "Archive FLAC", Container: "flac",
+		AudioCodec: "flac",
+	},
+	{
+		Name: "MP3 320k", Container: "mp3",
+		AudioCodec: "libmp3lame", AudioBitrate: "320k",
+	},
+}
+
+// validate checks a preset for values FFmpeg would reject
+func (p *ConversionPreset) validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return fmt.Errorf("preset name cannot be empty")
+	}
+	if !containerPattern.MatchString(p.Container) {
+		return fmt.Errorf("invalid preset container: %q", p.Container)
+	}
+	if p.VideoCodec == "" && p.AudioCodec == "none" {
+		return fmt.Errorf("preset %s has neither video nor audio", p.Name)
+	}
+	if p.CRF < 0 || p.CRF > 63 {
+		return fmt.Errorf("invalid CRF: %d", p.CRF)
+	}
+	for _, bitrate := range []string{p.VideoBitrate, p.AudioBitrate} {
+		if bitrate != "" && !bitratePattern.MatchString(bitrate) {
+			return fmt.Errorf("invalid bitrate: %s", bitrate)
+		}
+	}
+	if p.MaxHeight < 0 || p.FPS < 0 || p.SampleRate < 0 || p.Channels < 0 || p.TargetSizeMB < 0 {
+		return fmt.Errorf("preset %s has negative values", p.Name)
+	}
+	if p.TargetSizeMB > 0 && (p.VideoCodec == "" || p.VideoCodec == "copy") {
+		return fmt.Errorf("a target size needs a video encoder")
+	}
+	return nil
+}
+
+// audioOnly reports whether the preset drops the video stream
+func (p *ConversionPreset) audioOnly() bool {
+	return p.VideoCodec == ""
+}
+
+// parseBitrateKbps converts an FFmpeg bitrate to kbit/s, 0 when empty or invalid
+func parseBitrateKbps(bitrate string) float64 {
+	if !bitratePattern.MatchString(bitrate) {
+		return 0
+	}
+	multiplier := 0.001
+	switch bitrate[len(bitrate)-1] {
+	case 'k', 'K':
+		multiplier = 1
+		bitrate = bitrate[:len(bitrate)-1]
+	case 'm', 'M':
+		multiplier = 1000
+		bitrate = bitrate[:len(bitrate)-1]
+	}
+	value, _ := strconv.ParseFloat(bitrate, 64)
+	return value * multiplier
+}
+
+// targetVideoKbps returns the video bitrate that keeps a file of the given
+// duration below the preset's target size, or an error when that bitrate
+// would fall below minTargetVideoKbps
+func (p *ConversionPreset) targetVideoKbps(duration float64) (int, error) {
+	audioKbps := parseBitrateKbps(p.AudioBitrate)
+	if audioKbps == 0 && p.AudioCodec != "none" {
+		audioKbps = 128
+	}
+	totalKbps := p.TargetSizeMB * 8 * 1024 * targetSizeOverhead / duration
+	kbps := int(totalKbps - audioKbps)
+	if kbps < minTargetVideoKbps {
+		return 0, fmt.Errorf("source is too long to fit in %g MB: %s of video would need %d kbit/s, below the %d kbit/s minimum",
+			p.TargetSizeMB, formatDuration(duration), max(kbps, 0), minTargetVideoKbps)
+	}
+	return kbps, nil
+}
+
+// ffmpegArgs builds the FFmpeg arguments for converting sourcePath to targetPath.
+// duration is only needed for presets with a target size.
+func (p *ConversionPreset) ffmpegArgs(sourcePath, targetPath string, duration float64) ([]string, error) {
+	args := []string{"-i", sourcePath}
+
+	switch {
+	case p.VideoCodec == "":
+		args = append(args, "-vn")
+	case p.VideoCodec == "copy":
+		args = append(args, "-c:v", "copy")
+	default:
+		args = append(args, "-c:v", p.VideoCodec)
+		switch {
+		case p.TargetSizeMB > 0:
+			if duration <= 0 {
+				return nil, fmt.Errorf("cannot target %g MB without knowing the duration", p.TargetSizeMB)
+			}
+			kbps, err := p.targetVideoKbps(duration)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, "-b:v", fmt.Sprintf("%dk", kbps), "-maxrate", fmt.Sprintf("%dk", kbps), "-bufsize", fmt.Sprintf("%dk", 2*kbps))
+		case p.VideoBitrate != "":
+			args = append(args, "-b:v", p.VideoBitrate)
+		case p.CRF > 0:
+			args = append(args, "-crf", strconv.Itoa(p.CRF))
+		}
+		if p.Preset != "" {
+			args = append(args, "-preset", p.Preset)
+		}
+
+		var filters []string
+		if p.MaxHeight > 0 {
+			// Never upscale and keep the width even, which most encoders require
+			filters = append(filters, fmt.Sprintf("scale=-2:'min(ih,%d)'", p.MaxHeight))
+		}
+		if p.FPS > 0 {
+			filters = append(filters, "fps="+strconv.FormatFloat(p.FPS, 'f', -1, 64))
+		}
+		if p.VideoFilters != "" {
+			filters = append(filters, p.VideoFilters)
+		}
+		if len(filters) > 0 {
+			args = append(args, "-vf", strings.Join(filters, ","))
+		}
+	}
+
+	if p.AudioCodec == "none" {
+		args = append(args, "-an")
+	} else {
+		if p.AudioCodec != "" {
+			args = append(args, "-c:a", p.AudioCodec)
+		}
+		if p.AudioBitrate != "" {
+			args = append(args, "-b:a", p.AudioBitrate)
+		}
+		if p.SampleRate > 0 {
+			args = append(args, "-ar", strconv.Itoa(p.SampleRate))
+		}
+		if p.Channels > 0 {
+			args = append(args, "-ac", strconv.Itoa(p.Channels))
+		}
+		if p.AudioFilters != "" {
+			args = append(args, "-af", p.AudioFilters)
+		}
+	}
+
+	switch p.Container {
+	case "mp4", "m4a", "mov":
+		args = append(args, "-movflags", "+faststart")
+	}
+	return append(args, "-map_metadata", "0", "-y", targetPath), nil
+}
+
+// findConversionPreset looks up a built-in or user-defined preset by name,
+// ignoring case
+func (a *App) findConversionPreset(name string) (ConversionPreset, bool) {
+	for _, presets := range [][]ConversionPreset{builtInConversionPresets, a.currentSettings().ConversionPresets} {
+		for _, preset := range presets {
+			if strings.EqualFold(preset.Name, name) {
+				return preset, true
+			}
+		}
+	}
+	return ConversionPreset{}, false
+}
+
+// listConversionPresetsInternal returns the built-in presets followed by the
+// user-defined ones as JSON
+func (a *App) listConversionPresetsInternal() (string, error) {
+	userPresets := a.currentSettings().ConversionPresets
+	presets := make([]ConversionPreset, 0, len(builtInConversionPresets)+len(userPresets))
+	for _, preset := range builtInConversionPresets {
+		preset.BuiltIn = true
+		presets = append(presets, preset)
+	}
+	presets = append(presets, userPresets...)
+
+	result, err := json.Marshal(presets)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal conversion presets: %w", err)
+	}
+	return string(result), nil
+}
+
+// saveConversionPresetInternal adds a JSON-encoded user preset, replacing the
+// user preset with the same name
+func (a *App) saveConversionPresetInternal(presetJSON string) error {
+	var preset ConversionPreset
+	if err := json.Unmarshal([]byte(presetJSON), &preset); err != nil {
+		return fmt.Errorf("failed to parse conversion preset: %w", err)
+	}
+	preset.Name = strings.TrimSpace(preset.Name)
+	preset.Container = strings.TrimPrefix(strings.ToLower(preset.Container), ".")
+	preset.BuiltIn = false
+	if err := preset.validate(); err != nil {
+		return err
+	}
+	for _, builtIn := range builtInConversionPresets {
+		if strings.EqualFold(builtIn.Name, preset.Name) {
+			return fmt.Errorf("cannot overwrite built-in preset: %s", builtIn.Name)
+		}
+	}
+
+	err := a.updateSettings(func(settings *Settings) {
+		index := slices.IndexFunc(settings.ConversionPresets, func(p ConversionPreset) bool {
+			return strings.EqualFold(p.Name, preset.Name)
+		})
+		if index >= 0 {
+			settings.ConversionPresets[index] = preset
+		} else {
+			settings.ConversionPresets = append(settings.ConversionPresets, preset)
+		}
+	})
+	if err != nil {
+		a.logger.Errorf("Failed to save conversion preset: %v", err)
+		return err
+	}
+	a.logger.Infof("Conversion preset saved: %s", preset.Name)
+	return nil
+}
+
+// deleteConversionPresetInternal removes a user-defined preset
+func (a *App) deleteConversionPresetInternal(name string) error {
+	matchesName := func(p ConversionPreset) bool {
+		return strings.EqualFold(p.Name, name)
+	}
+	if !slices.ContainsFunc(a.currentSettings().ConversionPresets, matchesName) {
+		return fmt.Errorf("no user-defined conversion preset named %s", name)
+	}
+
+	err := a.updateSettings(func(settings *Settings) {
+		settings.ConversionPresets = slices.DeleteFunc(settings.ConversionPresets, matchesName)
+	})
+	if err != nil {
+		a.logger.Errorf("Failed to save conversion presets: %v", err)
+		return err
+	}
+	return nil
+}
+
+// probeDuration reads the duration of a media file from FFmpeg's input summary
+func probeDuration(ffmpegPath, sourcePath string) (float64, error) {
+	cmd := exec.Command(ffmpegPath, "-hide_banner", "-i", sourcePath)
+	setHideWindow(cmd)
+	// FFmpeg exits with an error when no output is given, but still prints the input summary
+	output, _ := cmd.CombinedOutput()
+
+	_, after, found := strings.Cut(string(output), "Duration:")
+	if !found {
+		return 0, fmt.Errorf("failed to read the duration of %s", sourcePath)
+	}
+	duration := parseFFmpegTime(strings.TrimSpace(strings.Split(after, ",")[0]))
+	if duration <= 0 {
+		return 0, fmt.Errorf("failed to read the duration of %s", sourcePath)
+	}
+	return duration, nil
+}
+
+// presetTargetPath returns where a preset conversion writes its output: next to
+// the source with the preset's extension, or with the preset name appended when
+// that would overwrite the source
+func presetTargetPath(sourcePath string, preset ConversionPreset) string {
+	base := strings.TrimSuffix(sourcePath, filepath.Ext(sourcePath))
+	targetPath := base + "." + preset.Container
+	if filepath.Clean(targetPath) == filepath.Clean(sourcePath) {
+		suffix := strings.Map(func(r rune) rune {
+			if r == ' ' || r == '<' || r == '>' || strings.ContainsRune(`/\:*?"|`, r) {
+				return '_'
+			}
+			return r
+		}, preset.Name)
+		targetPath = base + "_" + suffix + "." + preset.Container
+	}
+	return targetPath
+}
+
+// convertWithPresetInternal queues the conversion of a file with a built-in or
+// user-defined preset and returns the conversion job ID
+func (a *App) convertWithPresetInternal(sourcePath, presetName string) (string, error) {
+	return a.enqueuePresetConversion(sourcePath, presetName)
+}
+
+// enqueuePresetConversion checks the source and preset and queues the conversion
+func (a *App) enqueuePresetConversion(sourcePath, presetName string) (string, error) {
+	job, err := a.presetConversionJob(sourcePath, presetName)
+	if err != nil {
+		return "", err
+	}
+	return a.enqueueConversion(job), nil
+}
+
+// presetConversionJob checks the source and preset and returns the job that
+// converts the source, without queuing it
+func (a *App) presetConversionJob(sourcePath, presetName string) (*ConversionJob, error) {
+	if _, err := os.Stat(sourcePath); os.IsNotExist(err) {
+		return nil, fmt.Errorf("source file does not exist: %s", sourcePath)
+	}
+	preset, ok := a.findConversionPreset(presetName)
+	if !ok {
+		return nil, fmt.Errorf("unknown conversion preset: %s", presetName)
+	}
+
+	return &ConversionJob{
+		SourcePath: sourcePath,
+		TargetPath: presetTargetPath(sourcePath, preset),
+		Preset:     preset.Name,
+	}, nil
+}
+
+// presetConversionArgs probes the source of a preset job and returns the FFmpeg
+// arguments and the source duration
+func (a *App) presetConversionArgs(job *ConversionJob, ffmpegPath string) ([]string, float64, error) {
+	preset, ok := a.findConversionPreset(job.Preset)
+	if !ok {
+		return nil, 0, fmt.Errorf("unknown conversion preset: %s", job.Preset)
+	}
+
+	duration, err := mediaDuration(ffmpegPath, job.SourcePath)
+	if err != nil && preset.TargetSizeMB > 0 {
+		return nil, 0, err
+	}
+
+	args, err := preset.ffmpegArgs(job.SourcePath, job.TargetPath, duration)
+	if err != nil {
+		return nil, 0, err
+	}
+	a.logger.Infof("Converting with preset %s", preset.Name)
+	return args, duration, nil
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestConversionPresetArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		preset   ConversionPreset
+		duration float64
+		want     []string
+	}{
+		{
+			name:   "Phone720p",
+			preset: builtInConversionPresets[0],
+			want: []string{"-i", "in.mkv", "-c:v", "libx264", "-crf", "23", "-preset", "medium",
+				"-vf", "scale=-2:'min(ih,720)',fps=30", "-c:a", "aac", "-b:a", "128k", "-ar", "44100", "-ac", "2",
+				"-movflags", "+faststart", "-map_metadata", "0", "-y", "out.mp4"},
+		},
+		{
+			name:   "AudioOnly",
+			preset: ConversionPreset{Name: "FLAC", Container: "flac", AudioCodec: "flac", AudioFilters: "loudnorm"},
+			want:   []string{"-i", "in.mkv", "-vn", "-c:a", "flac", "-af", "loudnorm", "-map_metadata", "0", "-y", "out.mp4"},
+		},
+		{
+			name:     "TargetSize",
+			preset:   ConversionPreset{Name: "Small", Container: "mkv", VideoCodec: "libx264", AudioBitrate: "96k", TargetSizeMB: 24},
+			duration: 120,
+			// 24 MB over two minutes leaves 24*8*1024*0.95/120 - 96 = 1460 kbit/s for the video
+			want: []string{"-i", "in.mkv", "-c:v", "libx264", "-b:v", "1460k", "-maxrate", "1460k", "-bufsize", "2920k",
+				"-b:a", "96k", "-map_metadata", "0", "-y", "out.mp4"},
+		},
+		{
+			name:   "CopyVideoWithoutAudio",
+			preset: ConversionPreset{Name: "Mute", Container: "mp4", VideoCodec: "copy", AudioCodec: "none"},
+			want:   []string{"-i", "in.mkv", "-c:v", "copy", "-an", "-movflags", "+faststart", "-map_metadata", "0", "-y", "out.mp4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.preset.ffmpegArgs("in.mkv", "out.mp4", tt.duration)
+			if err != nil {
+				t.Fatalf("ffmpegArgs() error = %v", err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ffmpegArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConversionPresetTargetSizeNeedsDuration(t *testing.T) {
+	preset := builtInConversionPresets[2]
+	if _, err := preset.ffmpegArgs("in.mkv", "out.mp4", 0); err == nil {
+		t.Error("expected an error without a duration")
+	}
+}
+
+func TestConversionPresetTargetSizeTooLong(t *testing.T) {
+	preset := ConversionPreset{Name: "Small", Container: "mp4", VideoCodec: "libx264", AudioBitrate: "128k", TargetSizeMB: 25}
+
+	// 25 MB leaves 25*8*1024*0.95/900 - 128 = 88 kbit/s for fifteen minutes of video
+	_, err := preset.ffmpegArgs("in.mkv", "out.mp4", 900)
+	if err == nil || !strings.Contains(err.Error(), "15:00 of video would need 88 kbit/s") {
+		t.Errorf("expected an error instead of overshooting the target size, got %v", err)
+	}
+	if _, err := preset.ffmpegArgs("in.mkv", "out.mp4", 600); err != nil {
+		t.Errorf("ten minutes should fit, got %v", err)
+	}
+}
+
+func TestSaveConversionPreset(t *testing.T) {
+	t.Chdir(t.TempDir())
+	app, _ := newTestApp(t)
+
+	tests := []struct {
+		name    string
+		preset  string
+		wantErr bool
+	}{
+		{name: "Valid", preset: `{"name":"Tiny","container":".MKV","video_codec":"libx265","crf":30,"built_in":true}`},
+		{name: "Replace", preset: `{"name":"tiny","container":"mkv","video_codec":"libx265","crf":28}`},
+		{name: "BuiltInName", preset: `{"name":"phone 720p","container":"mp4","video_codec":"libx264"}`, wantErr: true},
+		{name: "BadContainer", preset: `{"name":"Bad","container":"mp4 -y"}`, wantErr: true},
+		{name: "BadBitrate", preset: `{"name":"Bad","container":"mp3","audio_bitrate":"loud"}`, wantErr: true},
+		{name: "NoStreams", preset: `{"name":"Bad","container":"mp4","audio_codec":"none"}`, wantErr: true},
+		{name: "InvalidJSON", preset: `{`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := app.saveConversionPresetInternal(tt.preset)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("saveConversionPresetInternal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	result, err := app.listConversionPresetsInternal()
+	if err != nil {
+		t.Fatalf("listConversionPresetsInternal() error = %v", err)
+	}
+	var presets []ConversionPreset
+	if err := json.Unmarshal([]byte(result), &presets); err != nil {
+		t.Fatalf("invalid presets JSON: %v", err)
+	}
+	if len(presets) != len(builtInConversionPresets)+1 || !presets[0].BuiltIn {
+		t.Fatalf("unexpected presets: %+v", presets)
+	}
+	if user := presets[len(presets)-1]; user.Name != "tiny" || user.Container != "mkv" || user.CRF != 28 || user.BuiltIn {
+		t.Errorf("unexpected user preset: %+v", user)
+	}
+
+	// User presets are kept in the settings file
+	data, err := os.ReadFile("settings.json")
+	if err != nil || !strings.Contains(string(data), `"conversion_presets"`) {
+		t.Errorf("presets not saved with the settings: %s, %v", data, err)
+	}
+
+	if err := app.deleteConversionPresetInternal("TINY"); err != nil {
+		t.Errorf("deleteConversionPresetInternal() error = %v", err)
+	}
+	if err := app.deleteConversionPresetInternal("Phone 720p"); err == nil {
+		t.Error("built-in presets should not be deletable")
+	}
+}
+
+func TestPresetTargetPath(t *testing.T) {
+	tests := []struct {
+		source string
+		preset ConversionPreset
+		want   string
+	}{
+		{source: "clip.mkv", preset: builtInConversionPresets[0], want: "clip.mp4"},
+		{source: "clip.mp4", preset: builtInConversionPresets[2], want: "clip_Discord__25MB.mp4"},
+	}
+
+	for _, tt := range tests {
+		if got := presetTargetPath(tt.source, tt.preset); got != tt.want {
+			t.Errorf("presetTargetPath(%q, %s) = %q, want %q", tt.source, tt.preset.Name, got, tt.want)
+		}
+	}
+}
+
+func TestConvertWithPreset(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, sink := newTestApp(t)
+	if err := os.WriteFile("clip.mkv", []byte("video"), 0644); err != nil {
+		t.Fatalf("failed to create source file: %v", err)
+	}
+	tools.install("ffmpeg"+getExecutableExtension(),
+		fakeRun{When: []string{"-hide_banner"}, Stderr: []string{"  Duration: 00:04:00.00, start: 0.000000, bitrate: 1500 kb/s"}, ExitCode: 1},
+		fakeRun{When: []string{"-i", "clip.mkv"}, Files: map[string]string{"clip.mp4": "converted"}},
+	)
+
+	id, err := app.convertWithPresetInternal("clip.mkv", "discord <25mb")
+	if err != nil {
+		t.Fatalf("convertWithPresetInternal() error = %v", err)
+	}
+	complete := sink.waitFor(t, "conversion-complete").Data[0].(map[string]interface{})
+	if complete["id"] != id || complete["targetPath"] != "clip.mp4" {
+		t.Errorf("unexpected completion %v, want job %s writing clip.mp4", complete, id)
+	}
+
+	calls := tools.calls("ffmpeg" + getExecutableExtension())
+	if len(calls) != 2 || !slices.Contains(calls[1], "682k") {
+		t.Errorf("expected a probe and a conversion at 682 kbit/s, got %q", calls)
+	}
+
+	if _, err := app.convertWithPresetInternal("clip.mkv", "missing"); err == nil {
+		t.Error("expected an error for an unknown preset")
+	}
+}
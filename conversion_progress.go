@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ffmpegProgressArgs make FFmpeg write key=value progress blocks to stdout
+// instead of the status line on stderr
+var ffmpegProgressArgs = []string{"-progress", "pipe:1", "-nostats"}
+
+// conversionProgress collects FFmpeg's -progress output. Every block of
+// key=value lines ends with a progress=continue or progress=end line.
+type conversionProgress struct {
+	mu       sync.Mutex // stdout and stderr are read concurrently
+	duration float64    // Source duration in seconds, 0 while unknown
+	values   map[string]string
+}
+
+// newConversionProgress creates a parser for a source of the given duration
+func newConversionProgress(duration float64) *conversionProgress {
+	return &conversionProgress{duration: duration, values: make(map[string]string)}
+}
+
+// handleStderrLine takes the duration from FFmpeg's input summary when it was
+// not known up front
+func (p *conversionProgress) handleStderrLine(line string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.duration > 0 {
+		return
+	}
+	// Duration: 00:02:30.45, start: 0.000000, bitrate: 1500 kb/s
+	if _, after, found := strings.Cut(line, "Duration:"); found {
+		p.duration = parseFFmpegTime(strings.TrimSpace(strings.Split(after, ",")[0]))
+	}
+}
+
+// handleLine records a progress line and returns the conversion-progress
+// payload once a block is complete
+func (p *conversionProgress) handleLine(line string) (map[string]interface{}, bool) {
+	key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+	if !found {
+		return nil, false
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.values[key] = strings.TrimSpace(value)
+	if key != "progress" {
+		return nil, false
+	}
+
+	data := p.eventData(value == "end")
+	clear(p.values)
+	return data, true
+}
+
+// eventData builds the conversion-progress payload from the current block.
+// FFmpeg reports "N/A" for values it does not know yet.
+func (p *conversionProgress) eventData(finished bool) map[string]interface{} {
+	// out_time_ms is in microseconds as well, despite its name
+	outTimeUs, err := strconv.ParseInt(p.values["out_time_us"], 10, 64)
+	if err != nil {
+		outTimeUs, _ = strconv.ParseInt(p.values["out_time_ms"], 10, 64)
+	}
+	outTime := max(float64(outTimeUs)/1e6, 0)
+	speed, _ := strconv.ParseFloat(strings.TrimSuffix(p.values["speed"], "x"), 64)
+	fps, _ := strconv.ParseFloat(p.values["fps"], 64)
+	totalSize, _ := strconv.ParseInt(p.values["total_size"], 10, 64)
+
+	percent := 0.0
+	etaSeconds := int64(-1)
+	if p.duration > 0 {
+		percent = min(outTime/p.duration*100, 100)
+		if speed > 0 {
+			etaSeconds = int64(max(p.duration-outTime, 0) / speed)
+		}
+	}
+	if finished {
+		percent = 100
+		etaSeconds = 0
+	}
+
+	eta := "Calculating..."
+	if etaSeconds >= 0 {
+		eta = formatDuration(float64(etaSeconds))
+	}
+	return map[string]interface{}{
+		"progress":         int(percent),
+		"out_time":         formatDuration(outTime),
+		"out_time_seconds": outTime,
+		"duration":         p.duration,
+		"speed":            speed,
+		"fps":              fps,
+		"total_size":       totalSize,
+		"size":             formatFileSizeHuman(float64(totalSize)),
+		"eta":              eta,
+		"eta_seconds":      etaSeconds,
+	}
+}
+
+// mediaDuration returns the duration of a media file, from ffprobe when
+// available and otherwise from FFmpeg's input summary
+func mediaDuration(ffmpegPath, sourcePath string) (float64, error) {
+	if info, err := probeMedia(sourcePath); err == nil && info.Duration > 0 {
+		return info.Duration, nil
+	}
+	return probeDuration(ffmpegPath, sourcePath)
+}
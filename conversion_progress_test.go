@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestConversionProgressBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration float64
+		stderr   []string
+		lines    []string
+		want     map[string]interface{}
+	}{
+		{
+			name:     "Running",
+			duration: 100,
+			lines:    []string{"frame=250", "fps=25.00", "total_size=1048576", "out_time_us=10000000", "speed=2x", "progress=continue"},
+			want:     map[string]interface{}{"progress": 10, "out_time": "0:10", "speed": 2.0, "fps": 25.0, "total_size": int64(1048576), "eta_seconds": int64(45), "eta": "0:45"},
+		},
+		{
+			name:   "DurationFromStderr",
+			stderr: []string{"  Duration: 00:01:00.00, start: 0.000000, bitrate: 1500 kb/s"},
+			lines:  []string{"out_time_ms=30000000", "speed=1x", "progress=continue"},
+			want:   map[string]interface{}{"progress": 50, "duration": 60.0, "eta_seconds": int64(30)},
+		},
+		{
+			name:     "Unknown",
+			duration: 100,
+			lines:    []string{"total_size=N/A", "out_time_us=N/A", "speed=N/A", "progress=continue"},
+			want:     map[string]interface{}{"progress": 0, "speed": 0.0, "eta_seconds": int64(-1), "eta": "Calculating..."},
+		},
+		{
+			name:  "End",
+			lines: []string{"out_time_us=5000000", "progress=end"},
+			want:  map[string]interface{}{"progress": 100, "eta_seconds": int64(0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			progress := newConversionProgress(tt.duration)
+			for _, line := range tt.stderr {
+				progress.handleStderrLine(line)
+			}
+
+			var data map[string]interface{}
+			for i, line := range tt.lines {
+				got, ok := progress.handleLine(line)
+				if ok != (i == len(tt.lines)-1) {
+					t.Fatalf("handleLine(%q) completed = %v", line, ok)
+				}
+				data = got
+			}
+			for key, want := range tt.want {
+				if data[key] != want {
+					t.Errorf("%s = %v (%T), want %v", key, data[key], data[key], want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultMaxConcurrentConversions is used when the setting is missing or invalid
+const defaultMaxConcurrentConversions = 2
+
+// errConversionJobNotFound is returned for operations on an unknown conversion job ID
+var errConversionJobNotFound = errors.New("conversion job not found")
+
+// errConversionJobNotActive is returned when cancelling a conversion that has already finished
+var errConversionJobNotActive = errors.New("conversion job is not active")
+
+// Files picked up when a batch conversion is given a folder. Video presets
+// only take video files; audio-only presets take both.
+var (
+	batchVideoExtensions = []string{".mp4", ".m4v", ".mov", ".mkv", ".webm", ".avi", ".flv", ".wmv", ".ts", ".mpg", ".mpeg"}
+	batchAudioExtensions = []string{".mp3", ".m4a", ".aac", ".flac", ".opus", ".ogg", ".wav", ".wma"}
+)
+
+// conversionQueue holds all conversion jobs and tracks how many of them are running
+type conversionQueue struct {
+	mu      sync.Mutex
+	jobs    map[string]*ConversionJob
+	order   []string // Job IDs in the order they were enqueued
+	running int
+}
+
+// newConversionQueue creates an empty conversion queue
+func newConversionQueue() *conversionQueue {
+	return &conversionQueue{
+		jobs: make(map[string]*ConversionJob),
+	}
+}
+
+// maxConcurrentConversions returns the configured number of parallel conversions
+func (a *App) maxConcurrentConversions() int {
+	maxConversions := a.currentSettings().MaxConcurrentConversions
+	if maxConversions < 1 {
+		return defaultMaxConcurrentConversions
+	}
+	return maxConversions
+}
+
+// enqueueConversion adds a job to the conversion queue and starts it if a slot is free
+func (a *App) enqueueConversion(job *ConversionJob) string {
+	job.ID = newJobID()
+	job.Status = JobStatusQueued
+	job.CreatedAt = time.Now()
+
+	a.conversions.mu.Lock()
+	a.conversions.jobs[job.ID] = job
+	a.conversions.order = append(a.conversions.order, job.ID)
+	a.conversions.mu.Unlock()
+
+	a.logger.Infof("Conversion job %s queued: %s -> %s", job.ID, job.SourcePath, job.TargetPath)
+	a.events.Emit("conversion-queued", map[string]interface{}{
+		"id":          job.ID,
+		"source_path": job.SourcePath,
+		"target_path": job.TargetPath,
+	})
+
+	a.scheduleConversions()
+	return job.ID
+}
+
+// scheduleConversions starts queued conversions until the concurrency limit is reached
+func (a *App) scheduleConversions() {
+	limit := a.maxConcurrentConversions()
+
+	a.conversions.mu.Lock()
+	var toStart []*ConversionJob
+	for _, id := range a.conversions.order {
+		if a.conversions.running >= limit {
+			break
+		}
+		job := a.conversions.jobs[id]
+		if job.Status != JobStatusQueued {
+			continue
+		}
+		job.Status = JobStatusRunning
+		job.StartedAt = time.Now()
+		a.conversions.running++
+		toStart = append(toStart, job)
+	}
+	a.conversions.mu.Unlock()
+
+	for _, job := range toStart {
+		go a.startConversionJob(job)
+	}
+}
+
+// startConversionJob builds the FFmpeg arguments for a job that has been given
+// a slot and launches FFmpeg
+func (a *App) startConversionJob(job *ConversionJob) {
+	ffmpegPath := ffmpegBinaryPath()
+
+	var args []string
+	var duration float64
+	var err error
+	if job.Preset != "" {
+		args, duration, err = a.presetConversionArgs(job, ffmpegPath)
+	} else {
+		media := a.inspectSource(job.SourcePath)
+		duration = media.Duration
+		// A container change alone needs no re-encoding when the target holds the source codecs
+		var remux bool
+		if args, remux = remuxArgs(media, job.SourcePath, job.TargetPath, job.TargetFormat); remux {
+			a.logger.Infof("Remuxing without re-encoding: %s -> %s", job.SourcePath, job.TargetPath)
+		} else {
+			args = formatConversionArgs(job.SourcePath, job.TargetPath, job.TargetFormat)
+		}
+	}
+
+	if err == nil {
+		err = a.runConversion(job, ffmpegPath, args, duration)
+	}
+	if err != nil {
+		a.logger.Errorf("Failed to start conversion job %s: %v", job.ID, err)
+		a.emitConversionEvent(job, "conversion-error", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
+// emitConversionEvent emits a conversion event tagged with the job ID, ensuring
+// only one terminal (complete/error/cancelled) event is emitted per job
+func (a *App) emitConversionEvent(job *ConversionJob, eventType string, data map[string]interface{}) {
+	payload := map[string]interface{}{"id": job.ID}
+	for key, value := range data {
+		payload[key] = value
+	}
+
+	terminal := eventType == "conversion-complete" || eventType == "conversion-error" || eventType == "conversion-cancelled"
+
+	a.conversions.mu.Lock()
+	if job.completionEmitted {
+		a.conversions.mu.Unlock()
+		return // Already emitted a terminal event for this job
+	}
+	if job.cancelled && eventType != "conversion-cancelled" {
+		a.conversions.mu.Unlock()
+		return // The killed process reports an error, but the job was cancelled
+	}
+
+	if terminal {
+		job.completionEmitted = true
+		if job.Status == JobStatusRunning {
+			a.conversions.running--
+		}
+		job.cmd = nil
+
+		switch eventType {
+		case "conversion-complete":
+			job.Status = JobStatusCompleted
+			job.Progress = 100
+		case "conversion-error":
+			job.Status = JobStatusFailed
+			job.Error = fmt.Sprint(data["error"])
+		case "conversion-cancelled":
+			job.Status = JobStatusCancelled
+		}
+	} else if progress, ok := data["progress"].(int); ok {
+		job.Progress = progress
+	}
+	a.conversions.mu.Unlock()
+
+	a.events.Emit(eventType, payload)
+
+	if terminal {
+		a.scheduleConversions()
+	}
+}
+
+// setConversionCommand records the FFmpeg process running a job. It returns
+// false if the job has been cancelled in the meantime and the process should be stopped.
+func (a *App) setConversionCommand(job *ConversionJob, cmd *exec.Cmd) bool {
+	a.conversions.mu.Lock()
+	defer a.conversions.mu.Unlock()
+
+	if job.cancelled || job.completionEmitted {
+		return false
+	}
+	job.cmd = cmd
+	return true
+}
+
+// listConversionJobsInternal returns all conversion jobs in queue order as JSON
+func (a *App) listConversionJobsInternal() (string, error) {
+	a.conversions.mu.Lock()
+	jobs := make([]ConversionJob, 0, len(a.conversions.order))
+	for _, id := range a.conversions.order {
+		job := *a.conversions.jobs[id]
+		job.cmd = nil
+		jobs = append(jobs, job)
+	}
+	a.conversions.mu.Unlock()
+
+	result, err := json.Marshal(jobs)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal conversion jobs: %w", err)
+	}
+	return string(result), nil
+}
+
+// cancelConversionJobInternal cancels a conversion. Running jobs have their
+// FFmpeg process killed; queued jobs are simply taken out of the schedule.
+func (a *App) cancelConversionJobInternal(id string) error {
+	a.conversions.mu.Lock()
+	job, exists := a.conversions.jobs[id]
+	if !exists {
+		a.conversions.mu.Unlock()
+		return fmt.Errorf("%w: %s", errConversionJobNotFound, id)
+	}
+	if job.Status != JobStatusRunning && job.Status != JobStatusQueued {
+		a.conversions.mu.Unlock()
+		return fmt.Errorf("%w: %s (status: %s)", errConversionJobNotActive, id, job.Status)
+	}
+
+	job.cancelled = true
+	cmd := job.cmd
+	job.cmd = nil
+	a.conversions.mu.Unlock()
+
+	if cmd != nil && cmd.Process != nil {
+		a.logger.Infof("Cancelling conversion job %s...", id)
+		if err := cmd.Process.Kill(); err != nil {
+			a.logger.Errorf("Failed to cancel conversion job %s: %v", id, err)
+			return fmt.Errorf("failed to cancel conversion: %w", err)
+		}
+	}
+
+	a.logger.Infof("Conversion job %s cancelled", id)
+	a.emitConversionEvent(job, "conversion-cancelled", nil)
+	return nil
+}
+
+// cancelAllConversionsInternal cancels every queued and running conversion,
+// used by the legacy CancelConversion binding that predates job IDs. Jobs that
+// finish in the meantime are skipped; a job that cannot be stopped does not
+// keep the others running.
+func (a *App) cancelAllConversionsInternal() error {
+	a.conversions.mu.Lock()
+	var ids []string
+	for _, id := range a.conversions.order {
+		status := a.conversions.jobs[id].Status
+		if status == JobStatusRunning || status == JobStatusQueued {
+			ids = append(ids, id)
+		}
+	}
+	a.conversions.mu.Unlock()
+
+	if len(ids) == 0 {
+		a.logger.Infof("No active conversion to cancel")
+		return fmt.Errorf("no active conversion to cancel")
+	}
+
+	var errs []error
+	for _, id := range ids {
+		if err := a.cancelConversionJobInternal(id); err != nil && !errors.Is(err, errConversionJobNotActive) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// batchSources expands the selected paths into the files to convert. Folders
+// contribute the media files directly inside them, in name order; audio files
+// only when audio is true.
+func batchSources(paths []string, audio bool) ([]string, error) {
+	extensions := batchVideoExtensions
+	if audio {
+		extensions = slices.Concat(batchVideoExtensions, batchAudioExtensions)
+	}
+
+	var sources []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("source does not exist: %s", path)
+		}
+		if !info.IsDir() {
+			sources = append(sources, path)
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read folder %s: %w", path, err)
+		}
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if entry.Type().IsRegular() && slices.Contains(extensions, ext) {
+				sources = append(sources, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+	return sources, nil
+}
+
+// convertBatchInternal queues the conversion of every selected file, and of
+// the media files in every selected folder, with one preset. pathsJSON holds
+// an array of paths; the job IDs are returned as a JSON array. Nothing is
+// queued unless every file can be converted, and two files may not be
+// converted to the same target.
+func (a *App) convertBatchInternal(pathsJSON, presetName string) (string, error) {
+	var paths []string
+	if err := json.Unmarshal([]byte(pathsJSON), &paths); err != nil {
+		return "", fmt.Errorf("failed to parse paths: %w", err)
+	}
+	preset, ok := a.findConversionPreset(presetName)
+	if !ok {
+		return "", fmt.Errorf("unknown conversion preset: %s", presetName)
+	}
+
+	sources, err := batchSources(paths, preset.audioOnly())
+	if err != nil {
+		return "", err
+	}
+	if len(sources) == 0 {
+		return "", fmt.Errorf("no media files to convert")
+	}
+
+	jobs := make([]*ConversionJob, 0, len(sources))
+	sourceByTarget := make(map[string]string, len(sources))
+	for _, source := range sources {
+		job, err := a.presetConversionJob(source, presetName)
+		if err != nil {
+			return "", err
+		}
+		target := filepath.Clean(job.TargetPath)
+		if other, exists := sourceByTarget[target]; exists {
+			return "", fmt.Errorf("%s and %s would both be converted to %s", other, source, job.TargetPath)
+		}
+		sourceByTarget[target] = source
+		jobs = append(jobs, job)
+	}
+
+	jobIDs := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		jobIDs = append(jobIDs, a.enqueueConversion(job))
+	}
+	a.logger.Infof("Queued %d conversions with preset %s", len(jobIDs), presetName)
+
+	result, err := json.Marshal(jobIDs)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal job IDs: %w", err)
+	}
+	return string(result), nil
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// conversionStatus returns the status of a conversion job
+func conversionStatus(app *App, id string) string {
+	app.conversions.mu.Lock()
+	defer app.conversions.mu.Unlock()
+	return app.conversions.jobs[id].Status
+}
+
+func TestConversionQueueRunsJobsInTurn(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, sink := newTestApp(t)
+	app.settings.MaxConcurrentConversions = 1
+	for _, name := range []string{"a.mkv", "b.mkv"} {
+		if err := os.WriteFile(name, []byte("video"), 0644); err != nil {
+			t.Fatalf("failed to create source file: %v", err)
+		}
+	}
+	tools.install("ffmpeg"+getExecutableExtension(),
+		fakeRun{When: []string{"a.mkv"}, Stdout: []string{"out_time_us=1000000", "progress=continue"}, Hang: true},
+		fakeRun{When: []string{"b.mkv"}, Files: map[string]string{"b.mp3": "converted"}},
+	)
+
+	if _, err := app.convertVideoInternal("a.mkv", "mp3"); err != nil {
+		t.Fatalf("convertVideoInternal() error = %v", err)
+	}
+	if _, err := app.convertVideoInternal("b.mkv", "mp3"); err != nil {
+		t.Fatalf("convertVideoInternal() error = %v", err)
+	}
+	first := sink.waitFor(t, "conversion-progress").Data[0].(map[string]interface{})["id"].(string)
+
+	var jobs []ConversionJob
+	result, err := app.listConversionJobsInternal()
+	if err != nil || json.Unmarshal([]byte(result), &jobs) != nil || len(jobs) != 2 {
+		t.Fatalf("unexpected conversion jobs: %s, %v", result, err)
+	}
+	second := jobs[1].ID
+	if jobs[0].ID != first || conversionStatus(app, second) != JobStatusQueued {
+		t.Fatalf("expected %s to wait for %s, got %+v", second, first, jobs)
+	}
+
+	if err := app.cancelConversionJobInternal(first); err != nil {
+		t.Fatalf("cancelConversionJobInternal() error = %v", err)
+	}
+	if got := sink.waitFor(t, "conversion-cancelled").Data[0].(map[string]interface{})["id"]; got != first {
+		t.Errorf("cancelled job = %v, want %s", got, first)
+	}
+
+	// Cancelling the first job frees the slot for the second one
+	complete := sink.waitFor(t, "conversion-complete").Data[0].(map[string]interface{})
+	if complete["id"] != second || complete["targetPath"] != "b.mp3" {
+		t.Errorf("unexpected completion: %v", complete)
+	}
+	if got := conversionStatus(app, first); got != JobStatusCancelled {
+		t.Errorf("first job status = %s, want %s", got, JobStatusCancelled)
+	}
+	if err := app.cancelConversionJobInternal(second); err == nil {
+		t.Error("expected an error when cancelling a finished job")
+	}
+	if err := app.cancelAllConversionsInternal(); err == nil {
+		t.Error("expected an error without active conversions")
+	}
+}
+
+func TestCancelAllConversionsKeepsCancelling(t *testing.T) {
+	app, sink := newTestApp(t)
+
+	// A process that has already exited cannot be killed
+	exited := exec.Command(os.Args[0], "-test.run=^$")
+	if err := exited.Run(); err != nil {
+		t.Fatalf("failed to run helper process: %v", err)
+	}
+	stuck := &ConversionJob{SourcePath: "a.mkv", TargetPath: "a.mp3"}
+	queued := &ConversionJob{SourcePath: "b.mkv", TargetPath: "b.mp3"}
+	app.settings.MaxConcurrentConversions = 1
+	app.conversions.running = 1
+	app.enqueueConversion(stuck)
+	app.enqueueConversion(queued)
+	app.conversions.mu.Lock()
+	stuck.Status = JobStatusRunning
+	stuck.cmd = exited
+	app.conversions.mu.Unlock()
+
+	if err := app.cancelAllConversionsInternal(); err == nil {
+		t.Error("expected the kill failure to be reported")
+	}
+	if got := sink.waitFor(t, "conversion-cancelled").Data[0].(map[string]interface{})["id"]; got != queued.ID {
+		t.Errorf("cancelled job = %v, want %s", got, queued.ID)
+	}
+	if got := conversionStatus(app, queued.ID); got != JobStatusCancelled {
+		t.Errorf("queued job status = %s, want %s", got, JobStatusCancelled)
+	}
+}
+
+func TestBatchSources(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.mkv", "a.MP4", "song.mp3", "notes.txt", filepath.Join("nested", "c.mp4")} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("media"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		paths   []string
+		audio   bool
+		want    []string
+		wantErr bool
+	}{
+		{name: "FolderForVideo", paths: []string{dir}, want: []string{filepath.Join(dir, "a.MP4"), filepath.Join(dir, "b.mkv")}},
+		{name: "FolderForAudio", paths: []string{dir}, audio: true, want: []string{filepath.Join(dir, "a.MP4"), filepath.Join(dir, "b.mkv"), filepath.Join(dir, "song.mp3")}},
+		{name: "SelectedFileKeepsExtension", paths: []string{filepath.Join(dir, "notes.txt")}, want: []string{filepath.Join(dir, "notes.txt")}},
+		{name: "Missing", paths: []string{filepath.Join(dir, "missing.mkv")}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := batchSources(tt.paths, tt.audio)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("batchSources() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("batchSources() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertBatch(t *testing.T) {
+	t.Chdir(t.TempDir())
+	app, sink := newTestApp(t)
+	// Keep the jobs queued so no FFmpeg is started
+	app.settings.MaxConcurrentConversions = 1
+	app.conversions.running = 1
+
+	if err := os.Mkdir("clips", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{filepath.Join("clips", "one.mkv"), filepath.Join("clips", "two.webm"), filepath.Join("clips", "song.mp3"), "one.webm"} {
+		if err := os.WriteFile(name, []byte("video"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct{ paths, preset string }{
+		{paths: `["clips"]`, preset: "missing"},
+		{paths: `{`, preset: "Phone 720p"},
+		{paths: `[]`, preset: "Phone 720p"},
+		// Both would become clips/one.mp4
+		{paths: `["clips", "one.webm", "clips/one.mkv"]`, preset: "Phone 720p"},
+		{paths: `["one.webm", "missing.mkv"]`, preset: "Phone 720p"},
+	} {
+		if _, err := app.convertBatchInternal(tt.paths, tt.preset); err == nil {
+			t.Errorf("convertBatchInternal(%s, %s) should fail", tt.paths, tt.preset)
+		}
+	}
+	if len(app.conversions.jobs) != 0 {
+		t.Fatalf("a failed batch must not queue anything, got %d jobs", len(app.conversions.jobs))
+	}
+
+	result, err := app.convertBatchInternal(`["clips"]`, "phone 720p")
+	if err != nil {
+		t.Fatalf("convertBatchInternal() error = %v", err)
+	}
+	var ids []string
+	if err := json.Unmarshal([]byte(result), &ids); err != nil || len(ids) != 2 {
+		t.Fatalf("unexpected job IDs: %s, %v", result, err)
+	}
+
+	app.conversions.mu.Lock()
+	job := *app.conversions.jobs[ids[1]]
+	app.conversions.mu.Unlock()
+	if job.Status != JobStatusQueued || job.Preset != "Phone 720p" || job.TargetPath != filepath.Join("clips", "two.mp4") {
+		t.Errorf("unexpected job: %+v", job)
+	}
+	if got := sink.waitFor(t, "conversion-queued").Data[0].(map[string]interface{})["id"]; got != ids[0] {
+		t.Errorf("queued event id = %v, want %s", got, ids[0])
+	}
+
+	// Audio presets also take the audio files of a folder
+	result, err = app.convertBatchInternal(`["clips"]`, "MP3 320k")
+	if err != nil || json.Unmarshal([]byte(result), &ids) != nil || len(ids) != 3 {
+		t.Fatalf("unexpected audio job IDs: %s, %v", result, err)
+	}
+
+	if err := app.cancelAllConversionsInternal(); err != nil {
+		t.Fatalf("cancelAllConversionsInternal() error = %v", err)
+	}
+	if got := conversionStatus(app, ids[0]); got != JobStatusCancelled {
+		t.Errorf("job status = %s, want %s", got, JobStatusCancelled)
+	}
+}
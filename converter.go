@@ -6,23 +6,17 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
-
-	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
-)
-
-// Global variable to track the current conversion process
-var (
-	currentConvertCmd *exec.Cmd
-	convertMutex      sync.Mutex
 )
 
-// convertVideoInternal converts a video file to a different format using FFmpeg
-func (a *App) convertVideoInternal(sourcePath, targetFormat string) error {
+// convertVideoInternal queues the conversion of a file to a different format
+// using FFmpeg and returns the conversion job ID
+func (a *App) convertVideoInternal(sourcePath, targetFormat string) (string, error) {
 	// Check if source file exists
 	if _, err := os.Stat(sourcePath); os.IsNotExist(err) {
-		return fmt.Errorf("source file does not exist: %s", sourcePath)
+		return "", fmt.Errorf("source file does not exist: %s", sourcePath)
 	}
 
 	// Get the directory and filename without extension
@@ -31,16 +25,16 @@ func (a *App) convertVideoInternal(sourcePath, targetFormat string) error {
 	ext := filepath.Ext(filename)
 	nameWithoutExt := strings.TrimSuffix(filename, ext)
 
-	// Create target path
-	targetPath := filepath.Join(dir, nameWithoutExt+"."+targetFormat)
-
-	// Check if FFmpeg is available
-	ffmpegPath := filepath.Join("./bin", "ffmpeg"+getExecutableExtension())
-	if _, err := os.Stat(ffmpegPath); os.IsNotExist(err) {
-		// Try to use system FFmpeg
-		ffmpegPath = "ffmpeg"
-	}
+	return a.enqueueConversion(&ConversionJob{
+		SourcePath:   sourcePath,
+		TargetPath:   filepath.Join(dir, nameWithoutExt+"."+targetFormat),
+		TargetFormat: targetFormat,
+	}), nil
+}
 
+// formatConversionArgs returns the FFmpeg arguments that re-encode the source
+// into the target format with the default settings for that format
+func formatConversionArgs(sourcePath, targetPath, targetFormat string) []string {
 	// Check if this is an audio-only format
 	audioOnlyFormats := map[string]bool{
 		"mp3":  true,
@@ -124,15 +118,17 @@ func (a *App) convertVideoInternal(sourcePath, targetFormat string) error {
 		}
 	}
 
+	return args
+}
+
+// runConversion starts FFmpeg with the given arguments for a job and reports
+// progress and the result as conversion events. A duration of 0 is read from
+// FFmpeg's output.
+func (a *App) runConversion(job *ConversionJob, ffmpegPath string, args []string, duration float64) error {
 	// Hide console window on Windows
-	cmd := exec.Command(ffmpegPath, args...)
+	cmd := exec.Command(ffmpegPath, append(slices.Clone(ffmpegProgressArgs), args...)...)
 	setHideWindow(cmd)
 
-	// Store the current conversion command for cancellation
-	convertMutex.Lock()
-	currentConvertCmd = cmd
-	convertMutex.Unlock()
-
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
 		return fmt.Errorf("failed to create stdout pipe: %w", err)
@@ -147,83 +143,50 @@ func (a *App) convertVideoInternal(sourcePath, targetFormat string) error {
 		return fmt.Errorf("failed to start conversion: %w", err)
 	}
 
-	wailsRuntime.LogInfof(a.ctx, "Conversion started: %s -> %s", sourcePath, targetPath)
+	// Store the command for cancellation. A job cancelled while FFmpeg was
+	// starting is stopped right away.
+	if !a.setConversionCommand(job, cmd) {
+		cmd.Process.Kill()
+	}
 
-	// Read stderr to get progress information
-	go func() {
-		buffer := make([]byte, 4096)
-		var duration float64
-		var currentTime float64
-
-		for {
-			n, err := stderr.Read(buffer)
-			if n > 0 {
-				output := string(buffer[:n])
-
-				// Parse duration from FFmpeg output
-				// Duration: 00:02:30.45, start: 0.000000, bitrate: 1500 kb/s
-				if strings.Contains(output, "Duration:") {
-					parts := strings.Split(output, "Duration:")
-					if len(parts) > 1 {
-						durationStr := strings.TrimSpace(strings.Split(parts[1], ",")[0])
-						duration = parseFFmpegTime(durationStr)
-					}
-				}
-
-				// Parse current time from FFmpeg output
-				// frame=  123 fps= 30 q=28.0 size=    1234kB time=00:01:15.23 bitrate=1234.5kbits/s speed=1.2x
-				if strings.Contains(output, "time=") {
-					parts := strings.Split(output, "time=")
-					if len(parts) > 1 {
-						timeStr := strings.TrimSpace(strings.Split(parts[1], " ")[0])
-						currentTime = parseFFmpegTime(timeStr)
-
-						// Calculate progress
-						if duration > 0 {
-							progress := (currentTime / duration) * 100
-							if progress > 100 {
-								progress = 100
-							}
-							wailsRuntime.EventsEmit(a.ctx, "conversion-progress", map[string]interface{}{
-								"progress": int(progress),
-							})
-						}
-					}
-				}
-			}
+	a.logger.Infof("Conversion %s started: %s -> %s", job.ID, job.SourcePath, job.TargetPath)
 
-			if err != nil {
-				break
-			}
-		}
+	// Wait closes the pipes, so the output is drained before waiting
+	var readers sync.WaitGroup
+	readers.Add(2)
+
+	progress := newConversionProgress(duration)
+
+	// Read stderr for the input summary, in case the duration is not known yet
+	go func() {
+		defer readers.Done()
+		scanOutputLines(stderr, progress.handleStderrLine)
 	}()
 
-	// Read stdout (usually empty for FFmpeg)
+	// Read the -progress blocks FFmpeg writes to stdout
 	go func() {
-		buffer := make([]byte, 1024)
-		for {
-			_, err := stdout.Read(buffer)
-			if err != nil {
-				break
+		defer readers.Done()
+		scanOutputLines(stdout, func(line string) {
+			if data, ok := progress.handleLine(line); ok {
+				a.emitConversionEvent(job, "conversion-progress", data)
 			}
-		}
+		})
 	}()
 
 	// Wait for the command to finish
 	go func() {
+		readers.Wait()
 		waitErr := cmd.Wait()
-		// Clear the current conversion command
-		convertMutex.Lock()
-		currentConvertCmd = nil
-		convertMutex.Unlock()
 
 		if waitErr != nil {
-			wailsRuntime.LogErrorf(a.ctx, "Conversion failed: %v", waitErr)
-			wailsRuntime.EventsEmit(a.ctx, "conversion-error", fmt.Sprintf("Conversion failed: %v", waitErr))
+			a.logger.Errorf("Conversion %s failed: %v", job.ID, waitErr)
+			a.emitConversionEvent(job, "conversion-error", map[string]interface{}{
+				"error": fmt.Sprintf("Conversion failed: %v", waitErr),
+			})
 		} else {
-			wailsRuntime.LogInfof(a.ctx, "Conversion completed successfully: %s", targetPath)
-			wailsRuntime.EventsEmit(a.ctx, "conversion-complete", map[string]interface{}{
-				"targetPath": targetPath,
+			a.logger.Infof("Conversion completed successfully: %s", job.TargetPath)
+			a.emitConversionEvent(job, "conversion-complete", map[string]interface{}{
+				"targetPath": job.TargetPath,
 			})
 		}
 	}()
@@ -231,28 +194,11 @@ func (a *App) convertVideoInternal(sourcePath, targetFormat string) error {
 	return nil
 }
 
-// CancelConversion cancels the current conversion
+// CancelConversion cancels every queued and running conversion
 //
 //export CancelConversion
 func (a *App) CancelConversion() error {
-	convertMutex.Lock()
-	defer convertMutex.Unlock()
-
-	if currentConvertCmd != nil && currentConvertCmd.Process != nil {
-		wailsRuntime.LogInfof(a.ctx, "Cancelling conversion...")
-		err := currentConvertCmd.Process.Kill()
-		if err != nil {
-			wailsRuntime.LogErrorf(a.ctx, "Failed to cancel conversion: %v", err)
-			return fmt.Errorf("failed to cancel conversion: %w", err)
-		}
-		currentConvertCmd = nil
-		wailsRuntime.LogInfof(a.ctx, "Conversion cancelled successfully")
-		wailsRuntime.EventsEmit(a.ctx, "conversion-cancelled")
-		return nil
-	}
-
-	wailsRuntime.LogInfof(a.ctx, "No active conversion to cancel")
-	return fmt.Errorf("no active conversion to cancel")
+	return a.cancelAllConversionsInternal()
 }
 
 // openInExplorerInternal opens the file explorer at the specified path
@@ -266,7 +212,7 @@ func (a *App) openInExplorerInternal(path string) error {
 		return fmt.Errorf("failed to open explorer: %w", err)
 	}
 
-	wailsRuntime.LogInfof(a.ctx, "Opened explorer at: %s", path)
+	a.logger.Infof("Opened explorer at: %s", path)
 	return nil
 }
 
@@ -311,6 +257,16 @@ func buildOpenInExplorerCommandForOS(goos, path string) (*exec.Cmd, error) {
 	}
 }
 
+// ffmpegBinaryPath returns the local FFmpeg binary if present, otherwise the system one
+func ffmpegBinaryPath() string {
+	ffmpegPath := filepath.Join("./bin", "ffmpeg"+getExecutableExtension())
+	if _, err := os.Stat(ffmpegPath); os.IsNotExist(err) {
+		// Try to use system FFmpeg
+		return "ffmpeg"
+	}
+	return ffmpegPath
+}
+
 // GetExecutableExtension returns the executable extension for the current OS
 func getExecutableExtension() string {
 	if runtime.GOOS == "windows" {
@@ -334,102 +290,25 @@ func parseFFmpegTime(timeStr string) float64 {
 	return hours*3600 + minutes*60 + seconds
 }
 
-// getActualDownloadPathInternal returns the actual path of the downloaded file
-// This is needed because yt-dlp uses %(ext)s placeholder
+// getActualDownloadPathInternal returns the file yt-dlp reported for the newest
+// completed download with the given title. Downloads of earlier sessions are
+// looked up in the download history.
 func (a *App) getActualDownloadPathInternal(title string) (string, error) {
-	// Sanitize title
-	sanitizedTitle := strings.ReplaceAll(title, " ", "_")
-	sanitizedTitle = strings.ReplaceAll(sanitizedTitle, "/", "_")
-	sanitizedTitle = strings.ReplaceAll(sanitizedTitle, "\\", "_")
-	sanitizedTitle = strings.ReplaceAll(sanitizedTitle, ":", "_")
-	sanitizedTitle = strings.ReplaceAll(sanitizedTitle, "*", "_")
-	sanitizedTitle = strings.ReplaceAll(sanitizedTitle, "?", "_")
-	sanitizedTitle = strings.ReplaceAll(sanitizedTitle, "\"", "_")
-	sanitizedTitle = strings.ReplaceAll(sanitizedTitle, "<", "_")
-	sanitizedTitle = strings.ReplaceAll(sanitizedTitle, ">", "_")
-	sanitizedTitle = strings.ReplaceAll(sanitizedTitle, "|", "_")
-
-	// Use the current download directory (which may be user-selected)
-	downloadsDir := a.GetDownloadDirectory()
-
-	// First, check for .part files (incomplete downloads)
-	// This indicates the download is still in progress or was interrupted
-	partExtensions := []string{".mp4.part", ".webm.part", ".mkv.part", ".avi.part", ".mov.part", ".flv.part", ".m4v.part"}
-	hasPartFile := false
-	for _, ext := range partExtensions {
-		path := filepath.Join(downloadsDir, sanitizedTitle+ext)
-		if _, err := os.Stat(path); err == nil {
-			hasPartFile = true
-			wailsRuntime.LogWarningf(a.ctx, "Found .part file, download may be incomplete: %s", path)
-			break
-		}
-
-		// Check for fragment files (e.g., .part-Frag21.part)
-		fragmentPattern := filepath.Join(downloadsDir, sanitizedTitle+ext+"-*")
-		if matches, _ := filepath.Glob(fragmentPattern); len(matches) > 0 {
-			hasPartFile = true
-			wailsRuntime.LogWarningf(a.ctx, "Found fragment files, download may be incomplete: %v", matches)
-			break
-		}
+	if filePath := a.completedFilePathFor(title); filePath != "" {
+		return filePath, nil
 	}
 
-	// Check for .ytdl files (another indicator of incomplete downloads)
-	ytdlPath := filepath.Join(downloadsDir, sanitizedTitle+".ytdl")
-	hasYtdlFile := false
-	if _, err := os.Stat(ytdlPath); err == nil {
-		hasYtdlFile = true
-		wailsRuntime.LogWarningf(a.ctx, "Found .ytdl file, download may be incomplete: %s", ytdlPath)
+	if a.hasPartialFilesFor(title) {
+		return "", fmt.Errorf("download incomplete: partial files of %s are still present", title)
 	}
 
-	// Try to find the file with common extensions
-	extensions := []string{".mp4", ".webm", ".mkv", ".avi", ".mov", ".flv", ".m4v"}
-	var foundPath string
-	var foundSize int64
-
-	for _, ext := range extensions {
-		path := filepath.Join(downloadsDir, sanitizedTitle+ext)
-		if fileInfo, err := os.Stat(path); err == nil {
-			// Prefer the largest file (in case there are multiple)
-			if fileInfo.Size() > foundSize {
-				foundPath = path
-				foundSize = fileInfo.Size()
-			}
-		}
-	}
-
-	if foundPath != "" {
-		// Check if the file is reasonably large (not empty or too small)
-		if foundSize < 1024 {
-			// File is too small, likely incomplete
-			if hasPartFile || hasYtdlFile {
-				return "", fmt.Errorf("download incomplete: file too small (%d bytes) and temporary files present", foundSize)
-			}
-			wailsRuntime.LogWarningf(a.ctx, "Found file but it's very small: %s (%d bytes)", foundPath, foundSize)
-		}
-
-		// Get absolute path
-		absPath, err := filepath.Abs(foundPath)
+	if a.history != nil {
+		filePath, err := a.history.completedFilePath(title)
 		if err != nil {
-			return foundPath, nil
-		}
-
-		// Log what we found
-		if hasPartFile || hasYtdlFile {
-			wailsRuntime.LogWarningf(a.ctx, "Returning file path despite temporary files: %s (size: %d bytes)", absPath, foundSize)
-		} else {
-			wailsRuntime.LogInfof(a.ctx, "Found completed file: %s (size: %d bytes)", absPath, foundSize)
+			a.logger.Warningf("Failed to look up %s in the download history: %v", title, err)
+		} else if filePath != "" {
+			return filePath, nil
 		}
-
-		return absPath, nil
 	}
-
-	// If no file found but we have temporary files, it's definitely incomplete
-	if hasPartFile || hasYtdlFile {
-		return "", fmt.Errorf("download incomplete: temporary files present but no completed file found")
-	}
-
-	// If not found, return the default path (this might happen for new downloads)
-	defaultPath := filepath.Join(downloadsDir, sanitizedTitle+".mp4")
-	wailsRuntime.LogInfof(a.ctx, "No completed file found, returning default path: %s", defaultPath)
-	return defaultPath, nil
+	return "", fmt.Errorf("no completed download found for %s", title)
 }
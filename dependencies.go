@@ -11,37 +11,35 @@ import (
 	"runtime"
 	"strings"
 	"time"
-
-	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // SetupDependencies checks for yt-dlp and ffmpeg binaries, downloads if missing
 func (a *App) SetupDependencies() {
 	// Show setup modal
-	wailsRuntime.LogInfo(a.ctx, "Emitting setup-started event")
-	wailsRuntime.EventsEmit(a.ctx, "setup-started")
+	a.logger.Info("Emitting setup-started event")
+	a.events.Emit("setup-started")
 
 	binDir := "./bin"
 	if err := os.MkdirAll(binDir, 0755); err != nil {
-		wailsRuntime.LogErrorf(a.ctx, "Failed to create bin directory: %v", err)
-		wailsRuntime.EventsEmit(a.ctx, "setup-error", fmt.Sprintf("Failed to create bin directory: %v", err))
+		a.logger.Errorf("Failed to create bin directory: %v", err)
+		a.events.Emit("setup-error", fmt.Sprintf("Failed to create bin directory: %v", err))
 		return
 	}
 
 	// Check for yt-dlp
 	ytDlpPath := filepath.Join(binDir, a.getYtDlpBinaryName())
 	if _, err := os.Stat(ytDlpPath); os.IsNotExist(err) {
-		wailsRuntime.LogInfo(a.ctx, "yt-dlp binary not found, downloading...")
+		a.logger.Info("yt-dlp binary not found, downloading...")
 		err := a.downloadYtDlp(ytDlpPath)
 		if err != nil {
-			wailsRuntime.LogErrorf(a.ctx, "Failed to download yt-dlp: %v", err)
+			a.logger.Errorf("Failed to download yt-dlp: %v", err)
 			a.logDetailedError("SetupDependencies", "", "", err)
-			wailsRuntime.EventsEmit(a.ctx, "setup-error", fmt.Sprintf("Failed to download yt-dlp: %v", err))
+			a.events.Emit("setup-error", fmt.Sprintf("Failed to download yt-dlp: %v", err))
 			return
 		}
-		wailsRuntime.LogInfo(a.ctx, "yt-dlp downloaded successfully")
+		a.logger.Info("yt-dlp downloaded successfully")
 	} else {
-		wailsRuntime.LogInfo(a.ctx, "yt-dlp binary found")
+		a.logger.Info("yt-dlp binary found")
 	}
 
 	// Check for ffmpeg - improved detection
@@ -49,29 +47,29 @@ func (a *App) SetupDependencies() {
 
 	// Check if ffmpeg is available globally first
 	if a.isCommandAvailable("ffmpeg") {
-		wailsRuntime.LogInfo(a.ctx, "ffmpeg found globally")
+		a.logger.Info("ffmpeg found globally")
 		ffmpegFound = true
 	} else {
 		// Check for local ffmpeg binary
 		ffmpegPath := filepath.Join(binDir, a.getFfmpegBinaryName())
 		if _, err := os.Stat(ffmpegPath); err == nil {
-			wailsRuntime.LogInfo(a.ctx, "ffmpeg binary found locally")
+			a.logger.Info("ffmpeg binary found locally")
 			ffmpegFound = true
 		}
 	}
 
 	if !ffmpegFound {
 		// Send warning but don't stop the setup process
-		wailsRuntime.LogInfo(a.ctx, "ffmpeg not found, sending warning")
-		wailsRuntime.EventsEmit(a.ctx, "ffmpeg-warning", "FFmpeg not found. Some features may not work properly.")
+		a.logger.Info("ffmpeg not found, sending warning")
+		a.events.Emit("ffmpeg-warning", "FFmpeg not found. Some features may not work properly.")
 	}
 
 	// Убедимся, что setup-complete отправляется с небольшой задержкой
 	// чтобы дать возможность другим событиям обработаться
 	go func() {
 		time.Sleep(300 * time.Millisecond) // Немного уменьшили задержку для ускорения
-		wailsRuntime.LogInfo(a.ctx, "Emitting setup-complete event")
-		wailsRuntime.EventsEmit(a.ctx, "setup-complete")
+		a.logger.Info("Emitting setup-complete event")
+		a.events.Emit("setup-complete")
 	}()
 }
 
@@ -90,7 +88,7 @@ func (a *App) downloadYtDlp(destPath string) error {
 		return fmt.Errorf("unsupported platform: %s", runtime.GOOS)
 	}
 
-	wailsRuntime.LogInfof(a.ctx, "Downloading yt-dlp from: %s", downloadURL)
+	a.logger.Infof("Downloading yt-dlp from: %s", downloadURL)
 
 	// Create HTTP request
 	resp, err := http.Get(downloadURL)
@@ -133,12 +131,12 @@ func (a *App) downloadYtDlp(destPath string) error {
 			}
 
 			// Emit progress event for both setup and update
-			wailsRuntime.EventsEmit(a.ctx, "setup-progress", map[string]interface{}{
+			a.events.Emit("setup-progress", map[string]interface{}{
 				"downloaded": downloaded,
 				"total":      total,
 				"percentage": progress,
 			})
-			wailsRuntime.EventsEmit(a.ctx, "yt-dlp-update-progress", map[string]interface{}{
+			a.events.Emit("yt-dlp-update-progress", map[string]interface{}{
 				"downloaded": downloaded,
 				"total":      total,
 				"percentage": progress,
@@ -219,21 +217,21 @@ func (a *App) updateYtDlpInternal() error {
 		return fmt.Errorf("yt-dlp not found, please run setup first")
 	}
 
-	wailsRuntime.LogInfo(a.ctx, "Starting yt-dlp update...")
+	a.logger.Info("Starting yt-dlp update...")
 
 	// Emit update start event
-	wailsRuntime.EventsEmit(a.ctx, "yt-dlp-update-start", nil)
+	a.events.Emit("yt-dlp-update-start", nil)
 
 	// Download the latest version
 	err := a.downloadYtDlp(ytDlpPath)
 	if err != nil {
-		wailsRuntime.LogErrorf(a.ctx, "Failed to update yt-dlp: %v", err)
-		wailsRuntime.EventsEmit(a.ctx, "yt-dlp-update-error", err.Error())
+		a.logger.Errorf("Failed to update yt-dlp: %v", err)
+		a.events.Emit("yt-dlp-update-error", err.Error())
 		return fmt.Errorf("failed to update yt-dlp: %w", err)
 	}
 
-	wailsRuntime.LogInfo(a.ctx, "yt-dlp updated successfully")
-	wailsRuntime.EventsEmit(a.ctx, "yt-dlp-update-complete", nil)
+	a.logger.Info("yt-dlp updated successfully")
+	a.events.Emit("yt-dlp-update-complete", nil)
 	return nil
 }
 
@@ -244,19 +242,19 @@ func (a *App) downloadFfmpeg(destPath string) error {
 	switch runtime.GOOS {
 	case "windows":
 		// For Windows, we'll provide instructions to install manually since automatic download is complex
-		wailsRuntime.EventsEmit(a.ctx, "ffmpeg-warning", "FFmpeg automatic download is complex on Windows. Please install FFmpeg manually from https://www.gyan.dev/ffmpeg/builds/")
+		a.events.Emit("ffmpeg-warning", "FFmpeg automatic download is complex on Windows. Please install FFmpeg manually from https://www.gyan.dev/ffmpeg/builds/")
 		return fmt.Errorf("automatic ffmpeg download not supported on Windows")
 	case "darwin":
 		downloadURL = "https://evermeet.cx/ffmpeg/getrelease/zip"
 	case "linux":
 		// For Linux, we'll provide instructions to install via package manager
-		wailsRuntime.EventsEmit(a.ctx, "ffmpeg-warning", "Please install FFmpeg using your distribution's package manager (e.g., sudo apt install ffmpeg)")
+		a.events.Emit("ffmpeg-warning", "Please install FFmpeg using your distribution's package manager (e.g., sudo apt install ffmpeg)")
 		return fmt.Errorf("automatic ffmpeg download not supported on Linux")
 	default:
 		return fmt.Errorf("unsupported platform for ffmpeg: %s", runtime.GOOS)
 	}
 
-	wailsRuntime.LogInfof(a.ctx, "Downloading ffmpeg from: %s", downloadURL)
+	a.logger.Infof("Downloading ffmpeg from: %s", downloadURL)
 
 	// Create HTTP request
 	resp, err := http.Get(downloadURL)
@@ -298,7 +296,7 @@ func (a *App) downloadFfmpeg(destPath string) error {
 			}
 
 			// Emit progress event
-			wailsRuntime.EventsEmit(a.ctx, "setup-progress", map[string]interface{}{
+			a.events.Emit("setup-progress", map[string]interface{}{
 				"downloaded": downloaded,
 				"total":      total,
 				"percentage": progress,
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Download archive modes
+const (
+	DownloadArchiveOff    = "off"
+	DownloadArchiveGlobal = "global" // One archive for every download
+	DownloadArchiveFolder = "folder" // One archive in each download folder
+)
+
+// globalDownloadArchiveFile is the archive used in DownloadArchiveGlobal mode
+const globalDownloadArchiveFile = "./download_archive.txt"
+
+// folderDownloadArchiveName is the archive file name in DownloadArchiveFolder mode
+const folderDownloadArchiveName = ".go-dlp-archive.txt"
+
+// archivedLineMarker is what yt-dlp prints for a video it skips because the
+// download archive already lists it
+const archivedLineMarker = "has already been recorded in the archive"
+
+// readDownloadArchive returns the video IDs recorded in a yt-dlp
+// --download-archive file, whose lines look like "youtube dQw4w9WgXcQ".
+// A missing file is an empty archive.
+func readDownloadArchive(path string) (map[string]bool, error) {
+	ids := make(map[string]bool)
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return ids, nil
+		}
+		return nil, fmt.Errorf("failed to open download archive: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			ids[fields[1]] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read download archive: %w", err)
+	}
+	return ids, nil
+}
+
+// seedDownloadArchive creates a download archive listing entries, in the
+// "<extractor> <id>" form yt-dlp writes. Entries whose extractor is unknown
+// are recorded as "generic" so readDownloadArchive, which matches on the ID
+// only, still skips them.
+func seedDownloadArchive(path string, entries []PlaylistEntry) error {
+	var archive strings.Builder
+	for _, entry := range entries {
+		if entry.ID == "" {
+			continue
+		}
+		extractor := strings.ToLower(entry.Extractor)
+		if extractor == "" {
+			extractor = "generic"
+		}
+		fmt.Fprintf(&archive, "%s %s\n", extractor, entry.ID)
+	}
+
+	if err := os.WriteFile(path, []byte(archive.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write download archive: %w", err)
+	}
+	return nil
+}
+
+// templateBaseDir returns the leading directories of an output template that
+// contain no template fields, e.g. "downloads" for
+// "downloads/%(playlist_title)s/%(title)s.%(ext)s"
+func templateBaseDir(outputPath string) string {
+	dir := filepath.Dir(outputPath)
+	for strings.Contains(dir, "%") {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+// downloadArchivePath returns the archive for downloads written to outputPath,
+// or "" when the archive is turned off
+func (s Settings) downloadArchivePath(outputPath string) string {
+	switch s.DownloadArchiveMode {
+	case DownloadArchiveOff:
+		return ""
+	case DownloadArchiveFolder:
+		return filepath.Join(templateBaseDir(outputPath), folderDownloadArchiveName)
+	default:
+		return globalDownloadArchiveFile
+	}
+}
+
+// jobArchivePath returns the archive yt-dlp records a job's downloads in.
+// Subscriptions always use their own archive so they never fetch an entry twice.
+func (a *App) jobArchivePath(job *DownloadJob) string {
+	if job.ArchivePath != "" {
+		return job.ArchivePath
+	}
+	return a.currentSettings().downloadArchivePath(job.OutputPath)
+}
+
+// jobForcesRedownload reports whether a job downloads entries its archive
+// already lists. Subscriptions never do.
+func (a *App) jobForcesRedownload(job *DownloadJob) bool {
+	return job.ArchivePath == "" && (a.currentSettings().ForceRedownload || job.Options.ForceRedownload)
+}
+
+// scratchArchivePath returns the archive a forced re-download hands to yt-dlp.
+// yt-dlp has no option to skip the archive check while still recording new
+// entries (--force-download-archive only writes entries in simulate mode), so
+// the job starts from an empty archive that is merged into the real one when it ends.
+func scratchArchivePath(archivePath, jobID string) string {
+	return fmt.Sprintf("%s.%s.tmp", archivePath, jobID)
+}
+
+// mergeDownloadArchive appends the entries of the archive src that dst does not
+// list yet, then removes src. A missing src has nothing to merge.
+func mergeDownloadArchive(dst, src string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read download archive: %w", err)
+	}
+	archived, err := readDownloadArchive(dst)
+	if err != nil {
+		return err
+	}
+
+	var added strings.Builder
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || archived[fields[1]] {
+			continue
+		}
+		archived[fields[1]] = true
+		fmt.Fprintf(&added, "%s %s\n", fields[0], fields[1])
+	}
+
+	if added.Len() > 0 {
+		file, err := os.OpenFile(dst, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open download archive: %w", err)
+		}
+		_, err = file.WriteString(added.String())
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write download archive: %w", err)
+		}
+	}
+	return os.Remove(src)
+}
+
+// mergeForcedArchive records the entries a finished run of a forced
+// re-download downloaded in the job's real archive
+func (a *App) mergeForcedArchive(job DownloadJob) {
+	if job.forcedArchive == "" {
+		return
+	}
+	if err := mergeDownloadArchive(job.forcedArchive, scratchArchivePath(job.forcedArchive, job.ID)); err != nil {
+		a.logger.Warningf("Failed to update download archive %s: %v", job.forcedArchive, err)
+	}
+}
+
+// markArchivedEntries flags the playlist entries that the archive for
+// downloads to outputPath already lists
+func (a *App) markArchivedEntries(entries []PlaylistEntry, outputPath string) {
+	path := a.currentSettings().downloadArchivePath(outputPath)
+	if path == "" {
+		return
+	}
+
+	archived, err := readDownloadArchive(path)
+	if err != nil {
+		a.logger.Warningf("Failed to read download archive %s: %v", path, err)
+		return
+	}
+	for i := range entries {
+		entries[i].AlreadyDownloaded = archived[entries[i].ID]
+	}
+}
+
+// updateDownloadArchiveSettingsInternal sets the download archive mode and
+// whether downloads ignore it
+func (a *App) updateDownloadArchiveSettingsInternal(mode string, forceRedownload bool) error {
+	switch mode {
+	case DownloadArchiveOff, DownloadArchiveGlobal, DownloadArchiveFolder:
+	default:
+		return fmt.Errorf("invalid download archive mode: %s", mode)
+	}
+
+	err := a.updateSettings(func(settings *Settings) {
+		settings.DownloadArchiveMode = mode
+		settings.ForceRedownload = forceRedownload
+	})
+	if err != nil {
+		a.logger.Errorf("Failed to save download archive settings: %v", err)
+		return err
+	}
+	return nil
+}
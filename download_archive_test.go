@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestJobArchivePath(t *testing.T) {
+	playlistOutput := filepath.Join("downloads", defaultPlaylistOutputTemplate)
+
+	tests := []struct {
+		name     string
+		settings Settings
+		job      DownloadJob
+		want     string
+		forced   bool
+	}{
+		{"DefaultIsGlobal", Settings{}, DownloadJob{OutputPath: playlistOutput}, globalDownloadArchiveFile, false},
+		{"Global", Settings{DownloadArchiveMode: DownloadArchiveGlobal}, DownloadJob{OutputPath: playlistOutput}, globalDownloadArchiveFile, false},
+		{"Folder", Settings{DownloadArchiveMode: DownloadArchiveFolder}, DownloadJob{OutputPath: playlistOutput}, filepath.Join("downloads", folderDownloadArchiveName), false},
+		{"Off", Settings{DownloadArchiveMode: DownloadArchiveOff}, DownloadJob{OutputPath: playlistOutput}, "", false},
+		{"ForcedBySettings", Settings{ForceRedownload: true}, DownloadJob{OutputPath: playlistOutput}, globalDownloadArchiveFile, true},
+		{"ForcedByOptions", Settings{}, DownloadJob{OutputPath: playlistOutput, Options: DownloadOptions{ForceRedownload: true}}, globalDownloadArchiveFile, true},
+		{"SubscriptionArchiveWins", Settings{ForceRedownload: true}, DownloadJob{ArchivePath: "archives/sub.txt"}, "archives/sub.txt", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &App{settings: tt.settings}
+			if got := app.jobArchivePath(&tt.job); got != tt.want {
+				t.Errorf("jobArchivePath() = %q, want %q", got, tt.want)
+			}
+			if got := app.jobForcesRedownload(&tt.job); got != tt.forced {
+				t.Errorf("jobForcesRedownload() = %v, want %v", got, tt.forced)
+			}
+		})
+	}
+}
+
+func TestMergeDownloadArchive(t *testing.T) {
+	dir := t.TempDir()
+	dst := filepath.Join(dir, "archive.txt")
+	src := filepath.Join(dir, "archive.txt.job.tmp")
+	if err := os.WriteFile(dst, []byte("youtube a1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(src, []byte("youtube a1\nyoutube b2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := mergeDownloadArchive(dst, src); err != nil {
+		t.Fatalf("mergeDownloadArchive() error = %v", err)
+	}
+	if data, _ := os.ReadFile(dst); string(data) != "youtube a1\nyoutube b2\n" {
+		t.Errorf("archive = %q, want the new entry appended once", data)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Errorf("the scratch archive should be removed, stat error = %v", err)
+	}
+	if err := mergeDownloadArchive(dst, src); err != nil {
+		t.Errorf("merging a missing scratch archive should do nothing, got %v", err)
+	}
+}
+
+func TestForcedRedownloadUsesScratchArchive(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, sink := newTestApp(t)
+	app.settings.ForceRedownload = true
+	output := filepath.Join("downloads", "Clip.mp4")
+	tools.install(app.getYtDlpBinaryName(), fakeRun{
+		Files:  map[string]string{output: "video"},
+		Stdout: []string{"[godlp-title] Clip", "[godlp-filepath] " + output},
+	})
+	if err := os.WriteFile(globalDownloadArchiveFile, []byte("youtube abc\n"), 0644); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+
+	id, err := app.downloadVideoInternal(fakeVideoURL, "best", filepath.Join("downloads", defaultOutputTemplate), "")
+	if err != nil {
+		t.Fatalf("downloadVideoInternal() error = %v", err)
+	}
+	sink.waitFor(t, "download-complete")
+
+	calls := tools.calls(app.getYtDlpBinaryName())
+	scratch := scratchArchivePath(globalDownloadArchiveFile, id)
+	if len(calls) != 1 || !slices.Contains(calls[0], scratch) || slices.Contains(calls[0], globalDownloadArchiveFile) {
+		t.Errorf("expected only the scratch archive %s to be passed, got %q", scratch, calls)
+	}
+}
+
+func TestUpdateDownloadArchiveSettings(t *testing.T) {
+	t.Chdir(t.TempDir())
+	app, _ := newTestApp(t)
+
+	if err := app.updateDownloadArchiveSettingsInternal("everywhere", false); err == nil {
+		t.Error("an unknown mode should be rejected")
+	}
+	if err := app.updateDownloadArchiveSettingsInternal(DownloadArchiveFolder, true); err != nil {
+		t.Fatalf("updateDownloadArchiveSettingsInternal() error = %v", err)
+	}
+	if app.settings.DownloadArchiveMode != DownloadArchiveFolder || !app.settings.ForceRedownload {
+		t.Errorf("settings not applied: %+v", app.settings)
+	}
+}
+
+func TestPlaylistItemsMarkArchivedEntries(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, _ := newTestApp(t)
+	tools.install(app.getYtDlpBinaryName(), fakeRun{When: []string{"--flat-playlist"}, Stdout: fakeChannelEntries})
+
+	if err := os.WriteFile(globalDownloadArchiveFile, []byte("example b2\n"), 0644); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+
+	result, err := app.getPlaylistItemsInternal("https://example.com/playlist")
+	if err != nil {
+		t.Fatalf("getPlaylistItemsInternal() error = %v", err)
+	}
+	var items struct {
+		Entries []PlaylistEntry `json:"entries"`
+	}
+	if err := json.Unmarshal([]byte(result), &items); err != nil {
+		t.Fatalf("failed to parse playlist items: %v", err)
+	}
+
+	var archived []string
+	for _, entry := range items.Entries {
+		if entry.AlreadyDownloaded {
+			archived = append(archived, entry.ID)
+		}
+	}
+	if !slices.Equal(archived, []string{"b2"}) {
+		t.Errorf("already downloaded entries = %v, want [b2]", archived)
+	}
+}
+
+func TestVideoDownloadAlreadyArchived(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, sink := newTestApp(t)
+	tools.install(app.getYtDlpBinaryName(), fakeRun{
+		When:   []string{"--download-archive"},
+		Stdout: []string{"[download] abc: has already been recorded in the archive"},
+	})
+
+	if _, err := app.downloadVideoInternal(fakeVideoURL, "best", filepath.Join("downloads", defaultOutputTemplate), ""); err != nil {
+		t.Fatalf("downloadVideoInternal() error = %v", err)
+	}
+
+	event := sink.waitFor(t, "download-complete")
+	if data := event.Data[0].(map[string]interface{}); data["already_downloaded"] != true {
+		t.Errorf("unexpected event data: %v", data)
+	}
+	calls := tools.calls(app.getYtDlpBinaryName())
+	if len(calls) != 1 || !slices.Contains(calls[0], globalDownloadArchiveFile) {
+		t.Errorf("expected the global archive to be passed, got %q", calls)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// audioCodecs lists the values yt-dlp accepts for --audio-format
+var audioCodecs = map[string]bool{
+	"best": true, "mp3": true, "m4a": true, "aac": true, "opus": true,
+	"vorbis": true, "flac": true, "alac": true, "wav": true,
+}
+
+// subtitleFormats lists the values yt-dlp accepts for --convert-subs that the UI offers
+var subtitleFormats = map[string]bool{"srt": true, "vtt": true, "ass": true}
+
+// audioQualityPattern matches a VBR quality (0-10) or a bitrate such as "192K"
+var audioQualityPattern = regexp.MustCompile(`^(?:10|[0-9]|[1-9][0-9]*[kK])$`)
+
+// parseDownloadOptions decodes and validates JSON-encoded DownloadOptions.
+// An empty string yields the default options.
+func parseDownloadOptions(optionsJSON string) (DownloadOptions, error) {
+	var options DownloadOptions
+	if strings.TrimSpace(optionsJSON) == "" {
+		return options, nil
+	}
+
+	if err := json.Unmarshal([]byte(optionsJSON), &options); err != nil {
+		return options, fmt.Errorf("failed to parse download options: %w", err)
+	}
+	if err := options.validate(); err != nil {
+		return options, err
+	}
+	return options, nil
+}
+
+// validate checks the options and fills in defaults
+func (o *DownloadOptions) validate() error {
+	if o.Audio != nil {
+		if err := o.Audio.validate(); err != nil {
+			return err
+		}
+	}
+	if o.Subtitles != nil {
+		if err := o.Subtitles.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks the audio profile and fills in defaults
+func (x *AudioExtraction) validate() error {
+	x.Codec = strings.ToLower(strings.TrimSpace(x.Codec))
+	if x.Codec == "" {
+		x.Codec = "best"
+	}
+	if !audioCodecs[x.Codec] {
+		return fmt.Errorf("unsupported audio codec: %s", x.Codec)
+	}
+
+	x.Quality = strings.TrimSpace(x.Quality)
+	if x.Quality == "" {
+		x.Quality = "5" // yt-dlp's default VBR quality
+	}
+	if !audioQualityPattern.MatchString(x.Quality) {
+		return fmt.Errorf("invalid audio quality %q, expected 0-10 or a bitrate like 192K", x.Quality)
+	}
+	return nil
+}
+
+// validate checks the subtitle selection and normalizes languages and format
+func (s *SubtitleSelection) validate() error {
+	languages := make([]string, 0, len(s.Languages))
+	for _, lang := range s.Languages {
+		lang = strings.TrimSpace(lang)
+		if lang == "" {
+			continue
+		}
+		// --sub-langs is comma separated, so a comma would split the entry
+		if strings.Contains(lang, ",") {
+			return fmt.Errorf("invalid subtitle language: %s", lang)
+		}
+		languages = append(languages, lang)
+	}
+	s.Languages = languages
+
+	s.Format = strings.ToLower(strings.TrimSpace(s.Format))
+	if s.Format != "" && !subtitleFormats[s.Format] {
+		return fmt.Errorf("unsupported subtitle format: %s", s.Format)
+	}
+	return nil
+}
+
+// subtitleArgs maps a subtitle selection to yt-dlp arguments
+func subtitleArgs(s *SubtitleSelection) []string {
+	if s == nil {
+		return nil
+	}
+
+	args := []string{"--write-subs"}
+	if s.Auto {
+		args = append(args, "--write-auto-subs")
+	}
+	if len(s.Languages) > 0 {
+		args = append(args, "--sub-langs", strings.Join(s.Languages, ","))
+	}
+	if s.Format != "" {
+		args = append(args, "--convert-subs", s.Format)
+	}
+	if s.Embed {
+		args = append(args, "--embed-subs")
+	}
+	return args
+}
+
+// defaultEmbedOptions returns the embed options configured in the settings
+func (s Settings) defaultEmbedOptions() EmbedOptions {
+	return EmbedOptions{
+		Metadata:  s.EmbedMetadata,
+		Thumbnail: s.EmbedThumbnail,
+		Chapters:  s.EmbedChapters,
+		InfoJSON:  s.EmbedInfoJSON,
+	}
+}
+
+// embedArgs maps embed options to yt-dlp arguments
+func embedArgs(embed EmbedOptions) []string {
+	var args []string
+	if embed.Metadata {
+		args = append(args, "--embed-metadata")
+	}
+	if embed.Thumbnail {
+		args = append(args, "--embed-thumbnail")
+	}
+	if embed.Chapters {
+		args = append(args, "--embed-chapters")
+	}
+	if embed.InfoJSON {
+		args = append(args, "--embed-info-json")
+	}
+	return args
+}
+
+// audioExtractionArgs maps an audio profile to yt-dlp arguments
+func audioExtractionArgs(x *AudioExtraction) []string {
+	if x == nil {
+		return nil
+	}
+	args := []string{"-x", "--audio-format", x.Codec, "--audio-quality", x.Quality}
+	if x.KeepOriginal {
+		args = append(args, "-k")
+	}
+	return args
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseDownloadOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected DownloadOptions
+		wantErr  bool
+	}{
+		{"Empty", "", DownloadOptions{}, false},
+		{"NoAudio", `{}`, DownloadOptions{}, false},
+		{"AudioDefaults", `{"audio":{}}`, DownloadOptions{Audio: &AudioExtraction{Codec: "best", Quality: "5"}}, false},
+		{"Mp3Bitrate", `{"audio":{"codec":"MP3","quality":"192K","keep_original":true}}`, DownloadOptions{Audio: &AudioExtraction{Codec: "mp3", Quality: "192K", KeepOriginal: true}}, false},
+		{"OpusVBR", `{"audio":{"codec":"opus","quality":"0"}}`, DownloadOptions{Audio: &AudioExtraction{Codec: "opus", Quality: "0"}}, false},
+		{"UnknownCodec", `{"audio":{"codec":"wma"}}`, DownloadOptions{}, true},
+		{"QualityOutOfRange", `{"audio":{"codec":"mp3","quality":"11"}}`, DownloadOptions{}, true},
+		{"QualityWithoutUnit", `{"audio":{"codec":"mp3","quality":"192"}}`, DownloadOptions{}, true},
+		{"InvalidJSON", `{"audio":`, DownloadOptions{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDownloadOptions(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parseDownloadOptions(%q) = %+v, want %+v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAudioExtractionArgs(t *testing.T) {
+	if args := audioExtractionArgs(nil); args != nil {
+		t.Errorf("expected no arguments without a profile, got %q", args)
+	}
+
+	args := audioExtractionArgs(&AudioExtraction{Codec: "m4a", Quality: "2", KeepOriginal: true})
+	expected := []string{"-x", "--audio-format", "m4a", "--audio-quality", "2", "-k"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("audioExtractionArgs() = %q, want %q", args, expected)
+	}
+}
+
+func TestEmbedArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		embed    EmbedOptions
+		expected []string
+	}{
+		{"Nothing", EmbedOptions{}, nil},
+		{"MetadataOnly", EmbedOptions{Metadata: true}, []string{"--embed-metadata"}},
+		{"ThumbnailAndChapters", EmbedOptions{Thumbnail: true, Chapters: true}, []string{"--embed-thumbnail", "--embed-chapters"}},
+		{"Everything", EmbedOptions{Metadata: true, Thumbnail: true, Chapters: true, InfoJSON: true},
+			[]string{"--embed-metadata", "--embed-thumbnail", "--embed-chapters", "--embed-info-json"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := embedArgs(tt.embed); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("embedArgs(%+v) = %q, want %q", tt.embed, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseDownloadOptionsEmbed(t *testing.T) {
+	options, err := parseDownloadOptions(`{"embed":{"metadata":true,"thumbnail":false,"chapters":true,"info_json":false}}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &EmbedOptions{Metadata: true, Chapters: true}
+	if !reflect.DeepEqual(options.Embed, expected) {
+		t.Errorf("Embed = %+v, want %+v", options.Embed, expected)
+	}
+}
+
+func TestVideoInfoMetadataFields(t *testing.T) {
+	raw := `{"id":"abc","title":"Talk","channel":"Conf","upload_date":"20240131","tags":["go","wails"],` +
+		`"chapters":[{"start_time":0,"end_time":61.5,"title":"Intro"},{"start_time":61.5,"end_time":300,"title":"Main"}]}`
+
+	var info VideoInfo
+	if err := json.Unmarshal([]byte(raw), &info); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if info.Channel != "Conf" || info.UploadDate != "20240131" {
+		t.Errorf("unexpected channel or upload date: %+v", info)
+	}
+	if !reflect.DeepEqual(info.Tags, []string{"go", "wails"}) {
+		t.Errorf("Tags = %q", info.Tags)
+	}
+	expected := []Chapter{{StartTime: 0, EndTime: 61.5, Title: "Intro"}, {StartTime: 61.5, EndTime: 300, Title: "Main"}}
+	if !reflect.DeepEqual(info.Chapters, expected) {
+		t.Errorf("Chapters = %+v, want %+v", info.Chapters, expected)
+	}
+}
+
+func TestSubtitleSelectionValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    SubtitleSelection
+		expected SubtitleSelection
+		wantErr  bool
+	}{
+		{"TrimsAndDropsEmptyLanguages", SubtitleSelection{Languages: []string{" en ", "", "de.*"}, Format: " SRT "},
+			SubtitleSelection{Languages: []string{"en", "de.*"}, Format: "srt"}, false},
+		{"NoLanguages", SubtitleSelection{Auto: true}, SubtitleSelection{Languages: []string{}, Auto: true}, false},
+		{"CommaInLanguage", SubtitleSelection{Languages: []string{"en,de"}}, SubtitleSelection{}, true},
+		{"UnsupportedFormat", SubtitleSelection{Format: "ttml"}, SubtitleSelection{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.input
+			err := got.validate()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("validate() = %+v, want %+v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSubtitleArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		selection *SubtitleSelection
+		expected  []string
+	}{
+		{"None", nil, nil},
+		{"DefaultLanguage", &SubtitleSelection{}, []string{"--write-subs"}},
+		{"SidecarSRT", &SubtitleSelection{Languages: []string{"en", "de"}, Format: "srt"},
+			[]string{"--write-subs", "--sub-langs", "en,de", "--convert-subs", "srt"}},
+		{"EmbeddedAuto", &SubtitleSelection{Languages: []string{"en"}, Auto: true, Embed: true},
+			[]string{"--write-subs", "--write-auto-subs", "--sub-langs", "en", "--embed-subs"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := subtitleArgs(tt.selection); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("subtitleArgs() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
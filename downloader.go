@@ -1,17 +1,14 @@
 package main
 
 import (
-	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
-	"strconv"
 	"strings"
 	"sync"
-	"time"
 
 	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -19,133 +16,58 @@ import (
 // defaultDownloadDir stores the user-specified download path
 var defaultDownloadDir string = "./downloads"
 
-// Global variable to track the current download process
-var (
-	currentDownloadCmd  *exec.Cmd
-	downloadMutex       sync.Mutex
-	downloadCache       map[string]string // Cache for download paths
-	downloadCancelChan  chan struct{}     // Channel for graceful cancellation
-	downloadStopReason  string            // "cancel" or "pause" for the next cancellation event
-	completedDownloads  map[string]bool   // Track completed downloads to prevent duplicates
-	completionEmitted   bool              // Track if completion event was already emitted
-	completionEmittedMu sync.Mutex        // Mutex to protect completionEmitted
-)
-
-// Initialize download cache
-func init() {
-	downloadCache = make(map[string]string)
-	completedDownloads = make(map[string]bool)
-	downloadStopReason = ""
-	completionEmitted = false
-}
-
-// emitDownloadEvent safely emits a download completion/error/cancelled event
-// ensuring only one such event is emitted per download
-func emitDownloadEvent(ctx context.Context, eventType string, data ...interface{}) {
-	completionEmittedMu.Lock()
-	defer completionEmittedMu.Unlock()
-
-	if completionEmitted {
-		return // Already emitted a completion event for this download
-	}
-
-	// Mark as emitted for all terminal events (complete, error, cancelled)
-	if eventType == "download-complete" || eventType == "download-error" || eventType == "download-cancelled" {
-		completionEmitted = true
+// downloadVideoInternal queues a video download using the selected format ID
+// and returns its job ID
+func (a *App) downloadVideoInternal(url, formatID, outputPath, optionsJSON string) (string, error) {
+	options, err := parseDownloadOptions(optionsJSON)
+	if err != nil {
+		return "", err
 	}
 
-	if len(data) > 0 {
-		wailsRuntime.EventsEmit(ctx, eventType, data[0])
-	} else {
-		wailsRuntime.EventsEmit(ctx, eventType)
-	}
+	return a.enqueueJob(&DownloadJob{
+		URL:        url,
+		FormatID:   formatID,
+		OutputPath: outputPath,
+		Options:    options,
+	}), nil
 }
 
-// downloadVideoInternal downloads a video using the selected format ID
-func (a *App) downloadVideoInternal(url, formatID, outputPath string) error {
-	ytDlpPath := filepath.Join("./bin", a.getYtDlpBinaryName())
-
-	// Initialize cancel channel for this download
-	downloadCancelChan = make(chan struct{})
-	downloadStopReason = ""
-
-	// Reset completion flag for this download
-	completionEmitted = false
-
-	// Build command arguments based on settings
-	args := []string{url, "-f", formatID, "-o", outputPath, "--newline", "--progress", "--continue", "--part"}
-
-	// Add JS runtime if enabled or if it's a YouTube URL (which requires it)
-	if a.settings.UseJSRuntime || a.isYouTubeURL(url) {
-		jsRuntimeCmd := a.getJSRuntimeCommand()
-		if jsRuntimeCmd != "" {
-			args = append(args, "--js-runtimes", jsRuntimeCmd)
-		} else {
-			// If JS runtime is required but not available, try to install based on settings
-			if a.settings.JSRuntimeType == "node" {
-				if !a.isNodeAvailable() {
-					wailsRuntime.LogInfof(a.ctx, "Node.js not found, attempting to download...")
-					err := a.installNode()
-					if err != nil {
-						wailsRuntime.LogErrorf(a.ctx, "Failed to download node.js: %v", err)
-						// Try fallback to deno
-						if a.isDenoAvailable() {
-							args = append(args, "--js-runtimes", "deno:"+filepath.Join("./bin", a.getDenoBinaryName()))
-						}
-					} else {
-						args = append(args, "--js-runtimes", "node")
-					}
-				} else {
-					args = append(args, "--js-runtimes", "node")
-				}
-			} else {
-				// Default to deno
-				if !a.isDenoAvailable() {
-					wailsRuntime.LogInfof(a.ctx, "Deno not found, attempting to download...")
-					err := a.downloadDenoWithProgress()
-					if err != nil {
-						wailsRuntime.LogErrorf(a.ctx, "Failed to download deno: %v", err)
-						// Try fallback to node
-						if a.isNodeAvailable() {
-							args = append(args, "--js-runtimes", "node")
-						}
-					} else {
-						args = append(args, "--js-runtimes", "deno:"+filepath.Join("./bin", a.getDenoBinaryName()))
-					}
-				} else {
-					args = append(args, "--js-runtimes", "deno:"+filepath.Join("./bin", a.getDenoBinaryName()))
-				}
-			}
-		}
-	}
-
-	// Add proxy settings if enabled
-	if a.settings.ProxyMode == "manual" && a.settings.ProxyAddress != "" {
-		args = append(args, "--proxy", a.settings.ProxyAddress)
+// downloadInvocation builds the yt-dlp invocation that downloads a job
+func (a *App) downloadInvocation(job *DownloadJob) YtDlpInvocation {
+	inv := a.newYtDlpInvocation(job.URL)
+	inv.FormatID = job.FormatID
+	inv.OutputPath = job.OutputPath
+	inv.Download = true
+	inv.Options = job.Options
+	inv.DownloadArchive = a.jobArchivePath(job)
+	forcedArchive := ""
+	if inv.DownloadArchive != "" && a.jobForcesRedownload(job) {
+		forcedArchive = inv.DownloadArchive
+		inv.DownloadArchive = scratchArchivePath(forcedArchive, job.ID)
 	}
-	// Note: When ProxyMode is "system", yt-dlp automatically uses system proxy settings
-
-	// Add cookies settings if enabled
-	if a.settings.CookiesMode == "browser" {
-		cookiesArg := fmt.Sprintf("--cookies-from-browser=%s", a.settings.CookiesBrowser)
-		args = append(args, cookiesArg)
-	} else if a.settings.CookiesMode == "file" && a.settings.CookiesFile != "" {
-		// Check if the cookies file exists before using it
-		if _, err := os.Stat(a.settings.CookiesFile); err == nil {
-			args = append(args, "--cookies", a.settings.CookiesFile)
-		} else {
-			wailsRuntime.LogInfof(a.ctx, "Cookies file does not exist: %s, proceeding without cookies", a.settings.CookiesFile)
+	a.queue.mu.Lock()
+	job.forcedArchive = forcedArchive
+	a.queue.mu.Unlock()
+	if job.Playlist {
+		inv.Flags = []string{"--ignore-errors", "--print", ytDlpItemStartPrint, "--print", ytDlpItemFinishPrint}
+		inv.Flags = append(inv.Flags, job.Filter.args()...)
+		inv.PlaylistItems = playlistItemsRange(job.StartItem, job.EndItem)
+		if len(job.Items) > 0 {
+			inv.PlaylistItems = playlistItemsList(job.Items)
 		}
 	}
+	return inv
+}
 
-	// Hide console window on Windows
-	cmd := exec.Command(ytDlpPath, args...)
-	setHideWindow(cmd)
+// startVideoDownload starts the yt-dlp process for a single video job
+func (a *App) startVideoDownload(job *DownloadJob) error {
+	inv := a.downloadInvocation(job)
+	cmd := a.ytDlpCommand(inv)
 
-	// Store the current download command for cancellation
-	downloadMutex.Lock()
-	currentDownloadCmd = cmd
-	downloadMutex.Unlock()
+	// Store the download command on the job for cancellation
+	if !a.setJobCommand(job, cmd) {
+		return nil // Job was stopped before the process started
+	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -161,643 +83,262 @@ func (a *App) downloadVideoInternal(url, formatID, outputPath string) error {
 		return fmt.Errorf("failed to start download: %w", err)
 	}
 
-	wailsRuntime.LogInfof(a.ctx, "Download started for URL: %s, Format: %s", url, formatID)
-
-	// Use a shared progress tracker with optimized parsing
-	// Pre-compile regex patterns for better performance
-	progressTracker := struct {
-		mu               sync.Mutex
-		previousProgress int
-		maxProgress      int
-		lastUpdateTime   time.Time
-	}{
-		previousProgress: -1,
-		maxProgress:      -1,
-		lastUpdateTime:   time.Time{},
+	// The job may have been stopped while the process was starting
+	if !a.jobCommandActive(job, cmd) {
+		cmd.Process.Kill()
 	}
 
-	// Pre-compiled regex patterns - UPDATED for Rutube compatibility
-	// Pattern handles: [download]   1.2% of ~   1.11GiB at  699.38KiB/s ETA 28:37 (frag 8/664)
-	// Key changes: ([~≈]?\s*[\d\.]+\s*...) allows spaces after tilde
-	reProgressMain := regexp.MustCompile(`\[download\]\s+([\d\.]+)%\s+of\s+([~≈]?\s*[\d\.]+\s*[kKmMgGtTpP]?i?B).*?at\s+([\d\.]+\s*[kKmMgGtTpP]?i?B/s|unknown)\s+ETA\s+([\d:]+|unknown)`)
-	reProgressSimple := regexp.MustCompile(`\[download\][^0-9]*([\d\.]+)\s*%`)
-
-	// Shared progress parser state for stdout/stderr chunks.
-	var (
-		progressStateMu     sync.Mutex
-		lastProgress        float64 = 0
-		lastSize            string  = "--"
-		lastSpeed           string  = "--"
-		lastEta             string  = "--"
-		lastEmittedProgress         = -1
-		lastEmittedSize     string
-		lastEmittedSpeed    string
-		lastEmittedEta      string
-		stdoutTail          string
-		stderrTail          string
-	)
-
-	processProgressLine := func(line string) {
-		if !strings.Contains(line, "[download]") {
-			return
-		}
+	a.logger.Infof("Download job %s started for URL: %s, Format: %s", job.ID, job.URL, job.FormatID)
 
-		var currentProgress float64 = -1
-		if matches := reProgressMain.FindStringSubmatch(line); len(matches) >= 5 {
-			if p, errParse := strconv.ParseFloat(matches[1], 64); errParse == nil {
-				currentProgress = p
-				if matches[2] != "unknown" {
-					lastSize = strings.Join(strings.Fields(matches[2]), " ")
-				}
-				if matches[3] != "unknown" {
-					lastSpeed = strings.TrimSpace(matches[3])
-				}
-				if matches[4] != "unknown" {
-					lastEta = strings.TrimSpace(matches[4])
-				}
-			}
-		} else if matchesSimple := reProgressSimple.FindStringSubmatch(line); len(matchesSimple) > 1 {
-			if p, errParse := strconv.ParseFloat(matchesSimple[1], 64); errParse == nil {
-				currentProgress = p
-			}
-		}
+	progress := newProgressReporter(a, job)
 
-		if currentProgress < 0 {
-			return
-		}
+	// The process is only waited for once both pipes are drained, since Wait
+	// closes them and would drop the last lines otherwise
+	var readers sync.WaitGroup
+	readers.Add(2)
 
-		if currentProgress < 1 && lastProgress > 90 {
-			lastProgress = 0
+	// Read stdout in real-time to get progress
+	go func() {
+		defer readers.Done()
+		if err := scanOutputLines(stdout, func(line string) { progress.handleLine(line) }); err != nil {
+			a.logger.Errorf("Error reading stdout: %v", err)
 		}
-		if currentProgress >= lastProgress {
-			lastProgress = currentProgress
+	}()
+
+	// Read stderr to check for cookies-related errors
+	var onCookiesError func(string)
+	if inv.UsesCookies() {
+		onCookiesError = func(stderrStr string) {
+			a.logger.Infof("Cookies-related error detected during download: %s", stderrStr)
+			a.retryVideoDownloadWithoutCookies(job, inv, cmd, progress)
 		}
+	}
+	var lastError string
+	go func() {
+		defer readers.Done()
+		lastError = a.scanDownloadStderr(stderr, progress, onCookiesError)
+	}()
 
-		progressInt := int(lastProgress)
-		shouldEmit := progressInt != lastEmittedProgress ||
-			lastSize != lastEmittedSize ||
-			lastSpeed != lastEmittedSpeed ||
-			lastEta != lastEmittedEta
-		if !shouldEmit {
-			return
+	// Wait for the command to finish
+	go func() {
+		readers.Wait()
+		waitErr := withStderrError(cmd.Wait(), lastError)
+		if !a.jobCommandActive(job, cmd) {
+			return // Job was stopped or its process was replaced by a retry
 		}
+		a.finishVideoDownload(job, progress, waitErr, "")
+	}()
+
+	return nil
+}
+
+// retryVideoDownloadWithoutCookies replaces a download process that failed because
+// of cookies with a new one that runs without them
+func (a *App) retryVideoDownloadWithoutCookies(job *DownloadJob, inv YtDlpInvocation, cmd *exec.Cmd, progress *progressReporter) {
+	// Detach the current process from the job so its exit is not reported
+	if !a.setJobCommand(job, nil) {
+		return // Job was stopped by the user
+	}
+
+	// Kill the current process and try without cookies
+	if cmd.Process != nil {
+		a.logger.Infof("Killing current download process due to cookies error")
+		cmd.Process.Kill()
+	}
 
-		lastEmittedProgress = progressInt
-		lastEmittedSize = lastSize
-		lastEmittedSpeed = lastSpeed
-		lastEmittedEta = lastEta
+	cmdWithoutCookies := a.ytDlpCommand(inv.NoCookies())
 
-		progressTracker.mu.Lock()
-		progressTracker.previousProgress = progressInt
-		progressTracker.mu.Unlock()
+	// Store the retry download command
+	if !a.setJobCommand(job, cmdWithoutCookies) {
+		return // Job was stopped by the user
+	}
 
-		wailsRuntime.EventsEmit(a.ctx, "download-progress", map[string]interface{}{
-			"progress": progressInt,
-			"size":     lastSize,
-			"speed":    lastSpeed,
-			"eta":      lastEta,
+	stdoutWithoutCookies, err := cmdWithoutCookies.StdoutPipe()
+	if err != nil {
+		a.logger.Errorf("Failed to create stdout pipe for retry: %v", err)
+		a.emitDownloadEvent(job, "download-error", map[string]interface{}{
+			"error": fmt.Sprintf("Download failed (retry): %v", err),
 		})
+		return
 	}
 
-	processProgressChunk := func(chunk string, fromStderr bool) {
-		progressStateMu.Lock()
-		if fromStderr {
-			chunk = stderrTail + chunk
-		} else {
-			chunk = stdoutTail + chunk
-		}
-		lines := strings.Split(chunk, "\n")
-		if len(lines) == 0 {
-			progressStateMu.Unlock()
-			return
-		}
-		if fromStderr {
-			stderrTail = lines[len(lines)-1]
-		} else {
-			stdoutTail = lines[len(lines)-1]
-		}
+	stderrWithoutCookies, err := cmdWithoutCookies.StderrPipe()
+	if err != nil {
+		a.logger.Errorf("Failed to create stderr pipe for retry: %v", err)
+		a.emitDownloadEvent(job, "download-error", map[string]interface{}{
+			"error": fmt.Sprintf("Download failed (retry): %v", err),
+		})
+		return
+	}
 
-		for _, line := range lines[:len(lines)-1] {
-			processProgressLine(line)
-		}
-		progressStateMu.Unlock()
+	if err := cmdWithoutCookies.Start(); err != nil {
+		a.logger.Errorf("Failed to start retry download: %v", err)
+		a.emitDownloadEvent(job, "download-error", map[string]interface{}{
+			"error": fmt.Sprintf("Download failed (retry): %v", err),
+		})
+		return
 	}
 
-	// Read stdout and stderr in real-time to get progress and errors
+	a.logger.Infof("Retry download started without cookies")
+
+	var readers sync.WaitGroup
+	readers.Add(2)
+
+	// Read progress from the retry command
 	go func() {
-		buffer := make([]byte, 1024)
-		for {
-			n, err := stdout.Read(buffer)
-			if n > 0 {
-				processProgressChunk(string(buffer[:n]), false)
-			}
-
-			if err == io.EOF {
-				processProgressChunk("\n", false)
-				break
-			}
-
-			if err != nil {
-				wailsRuntime.LogErrorf(a.ctx, "Error reading stdout: %v", err)
-				break
-			}
+		defer readers.Done()
+		if err := scanOutputLines(stdoutWithoutCookies, func(line string) { progress.handleLine(line) }); err != nil {
+			a.logger.Errorf("Error reading stdout (retry): %v", err)
 		}
 	}()
 
-	// Read stderr to check for cookies-related errors
+	// Read stderr for errors, as for the first attempt but without another retry
+	var lastError string
 	go func() {
-		buffer := make([]byte, 4096)
-		var stderrContent strings.Builder
-
-		for {
-			n, err := stderr.Read(buffer)
-			if n > 0 {
-				processProgressChunk(string(buffer[:n]), true)
-				stderrContent.Write(buffer[:n])
-				stderrStr := stderrContent.String()
-
-				// Check for cookies-related errors
-				if strings.Contains(stderrStr, "cookies") ||
-					strings.Contains(stderrStr, "Sign in to confirm") ||
-					strings.Contains(stderrStr, "authentication") ||
-					strings.Contains(stderrStr, "Requested format is not available") {
-
-					wailsRuntime.LogInfof(a.ctx, "Cookies-related error detected during download: %s", stderrStr)
-
-					// Kill the current process and try without cookies
-					if cmd.Process != nil {
-						wailsRuntime.LogInfof(a.ctx, "Killing current download process due to cookies error")
-						cmd.Process.Kill()
-					}
-
-					// Clear the current download command
-					downloadMutex.Lock()
-					currentDownloadCmd = nil
-					downloadMutex.Unlock()
-
-					// Try downloading without cookies
-					argsWithoutCookies := []string{url, "-f", formatID, "-o", outputPath, "--newline", "--progress", "--continue", "--part"}
-
-					// Add proxy settings if enabled (but no cookies)
-					if a.settings.ProxyMode == "manual" && a.settings.ProxyAddress != "" {
-						argsWithoutCookies = append(argsWithoutCookies, "--proxy", a.settings.ProxyAddress)
-					} else if a.settings.ProxyMode == "system" {
-						argsWithoutCookies = append(argsWithoutCookies, "--proxy", "system")
-					}
-
-					cmdWithoutCookies := exec.Command(ytDlpPath, argsWithoutCookies...)
-					setHideWindow(cmdWithoutCookies)
-
-					// Store the retry download command
-					downloadMutex.Lock()
-					currentDownloadCmd = cmdWithoutCookies
-					downloadMutex.Unlock()
-
-					stdoutWithoutCookies, err := cmdWithoutCookies.StdoutPipe()
-					if err != nil {
-						wailsRuntime.LogErrorf(a.ctx, "Failed to create stdout pipe for retry: %v", err)
-						return
-					}
-
-					if err := cmdWithoutCookies.Start(); err != nil {
-						wailsRuntime.LogErrorf(a.ctx, "Failed to start retry download: %v", err)
-						return
-					}
-
-					wailsRuntime.LogInfof(a.ctx, "Retry download started without cookies")
-
-					// Read progress from the retry command
-					go func() {
-						buffer := make([]byte, 1024)
-
-						// UPDATED: Variables for storing last known values in Retry block
-						var (
-							lastProgress float64 = 0
-							lastSize     string  = "--"
-							lastSpeed    string  = "--"
-							lastEta      string  = "--"
-							retryMu      sync.Mutex
-						)
-
-						for {
-							n, err := stdoutWithoutCookies.Read(buffer)
-							if n > 0 {
-								output := string(buffer[:n])
-
-								// Log the raw output for debugging
-								if strings.Contains(output, "[download]") {
-									wailsRuntime.LogInfof(a.ctx, "Raw retry download output: %s", output)
-								}
-
-								// Use the same updated regex for retry
-								matches := reProgressMain.FindStringSubmatch(output)
-
-								var currentProgress float64 = -1
-
-								retryMu.Lock()
-
-								if len(matches) >= 5 {
-									// Parse data
-									p, errParse := strconv.ParseFloat(matches[1], 64)
-									if errParse == nil {
-										currentProgress = p
-
-										// Update metadata only if valid
-										if matches[2] != "unknown" {
-											lastSize = strings.Join(strings.Fields(matches[2]), " ")
-										}
-										if matches[3] != "unknown" {
-											lastSpeed = matches[3]
-										}
-										if matches[4] != "unknown" {
-											lastEta = matches[4]
-										}
-									}
-								} else {
-									// Fallback: try to find at least percentage
-									matchesSimple := reProgressSimple.FindStringSubmatch(output)
-									if len(matchesSimple) > 1 {
-										p, errParse := strconv.ParseFloat(matchesSimple[1], 64)
-										if errParse == nil {
-											currentProgress = p
-										}
-									}
-								}
-
-								// UI UPDATE LOGIC with protection from backward jumps
-								if currentProgress >= 0 {
-									// Exception: if we started new download (progress < 1), reset
-									if currentProgress < 1 && lastProgress > 90 {
-										lastProgress = 0
-									}
-
-									if currentProgress < lastProgress {
-										currentProgress = lastProgress
-									} else {
-										lastProgress = currentProgress
-									}
-
-									progressTracker.mu.Lock()
-									if int(lastProgress) != progressTracker.previousProgress {
-										progressTracker.previousProgress = int(lastProgress)
-										progressTracker.mu.Unlock()
-
-										wailsRuntime.EventsEmit(a.ctx, "download-progress", map[string]interface{}{
-											"progress": int(lastProgress),
-											"size":     lastSize,
-											"speed":    lastSpeed,
-											"eta":      lastEta,
-										})
-									} else {
-										progressTracker.mu.Unlock()
-									}
-								}
-
-								retryMu.Unlock()
-							}
-
-							if err == io.EOF {
-								break
-							}
-
-							if err != nil {
-								wailsRuntime.LogErrorf(a.ctx, "Error reading stdout (retry): %v", err)
-								break
-							}
-						}
-
-						waitErr := cmdWithoutCookies.Wait()
-						// Clear the current download command
-						downloadMutex.Lock()
-						currentDownloadCmd = nil
-						downloadMutex.Unlock()
-
-						if waitErr != nil {
-							wailsRuntime.LogErrorf(a.ctx, "Download failed (retry): %v", waitErr)
-							errorMsg := fmt.Sprintf("Download failed (retry): %v", waitErr)
-							a.logDetailedError("DownloadVideo", url, formatID, waitErr)
-							emitDownloadEvent(a.ctx, "download-error", errorMsg)
-						} else {
-							wailsRuntime.LogInfof(a.ctx, "Download completed successfully (retry) for URL: %s, Format: %s", url, formatID)
-
-							// Wait longer for yt-dlp to finish all operations
-							time.Sleep(2 * time.Second)
-
-							// Check if download is actually complete (no .part files)
-							outputPathWithoutExt := strings.TrimSuffix(outputPath, ".%(ext)s")
-							sanitizedTitle := filepath.Base(outputPathWithoutExt)
-
-							// Check for .part files (including fragment files)
-							downloadsDir := a.getDownloadDirectoryInternal()
-							partExtensions := []string{".mp4.part", ".webm.part", ".mkv.part", ".avi.part", ".mov.part", ".flv.part", ".m4v.part"}
-							hasPartFile := false
-
-							for _, ext := range partExtensions {
-								partPath := filepath.Join(downloadsDir, sanitizedTitle+ext)
-								if _, err := os.Stat(partPath); err == nil {
-									hasPartFile = true
-									wailsRuntime.LogWarningf(a.ctx, "Found .part file after retry download completion: %s", partPath)
-									break
-								}
-
-								// Check for fragment files (e.g., .part-Frag21.part)
-								fragmentPattern := filepath.Join(downloadsDir, sanitizedTitle+ext+"-*")
-								if matches, _ := filepath.Glob(fragmentPattern); len(matches) > 0 {
-									hasPartFile = true
-									wailsRuntime.LogWarningf(a.ctx, "Found fragment files after retry download completion: %v", matches)
-									break
-								}
-							}
-
-							// Check for .ytdl files
-							ytdlPath := filepath.Join(downloadsDir, sanitizedTitle+".ytdl")
-							hasYtdlFile := false
-							if _, err := os.Stat(ytdlPath); err == nil {
-								hasYtdlFile = true
-								wailsRuntime.LogWarningf(a.ctx, "Found .ytdl file after retry download completion: %s", ytdlPath)
-							}
-
-							// Additional check: try to find the actual completed file
-							completedFileFound := false
-							extensions := []string{".mp4", ".webm", ".mkv", ".avi", ".mov", ".flv", ".m4v"}
-
-							for _, ext := range extensions {
-								potentialPath := filepath.Join(downloadsDir, sanitizedTitle+ext)
-								if fileInfo, err := os.Stat(potentialPath); err == nil {
-									// Check if file is not empty and was modified recently (indicating it's complete)
-									if fileInfo.Size() > 0 && time.Since(fileInfo.ModTime()) < 10*time.Second {
-										completedFileFound = true
-										wailsRuntime.LogInfof(a.ctx, "Found completed file: %s (size: %d bytes)", potentialPath, fileInfo.Size())
-										break
-									}
-								}
-							}
-
-							// Only emit download-complete if no .part or .ytdl files exist AND we found a completed file
-							if !hasPartFile && !hasYtdlFile && completedFileFound {
-								progressTracker.mu.Lock()
-								if progressTracker.previousProgress != 100 {
-									wailsRuntime.EventsEmit(a.ctx, "download-progress", map[string]interface{}{
-										"progress": 100,
-										"size":     "Complete",
-										"speed":    "0",
-										"eta":      "00:00",
-									})
-								}
-								progressTracker.mu.Unlock()
-								emitDownloadEvent(a.ctx, "download-complete")
-							} else if hasPartFile || hasYtdlFile {
-								// Download is incomplete, emit error
-								wailsRuntime.LogErrorf(a.ctx, "Download incomplete (retry): .part or .ytdl files still present")
-								emitDownloadEvent(a.ctx, "download-error", "Download incomplete: File is still being processed")
-							} else {
-								// No completed file found, wait a bit more and retry
-								wailsRuntime.LogWarningf(a.ctx, "No completed file found after retry download, waiting additional time...")
-								time.Sleep(3 * time.Second)
-
-								// Retry check for completed file
-								for _, ext := range extensions {
-									potentialPath := filepath.Join(downloadsDir, sanitizedTitle+ext)
-									if fileInfo, err := os.Stat(potentialPath); err == nil && fileInfo.Size() > 0 {
-										completedFileFound = true
-										wailsRuntime.LogInfof(a.ctx, "Found completed file on retry: %s (size: %d bytes)", potentialPath, fileInfo.Size())
-										break
-									}
-								}
-
-								if completedFileFound {
-									progressTracker.mu.Lock()
-									if progressTracker.previousProgress != 100 {
-										wailsRuntime.EventsEmit(a.ctx, "download-progress", map[string]interface{}{
-											"progress": 100,
-											"size":     "Complete",
-											"speed":    "0",
-											"eta":      "00:00",
-										})
-									}
-									progressTracker.mu.Unlock()
-									emitDownloadEvent(a.ctx, "download-complete")
-								} else {
-									wailsRuntime.LogErrorf(a.ctx, "Download failed (retry): No completed file found after extended wait")
-									emitDownloadEvent(a.ctx, "download-error", "Download failed: No completed file found")
-								}
-							}
-						}
-					}()
-
-					return
-				}
-			}
-
-			if err == io.EOF {
-				processProgressChunk("\n", true)
-				break
-			}
-
-			if err != nil {
-				wailsRuntime.LogErrorf(a.ctx, "Error reading stderr: %v", err)
-				break
-			}
-		}
+		defer readers.Done()
+		lastError = a.scanDownloadStderr(stderrWithoutCookies, progress, nil)
 	}()
 
 	// Wait for the command to finish
 	go func() {
-		waitErr := cmd.Wait()
-		// Clear the current download command
-		downloadMutex.Lock()
-		currentDownloadCmd = nil
-		downloadMutex.Unlock()
-
-		if waitErr != nil {
-			wailsRuntime.LogErrorf(a.ctx, "Download failed: %v", waitErr)
-			a.logDetailedError("DownloadVideo", url, formatID, waitErr)
-			emitDownloadEvent(a.ctx, "download-error", fmt.Sprintf("Download failed: %v", waitErr))
-		} else {
-			wailsRuntime.LogInfof(a.ctx, "Download completed successfully for URL: %s, Format: %s", url, formatID)
-
-			// Wait longer for yt-dlp to finish all operations (renaming, merging, etc.)
-			time.Sleep(2 * time.Second)
-
-			// Check if the download is actually complete (no .part files)
-			// Extract title from outputPath for checking
-			outputPathWithoutExt := strings.TrimSuffix(outputPath, ".%(ext)s")
-			sanitizedTitle := filepath.Base(outputPathWithoutExt)
-
-			// Check for .part files (including fragment files)
-			downloadsDir := a.getDownloadDirectoryInternal()
-			partExtensions := []string{".mp4.part", ".webm.part", ".mkv.part", ".avi.part", ".mov.part", ".flv.part", ".m4v.part"}
-			hasPartFile := false
-
-			for _, ext := range partExtensions {
-				partPath := filepath.Join(downloadsDir, sanitizedTitle+ext)
-				if _, err := os.Stat(partPath); err == nil {
-					hasPartFile = true
-					wailsRuntime.LogWarningf(a.ctx, "Found .part file after download completion: %s", partPath)
-					break
-				}
-
-				// Check for fragment files (e.g., .part-Frag21.part)
-				fragmentPattern := filepath.Join(downloadsDir, sanitizedTitle+ext+"-*")
-				if matches, _ := filepath.Glob(fragmentPattern); len(matches) > 0 {
-					hasPartFile = true
-					wailsRuntime.LogWarningf(a.ctx, "Found fragment files after download completion: %v", matches)
-					break
-				}
-			}
-
-			// Check for .ytdl files
-			ytdlPath := filepath.Join(downloadsDir, sanitizedTitle+".ytdl")
-			hasYtdlFile := false
-			if _, err := os.Stat(ytdlPath); err == nil {
-				hasYtdlFile = true
-				wailsRuntime.LogWarningf(a.ctx, "Found .ytdl file after download completion: %s", ytdlPath)
-			}
-
-			// Additional check: try to find the actual completed file
-			completedFileFound := false
-			extensions := []string{".mp4", ".webm", ".mkv", ".avi", ".mov", ".flv", ".m4v"}
-
-			for _, ext := range extensions {
-				potentialPath := filepath.Join(downloadsDir, sanitizedTitle+ext)
-				if fileInfo, err := os.Stat(potentialPath); err == nil {
-					// Check if file is not empty and was modified recently (indicating it's complete)
-					if fileInfo.Size() > 0 && time.Since(fileInfo.ModTime()) < 10*time.Second {
-						completedFileFound = true
-						wailsRuntime.LogInfof(a.ctx, "Found completed file: %s (size: %d bytes)", potentialPath, fileInfo.Size())
-						break
-					}
-				}
-			}
-
-			// Only emit download-complete if no .part or .ytdl files exist AND we found a completed file
-			if !hasPartFile && !hasYtdlFile && completedFileFound {
-				// Ensure we emit 100% progress when download completes
-				progressTracker.mu.Lock()
-				if progressTracker.previousProgress != 100 {
-					wailsRuntime.EventsEmit(a.ctx, "download-progress", map[string]interface{}{
-						"progress": 100,
-						"size":     "Complete",
-						"speed":    "0",
-						"eta":      "00:00",
-					})
-				}
-				progressTracker.mu.Unlock()
-				emitDownloadEvent(a.ctx, "download-complete")
-			} else if hasPartFile || hasYtdlFile {
-				// Download is incomplete, emit error
-				wailsRuntime.LogErrorf(a.ctx, "Download incomplete: .part or .ytdl files still present")
-				emitDownloadEvent(a.ctx, "download-error", "Download incomplete: File is still being processed")
-			} else {
-				// No completed file found, wait a bit more and retry
-				wailsRuntime.LogWarningf(a.ctx, "No completed file found after download, waiting additional time...")
-				time.Sleep(3 * time.Second)
-
-				// Retry check for completed file
-				for _, ext := range extensions {
-					potentialPath := filepath.Join(downloadsDir, sanitizedTitle+ext)
-					if fileInfo, err := os.Stat(potentialPath); err == nil && fileInfo.Size() > 0 {
-						completedFileFound = true
-						wailsRuntime.LogInfof(a.ctx, "Found completed file on retry: %s (size: %d bytes)", potentialPath, fileInfo.Size())
-						break
-					}
-				}
-
-				if completedFileFound {
-					progressTracker.mu.Lock()
-					if progressTracker.previousProgress != 100 {
-						wailsRuntime.EventsEmit(a.ctx, "download-progress", map[string]interface{}{
-							"progress": 100,
-							"size":     "Complete",
-							"speed":    "0",
-							"eta":      "00:00",
-						})
-					}
-					progressTracker.mu.Unlock()
-					emitDownloadEvent(a.ctx, "download-complete")
-				} else {
-					wailsRuntime.LogErrorf(a.ctx, "Download failed: No completed file found after extended wait")
-					emitDownloadEvent(a.ctx, "download-error", "Download failed: No completed file found")
-				}
-			}
+		readers.Wait()
+		waitErr := withStderrError(cmdWithoutCookies.Wait(), lastError)
+		if !a.jobCommandActive(job, cmdWithoutCookies) {
+			return // Job was stopped by the user
 		}
+		a.finishVideoDownload(job, progress, waitErr, "(retry)")
 	}()
-
-	return nil
 }
 
-// cancelDownloadInternal cancels the current download gracefully
-func (a *App) cancelDownloadInternal() error {
-	return a.stopDownloadInternal("cancel")
+// scanDownloadStderr passes yt-dlp's stderr to the progress reporter and returns
+// the last error line. onCookiesError is called once with the output so far when
+// a cookies-related error shows up, after which the remaining lines are only drained.
+func (a *App) scanDownloadStderr(stderr io.Reader, progress *progressReporter, onCookiesError func(stderrStr string)) string {
+	var stderrContent strings.Builder
+	lastError := ""
+	retried := false
+
+	err := scanOutputLines(stderr, func(line string) {
+		if retried || progress.handleLine(line) {
+			return
+		}
+		stderrContent.WriteString(line + "\n")
+		if strings.HasPrefix(strings.TrimSpace(line), "ERROR:") {
+			lastError = strings.TrimSpace(line)
+		}
+
+		// Check for cookies-related errors
+		if onCookiesError != nil && isCookiesRelatedError(stderrContent.String()) {
+			retried = true
+			onCookiesError(stderrContent.String())
+		}
+	})
+	if err != nil {
+		a.logger.Errorf("Error reading stderr: %v", err)
+	}
+	return lastError
 }
 
-// pauseDownloadInternal pauses the current download gracefully
-func (a *App) pauseDownloadInternal() error {
-	return a.stopDownloadInternal("pause")
+// withStderrError adds the last error yt-dlp printed to the error of a failed process
+func withStderrError(err error, lastError string) error {
+	if err == nil || lastError == "" {
+		return err
+	}
+	return fmt.Errorf("%w: %s", err, lastError)
 }
 
-func (a *App) stopDownloadInternal(reason string) error {
-	downloadMutex.Lock()
+// finishVideoDownload checks the result of a finished yt-dlp process and emits
+// the terminal event for the job. attempt is appended to log messages, e.g. "(retry)".
+func (a *App) finishVideoDownload(job *DownloadJob, progress *progressReporter, waitErr error, attempt string) {
+	logSuffix := ""
+	if attempt != "" {
+		logSuffix = " " + attempt
+	}
 
-	if currentDownloadCmd != nil {
-		downloadStopReason = reason
+	if waitErr != nil {
+		a.logger.Errorf("Download failed%s: %v", logSuffix, waitErr)
+		a.logDetailedError("DownloadVideo", job.URL, job.FormatID, waitErr)
+		a.emitDownloadEvent(job, "download-error", map[string]interface{}{
+			"error": fmt.Sprintf("Download failed%s: %v", logSuffix, waitErr),
+		})
+		return
+	}
 
-		// Signal cancellation through channel first
-		if downloadCancelChan != nil {
-			close(downloadCancelChan)
-			downloadCancelChan = nil
-		}
+	// yt-dlp prints the final path once the file has been merged and moved into place
+	files := progress.outputFiles()
+	if len(files) == 0 && progress.archivedCount() > 0 {
+		a.logger.Infof("Download skipped%s, %s is already in the download archive", logSuffix, job.URL)
+		progress.finish()
+		a.emitDownloadEvent(job, "download-complete", map[string]interface{}{
+			"already_downloaded": true,
+		})
+		return
+	}
+	if len(files) == 0 {
+		a.logger.Errorf("Download finished%s but yt-dlp did not report an output file", logSuffix)
+		a.emitDownloadEvent(job, "download-error", map[string]interface{}{
+			"error": "Download failed: yt-dlp did not report an output file",
+		})
+		return
+	}
 
-		// Then try to kill the process if it's still running
-		if currentDownloadCmd.Process != nil {
-			wailsRuntime.LogInfof(a.ctx, "Cancelling download...")
-			err := currentDownloadCmd.Process.Kill()
-			if err != nil {
-				wailsRuntime.LogErrorf(a.ctx, "Failed to cancel download: %v", err)
-				downloadMutex.Unlock()
-				return fmt.Errorf("failed to cancel download: %w", err)
-			}
-		}
-		currentDownloadCmd = nil
-		wailsRuntime.LogInfof(a.ctx, "Download stopped successfully, reason: %s", reason)
-		emitDownloadEvent(a.ctx, "download-cancelled", map[string]string{"reason": reason})
-		downloadMutex.Unlock()
-		return nil
+	filePath := files[len(files)-1]
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		a.logger.Errorf("Downloaded file not found%s: %v", logSuffix, err)
+		a.emitDownloadEvent(job, "download-error", map[string]interface{}{
+			"error": fmt.Sprintf("Download failed: output file not found: %s", filePath),
+		})
+		return
 	}
 
-	downloadMutex.Unlock()
-	wailsRuntime.LogInfof(a.ctx, "No active download to cancel")
-	return fmt.Errorf("no active download to cancel")
+	a.setJobFilePath(job, filePath)
+	a.logger.Infof("Download completed successfully%s: %s (size: %d bytes)", logSuffix, job.FilePath, fileInfo.Size())
+
+	// Ensure we emit 100% progress when download completes
+	progress.finish()
+	a.emitDownloadEvent(job, "download-complete", map[string]interface{}{
+		"file_path": job.FilePath,
+	})
 }
 
-// getDownloadPathInternal returns a suggested download path
-func (a *App) getDownloadPathInternal(title string) string {
-	// Check cache first
-	if cachedPath, exists := downloadCache[title]; exists {
-		return cachedPath
-	}
+// cancelDownloadInternal cancels all running downloads gracefully
+func (a *App) cancelDownloadInternal() error {
+	return a.stopAllJobsInternal("cancel")
+}
 
-	// Sanitize title for filename
-	sanitizedTitle := strings.ReplaceAll(title, " ", "_")
-	sanitizedTitle = strings.ReplaceAll(sanitizedTitle, "/", "_")
-	sanitizedTitle = strings.ReplaceAll(sanitizedTitle, "\\", "_")
-	sanitizedTitle = strings.ReplaceAll(sanitizedTitle, ":", "_")
-	sanitizedTitle = strings.ReplaceAll(sanitizedTitle, "*", "_")
-	sanitizedTitle = strings.ReplaceAll(sanitizedTitle, "?", "_")
-	sanitizedTitle = strings.ReplaceAll(sanitizedTitle, "\"", "_")
-	sanitizedTitle = strings.ReplaceAll(sanitizedTitle, "<", "_")
-	sanitizedTitle = strings.ReplaceAll(sanitizedTitle, ">", "_")
-	sanitizedTitle = strings.ReplaceAll(sanitizedTitle, "|", "_")
+// pauseDownloadInternal pauses all running downloads gracefully
+func (a *App) pauseDownloadInternal() error {
+	return a.stopAllJobsInternal("pause")
+}
 
+// getDownloadPathInternal returns the output template for a new download,
+// inside the download directory. yt-dlp fills in the fields and sanitizes them.
+func (a *App) getDownloadPathInternal(playlist bool) string {
 	// Create downloads directory if it doesn't exist
 	os.MkdirAll(defaultDownloadDir, 0755)
 
-	path := filepath.Join(defaultDownloadDir, sanitizedTitle+".%(ext)s")
+	return filepath.Join(defaultDownloadDir, a.currentSettings().outputTemplate(playlist))
+}
 
-	// Cache the path
-	downloadCache[title] = path
+// previewOutputTemplateInternal renders an output template against the JSON
+// returned by AnalyzeURL. An empty template previews the configured one.
+func (a *App) previewOutputTemplateInternal(template, videoInfoJSON string) (string, error) {
+	settings := a.currentSettings()
+	if template == "" {
+		template = settings.outputTemplate(false)
+	}
+	if err := validateOutputTemplate(template); err != nil {
+		return "", err
+	}
+
+	var info map[string]interface{}
+	if err := json.Unmarshal([]byte(videoInfoJSON), &info); err != nil {
+		return "", fmt.Errorf("failed to parse video info: %w", err)
+	}
 
-	return path
+	rendered := renderOutputTemplate(template, info, settings.RestrictFilenames, settings.WindowsFilenames)
+	return filepath.Join(defaultDownloadDir, filepath.FromSlash(rendered)), nil
 }
 
 // selectDownloadDirectoryInternal opens a dialog to select the download directory
@@ -815,7 +356,7 @@ func (a *App) selectDownloadDirectoryInternal() (string, error) {
 
 	// Update the default download directory
 	defaultDownloadDir = selectedPath
-	wailsRuntime.LogInfof(a.ctx, "Download directory changed to: %s", selectedPath)
+	a.logger.Infof("Download directory changed to: %s", selectedPath)
 
 	return selectedPath, nil
 }
@@ -840,7 +381,7 @@ func (a *App) setDownloadDirectoryInternal(path string) error {
 	}
 
 	defaultDownloadDir = path
-	wailsRuntime.LogInfof(a.ctx, "Download directory set to: %s", path)
+	a.logger.Infof("Download directory set to: %s", path)
 	return nil
 }
 
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// EventSink receives the events the backend emits for its front end
+type EventSink interface {
+	Emit(name string, data ...interface{})
+}
+
+// Logger receives the backend's log messages
+type Logger interface {
+	Info(message string)
+	Infof(format string, args ...interface{})
+	Warningf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// wailsSink forwards events and log messages to the Wails runtime
+type wailsSink struct {
+	ctx context.Context
+}
+
+// Emit sends an event to the Wails front end
+func (s wailsSink) Emit(name string, data ...interface{}) {
+	wailsRuntime.EventsEmit(s.ctx, name, data...)
+}
+
+// Info logs a message through the Wails logger
+func (s wailsSink) Info(message string) {
+	wailsRuntime.LogInfo(s.ctx, message)
+}
+
+// Infof logs a formatted message through the Wails logger
+func (s wailsSink) Infof(format string, args ...interface{}) {
+	wailsRuntime.LogInfof(s.ctx, format, args...)
+}
+
+// Warningf logs a formatted warning through the Wails logger
+func (s wailsSink) Warningf(format string, args ...interface{}) {
+	wailsRuntime.LogWarningf(s.ctx, format, args...)
+}
+
+// Errorf logs a formatted error through the Wails logger
+func (s wailsSink) Errorf(format string, args ...interface{}) {
+	wailsRuntime.LogErrorf(s.ctx, format, args...)
+}
+
+// nopSink drops all events and log messages
+type nopSink struct{}
+
+// Emit drops the event
+func (nopSink) Emit(name string, data ...interface{}) {}
+
+// Info drops the message
+func (nopSink) Info(message string) {}
+
+// Infof drops the message
+func (nopSink) Infof(format string, args ...interface{}) {}
+
+// Warningf drops the message
+func (nopSink) Warningf(format string, args ...interface{}) {}
+
+// Errorf drops the message
+func (nopSink) Errorf(format string, args ...interface{}) {}
+
+// multiEventSink sends every event to several sinks
+type multiEventSink []EventSink
+
+// Emit sends the event to every sink in order
+func (m multiEventSink) Emit(name string, data ...interface{}) {
+	for _, sink := range m {
+		sink.Emit(name, data...)
+	}
+}
+
+// multiLogger sends every log message to several loggers
+type multiLogger []Logger
+
+// Info logs the message with every logger
+func (m multiLogger) Info(message string) {
+	for _, logger := range m {
+		logger.Info(message)
+	}
+}
+
+// Infof logs the message with every logger
+func (m multiLogger) Infof(format string, args ...interface{}) {
+	for _, logger := range m {
+		logger.Infof(format, args...)
+	}
+}
+
+// Warningf logs the warning with every logger
+func (m multiLogger) Warningf(format string, args ...interface{}) {
+	for _, logger := range m {
+		logger.Warningf(format, args...)
+	}
+}
+
+// Errorf logs the error with every logger
+func (m multiLogger) Errorf(format string, args ...interface{}) {
+	for _, logger := range m {
+		logger.Errorf(format, args...)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordedEvent is an event captured by a recordingSink
+type recordedEvent struct {
+	Name string
+	Data []interface{}
+}
+
+// recordingSink keeps every event and log message in memory so tests can
+// assert on what the app reported
+type recordingSink struct {
+	mu     sync.Mutex
+	events []recordedEvent
+	logs   []string
+}
+
+func (r *recordingSink) Emit(name string, data ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, recordedEvent{Name: name, Data: data})
+}
+
+func (r *recordingSink) Info(message string) { r.log("INF " + message) }
+
+func (r *recordingSink) Infof(format string, args ...interface{}) {
+	r.log("INF " + fmt.Sprintf(format, args...))
+}
+
+func (r *recordingSink) Warningf(format string, args ...interface{}) {
+	r.log("WRN " + fmt.Sprintf(format, args...))
+}
+
+func (r *recordingSink) Errorf(format string, args ...interface{}) {
+	r.log("ERR " + fmt.Sprintf(format, args...))
+}
+
+func (r *recordingSink) log(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logs = append(r.logs, line)
+}
+
+// Events returns a copy of the recorded events
+func (r *recordingSink) Events() []recordedEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]recordedEvent(nil), r.events...)
+}
+
+// Names returns the names of the recorded events in order
+func (r *recordingSink) Names() []string {
+	var names []string
+	for _, event := range r.Events() {
+		names = append(names, event.Name)
+	}
+	return names
+}
+
+// waitFor waits until an event with the given name was recorded
+func (r *recordingSink) waitFor(t *testing.T, name string) recordedEvent {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, event := range r.Events() {
+			if event.Name == name {
+				return event
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %q, got %v", name, r.Names())
+	return recordedEvent{}
+}
+
+// newTestApp creates an App that records its events and keeps all state in
+// a temporary directory
+func newTestApp(t *testing.T) (*App, *recordingSink) {
+	t.Helper()
+	sink := &recordingSink{}
+	app := &App{
+		ctx:     context.Background(),
+		events:  sink,
+		logger:  sink,
+		queue:   newDownloadQueue(),
+		history: newHistoryStore(filepath.Join(t.TempDir(), "history.json")),
+
+		conversions:   newConversionQueue(),
+		subscriptions: newSubscriptionStore(filepath.Join(t.TempDir(), "subscriptions.json")),
+	}
+	return app, sink
+}
+
+func TestMultiSinksFanOut(t *testing.T) {
+	first, second := &recordingSink{}, &recordingSink{}
+	events := multiEventSink{first, second}
+	logger := multiLogger{first, second}
+
+	events.Emit("setup-started")
+	events.Emit("conversion-progress", map[string]interface{}{"progress": 10})
+	logger.Warningf("disk %s", "full")
+
+	for _, sink := range []*recordingSink{first, second} {
+		if got := sink.Names(); !reflect.DeepEqual(got, []string{"setup-started", "conversion-progress"}) {
+			t.Errorf("events = %v", got)
+		}
+		if !reflect.DeepEqual(sink.logs, []string{"WRN disk full"}) {
+			t.Errorf("logs = %v", sink.logs)
+		}
+	}
+}
+
+func TestVideoDownloadEventSequence(t *testing.T) {
+	app, sink := newTestApp(t)
+	output := filepath.Join(t.TempDir(), "Clip.m4a")
+	if err := os.WriteFile(output, []byte("audio"), 0644); err != nil {
+		t.Fatalf("failed to create output file: %v", err)
+	}
+
+	job := &DownloadJob{ID: "job", URL: "https://example.com/v", Status: JobStatusRunning}
+	app.queue.jobs[job.ID] = job
+	app.queue.order = append(app.queue.order, job.ID)
+	app.queue.running = 1
+
+	progress := newProgressReporter(app, job)
+	for _, line := range []string{
+		"[youtube] abc: Downloading webpage",
+		"[godlp-title] Clip",
+		`[godlp-progress] {"progress":{"status":"downloading","downloaded_bytes":512,"total_bytes":1024,"speed":256,"eta":2},"playlist_index":null,"playlist_count":null}`,
+		`[godlp-progress] {"progress":{"status":"finished","downloaded_bytes":1024,"total_bytes":1024},"playlist_index":null,"playlist_count":null}`,
+		`[godlp-progress] {"progress":{"status":"started","postprocessor":"ExtractAudio"},"playlist_index":null,"playlist_count":null}`,
+		`[godlp-progress] {"progress":{"status":"started","postprocessor":"ExtractAudio"},"playlist_index":null,"playlist_count":null}`,
+		`[godlp-progress] {"progress":{"status":"finished","postprocessor":"ExtractAudio"},"playlist_index":null,"playlist_count":null}`,
+		"[godlp-filepath] " + output,
+	} {
+		progress.handleLine(line)
+	}
+	app.finishVideoDownload(job, progress, nil, "")
+
+	events := sink.Events()
+	expectedNames := []string{"download-progress", "download-progress", "download-progress", "download-progress", "download-complete"}
+	if got := sink.Names(); !reflect.DeepEqual(got, expectedNames) {
+		t.Fatalf("events = %v, want %v", got, expectedNames)
+	}
+
+	expected := []map[string]interface{}{
+		{"id": "job", "progress": 50, "phase": progressPhaseDownload},
+		{"id": "job", "progress": 100, "phase": progressPhaseDownload},
+		{"id": "job", "progress": 100, "phase": progressPhasePostprocess, "status": "started"},
+		{"id": "job", "progress": 100, "phase": progressPhasePostprocess, "status": "finished"},
+		{"id": "job", "file_path": output},
+	}
+	for i, want := range expected {
+		data := events[i].Data[0].(map[string]interface{})
+		for key, value := range want {
+			if data[key] != value {
+				t.Errorf("event %d (%s): %s = %v, want %v", i, events[i].Name, key, data[key], value)
+			}
+		}
+	}
+
+	if job.Status != JobStatusCompleted || job.Title != "Clip" || app.queue.running != 0 {
+		t.Errorf("unexpected job state: status=%s title=%q running=%d", job.Status, job.Title, app.queue.running)
+	}
+}
+
+func TestVideoDownloadWithoutOutputFileFails(t *testing.T) {
+	app, sink := newTestApp(t)
+	job := &DownloadJob{ID: "job", Status: JobStatusRunning}
+	app.queue.jobs[job.ID] = job
+	app.queue.order = append(app.queue.order, job.ID)
+	app.queue.running = 1
+
+	app.finishVideoDownload(job, newProgressReporter(app, job), nil, "")
+
+	event := sink.waitFor(t, "download-error")
+	if got := event.Data[0].(map[string]interface{})["error"]; got != "Download failed: yt-dlp did not report an output file" {
+		t.Errorf("error = %v", got)
+	}
+	if job.Status != JobStatusFailed {
+		t.Errorf("job status = %s, want failed", job.Status)
+	}
+}
+
+func TestDownloadVideoInternalRejectsInvalidOptions(t *testing.T) {
+	app, sink := newTestApp(t)
+
+	if _, err := app.downloadVideoInternal("https://example.com/v", "best", "out.%(ext)s", `{"audio":{"codec":"xyz"}}`); err == nil {
+		t.Fatal("expected an error for an unsupported codec")
+	}
+	if names := sink.Names(); len(names) != 0 || len(app.queue.order) != 0 {
+		t.Errorf("nothing should be queued or emitted, got events %v and %d jobs", names, len(app.queue.order))
+	}
+}
+
+func TestConvertVideoInternalMissingSource(t *testing.T) {
+	app, sink := newTestApp(t)
+
+	if _, err := app.convertVideoInternal(filepath.Join(t.TempDir(), "missing.mp4"), "mp3"); err == nil {
+		t.Fatal("expected an error for a missing source file")
+	}
+	if names := sink.Names(); len(names) != 0 {
+		t.Errorf("no events expected, got %v", names)
+	}
+}
+
+func TestSetupDependenciesWithExistingYtDlp(t *testing.T) {
+	t.Chdir(t.TempDir())
+	app, sink := newTestApp(t)
+	if err := os.MkdirAll("bin", 0755); err != nil {
+		t.Fatalf("failed to create bin directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join("bin", app.getYtDlpBinaryName()), []byte{}, 0755); err != nil {
+		t.Fatalf("failed to create yt-dlp stand-in: %v", err)
+	}
+
+	app.SetupDependencies()
+	sink.waitFor(t, "setup-complete")
+
+	names := sink.Names()
+	if names[0] != "setup-started" || names[len(names)-1] != "setup-complete" {
+		t.Errorf("events = %v, want setup-started first and setup-complete last", names)
+	}
+	for _, name := range names {
+		if name == "setup-error" || name == "setup-progress" {
+			t.Errorf("existing yt-dlp should not be downloaded again, got %v", names)
+		}
+	}
+}
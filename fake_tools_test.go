@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeToolsEnv names the directory holding the scenarios of the fake tools.
+// When it is set, the test binary acts as the tool it was started as.
+const fakeToolsEnv = "GODLP_FAKE_TOOLS"
+
+// fakeRun is one recorded run of an external tool
+type fakeRun struct {
+	When     []string          `json:"when,omitempty"`   // Arguments the invocation must contain
+	Unless   []string          `json:"unless,omitempty"` // Arguments the invocation must not contain
+	Stdout   []string          `json:"stdout,omitempty"`
+	Stderr   []string          `json:"stderr,omitempty"`
+	Files    map[string]string `json:"files,omitempty"` // Files to write, relative to the working directory
+	ExitCode int               `json:"exit_code,omitempty"`
+	Hang     bool              `json:"hang,omitempty"` // Keep running until killed
+}
+
+// fakeScenario lists the runs a fake tool replays, first match wins
+type fakeScenario struct {
+	Runs []fakeRun `json:"runs"`
+}
+
+func TestMain(m *testing.M) {
+	if dir := os.Getenv(fakeToolsEnv); dir != "" {
+		os.Exit(runFakeTool(dir, os.Args[0], os.Args[1:], os.Stdout, os.Stderr))
+	}
+	os.Exit(m.Run())
+}
+
+// runFakeTool replays the scenario recorded for the tool named by argv0
+func runFakeTool(dir, argv0 string, args []string, stdout, stderr io.Writer) int {
+	name := strings.TrimSuffix(filepath.Base(argv0), ".exe")
+
+	data, err := os.ReadFile(filepath.Join(dir, name+".json"))
+	if err != nil {
+		fmt.Fprintf(stderr, "fake %s: %v\n", name, err)
+		return 127
+	}
+	var scenario fakeScenario
+	if err := json.Unmarshal(data, &scenario); err != nil {
+		fmt.Fprintf(stderr, "fake %s: %v\n", name, err)
+		return 127
+	}
+
+	if err := appendFakeCall(filepath.Join(dir, name+".calls"), args); err != nil {
+		fmt.Fprintf(stderr, "fake %s: %v\n", name, err)
+		return 127
+	}
+
+	for _, run := range scenario.Runs {
+		if !run.matches(args) {
+			continue
+		}
+		for path, content := range run.Files {
+			os.MkdirAll(filepath.Dir(path), 0755)
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				fmt.Fprintf(stderr, "fake %s: %v\n", name, err)
+				return 127
+			}
+		}
+		for _, line := range run.Stdout {
+			fmt.Fprintln(stdout, line)
+		}
+		for _, line := range run.Stderr {
+			fmt.Fprintln(stderr, line)
+		}
+		if run.Hang {
+			time.Sleep(time.Minute)
+		}
+		return run.ExitCode
+	}
+
+	fmt.Fprintf(stderr, "fake %s: no run matches %q\n", name, args)
+	return 127
+}
+
+// matches reports whether the run was recorded for these arguments
+func (r fakeRun) matches(args []string) bool {
+	for _, arg := range r.When {
+		if !slices.Contains(args, arg) {
+			return false
+		}
+	}
+	for _, arg := range r.Unless {
+		if slices.Contains(args, arg) {
+			return false
+		}
+	}
+	return true
+}
+
+// appendFakeCall records the arguments of an invocation as a JSON line
+func appendFakeCall(path string, args []string) error {
+	line, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// fakeToolHarness installs scripted stand-ins for yt-dlp and ffmpeg in the
+// ./bin directory of a temporary working directory
+type fakeToolHarness struct {
+	t   *testing.T
+	bin string
+}
+
+// newFakeToolHarness changes into a temporary directory with an empty ./bin
+func newFakeToolHarness(t *testing.T) *fakeToolHarness {
+	t.Helper()
+	t.Chdir(t.TempDir())
+
+	bin, err := filepath.Abs("bin")
+	if err != nil {
+		t.Fatalf("failed to resolve bin directory: %v", err)
+	}
+	if err := os.MkdirAll(bin, 0755); err != nil {
+		t.Fatalf("failed to create bin directory: %v", err)
+	}
+	t.Setenv(fakeToolsEnv, bin)
+	return &fakeToolHarness{t: t, bin: bin}
+}
+
+// install puts a fake tool named name into ./bin that replays runs
+func (h *fakeToolHarness) install(name string, runs ...fakeRun) {
+	h.t.Helper()
+
+	executable, err := os.Executable()
+	if err != nil {
+		h.t.Fatalf("failed to locate test binary: %v", err)
+	}
+	path := filepath.Join(h.bin, name)
+	if err := os.Symlink(executable, path); err != nil {
+		data, err := os.ReadFile(executable)
+		if err != nil {
+			h.t.Fatalf("failed to read test binary: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0755); err != nil {
+			h.t.Fatalf("failed to install fake %s: %v", name, err)
+		}
+	}
+
+	data, err := json.Marshal(fakeScenario{Runs: runs})
+	if err != nil {
+		h.t.Fatalf("failed to encode scenario: %v", err)
+	}
+	scenario := filepath.Join(h.bin, strings.TrimSuffix(name, ".exe")+".json")
+	if err := os.WriteFile(scenario, data, 0644); err != nil {
+		h.t.Fatalf("failed to write scenario: %v", err)
+	}
+}
+
+// calls returns the arguments of every invocation of the fake tool
+func (h *fakeToolHarness) calls(name string) [][]string {
+	h.t.Helper()
+
+	f, err := os.Open(filepath.Join(h.bin, strings.TrimSuffix(name, ".exe")+".calls"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		h.t.Fatalf("failed to read calls: %v", err)
+	}
+	defer f.Close()
+
+	var calls [][]string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var args []string
+		if err := json.Unmarshal(scanner.Bytes(), &args); err != nil {
+			h.t.Fatalf("failed to decode call: %v", err)
+		}
+		calls = append(calls, args)
+	}
+	return calls
+}
+
+func TestFakeRunMatches(t *testing.T) {
+	tests := []struct {
+		name string
+		run  fakeRun
+		args []string
+		want bool
+	}{
+		{"no constraints", fakeRun{}, []string{"--simulate"}, true},
+		{"required present", fakeRun{When: []string{"--simulate"}}, []string{"--print-json", "--simulate"}, true},
+		{"required missing", fakeRun{When: []string{"--dump-single-json"}}, []string{"--simulate"}, false},
+		{"excluded present", fakeRun{Unless: []string{"--no-warnings"}}, []string{"--no-warnings"}, false},
+		{"excluded missing", fakeRun{Unless: []string{"--no-warnings"}}, []string{"--simulate"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.run.matches(tt.args); got != tt.want {
+				t.Errorf("matches(%v) = %v, want %v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
@@ -384,9 +384,7 @@ const App: React.FC = () => {
               <ConversionScreen
                 sourceFile={downloadPath!}
                 onBack={() => setCurrentStep('completion')}
-                onConvert={async (targetFormat: string) => {
-                  await apiService.convertVideo(downloadPath!, targetFormat);
-                }}
+                onConvert={(targetFormat: string) => apiService.convertVideo(downloadPath!, targetFormat)}
               />
             </Box>
           </Fade>
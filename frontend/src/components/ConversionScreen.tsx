@@ -1,4 +1,4 @@
-import React, { useState, useEffect } from 'react';
+import React, { useState, useEffect, useRef } from 'react';
 import { Box, Card, Typography, Button, Grid, Paper, Avatar, LinearProgress, MenuItem, Select, SelectChangeEvent, FormControl, InputLabel, Divider, alpha } from '@mui/material';
 import {
   Transform as TransformIcon,
@@ -10,12 +10,13 @@ import {
 } from '@mui/icons-material';
 import { useLanguage } from '../i18n/LanguageContext';
 import { useTheme } from '../contexts/ThemeContext';
-import { EventsOn, EventsOff } from '../../wailsjs/runtime/runtime';
+import { EventsOn } from '../../wailsjs/runtime/runtime';
+import { apiService, ConversionJob } from '../services/api';
 
 interface ConversionScreenProps {
   sourceFile: string;
   onBack: () => void;
-  onConvert: (targetFormat: string) => Promise<void>;
+  onConvert: (targetFormat: string) => Promise<string>; // Resolves to the conversion job ID
 }
 
 const ConversionScreen: React.FC<ConversionScreenProps> = ({
@@ -30,8 +31,11 @@ const ConversionScreen: React.FC<ConversionScreenProps> = ({
   const [targetFormat, setTargetFormat] = useState<string>('mp4');
   const [isConverting, setIsConverting] = useState<boolean>(false);
   const [conversionProgress, setConversionProgress] = useState<number>(0);
+  const [conversionStats, setConversionStats] = useState<{ speed: number; fps: number; eta: string } | null>(null);
   const [conversionStatus, setConversionStatus] = useState<'idle' | 'converting' | 'success' | 'error'>('idle');
   const [errorMessage, setErrorMessage] = useState<string>('');
+  // Other conversions run in parallel, so only events of this job are shown
+  const jobIdRef = useRef<string | null>(null);
 
   const videoFormats = [
     { value: 'mp4', label: 'MP4', description: 'Universal format' },
@@ -66,12 +70,19 @@ const ConversionScreen: React.FC<ConversionScreenProps> = ({
 
     setIsConverting(true);
     setConversionProgress(0);
+    setConversionStats(null);
     setConversionStatus('converting');
     setErrorMessage('');
+    jobIdRef.current = null;
 
     try {
-      await onConvert(targetFormat);
-      // Progress will be handled by events
+      const jobId = await onConvert(targetFormat);
+      jobIdRef.current = jobId;
+      // Progress will be handled by events; a quick job may have finished already
+      const job = (await apiService.listConversionJobs()).find((j) => j.id === jobId);
+      if (job) {
+        showFinishedJob(job);
+      }
     } catch (error) {
       setConversionStatus('error');
       setErrorMessage(error instanceof Error ? error.message : t.conversionError);
@@ -79,6 +90,28 @@ const ConversionScreen: React.FC<ConversionScreenProps> = ({
     }
   };
 
+  // Shows the outcome of a job that finished before its ID was known
+  const showFinishedJob = (job: ConversionJob) => {
+    if (job.status === 'completed') {
+      setConversionProgress(100);
+      setConversionStatus('success');
+      setIsConverting(false);
+    } else if (job.status === 'failed' || job.status === 'cancelled') {
+      setConversionStatus('error');
+      setErrorMessage(job.status === 'failed' ? job.error ?? '' : 'Conversion cancelled');
+      setIsConverting(false);
+    }
+  };
+
+  const handleCancel = async () => {
+    if (!jobIdRef.current) return;
+    try {
+      await apiService.cancelConversionJob(jobIdRef.current);
+    } catch (error) {
+      console.error('Failed to cancel conversion:', error);
+    }
+  };
+
   const handleBack = () => {
     if (!isConverting) {
       onBack();
@@ -87,36 +120,42 @@ const ConversionScreen: React.FC<ConversionScreenProps> = ({
 
   // Listen for conversion events
   useEffect(() => {
-    EventsOn('conversion-progress', (data: any) => {
-      if (data && typeof data.progress === 'number') {
-        setConversionProgress(data.progress);
-      }
-    });
+    const isOwnJob = (data: any) => typeof data === 'object' && data !== null && data.id === jobIdRef.current;
 
-    EventsOn('conversion-complete', () => {
-      setConversionProgress(100);
-      setConversionStatus('success');
-      setIsConverting(false);
-    });
+    // Unsubscribe only these listeners, the app listens to the same events
+    const unsubscribers = [
+      EventsOn('conversion-progress', (data: any) => {
+        if (isOwnJob(data) && typeof data.progress === 'number') {
+          setConversionProgress(data.progress);
+          if (typeof data.speed === 'number') {
+            setConversionStats({ speed: data.speed, fps: data.fps, eta: data.eta });
+          }
+        }
+      }),
 
-    EventsOn('conversion-error', (error: string) => {
-      setConversionStatus('error');
-      setErrorMessage(error);
-      setIsConverting(false);
-    });
+      EventsOn('conversion-complete', (data: any) => {
+        if (!isOwnJob(data)) return;
+        setConversionProgress(100);
+        setConversionStatus('success');
+        setIsConverting(false);
+      }),
 
-    EventsOn('conversion-cancelled', () => {
-      setConversionStatus('error');
-      setErrorMessage('Conversion cancelled');
-      setIsConverting(false);
-    });
-
-    return () => {
-      EventsOff('conversion-progress');
-      EventsOff('conversion-complete');
-      EventsOff('conversion-error');
-      EventsOff('conversion-cancelled');
-    };
+      EventsOn('conversion-error', (data: any) => {
+        if (!isOwnJob(data)) return;
+        setConversionStatus('error');
+        setErrorMessage(String(data.error ?? ''));
+        setIsConverting(false);
+      }),
+
+      EventsOn('conversion-cancelled', (data: any) => {
+        if (!isOwnJob(data)) return;
+        setConversionStatus('error');
+        setErrorMessage('Conversion cancelled');
+        setIsConverting(false);
+      }),
+    ];
+
+    return () => unsubscribers.forEach((unsubscribe) => unsubscribe());
   }, []);
 
   // Get status icon and color
@@ -350,6 +389,11 @@ const ConversionScreen: React.FC<ConversionScreenProps> = ({
                 },
               }}
             />
+            {conversionStats && conversionStats.speed > 0 && (
+              <Typography variant="caption" color="text.secondary" sx={{ display: 'block', mt: 1 }}>
+                {conversionStats.speed.toFixed(1)}x{conversionStats.fps > 0 ? ` · ${conversionStats.fps.toFixed(0)} fps` : ''} · {t.timeLeft}: {conversionStats.eta}
+              </Typography>
+            )}
           </Box>
         )}
 
@@ -402,6 +446,25 @@ const ConversionScreen: React.FC<ConversionScreenProps> = ({
 
         {/* Action Buttons */}
         <Grid container spacing={2}>
+          {conversionStatus === 'converting' && (
+            <Grid size={{ xs: 12 }}>
+              <Button
+                variant="outlined"
+                color="error"
+                onClick={handleCancel}
+                fullWidth
+                sx={{
+                  py: 2,
+                  fontSize: '1rem',
+                  borderRadius: 3,
+                  borderWidth: 2,
+                  '&:hover': { borderWidth: 2 },
+                }}
+              >
+                {t.cancel}
+              </Button>
+            </Grid>
+          )}
           {conversionStatus === 'idle' && (
             <Grid size={{ xs: 12 }}>
               <Button
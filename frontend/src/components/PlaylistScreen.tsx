@@ -35,6 +35,12 @@ interface PlaylistEntry {
   thumbnail: string;
   url: string;
   duration: number;
+  uploader?: string;
+  upload_date?: string;
+  view_count?: number;
+  availability?: string;
+  unavailable?: boolean;
+  already_downloaded?: boolean;
 }
 
 interface PlaylistScreenProps {
@@ -301,6 +307,7 @@ const PlaylistScreen: React.FC<PlaylistScreenProps> = ({
                       <Box sx={{ display: 'flex', justifyContent: 'space-between', mt: 0.5 }}>
                         <Typography variant="caption" color="text.secondary">
                           {t.videoIndex}: {index + 1}
+                          {entry.already_downloaded && ` · ${t.alreadyDownloaded}`}
                         </Typography>
                         <Typography variant="caption" color="text.secondary">
                           {entry.duration > 0 ? formatDuration(entry.duration) : t.unknownDuration}
@@ -1,6 +1,6 @@
 // Хук для логики приложения
 
-import { useState, useEffect, useCallback } from 'react';
+import { useState, useEffect, useCallback, useRef } from 'react';
 import { apiService, subscribeToEvents } from '../services/api';
 import { UpdateSettingsWithCookiesFile, GetSettings as GetSettingsAPI, UpdateAutoRedirectToQueue } from '../../wailsjs/go/main/App';
 import { VideoInfo } from '../types';
@@ -14,6 +14,12 @@ interface PlaylistEntry {
   thumbnail: string;
   url: string;
   duration: number;
+  uploader?: string;
+  upload_date?: string;
+  view_count?: number;
+  availability?: string;
+  unavailable?: boolean;
+  already_downloaded?: boolean;
 }
 
 export const useAppLogic = () => {
@@ -83,6 +89,20 @@ export const useAppLogic = () => {
   // Состояние для уведомлений
   const [notification, setNotification] = useState<{type: 'success' | 'error', message: string} | null>(null);
 
+  // Job ID of the download started from the download screen; events of other
+  // jobs (subscriptions, the local API) must not move that screen
+  const directJobIdRef = useRef<string | null>(null);
+
+  // Finds which of the UI's downloads an event belongs to, or null for other jobs
+  const resolveDownloadEvent = (data: any): { queueItem?: QueueItem } | null => {
+    const jobId = data && typeof data === 'object' ? data.id : undefined;
+    if (!jobId) return null;
+    const queueItem = downloadQueueManager.getByJobId(jobId);
+    if (queueItem) return { queueItem };
+    if (jobId === directJobIdRef.current) return {};
+    return null;
+  };
+
   // Обработчики событий
   useEffect(() => {
     // Подписываемся на события от Go
@@ -113,7 +133,18 @@ export const useAppLogic = () => {
       }),
 
       subscribeToEvents('download-progress', (data: any) => {
+        const owner = resolveDownloadEvent(data);
+        if (!owner) return;
+
         if (typeof data === 'object') {
+          if (data.phase === 'postprocess') {
+            // Post-processing (merging, audio extraction) has no speed or ETA
+            setDownloadProgress(Number(data.progress || 0));
+            setDownloadSpeed('0');
+            setDownloadEta(data.postprocessor ? `${data.postprocessor}...` : 'Processing...');
+            return;
+          }
+
           const newProgress = Number(data.progress || 0);
           // [FIX] Убраны проверки if (data.size), теперь обновляем всегда, используя fallback
           const newSize = data.size || 'Calculating...';
@@ -126,12 +157,10 @@ export const useAppLogic = () => {
           setDownloadEta(newEta);
 
           // Обновляем прогресс в очереди загрузок
-          const activeDownloads = downloadQueueManager.getActiveDownloads();
-          if (activeDownloads.length > 0) {
-            const activeItem = activeDownloads[0];
+          if (owner.queueItem) {
             // [FIX] Передаем значения без undefined
             downloadQueueManager.updateProgress(
-              activeItem.id,
+              owner.queueItem.id,
               newProgress,
               newSpeed,
               newSize,
@@ -143,31 +172,18 @@ export const useAppLogic = () => {
         }
       }),
 
-      subscribeToEvents('download-complete', async () => {
-        const isDirectDownload = currentStep === 'download' || isDownloading;
-        const cancelingId = downloadQueueManager.getCancelingId();
-        const activeDownloads = downloadQueueManager.getActiveDownloads();
-        const activeQueueItem = cancelingId
-          ? downloadQueueManager.getById(cancelingId)
-          : (activeDownloads.length > 0 ? activeDownloads[0] : undefined);
+      subscribeToEvents('download-complete', async (data: any) => {
+        const owner = resolveDownloadEvent(data);
+        if (!owner) return;
+        const reportedFilePath: string | undefined = data.file_path;
+        const activeQueueItem = owner.queueItem;
 
-        setIsDownloading(false);
-        setDownloadSize('Complete');
-        setDownloadSpeed('0');
-        setDownloadEta('00:00');
-
-        if (!isDirectDownload && activeQueueItem) {
+        if (activeQueueItem) {
           downloadQueueManager.setStatus(activeQueueItem.id, 'completed');
-          downloadQueueManager.clearCancelingId();
-          downloadQueueManager.clearCancelIntent();
 
           try {
-            let completedOutputPath = activeQueueItem.outputPath;
-            try {
-              completedOutputPath = await apiService.getActualDownloadPath(activeQueueItem.title);
-            } catch (pathError) {
-              console.warn('Failed to resolve actual path for queue item, using template path:', pathError);
-            }
+            // Downloads skipped by the archive report no file; keep the template path
+            const completedOutputPath = reportedFilePath || activeQueueItem.outputPath;
 
             await downloadHistoryDB.addItem({
               url: activeQueueItem.url,
@@ -189,37 +205,29 @@ export const useAppLogic = () => {
 
           return;
         }
-        if (!isDirectDownload && !activeQueueItem) {
-          downloadQueueManager.clearCancelingId();
-          downloadQueueManager.clearCancelIntent();
+
+        directJobIdRef.current = null;
+        setIsDownloading(false);
+        setDownloadSize('Complete');
+        setDownloadSpeed('0');
+        setDownloadEta('00:00');
+
+        if (!reportedFilePath && data.already_downloaded) {
+          pendingDownloadsManager.clearPendingDownloads();
+          showSuccess('Already downloaded, skipped by the download archive');
+          setCurrentStep('input');
           return;
         }
 
         // Дополнительная проверка перед показом экрана завершения
         if (videoInfo) {
           try {
-            const actualPath = await apiService.getActualDownloadPath(videoInfo.title);
+            const actualPath = reportedFilePath;
 
             // Проверяем, существует ли файл и достаточно ли он большой
             if (actualPath && actualPath !== '') {
-              // Проверяем, не является ли путь ошибкой
-              if (actualPath.includes('download incomplete') || actualPath.includes('temporary files')) {
-                console.warn('Download appears to be incomplete, not showing completion screen');
-                // Показываем ошибку вместо экрана завершения
-                showError('Download appears to be incomplete. Please check the download status.');
-                setCurrentStep('selection');
-                return;
-              }
-
               setDownloadPath(actualPath);
 
-              // Удаляем из очереди вместо установки статуса completed
-              const activeDownloads = downloadQueueManager.getActiveDownloads();
-              if (activeDownloads.length > 0) {
-                const activeItem = activeDownloads[0];
-                downloadQueueManager.setStatus(activeItem.id, 'completed');
-              }
-
               // Очищаем pending downloads из localStorage только для прямой загрузки
               pendingDownloadsManager.clearPendingDownloads();
 
@@ -267,23 +275,14 @@ export const useAppLogic = () => {
         }
       }),
 
-      subscribeToEvents('download-error', async (error: string) => {
-        const isDirectDownload = currentStep === 'download' || isDownloading;
-        const cancelingId = downloadQueueManager.getCancelingId();
-        const activeDownloads = downloadQueueManager.getActiveDownloads();
-        const activeQueueItem = cancelingId
-          ? downloadQueueManager.getById(cancelingId)
-          : (activeDownloads.length > 0 ? activeDownloads[0] : undefined);
-
-        setIsDownloading(false);
-        setDownloadSize('Error');
-        setDownloadSpeed('0');
-        setDownloadEta('00:00');
+      subscribeToEvents('download-error', async (data: any) => {
+        const owner = resolveDownloadEvent(data);
+        if (!owner) return;
+        const error = String(data.error ?? '');
+        const activeQueueItem = owner.queueItem;
 
-        if (!isDirectDownload && activeQueueItem) {
+        if (activeQueueItem) {
           downloadQueueManager.setStatus(activeQueueItem.id, 'failed');
-          downloadQueueManager.clearCancelingId();
-          downloadQueueManager.clearCancelIntent();
 
           try {
             await downloadHistoryDB.addItem({
@@ -306,17 +305,12 @@ export const useAppLogic = () => {
 
           return;
         }
-        if (!isDirectDownload && !activeQueueItem) {
-          downloadQueueManager.clearCancelingId();
-          downloadQueueManager.clearCancelIntent();
-          return;
-        }
 
-        // Удаляем из очереди вместо установки статуса failed
-        if (activeDownloads.length > 0) {
-          const activeItem = activeDownloads[0];
-          downloadQueueManager.setStatus(activeItem.id, 'failed');
-        }
+        directJobIdRef.current = null;
+        setIsDownloading(false);
+        setDownloadSize('Error');
+        setDownloadSpeed('0');
+        setDownloadEta('00:00');
 
         // Очищаем pending downloads из localStorage
         pendingDownloadsManager.clearPendingDownloads();
@@ -352,31 +346,18 @@ export const useAppLogic = () => {
       }),
 
       subscribeToEvents('download-cancelled', async (data: any) => {
-        const isDirectDownload = currentStep === 'download' || isDownloading;
-        const cancelingId = downloadQueueManager.getCancelingId();
-        const cancelIntent = (typeof data === 'object' && data?.reason ? String(data.reason) : null) || downloadQueueManager.getCancelIntent();
-        const activeDownloads = downloadQueueManager.getActiveDownloads();
-        const activeQueueItem = cancelingId
-          ? downloadQueueManager.getById(cancelingId)
-          : (activeDownloads.length > 0 ? activeDownloads[0] : undefined);
+        const owner = resolveDownloadEvent(data);
+        if (!owner) return;
+        const activeQueueItem = owner.queueItem;
 
-        setIsDownloading(false);
-        setDownloadSize('Cancelled');
-        setDownloadSpeed('0');
-        setDownloadEta('00:00');
-
-        if (!isDirectDownload && activeQueueItem) {
-          if (cancelIntent === 'pause') {
+        if (activeQueueItem) {
+          if (data.reason === 'pause') {
             downloadQueueManager.setStatus(activeQueueItem.id, 'paused');
-            downloadQueueManager.clearCancelingId();
-            downloadQueueManager.clearCancelIntent();
             showSuccess('Download paused');
             return;
           }
 
           downloadQueueManager.setStatus(activeQueueItem.id, 'cancelled');
-          downloadQueueManager.clearCancelingId();
-          downloadQueueManager.clearCancelIntent();
 
           try {
             await downloadHistoryDB.addItem({
@@ -399,18 +380,12 @@ export const useAppLogic = () => {
 
           return;
         }
-        if (!isDirectDownload && !activeQueueItem) {
-          downloadQueueManager.clearCancelingId();
-          downloadQueueManager.clearCancelIntent();
-          return;
-        }
 
-        // Обновляем статус в очереди загрузок
-        if (activeDownloads.length > 0) {
-          const activeItem = activeDownloads[0];
-          downloadQueueManager.setStatus(activeItem.id, 'cancelled');
-        }
-        downloadQueueManager.clearCancelIntent();
+        directJobIdRef.current = null;
+        setIsDownloading(false);
+        setDownloadSize('Cancelled');
+        setDownloadSpeed('0');
+        setDownloadEta('00:00');
 
         // Очищаем pending downloads из localStorage
         pendingDownloadsManager.clearPendingDownloads();
@@ -523,7 +498,8 @@ export const useAppLogic = () => {
         }
       }),
 
-      subscribeToEvents('conversion-error', (error: string) => {
+      subscribeToEvents('conversion-error', (data: any) => {
+        const error: string = typeof data === 'object' && data !== null ? String(data.error ?? '') : String(data);
         setIsDownloading(false);
         showError(`Conversion Error: ${error}`);
       }),
@@ -586,6 +562,8 @@ export const useAppLogic = () => {
     setNotification({type: 'success', message});
   };
 
+  // History entries of downloads skipped by the archive hold an output template;
+  // the backend looks the title up in its download history
   const resolveActualOutputPath = async (path: string): Promise<string> => {
     if (!path || !path.includes('.%(ext)s')) {
       return path;
@@ -593,7 +571,7 @@ export const useAppLogic = () => {
 
     const templateName = path.split(/[\\/]/).pop() || '';
     const templateTitle = templateName.replace(/\.%\(ext\)s$/, '');
-    if (!templateTitle) {
+    if (!templateTitle || templateTitle.includes('%(')) {
       return path;
     }
 
@@ -1081,10 +1059,9 @@ export const useAppLogic = () => {
 
               try {
                 // Download the entire playlist
-                const playlistTitle = parsedPlaylistResult.title || 'playlist';
-                const outputPath = await apiService.getDownloadPath(`${playlistTitle}_%(title)s`);
-                await apiService.downloadPlaylist(url, bestFormat.format_id, outputPath, 1, 0); // 0 means no end limit
-                showSuccess('Playlist download completed successfully!');
+                const outputPath = await apiService.getDownloadPath(true);
+                directJobIdRef.current = await apiService.downloadPlaylist(url, bestFormat.format_id, outputPath, 1, 0); // 0 means no end limit
+                showSuccess('Playlist download started');
               } catch (error) {
                 console.error('Playlist download error:', error);
                 setIsDownloading(false);
@@ -1154,10 +1131,10 @@ export const useAppLogic = () => {
         setCurrentStep('download');
 
         try {
-          const outputPath = await apiService.getDownloadPath(parsedResult.title);
+          const outputPath = await apiService.getDownloadPath();
           setDownloadPath(outputPath);
-          await apiService.downloadVideo(url, bestFormat.format_id, outputPath);
-          showSuccess('Download completed successfully!');
+          directJobIdRef.current = await apiService.downloadVideo(url, bestFormat.format_id, outputPath);
+          showSuccess('Download started');
         } catch (error) {
           console.error('Download error:', error);
           setIsDownloading(false);
@@ -1188,7 +1165,7 @@ export const useAppLogic = () => {
     downloadQueueManager.addToQueue({
       url: url,
       formatID: selectedFormat,
-      outputPath: await apiService.getDownloadPath(videoInfo.title),
+      outputPath: await apiService.getDownloadPath(),
       title: videoInfo.title,
       priority: 'normal'
     });
@@ -1203,7 +1180,9 @@ export const useAppLogic = () => {
   // Функция для отмены загрузки
   const handleCancelDownload = async () => {
     try {
-      await apiService.cancelDownload();
+      if (directJobIdRef.current) {
+        await apiService.cancelJob(directJobIdRef.current);
+      }
       setIsDownloading(false);
       setCurrentStep('input');
       showSuccess('Download cancelled!');
@@ -1239,9 +1218,8 @@ export const useAppLogic = () => {
   // Функция для открытия файла в проводнике
   const handleOpenInExplorer = async (path?: string) => {
     try {
-      const sourcePath = path || downloadPath;
-      const actualPath = await resolveActualOutputPath(sourcePath);
-      await apiService.openInExplorer(actualPath);
+      // Template paths open their folder
+      await apiService.openInExplorer(path || downloadPath);
     } catch (error) {
       console.error('Error opening in explorer:', error);
       showError('Failed to open file location');
@@ -1453,7 +1431,7 @@ export const useAppLogic = () => {
           const queueTitle = entry.title && !/^URL\s+\d+$/i.test(entry.title)
             ? entry.title
             : `Video ${i + 1}`;
-          const outputPath = await apiService.getDownloadPath(queueTitle);
+          const outputPath = await apiService.getDownloadPath();
 
           downloadQueueManager.addToQueue({
             url: entry.url,
@@ -1483,7 +1461,7 @@ export const useAppLogic = () => {
           continue;
         }
 
-        const outputPath = await apiService.getDownloadPath(`${playlistInfo.title || 'playlist'} - ${playlistEntry.title}`);
+        const outputPath = await apiService.getDownloadPath();
 
         downloadQueueManager.addToQueue({
           url: resolvePlaylistEntryURL(playlistEntry),
@@ -129,6 +129,7 @@ export const ar: PartialTranslations = {
   selected: 'محدد',
   videoIndex: 'فيديو #',
   unknownDuration: 'مدة غير معروفة',
+  alreadyDownloaded: 'تم تنزيله مسبقًا',
   downloadSelected: 'تنزيل المحدد',
   downloadQueue: 'طابور التنزيل',
   queueDescription: 'إدارة التنزيلات الحالية والمجدولة',
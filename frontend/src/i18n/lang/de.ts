@@ -129,6 +129,7 @@ export const de: PartialTranslations = {
   selected: 'Ausgewählt',
   videoIndex: 'Video #',
   unknownDuration: 'Unbekannte Dauer',
+  alreadyDownloaded: 'Bereits heruntergeladen',
   downloadSelected: 'Auswahl herunterladen',
   downloadQueue: 'Download-Warteschlange',
   queueDescription: 'Aktuelle und geplante Downloads verwalten',
@@ -129,6 +129,7 @@ export const en: PartialTranslations = {
   selected: 'Selected',
   videoIndex: 'Video #',
   unknownDuration: 'Unknown duration',
+  alreadyDownloaded: 'Already downloaded',
   downloadSelected: 'Download selected',
   downloadQueue: 'Download Queue',
   queueDescription: 'Manage current and planned downloads',
@@ -129,6 +129,7 @@ export const es: PartialTranslations = {
   selected: 'Seleccionados',
   videoIndex: 'Vídeo #',
   unknownDuration: 'Duración desconocida',
+  alreadyDownloaded: 'Ya descargado',
   downloadSelected: 'Descargar seleccionados',
   downloadQueue: 'Cola de Descargas',
   queueDescription: 'Gestionar descargas actuales y planificadas',
@@ -129,6 +129,7 @@ export const fr: PartialTranslations = {
   selected: 'Sélectionné',
   videoIndex: 'Vidéo #',
   unknownDuration: 'Durée inconnue',
+  alreadyDownloaded: 'Déjà téléchargé',
   downloadSelected: 'Télécharger la sélection',
   downloadQueue: 'File d\'attente de téléchargement',
   queueDescription: 'Gérer les téléchargements actuels et planifiés',
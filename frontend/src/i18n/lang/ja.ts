@@ -129,6 +129,7 @@ export const ja: PartialTranslations = {
   selected: '選択済み',
   videoIndex: '動画 #',
   unknownDuration: '不明な再生時間',
+  alreadyDownloaded: 'ダウンロード済み',
   downloadSelected: '選択したものをダウンロード',
   downloadQueue: 'ダウンロードキュー',
   queueDescription: '現在および計画されているダウンロードを管理',
@@ -129,6 +129,7 @@ export const ko: PartialTranslations = {
   selected: '선택됨',
   videoIndex: '동영상 #',
   unknownDuration: '알 수 없는 길이',
+  alreadyDownloaded: '이미 다운로드됨',
   downloadSelected: '선택 항목 다운로드',
   downloadQueue: '다운로드 대기열',
   queueDescription: '현재 및 예약된 다운로드 관리',
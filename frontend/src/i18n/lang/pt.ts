@@ -129,6 +129,7 @@ export const pt: PartialTranslations = {
   selected: 'Selecionado',
   videoIndex: 'Vídeo #',
   unknownDuration: 'Duração desconhecida',
+  alreadyDownloaded: 'Já baixado',
   downloadSelected: 'Baixar selecionados',
   downloadQueue: 'Fila de Download',
   queueDescription: 'Gerenciar downloads atuais e planejados',
@@ -129,6 +129,7 @@ export const ru: PartialTranslations = {
   selected: 'Выбрано',
   videoIndex: 'Видео №',
   unknownDuration: 'Неизвестная длительность',
+  alreadyDownloaded: 'Уже загружено',
   downloadSelected: 'Скачать выбранное',
   downloadQueue: 'Очередь загрузок',
   queueDescription: 'Управление текущими и запланированными загрузками',
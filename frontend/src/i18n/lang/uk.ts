@@ -129,6 +129,7 @@ export const uk: PartialTranslations = {
   selected: 'Вибрано',
   videoIndex: 'Відео №',
   unknownDuration: 'Невідома тривалість',
+  alreadyDownloaded: 'Вже завантажено',
   downloadSelected: 'Завантажити вибране',
   downloadQueue: 'Черга завантажень',
   queueDescription: 'Управління поточними і запланованими завантаженнями',
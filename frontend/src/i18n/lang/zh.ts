@@ -129,6 +129,7 @@ export const zh: PartialTranslations = {
   selected: '已选择',
   videoIndex: '视频编号',
   unknownDuration: '未知时长',
+  alreadyDownloaded: '已下载',
   downloadSelected: '下载所选',
   downloadQueue: '下载队列',
   queueDescription: '管理当前和计划的下载',
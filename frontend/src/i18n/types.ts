@@ -162,6 +162,7 @@ export interface Translations {
   selected: string;
   videoIndex: string;
   unknownDuration: string;
+  alreadyDownloaded: string;
   downloadSelected: string;
 
   // Очередь загрузок
@@ -1,7 +1,7 @@
 ﻿// РЎРµСЂРІРёСЃ РґР»СЏ СЂР°Р±РѕС‚С‹ СЃ API Wails
 
 import { EventsOn } from '../../wailsjs/runtime/runtime';
-import { AnalyzeURL, DownloadVideo, GetDownloadPath, GetActualDownloadPath, GetDownloadDirectory, SetDownloadDirectory, SelectDownloadDirectory, GetSettings, GetYtDlpVersion, GetLatestYtDlpVersion, UpdateYtDlp, ValidateCookiesFile, CancelDownload, OpenInExplorer, ConvertVideo, AnalyzePlaylist, GetPlaylistItems, DownloadPlaylist, GetClipboardText, ReadLinksFromFile, ProcessDroppedFiles, SelectTextFile, ApplyAppUpdate } from '../../wailsjs/go/main/App';
+import { AnalyzeURL, DownloadVideo, GetDownloadPath, GetActualDownloadPath, GetDownloadDirectory, SetDownloadDirectory, SelectDownloadDirectory, GetSettings, GetYtDlpVersion, GetLatestYtDlpVersion, UpdateYtDlp, ValidateCookiesFile, CancelJob, PauseJob, OpenInExplorer, ConvertVideo, ProbeMedia, ListConversionPresets, SaveConversionPreset, DeleteConversionPreset, ConvertWithPreset, ConvertBatch, ListConversionJobs, CancelConversionJob, UpdateMaxConcurrentConversions, AnalyzePlaylist, AnalyzePlaylistDeep, CancelPlaylistAnalysis, GetPlaylistItems, DownloadPlaylist, DownloadPlaylistSelection, GetClipboardText, ReadLinksFromFile, ProcessDroppedFiles, SelectTextFile, ApplyAppUpdate, PreviewOutputTemplate, UpdateOutputTemplates, UpdateDownloadArchiveSettings, UpdateAPISettings, ResumeDownload, DiscardJob, RegenerateAPIToken, SetClipboardWatch, AddSubscription, ListSubscriptions, RemoveSubscription, SetSubscriptionEnabled, SyncSubscription, UpdateSubscriptionSyncInterval } from '../../wailsjs/go/main/App';
 
 
 // РўРёРїС‹ РґР»СЏ СЃРѕР±С‹С‚РёР№
@@ -14,17 +14,37 @@ export type SetupEventHandlers = {
 };
 
 export type DownloadEventHandlers = {
-  'download-progress': (data: { progress: number; size: string; speed: string; eta: string } | number) => void;
-  'download-complete': () => void;
-  'download-error': (error: string) => void;
-  'download-cancelled': (data?: { reason?: string }) => void;
+  'download-progress': (data: {
+    id: string; progress: number; size?: string; speed?: string; eta?: string;
+    phase?: 'download' | 'postprocess'; phase_progress?: number; postprocessor?: string; status?: string;
+    downloaded_bytes?: number; total_bytes?: number; speed_bps?: number; eta_seconds?: number;
+    fragment_index?: number; fragment_count?: number; playlist_index?: number; playlist_count?: number;
+    // Playlist jobs only: progress covers the whole playlist, item_progress the current item
+    item_index?: number; item_count?: number; item_title?: string; item_progress?: number;
+    succeeded?: number; failed?: number; skipped?: number;
+  } | number) => void;
+  'download-complete': (data: { id: string; file_path?: string; files?: string[]; already_downloaded?: boolean; succeeded?: number; failed?: number; skipped?: number }) => void;
+  'download-error': (data: { id: string; error: string } | string) => void;
+  'download-cancelled': (data?: { id?: string; reason?: string; downloaded_bytes?: number }) => void; // downloaded_bytes is set when paused
+  'download-resumed': (data: { id: string; downloaded_bytes: number }) => void;
+  'download-discarded': (data: { id: string; freed_bytes: number }) => void;
+  'playlist-item-start': (data: { id: string; playlist_index: number; video_id: string; title: string; item_index: number; item_count: number }) => void;
+  'playlist-item-finish': (data: { id: string; playlist_index: number; video_id: string; file_path: string; item_index: number; item_count: number }) => void;
+  'playlist-download-summary': (data: {
+    id: string; total: number; succeeded: number; failed: number; skipped: number;
+    errors: { item_index: number; video_id?: string; title?: string; error: string }[]; // item_index is 0 when unknown
+  }) => void;
 };
 
 export type ConversionEventHandlers = {
-  'conversion-progress': (data: { progress: number } | number) => void;
-  'conversion-complete': (data: { targetPath: string }) => void;
-  'conversion-error': (error: string) => void;
-  'conversion-cancelled': () => void;
+  'conversion-progress': (data: {
+    id: string; progress: number; out_time: string; out_time_seconds: number; duration: number;
+    speed: number; fps: number; total_size: number; size: string; eta: string; eta_seconds: number; // eta_seconds is -1 when unknown
+  } | number) => void;
+  'conversion-queued': (data: { id: string; source_path: string; target_path: string }) => void;
+  'conversion-complete': (data: { id: string; targetPath: string }) => void;
+  'conversion-error': (data: { id: string; error: string }) => void;
+  'conversion-cancelled': (data: { id: string }) => void;
 };
 
 export type YtDlpUpdateEventHandlers = {
@@ -34,6 +54,96 @@ export type YtDlpUpdateEventHandlers = {
   'yt-dlp-update-error': (error: string) => void;
 };
 
+export type SubscriptionEventHandlers = {
+  'subscription-new-items': (data: { id: string; url: string; count: number; items: { id: string; title: string; url: string; thumbnail: string; duration: number }[]; job_ids: string[]; skipped_existing: number }) => void;
+  'subscription-sync-error': (data: { id: string; error: string }) => void;
+};
+
+// Playlist entry as returned by the analyzer; a deep analysis fills in the metadata
+export type PlaylistEntry = {
+  id: string;
+  title: string;
+  thumbnail: string;
+  url: string;
+  duration: number;
+  uploader: string;
+  upload_date: string; // YYYYMMDD
+  view_count: number;
+  availability: string;
+  unavailable: boolean; // Private, deleted or otherwise not downloadable
+  already_downloaded: boolean;
+};
+
+// Media file details reported by ffprobe
+export type MediaStream = {
+  index: number;
+  type: 'video' | 'audio' | 'subtitle' | 'data' | 'attachment';
+  codec: string;
+  profile?: string;
+  bitrate?: number;
+  width?: number;
+  height?: number;
+  fps?: number;
+  sample_rate?: number;
+  channels?: number;
+  language?: string;
+  attached_pic?: boolean; // Cover art
+  tags?: Record<string, string>;
+};
+
+export type MediaInfo = {
+  path: string;
+  format: string;
+  duration: number;
+  bitrate: number;
+  size: number;
+  streams: MediaStream[];
+  chapters: { start_time: number; end_time: number; title: string }[];
+  tags?: Record<string, string>;
+};
+
+// FFmpeg encoding settings; leave video_codec empty for audio-only presets
+export type ConversionPreset = {
+  name: string;
+  container: string;
+  video_codec?: string; // 'copy' keeps the source stream
+  crf?: number;
+  video_bitrate?: string;
+  preset?: string;
+  max_height?: number;
+  fps?: number;
+  audio_codec?: string; // 'none' drops the audio
+  audio_bitrate?: string;
+  sample_rate?: number;
+  channels?: number;
+  video_filters?: string;
+  audio_filters?: string;
+  target_size_mb?: number;
+  built_in: boolean;
+};
+
+export type ConversionJob = {
+  id: string;
+  source_path: string;
+  target_path: string;
+  target_format?: string;
+  preset?: string;
+  status: 'queued' | 'running' | 'completed' | 'failed' | 'cancelled';
+  progress: number;
+  error?: string;
+  created_at: string;
+  started_at?: string;
+};
+
+export type PlaylistAnalysisEventHandlers = {
+  'playlist-entry-analyzed': (data: { playlist_id: string; index: number; entry: PlaylistEntry; error?: string }) => void;
+  'playlist-analysis-complete': (data: { playlist_id: string; analyzed: number; failed: number; cancelled: boolean }) => void;
+};
+
+export type ClipboardEventHandlers = {
+  'clipboard-url-detected': (data: { url: string; site: string }) => void;
+};
+
 export type NativeAppUpdateEventHandlers = {
   'app-update-start': () => void;
   'app-update-progress': (data: { downloaded: number; total: number; percentage: number; status: string }) => void;
@@ -41,7 +151,72 @@ export type NativeAppUpdateEventHandlers = {
   'app-update-error': (error: string) => void;
 };
 
-export type AppEventHandlers = SetupEventHandlers & DownloadEventHandlers & ConversionEventHandlers & YtDlpUpdateEventHandlers & NativeAppUpdateEventHandlers;
+// Audio-only download profile (yt-dlp -x)
+export type AudioExtraction = {
+  codec: string;
+  quality: string;
+  keep_original: boolean;
+};
+
+// Metadata embedded into the downloaded file
+export type EmbedOptions = {
+  metadata: boolean;
+  thumbnail: boolean;
+  chapters: boolean;
+  info_json: boolean;
+};
+
+// Subtitles downloaded with a video
+export type SubtitleSelection = {
+  languages: string[]; // Empty for yt-dlp's default
+  auto: boolean; // Also use auto-generated captions
+  format: '' | 'srt' | 'vtt' | 'ass'; // Empty keeps the original format
+  embed: boolean; // Embed instead of writing sidecar files
+};
+
+// Per-download options passed to DownloadVideo and DownloadPlaylist
+export type DownloadOptions = {
+  audio?: AudioExtraction;
+  embed?: EmbedOptions; // Omit to use the defaults from the settings
+  subtitles?: SubtitleSelection;
+  force_redownload?: boolean; // Download even if the download archive lists the video
+};
+
+// Skips playlist entries that do not match (yt-dlp --match-filter)
+export type PlaylistFilter = {
+  min_duration?: number; // Seconds
+  max_duration?: number;
+  date_after?: string; // Upload date YYYYMMDD, inclusive
+  date_before?: string;
+  title_regex?: string; // Python regex, e.g. "(?i)live"
+  max_downloads?: number;
+};
+
+// Entries of a playlist download: indices (1-based) and/or IDs, otherwise the range
+export type PlaylistSelection = {
+  items?: number[];
+  ids?: string[];
+  start_item?: number;
+  end_item?: number;
+  filter?: PlaylistFilter;
+};
+
+// Channel or playlist whose new entries are downloaded automatically
+export type Subscription = {
+  id: string;
+  url: string;
+  format_id: string;
+  output_dir: string;
+  options: DownloadOptions;
+  enabled: boolean;
+  archive_path: string;
+  skip_existing: boolean;
+  created_at: string;
+  last_sync: string;
+  last_error?: string;
+};
+
+export type AppEventHandlers = SetupEventHandlers & DownloadEventHandlers & ConversionEventHandlers & YtDlpUpdateEventHandlers & NativeAppUpdateEventHandlers & ClipboardEventHandlers & SubscriptionEventHandlers & PlaylistAnalysisEventHandlers;
 
 // Р¤СѓРЅРєС†РёРё API
 export const apiService = {
@@ -55,34 +230,82 @@ export const apiService = {
     return await AnalyzePlaylist(url);
   },
 
+  // Like analyzePlaylist, then streams full entry metadata as playlist-entry-analyzed events
+  analyzePlaylistDeep: async (url: string): Promise<string> => {
+    return await AnalyzePlaylistDeep(url);
+  },
+
+  cancelPlaylistAnalysis: async (): Promise<void> => {
+    return await CancelPlaylistAnalysis();
+  },
+
   // Р¤СѓРЅРєС†РёРё РґР»СЏ РїРѕР»СѓС‡РµРЅРёСЏ СЌР»РµРјРµРЅС‚РѕРІ РїР»РµР№Р»РёСЃС‚Р°
   getPlaylistItems: async (url: string): Promise<string> => {
     return await GetPlaylistItems(url);
   },
 
   // Р¤СѓРЅРєС†РёРё РґР»СЏ Р·Р°РіСЂСѓР·РєРё РІРёРґРµРѕ
-  downloadVideo: async (url: string, formatID: string, outputPath: string): Promise<void> => {
-    return await DownloadVideo(url, formatID, outputPath);
+  // Queues a video download and returns its job ID; download events carry it as "id"
+  downloadVideo: async (url: string, formatID: string, outputPath: string, options?: DownloadOptions): Promise<string> => {
+    return await DownloadVideo(url, formatID, outputPath, options ? JSON.stringify(options) : '');
   },
 
   // Р¤СѓРЅРєС†РёРё РґР»СЏ Р·Р°РіСЂСѓР·РєРё РїР»РµР№Р»РёСЃС‚Р°
-  downloadPlaylist: async (url: string, formatID: string, outputPath: string, startItem: number, endItem: number): Promise<void> => {
-    return await DownloadPlaylist(url, formatID, outputPath, startItem, endItem);
+  downloadPlaylist: async (url: string, formatID: string, outputPath: string, startItem: number, endItem: number, options?: DownloadOptions): Promise<string> => {
+    return await DownloadPlaylist(url, formatID, outputPath, startItem, endItem, options ? JSON.stringify(options) : '');
+  },
+
+  // Queues selected playlist entries and returns the job ID
+  downloadPlaylistSelection: async (url: string, formatID: string, outputPath: string, selection: PlaylistSelection, options?: DownloadOptions): Promise<string> => {
+    return await DownloadPlaylistSelection(url, formatID, outputPath, JSON.stringify(selection), options ? JSON.stringify(options) : '');
   },
 
-  // РћС‚РјРµРЅР° Р·Р°РіСЂСѓР·РєРё
-  cancelDownload: async (): Promise<void> => {
-    return await CancelDownload();
+  // Cancels a single download job; the other queued and running jobs keep going
+  cancelJob: async (jobId: string): Promise<void> => {
+    return await CancelJob(jobId);
   },
 
-  pauseDownload: async (): Promise<void> => {
-    // @ts-ignore - method is available after backend regeneration
-    return await window.go.main.App.PauseDownload();
+  // Pauses a single download job and keeps its partial files for resuming
+  pauseJob: async (jobId: string): Promise<void> => {
+    return await PauseJob(jobId);
+  },
+
+  // Resumes a paused job from its partial files, or every paused job when jobId is empty
+  resumeDownload: async (jobId: string = ''): Promise<void> => {
+    return await ResumeDownload(jobId);
+  },
+
+  // Cancels a job and deletes the partial files it left behind
+  discardJob: async (jobId: string): Promise<void> => {
+    return await DiscardJob(jobId);
   },
 
   // РџРѕР»СѓС‡РµРЅРёРµ РїСѓС‚Рё РґР»СЏ Р·Р°РіСЂСѓР·РєРё
-  getDownloadPath: async (title: string): Promise<string> => {
-    return await GetDownloadPath(title);
+  getDownloadPath: async (playlist: boolean = false): Promise<string> => {
+    return await GetDownloadPath(playlist);
+  },
+
+  // Preview of the file name an output template produces for analyzed video info
+  previewOutputTemplate: async (template: string, videoInfoJSON: string): Promise<string> => {
+    return await PreviewOutputTemplate(template, videoInfoJSON);
+  },
+
+  updateOutputTemplates: async (outputTemplate: string, playlistOutputTemplate: string, restrictFilenames: boolean, windowsFilenames: boolean): Promise<void> => {
+    return await UpdateOutputTemplates(outputTemplate, playlistOutputTemplate, restrictFilenames, windowsFilenames);
+  },
+
+  // Download archive: "off", "global" or one archive per "folder"
+  updateDownloadArchiveSettings: async (mode: 'off' | 'global' | 'folder', forceRedownload: boolean): Promise<void> => {
+    return await UpdateDownloadArchiveSettings(mode, forceRedownload);
+  },
+
+  // Local HTTP API for browser extensions; port 0 uses the default
+  updateAPISettings: async (enabled: boolean, port: number = 0): Promise<void> => {
+    return await UpdateAPISettings(enabled, port);
+  },
+
+  regenerateAPIToken: async (): Promise<string> => {
+    return await RegenerateAPIToken();
   },
 
   // РџРѕР»СѓС‡РµРЅРёРµ Р°РєС‚СѓР°Р»СЊРЅРѕРіРѕ РїСѓС‚Рё Рє Р·Р°РіСЂСѓР¶РµРЅРЅРѕРјСѓ С„Р°Р№Р»Сѓ (СЃ СЂРµР°Р»СЊРЅС‹Рј СЂР°СЃС€РёСЂРµРЅРёРµРј)
@@ -139,10 +362,50 @@ export const apiService = {
   },
 
   // РљРѕРЅРІРµСЂС‚Р°С†РёСЏ РІРёРґРµРѕ
-  convertVideo: async (sourcePath: string, targetFormat: string): Promise<void> => {
+  // Returns the ID of the queued conversion job
+  convertVideo: async (sourcePath: string, targetFormat: string): Promise<string> => {
     return await ConvertVideo(sourcePath, targetFormat);
   },
 
+  probeMedia: async (path: string): Promise<MediaInfo> => {
+    return JSON.parse(await ProbeMedia(path));
+  },
+
+  // Built-in presets first, then the user-defined ones
+  listConversionPresets: async (): Promise<ConversionPreset[]> => {
+    return JSON.parse(await ListConversionPresets());
+  },
+
+  saveConversionPreset: async (preset: ConversionPreset): Promise<void> => {
+    return await SaveConversionPreset(JSON.stringify(preset));
+  },
+
+  deleteConversionPreset: async (name: string): Promise<void> => {
+    return await DeleteConversionPreset(name);
+  },
+
+  // Returns the ID of the queued conversion job
+  convertWithPreset: async (sourcePath: string, presetName: string): Promise<string> => {
+    return await ConvertWithPreset(sourcePath, presetName);
+  },
+
+  // Returns the IDs of the queued conversion jobs
+  convertBatch: async (paths: string[], presetName: string): Promise<string[]> => {
+    return JSON.parse(await ConvertBatch(JSON.stringify(paths), presetName));
+  },
+
+  listConversionJobs: async (): Promise<ConversionJob[]> => {
+    return JSON.parse(await ListConversionJobs());
+  },
+
+  cancelConversionJob: async (id: string): Promise<void> => {
+    return await CancelConversionJob(id);
+  },
+
+  updateMaxConcurrentConversions: async (maxConversions: number): Promise<void> => {
+    return await UpdateMaxConcurrentConversions(maxConversions);
+  },
+
   // Р Р°Р±РѕС‚Р° СЃ РІРµСЂСЃРёСЏРјРё РїСЂРёР»РѕР¶РµРЅРёСЏ
   getCurrentVersion: async (): Promise<string> => {
     try {
@@ -224,6 +487,36 @@ export const apiService = {
     return await GetClipboardText();
   },
 
+  // Subscriptions to channels and playlists
+  addSubscription: async (url: string, formatID: string, outputDir: string, options?: DownloadOptions, skipExisting = false): Promise<Subscription> => {
+    return JSON.parse(await AddSubscription(url, formatID, outputDir, options ? JSON.stringify(options) : '', skipExisting));
+  },
+
+  listSubscriptions: async (): Promise<Subscription[]> => {
+    return JSON.parse(await ListSubscriptions());
+  },
+
+  removeSubscription: async (id: string): Promise<void> => {
+    return await RemoveSubscription(id);
+  },
+
+  setSubscriptionEnabled: async (id: string, enabled: boolean): Promise<void> => {
+    return await SetSubscriptionEnabled(id, enabled);
+  },
+
+  syncSubscription: async (id: string): Promise<number> => {
+    return await SyncSubscription(id);
+  },
+
+  updateSubscriptionSyncInterval: async (minutes: number): Promise<void> => {
+    return await UpdateSubscriptionSyncInterval(minutes);
+  },
+
+  // Background watcher that emits clipboard-url-detected for supported links
+  setClipboardWatch: async (enabled: boolean): Promise<void> => {
+    return await SetClipboardWatch(enabled);
+  },
+
   // Р§С‚РµРЅРёРµ СЃСЃС‹Р»РѕРє РёР· С„Р°Р№Р»Р°
   readLinksFromFile: async (filePath: string): Promise<string[]> => {
     const result = await ReadLinksFromFile(filePath);
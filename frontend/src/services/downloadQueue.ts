@@ -14,6 +14,7 @@ export interface QueueItem {
   speed?: string;
   size?: string;
   eta?: string;
+  jobId?: string; // Backend job ID, set once the download is queued; events carry it as "id"
 }
 
 class DownloadQueueManager {
@@ -23,8 +24,6 @@ class DownloadQueueManager {
   private rateLimitDelay = 2000;
   private lastDownloadTime = 0;
   private minTimeBetweenDownloads = 2000;
-  private cancelingId: string | null = null;
-  private cancelIntent: 'cancel' | 'pause' | null = null;
 
   addToQueue(item: Omit<QueueItem, 'id' | 'status' | 'progress' | 'addedAt'>): QueueItem {
     const isDuplicate = this.queue.some(
@@ -63,6 +62,10 @@ class DownloadQueueManager {
     return this.queue.find(item => item.id === id);
   }
 
+  getByJobId(jobId: string): QueueItem | undefined {
+    return this.queue.find(item => item.jobId === jobId);
+  }
+
   updateProgress(id: string, progress: number, speed?: string, size?: string, eta?: string): void {
     const item = this.queue.find(q => q.id === id);
     if (!item) return;
@@ -102,12 +105,14 @@ class DownloadQueueManager {
     const item = this.queue.find(q => q.id === id);
     if (!item || (item.status !== 'pending' && item.status !== 'in-progress' && item.status !== 'paused')) return;
 
-    if (item.status === 'in-progress') {
-      this.cancelingId = id;
-      this.cancelIntent = 'cancel';
-      void apiService.cancelDownload();
+    if (item.status === 'in-progress' && item.jobId) {
+      // The download-cancelled event for this job updates the item
+      void apiService.cancelJob(item.jobId);
       return;
     }
+    if (item.status === 'paused' && item.jobId) {
+      void apiService.cancelJob(item.jobId);
+    }
 
     this.removeFromQueue(id);
   }
@@ -116,14 +121,12 @@ class DownloadQueueManager {
     const item = this.queue.find(q => q.id === id);
     if (!item) return;
 
-    if (item.status === 'in-progress') {
-      this.cancelingId = id;
-      this.cancelIntent = 'pause';
-      void apiService.pauseDownload();
+    if (item.status === 'in-progress' && item.jobId) {
+      void apiService.pauseJob(item.jobId);
       return;
     }
 
-    if (item.status === 'pending') {
+    if (item.status === 'pending' || item.status === 'in-progress') {
       this.setStatus(id, 'paused');
     }
   }
@@ -132,26 +135,20 @@ class DownloadQueueManager {
     const item = this.queue.find(q => q.id === id);
     if (!item || item.status !== 'paused') return;
 
+    if (item.jobId) {
+      // The backend job kept its partial files; resume it instead of starting over
+      this.setStatus(id, 'in-progress');
+      apiService.resumeDownload(item.jobId).catch(error => {
+        console.error(`Resume failed for ${id}:`, error);
+        this.setStatus(id, 'failed');
+      });
+      return;
+    }
+
     this.setStatus(id, 'pending');
     this.processQueue();
   }
 
-  getCancelingId(): string | null {
-    return this.cancelingId;
-  }
-
-  getCancelIntent(): 'cancel' | 'pause' | null {
-    return this.cancelIntent;
-  }
-
-  clearCancelingId(): void {
-    this.cancelingId = null;
-  }
-
-  clearCancelIntent(): void {
-    this.cancelIntent = null;
-  }
-
   resumeQueue(): void {
     this.processQueue();
   }
@@ -209,13 +206,12 @@ class DownloadQueueManager {
         const delay = requiredDelay - timeSinceLastDownload;
         await new Promise(resolve => setTimeout(resolve, delay));
       }
+      // The item may have been paused or cancelled while waiting
+      if (item.status !== 'in-progress' || !this.queue.includes(item)) return;
 
-      await apiService.downloadVideo(item.url, item.formatID, item.outputPath);
+      item.jobId = await apiService.downloadVideo(item.url, item.formatID, item.outputPath);
       this.lastDownloadTime = Date.now();
     } catch (error) {
-      if (this.cancelingId === id) {
-        return;
-      }
       console.error(`Download failed for ${id}:`, error);
       this.setStatus(id, 'failed');
     }
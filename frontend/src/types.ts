@@ -24,4 +24,29 @@ export interface VideoInfo {
   description?: string;
   uploader?: string;
   view_count?: number | null;
+  channel?: string;
+  upload_date?: string;
+  tags?: string[] | null;
+  chapters?: Chapter[] | null;
+  subtitles?: Record<string, SubtitleTrack[]> | null;
+  automatic_captions?: Record<string, SubtitleTrack[]> | null;
+  available_subtitles?: SubtitleLanguage[];
+}
+
+export interface SubtitleLanguage {
+  code: string;
+  name: string;
+  formats: string[];
+  auto: boolean; // Auto-generated captions
+}
+
+export interface SubtitleTrack {
+  ext: string;
+  name?: string;
+}
+
+export interface Chapter {
+  start_time: number;
+  end_time: number;
+  title: string;
 }
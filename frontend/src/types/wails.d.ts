@@ -10,8 +10,8 @@ declare module '../../wailsjs/runtime/runtime' {
 
 declare module '../wailsjs/go/main/App' {
   export function AnalyzeURL(url: string): Promise<string>;
-  export function DownloadVideo(url: string, formatID: string, outputPath: string): Promise<void>;
-  export function GetDownloadPath(title: string): Promise<string>;
+  export function DownloadVideo(url: string, formatID: string, outputPath: string, optionsJSON: string): Promise<void>;
+  export function GetDownloadPath(playlist: boolean): Promise<string>;
   export function GetSettings(): Promise<string>;
   export function ValidateCookiesFile(filePath: string): Promise<boolean>;
   export function GetYtDlpVersion(): Promise<string>;
@@ -28,8 +28,8 @@ declare module '../wailsjs/go/main/App' {
 
 declare module '../../wailsjs/go/main/App' {
   export function AnalyzeURL(url: string): Promise<string>;
-  export function DownloadVideo(url: string, formatID: string, outputPath: string): Promise<void>;
-  export function GetDownloadPath(title: string): Promise<string>;
+  export function DownloadVideo(url: string, formatID: string, outputPath: string, optionsJSON: string): Promise<void>;
+  export function GetDownloadPath(playlist: boolean): Promise<string>;
   export function GetSettings(): Promise<string>;
   export function ValidateCookiesFile(filePath: string): Promise<boolean>;
   export function GetYtDlpVersion(): Promise<string>;
@@ -1,8 +1,12 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AddSubscription(arg1:string,arg2:string,arg3:string,arg4:string,arg5:boolean):Promise<string>;
+
 export function AnalyzePlaylist(arg1:string):Promise<string>;
 
+export function AnalyzePlaylistDeep(arg1:string):Promise<string>;
+
 export function AnalyzeURL(arg1:string):Promise<string>;
 
 export function ApplyAppUpdate():Promise<void>;
@@ -11,17 +15,39 @@ export function AutoSaveSettings():Promise<void>;
 
 export function CancelConversion():Promise<void>;
 
+export function CancelConversionJob(arg1:string):Promise<void>;
+
 export function CancelDownload():Promise<void>;
 
+export function CancelJob(arg1:string):Promise<void>;
+
+export function CancelPlaylistAnalysis():Promise<void>;
+
 export function CheckForUpdate():Promise<string>;
 
-export function ConvertVideo(arg1:string,arg2:string):Promise<void>;
+export function ConvertBatch(arg1:string,arg2:string):Promise<string>;
+
+export function ConvertVideo(arg1:string,arg2:string):Promise<string>;
+
+export function ConvertWithPreset(arg1:string,arg2:string):Promise<string>;
+
+export function DeleteConversionPreset(arg1:string):Promise<void>;
+
+export function DeleteHistoryItem(arg1:string):Promise<void>;
+
+export function DiscardJob(arg1:string):Promise<void>;
 
 export function DownloadDeno():Promise<void>;
 
-export function DownloadPlaylist(arg1:string,arg2:string,arg3:string,arg4:number,arg5:number):Promise<void>;
+export function DownloadPlaylist(arg1:string,arg2:string,arg3:string,arg4:number,arg5:number,arg6:string):Promise<string>;
 
-export function DownloadVideo(arg1:string,arg2:string,arg3:string):Promise<void>;
+export function DownloadPlaylistSelection(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<string>;
+
+export function DownloadVideo(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
+
+export function EnqueueDownload(arg1:string,arg2:string,arg3:string,arg4:string):Promise<string>;
+
+export function ExportHistory(arg1:string,arg2:string):Promise<string>;
 
 export function GetActualDownloadPath(arg1:string):Promise<string>;
 
@@ -33,7 +59,7 @@ export function GetDenoVersion():Promise<string>;
 
 export function GetDownloadDirectory():Promise<string>;
 
-export function GetDownloadPath(arg1:string):Promise<string>;
+export function GetDownloadPath(arg1:boolean):Promise<string>;
 
 export function GetJSRuntimeType():Promise<string>;
 
@@ -65,40 +91,88 @@ export function IsDenoAvailable():Promise<boolean>;
 
 export function IsNodeAvailable():Promise<boolean>;
 
+export function ListConversionJobs():Promise<string>;
+
+export function ListConversionPresets():Promise<string>;
+
+export function ListJobs():Promise<string>;
+
+export function ListSubscriptions():Promise<string>;
+
 export function OpenInExplorer(arg1:string):Promise<void>;
 
 export function PauseDownload():Promise<void>;
 
+export function PauseJob(arg1:string):Promise<void>;
+
+export function PreviewOutputTemplate(arg1:string,arg2:string):Promise<string>;
+
+export function ProbeMedia(arg1:string):Promise<string>;
+
 export function ProcessDroppedFiles(arg1:Array<string>):Promise<string>;
 
+export function QueryHistory(arg1:string):Promise<string>;
+
 export function ReadLinksFromFile(arg1:string):Promise<string>;
 
+export function RegenerateAPIToken():Promise<string>;
+
+export function RemoveSubscription(arg1:string):Promise<void>;
+
+export function ResumeAllJobs():Promise<void>;
+
+export function ResumeDownload(arg1:string):Promise<void>;
+
+export function ResumeJob(arg1:string):Promise<void>;
+
+export function SaveConversionPreset(arg1:string):Promise<void>;
+
 export function SelectCookiesFile():Promise<string>;
 
 export function SelectDownloadDirectory():Promise<string>;
 
 export function SelectTextFile():Promise<string>;
 
+export function SetClipboardWatch(arg1:boolean):Promise<void>;
+
 export function SetDownloadDirectory(arg1:string):Promise<void>;
 
+export function SetSubscriptionEnabled(arg1:string,arg2:boolean):Promise<void>;
+
 export function SetupDependencies():Promise<void>;
 
 export function ShouldUpdate():Promise<boolean>;
 
+export function SyncSubscription(arg1:string):Promise<number>;
+
+export function UpdateAPISettings(arg1:boolean,arg2:number):Promise<void>;
+
 export function UpdateAutoRedirectToQueue(arg1:boolean):Promise<void>;
 
 export function UpdateDeno():Promise<void>;
 
+export function UpdateDownloadArchiveSettings(arg1:string,arg2:boolean):Promise<void>;
+
+export function UpdateEmbedSettings(arg1:boolean,arg2:boolean,arg3:boolean,arg4:boolean):Promise<void>;
+
 export function UpdateJSRuntimeSetting(arg1:boolean):Promise<void>;
 
 export function UpdateJSRuntimeType(arg1:string):Promise<void>;
 
 export function UpdateLanguage(arg1:string):Promise<void>;
 
+export function UpdateMaxConcurrentConversions(arg1:number):Promise<void>;
+
+export function UpdateMaxConcurrentDownloads(arg1:number):Promise<void>;
+
 export function UpdateNode():Promise<void>;
 
+export function UpdateOutputTemplates(arg1:string,arg2:string,arg3:boolean,arg4:boolean):Promise<void>;
+
 export function UpdateSettingsWithCookiesFile(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<void>;
 
+export function UpdateSubscriptionSyncInterval(arg1:number):Promise<void>;
+
 export function UpdateYtDlp():Promise<void>;
 
 export function ValidateCookiesFile(arg1:string):Promise<boolean>;
@@ -2,10 +2,18 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AddSubscription(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['AddSubscription'](arg1, arg2, arg3, arg4, arg5);
+}
+
 export function AnalyzePlaylist(arg1) {
   return window['go']['main']['App']['AnalyzePlaylist'](arg1);
 }
 
+export function AnalyzePlaylistDeep(arg1) {
+  return window['go']['main']['App']['AnalyzePlaylistDeep'](arg1);
+}
+
 export function AnalyzeURL(arg1) {
   return window['go']['main']['App']['AnalyzeURL'](arg1);
 }
@@ -22,28 +30,72 @@ export function CancelConversion() {
   return window['go']['main']['App']['CancelConversion']();
 }
 
+export function CancelConversionJob(arg1) {
+  return window['go']['main']['App']['CancelConversionJob'](arg1);
+}
+
 export function CancelDownload() {
   return window['go']['main']['App']['CancelDownload']();
 }
 
+export function CancelJob(arg1) {
+  return window['go']['main']['App']['CancelJob'](arg1);
+}
+
+export function CancelPlaylistAnalysis() {
+  return window['go']['main']['App']['CancelPlaylistAnalysis']();
+}
+
 export function CheckForUpdate() {
   return window['go']['main']['App']['CheckForUpdate']();
 }
 
+export function ConvertBatch(arg1, arg2) {
+  return window['go']['main']['App']['ConvertBatch'](arg1, arg2);
+}
+
 export function ConvertVideo(arg1, arg2) {
   return window['go']['main']['App']['ConvertVideo'](arg1, arg2);
 }
 
+export function ConvertWithPreset(arg1, arg2) {
+  return window['go']['main']['App']['ConvertWithPreset'](arg1, arg2);
+}
+
+export function DeleteConversionPreset(arg1) {
+  return window['go']['main']['App']['DeleteConversionPreset'](arg1);
+}
+
+export function DeleteHistoryItem(arg1) {
+  return window['go']['main']['App']['DeleteHistoryItem'](arg1);
+}
+
+export function DiscardJob(arg1) {
+  return window['go']['main']['App']['DiscardJob'](arg1);
+}
+
 export function DownloadDeno() {
   return window['go']['main']['App']['DownloadDeno']();
 }
 
-export function DownloadPlaylist(arg1, arg2, arg3, arg4, arg5) {
-  return window['go']['main']['App']['DownloadPlaylist'](arg1, arg2, arg3, arg4, arg5);
+export function DownloadPlaylist(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['DownloadPlaylist'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function DownloadPlaylistSelection(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['DownloadPlaylistSelection'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function DownloadVideo(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['DownloadVideo'](arg1, arg2, arg3, arg4);
 }
 
-export function DownloadVideo(arg1, arg2, arg3) {
-  return window['go']['main']['App']['DownloadVideo'](arg1, arg2, arg3);
+export function EnqueueDownload(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['EnqueueDownload'](arg1, arg2, arg3, arg4);
+}
+
+export function ExportHistory(arg1, arg2) {
+  return window['go']['main']['App']['ExportHistory'](arg1, arg2);
 }
 
 export function GetActualDownloadPath(arg1) {
@@ -130,6 +182,22 @@ export function IsNodeAvailable() {
   return window['go']['main']['App']['IsNodeAvailable']();
 }
 
+export function ListConversionJobs() {
+  return window['go']['main']['App']['ListConversionJobs']();
+}
+
+export function ListConversionPresets() {
+  return window['go']['main']['App']['ListConversionPresets']();
+}
+
+export function ListJobs() {
+  return window['go']['main']['App']['ListJobs']();
+}
+
+export function ListSubscriptions() {
+  return window['go']['main']['App']['ListSubscriptions']();
+}
+
 export function OpenInExplorer(arg1) {
   return window['go']['main']['App']['OpenInExplorer'](arg1);
 }
@@ -138,14 +206,54 @@ export function PauseDownload() {
   return window['go']['main']['App']['PauseDownload']();
 }
 
+export function PauseJob(arg1) {
+  return window['go']['main']['App']['PauseJob'](arg1);
+}
+
+export function PreviewOutputTemplate(arg1, arg2) {
+  return window['go']['main']['App']['PreviewOutputTemplate'](arg1, arg2);
+}
+
+export function ProbeMedia(arg1) {
+  return window['go']['main']['App']['ProbeMedia'](arg1);
+}
+
 export function ProcessDroppedFiles(arg1) {
   return window['go']['main']['App']['ProcessDroppedFiles'](arg1);
 }
 
+export function QueryHistory(arg1) {
+  return window['go']['main']['App']['QueryHistory'](arg1);
+}
+
 export function ReadLinksFromFile(arg1) {
   return window['go']['main']['App']['ReadLinksFromFile'](arg1);
 }
 
+export function RegenerateAPIToken() {
+  return window['go']['main']['App']['RegenerateAPIToken']();
+}
+
+export function RemoveSubscription(arg1) {
+  return window['go']['main']['App']['RemoveSubscription'](arg1);
+}
+
+export function ResumeAllJobs() {
+  return window['go']['main']['App']['ResumeAllJobs']();
+}
+
+export function ResumeDownload(arg1) {
+  return window['go']['main']['App']['ResumeDownload'](arg1);
+}
+
+export function ResumeJob(arg1) {
+  return window['go']['main']['App']['ResumeJob'](arg1);
+}
+
+export function SaveConversionPreset(arg1) {
+  return window['go']['main']['App']['SaveConversionPreset'](arg1);
+}
+
 export function SelectCookiesFile() {
   return window['go']['main']['App']['SelectCookiesFile']();
 }
@@ -158,10 +266,18 @@ export function SelectTextFile() {
   return window['go']['main']['App']['SelectTextFile']();
 }
 
+export function SetClipboardWatch(arg1) {
+  return window['go']['main']['App']['SetClipboardWatch'](arg1);
+}
+
 export function SetDownloadDirectory(arg1) {
   return window['go']['main']['App']['SetDownloadDirectory'](arg1);
 }
 
+export function SetSubscriptionEnabled(arg1, arg2) {
+  return window['go']['main']['App']['SetSubscriptionEnabled'](arg1, arg2);
+}
+
 export function SetupDependencies() {
   return window['go']['main']['App']['SetupDependencies']();
 }
@@ -170,6 +286,14 @@ export function ShouldUpdate() {
   return window['go']['main']['App']['ShouldUpdate']();
 }
 
+export function SyncSubscription(arg1) {
+  return window['go']['main']['App']['SyncSubscription'](arg1);
+}
+
+export function UpdateAPISettings(arg1, arg2) {
+  return window['go']['main']['App']['UpdateAPISettings'](arg1, arg2);
+}
+
 export function UpdateAutoRedirectToQueue(arg1) {
   return window['go']['main']['App']['UpdateAutoRedirectToQueue'](arg1);
 }
@@ -178,6 +302,14 @@ export function UpdateDeno() {
   return window['go']['main']['App']['UpdateDeno']();
 }
 
+export function UpdateDownloadArchiveSettings(arg1, arg2) {
+  return window['go']['main']['App']['UpdateDownloadArchiveSettings'](arg1, arg2);
+}
+
+export function UpdateEmbedSettings(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['UpdateEmbedSettings'](arg1, arg2, arg3, arg4);
+}
+
 export function UpdateJSRuntimeSetting(arg1) {
   return window['go']['main']['App']['UpdateJSRuntimeSetting'](arg1);
 }
@@ -190,14 +322,30 @@ export function UpdateLanguage(arg1) {
   return window['go']['main']['App']['UpdateLanguage'](arg1);
 }
 
+export function UpdateMaxConcurrentConversions(arg1) {
+  return window['go']['main']['App']['UpdateMaxConcurrentConversions'](arg1);
+}
+
+export function UpdateMaxConcurrentDownloads(arg1) {
+  return window['go']['main']['App']['UpdateMaxConcurrentDownloads'](arg1);
+}
+
 export function UpdateNode() {
   return window['go']['main']['App']['UpdateNode']();
 }
 
+export function UpdateOutputTemplates(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['UpdateOutputTemplates'](arg1, arg2, arg3, arg4);
+}
+
 export function UpdateSettingsWithCookiesFile(arg1, arg2, arg3, arg4, arg5) {
   return window['go']['main']['App']['UpdateSettingsWithCookiesFile'](arg1, arg2, arg3, arg4, arg5);
 }
 
+export function UpdateSubscriptionSyncInterval(arg1) {
+  return window['go']['main']['App']['UpdateSubscriptionSyncInterval'](arg1);
+}
+
 export function UpdateYtDlp() {
   return window['go']['main']['App']['UpdateYtDlp']();
 }
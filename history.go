@@ -0,0 +1,337 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// historyFile stores the download history next to settings.json
+const historyFile = "./history.json"
+
+// historyStore keeps finished downloads in memory and mirrors them to a JSON file
+type historyStore struct {
+	mu     sync.Mutex
+	path   string
+	items  []HistoryItem
+	loaded bool
+}
+
+// newHistoryStore creates a history store backed by the given file.
+// The file is read lazily on first use.
+func newHistoryStore(path string) *historyStore {
+	return &historyStore{path: path}
+}
+
+// load reads the history file once. Must be called with mu held.
+func (h *historyStore) load() error {
+	if h.loaded {
+		return nil
+	}
+
+	data, err := os.ReadFile(h.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			h.loaded = true
+			return nil
+		}
+		return fmt.Errorf("failed to read history file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &h.items); err != nil {
+		return fmt.Errorf("failed to parse history file: %w", err)
+	}
+	h.loaded = true
+	return nil
+}
+
+// save writes the history file. Must be called with mu held.
+func (h *historyStore) save() error {
+	data, err := json.MarshalIndent(h.items, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal history: %w", err)
+	}
+
+	tmpPath := h.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write history file: %w", err)
+	}
+	if err := os.Rename(tmpPath, h.path); err != nil {
+		return fmt.Errorf("failed to replace history file: %w", err)
+	}
+	return nil
+}
+
+// add appends an item and persists the history
+func (h *historyStore) add(item HistoryItem) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.load(); err != nil {
+		return err
+	}
+	h.items = append(h.items, item)
+	return h.save()
+}
+
+// delete removes the item with the given ID and persists the history
+func (h *historyStore) delete(id string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.load(); err != nil {
+		return err
+	}
+	for i, item := range h.items {
+		if item.ID == id {
+			h.items = append(h.items[:i], h.items[i+1:]...)
+			return h.save()
+		}
+	}
+	return fmt.Errorf("history item not found: %s", id)
+}
+
+// query returns the items matching the filter, newest first
+func (h *historyStore) query(filter HistoryFilter) ([]HistoryItem, error) {
+	from, err := parseHistoryDate(filter.From, false)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseHistoryDate(filter.To, true)
+	if err != nil {
+		return nil, err
+	}
+	text := strings.ToLower(strings.TrimSpace(filter.Text))
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.load(); err != nil {
+		return nil, err
+	}
+
+	result := []HistoryItem{}
+	for _, item := range h.items {
+		if filter.Status != "" && item.Status != filter.Status {
+			continue
+		}
+		if !from.IsZero() && item.FinishedAt.Before(from) {
+			continue
+		}
+		if !to.IsZero() && item.FinishedAt.After(to) {
+			continue
+		}
+		if text != "" &&
+			!strings.Contains(strings.ToLower(item.Title), text) &&
+			!strings.Contains(strings.ToLower(item.URL), text) &&
+			!strings.Contains(strings.ToLower(item.FilePath), text) {
+			continue
+		}
+		result = append(result, item)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].FinishedAt.After(result[j].FinishedAt)
+	})
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result, nil
+}
+
+// completedFilePath returns the file of the newest completed download with the
+// given title that is still on disk, or "" when there is none
+func (h *historyStore) completedFilePath(title string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.load(); err != nil {
+		return "", err
+	}
+	for i := len(h.items) - 1; i >= 0; i-- {
+		item := h.items[i]
+		if item.Status != JobStatusCompleted || item.Title != title || item.FilePath == "" {
+			continue
+		}
+		// Playlist downloads record their folder, not a file
+		if info, err := os.Stat(item.FilePath); err == nil && !info.IsDir() {
+			return item.FilePath, nil
+		}
+	}
+	return "", nil
+}
+
+// parseHistoryDate parses a filter date. A plain date used as an upper bound
+// covers the whole day.
+func parseHistoryDate(value string, endOfDay bool) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or RFC 3339", value)
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+// writeHistoryCSV writes history items as CSV with a header row
+func writeHistoryCSV(w io.Writer, items []HistoryItem) error {
+	writer := csv.NewWriter(w)
+	header := []string{"id", "job_id", "url", "title", "format_id", "file_path", "file_size", "download_seconds", "status", "error", "finished_at"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		record := []string{
+			item.ID,
+			item.JobID,
+			item.URL,
+			item.Title,
+			item.FormatID,
+			item.FilePath,
+			strconv.FormatInt(item.FileSize, 10),
+			strconv.FormatFloat(item.DownloadSeconds, 'f', 1, 64),
+			item.Status,
+			item.Error,
+			item.FinishedAt.Format(time.RFC3339),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// recordJobHistory adds a finished, failed or cancelled job to the history
+func (a *App) recordJobHistory(job DownloadJob) {
+	if a.history == nil {
+		return
+	}
+
+	item := HistoryItem{
+		ID:         newJobID(),
+		JobID:      job.ID,
+		URL:        job.URL,
+		Title:      job.Title,
+		FormatID:   job.FormatID,
+		FilePath:   job.FilePath,
+		Status:     job.Status,
+		Error:      job.Error,
+		FinishedAt: time.Now(),
+	}
+	if !job.StartedAt.IsZero() {
+		item.DownloadSeconds = item.FinishedAt.Sub(job.StartedAt).Seconds()
+	}
+	if job.FilePath != "" {
+		if info, err := os.Stat(job.FilePath); err == nil && !info.IsDir() {
+			item.FileSize = info.Size()
+		}
+	}
+
+	if err := a.history.add(item); err != nil {
+		a.logger.Errorf("Failed to record download history: %v", err)
+	}
+}
+
+// queryHistoryInternal returns history items matching a JSON-encoded HistoryFilter
+func (a *App) queryHistoryInternal(filterJSON string) (string, error) {
+	var filter HistoryFilter
+	if strings.TrimSpace(filterJSON) != "" {
+		if err := json.Unmarshal([]byte(filterJSON), &filter); err != nil {
+			return "", fmt.Errorf("failed to parse history filter: %w", err)
+		}
+	}
+
+	items, err := a.history.query(filter)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := json.Marshal(items)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal history: %w", err)
+	}
+	return string(result), nil
+}
+
+// exportHistoryInternal writes the whole history to a CSV or JSON file.
+// When filePath is empty a save dialog is shown. Returns the written path.
+func (a *App) exportHistoryInternal(format, filePath string) (string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format != "csv" && format != "json" {
+		return "", fmt.Errorf("unsupported export format: %s", format)
+	}
+
+	if filePath == "" {
+		selectedPath, err := wailsRuntime.SaveFileDialog(a.ctx, wailsRuntime.SaveDialogOptions{
+			Title:           "Export Download History",
+			DefaultFilename: "go-dlp-history." + format,
+			Filters: []wailsRuntime.FileFilter{
+				{
+					DisplayName: strings.ToUpper(format) + " Files (*." + format + ")",
+					Pattern:     "*." + format,
+				},
+			},
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to open save dialog: %w", err)
+		}
+		if selectedPath == "" {
+			return "", fmt.Errorf("no file selected")
+		}
+		filePath = selectedPath
+	}
+
+	items, err := a.history.query(HistoryFilter{})
+	if err != nil {
+		return "", err
+	}
+
+	if dir := filepath.Dir(filePath); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create export directory: %w", err)
+		}
+	}
+
+	file, err := os.Create(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer file.Close()
+
+	if format == "csv" {
+		err = writeHistoryCSV(file, items)
+	} else {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(items)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	a.logger.Infof("Exported %d history items to: %s", len(items), filePath)
+	return filePath, nil
+}
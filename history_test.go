@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestHistoryStore(t *testing.T) *historyStore {
+	t.Helper()
+	store := newHistoryStore(filepath.Join(t.TempDir(), "history.json"))
+	base := time.Date(2026, 3, 10, 12, 0, 0, 0, time.Local)
+	items := []HistoryItem{
+		{ID: "1", URL: "https://youtube.com/watch?v=a", Title: "Cats compilation", Status: JobStatusCompleted, FinishedAt: base},
+		{ID: "2", URL: "https://rutube.ru/video/b", Title: "News", Status: JobStatusFailed, Error: "HTTP 403", FinishedAt: base.Add(24 * time.Hour)},
+		{ID: "3", URL: "https://youtube.com/watch?v=c", Title: "Dogs", Status: JobStatusCancelled, FinishedAt: base.Add(48 * time.Hour)},
+	}
+	for _, item := range items {
+		if err := store.add(item); err != nil {
+			t.Fatalf("unexpected add error: %v", err)
+		}
+	}
+	return store
+}
+
+func TestHistoryStore_QueryFilters(t *testing.T) {
+	store := newTestHistoryStore(t)
+
+	tests := []struct {
+		name   string
+		filter HistoryFilter
+		want   []string
+	}{
+		{"All newest first", HistoryFilter{}, []string{"3", "2", "1"}},
+		{"Text matches URL", HistoryFilter{Text: "YOUTUBE"}, []string{"3", "1"}},
+		{"Status", HistoryFilter{Status: JobStatusFailed}, []string{"2"}},
+		{"Date range", HistoryFilter{From: "2026-03-11", To: "2026-03-11"}, []string{"2"}},
+		{"Limit", HistoryFilter{Limit: 1}, []string{"3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := store.query(tt.filter)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(items) != len(tt.want) {
+				t.Fatalf("expected %d items, got %d: %#v", len(tt.want), len(items), items)
+			}
+			for i, id := range tt.want {
+				if items[i].ID != id {
+					t.Fatalf("item %d: expected ID %s, got %s", i, id, items[i].ID)
+				}
+			}
+		})
+	}
+}
+
+func TestHistoryStore_InvalidDate(t *testing.T) {
+	store := newTestHistoryStore(t)
+	if _, err := store.query(HistoryFilter{From: "10/03/2026"}); err == nil {
+		t.Fatal("expected error for invalid date")
+	}
+}
+
+func TestHistoryStore_DeletePersists(t *testing.T) {
+	store := newTestHistoryStore(t)
+	if err := store.delete("2"); err != nil {
+		t.Fatalf("unexpected delete error: %v", err)
+	}
+	if err := store.delete("2"); err == nil {
+		t.Fatal("expected error when deleting a missing item")
+	}
+
+	reloaded := newHistoryStore(store.path)
+	items, err := reloaded.query(HistoryFilter{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items after reload, got %d", len(items))
+	}
+}
+
+func TestWriteHistoryCSV(t *testing.T) {
+	var buf bytes.Buffer
+	items := []HistoryItem{{ID: "1", URL: "https://example.com", Title: `Title, with "quotes"`, FileSize: 1024, DownloadSeconds: 12.34, Status: JobStatusCompleted}}
+	if err := writeHistoryCSV(&buf, items); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV back: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected header and one row, got %d records", len(records))
+	}
+	if records[0][7] != "download_seconds" {
+		t.Fatalf("unexpected CSV header: %#v", records[0])
+	}
+	if records[1][3] != items[0].Title || records[1][6] != "1024" || records[1][7] != "12.3" {
+		t.Fatalf("unexpected CSV row: %#v", records[1])
+	}
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// containerCodecs lists the codecs a target container can hold without
+// re-encoding. Audio-only containers drop the video instead of rejecting it.
+type containerCodecs struct {
+	video     []string
+	audio     []string
+	subtitle  []string
+	any       bool // Holds every codec, like Matroska
+	audioOnly bool
+	faststart bool // Move the index to the front for streaming (-movflags +faststart)
+}
+
+// mp4Codecs are the codecs the MP4 family of containers holds
+var mp4Codecs = containerCodecs{
+	video:     []string{"h264", "hevc", "av1", "vp9", "mpeg4"},
+	audio:     []string{"aac", "mp3", "alac", "ac3", "eac3", "opus", "flac"},
+	subtitle:  []string{"mov_text"},
+	faststart: true,
+}
+
+// remuxContainers are the target formats a conversion may remux into
+var remuxContainers = map[string]containerCodecs{
+	"mp4":  mp4Codecs,
+	"m4v":  mp4Codecs,
+	"mov":  mp4Codecs,
+	"mkv":  {any: true},
+	"webm": {video: []string{"vp8", "vp9", "av1"}, audio: []string{"vorbis", "opus"}, subtitle: []string{"webvtt"}},
+	"m4a":  {audio: []string{"aac", "alac"}, audioOnly: true, faststart: true},
+	"mp3":  {audio: []string{"mp3"}, audioOnly: true},
+	"aac":  {audio: []string{"aac"}, audioOnly: true},
+	"flac": {audio: []string{"flac"}, audioOnly: true},
+	"opus": {audio: []string{"opus"}, audioOnly: true},
+	"ogg":  {audio: []string{"vorbis", "opus", "flac"}, audioOnly: true},
+	"wav":  {audio: []string{"pcm_s16le", "pcm_s24le", "pcm_f32le"}, audioOnly: true},
+}
+
+// holds reports whether the container can hold a stream of the given type and codec as is
+func (c containerCodecs) holds(streamType, codec string) bool {
+	if c.any {
+		return true
+	}
+	switch streamType {
+	case "video":
+		return slices.Contains(c.video, codec)
+	case "audio":
+		return slices.Contains(c.audio, codec)
+	case "subtitle":
+		return slices.Contains(c.subtitle, codec)
+	}
+	return false
+}
+
+// ffprobeOutput mirrors the JSON printed by ffprobe -print_format json
+type ffprobeOutput struct {
+	Streams []struct {
+		Index        int               `json:"index"`
+		CodecName    string            `json:"codec_name"`
+		CodecType    string            `json:"codec_type"`
+		Profile      string            `json:"profile"`
+		BitRate      string            `json:"bit_rate"`
+		Width        int               `json:"width"`
+		Height       int               `json:"height"`
+		AvgFrameRate string            `json:"avg_frame_rate"`
+		SampleRate   string            `json:"sample_rate"`
+		Channels     int               `json:"channels"`
+		Tags         map[string]string `json:"tags"`
+		Disposition  struct {
+			AttachedPic int `json:"attached_pic"`
+		} `json:"disposition"`
+	} `json:"streams"`
+	Chapters []struct {
+		StartTime string            `json:"start_time"`
+		EndTime   string            `json:"end_time"`
+		Tags      map[string]string `json:"tags"`
+	} `json:"chapters"`
+	Format struct {
+		FormatName string            `json:"format_name"`
+		Duration   string            `json:"duration"`
+		BitRate    string            `json:"bit_rate"`
+		Size       string            `json:"size"`
+		Tags       map[string]string `json:"tags"`
+	} `json:"format"`
+}
+
+// parseFrameRate converts an ffprobe rate such as "30000/1001" to frames per second
+func parseFrameRate(rate string) float64 {
+	num, den, found := strings.Cut(rate, "/")
+	numerator, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0
+	}
+	if !found {
+		return numerator
+	}
+	denominator, err := strconv.ParseFloat(den, 64)
+	if err != nil || denominator == 0 {
+		return 0
+	}
+	return numerator / denominator
+}
+
+// parseFFprobeOutput converts ffprobe's JSON into a MediaInfo. ffprobe prints
+// most numbers as strings, which are left at zero when missing.
+func parseFFprobeOutput(path string, data []byte) (MediaInfo, error) {
+	var raw ffprobeOutput
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return MediaInfo{}, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+
+	info := MediaInfo{
+		Path:     path,
+		Format:   raw.Format.FormatName,
+		Streams:  make([]MediaStream, 0, len(raw.Streams)),
+		Chapters: make([]Chapter, 0, len(raw.Chapters)),
+		Tags:     raw.Format.Tags,
+	}
+	info.Duration, _ = strconv.ParseFloat(raw.Format.Duration, 64)
+	info.Bitrate, _ = strconv.ParseInt(raw.Format.BitRate, 10, 64)
+	info.Size, _ = strconv.ParseInt(raw.Format.Size, 10, 64)
+
+	for _, s := range raw.Streams {
+		stream := MediaStream{
+			Index:       s.Index,
+			Type:        s.CodecType,
+			Codec:       s.CodecName,
+			Profile:     s.Profile,
+			Width:       s.Width,
+			Height:      s.Height,
+			Channels:    s.Channels,
+			Language:    s.Tags["language"],
+			AttachedPic: s.Disposition.AttachedPic == 1,
+			Tags:        s.Tags,
+		}
+		stream.Bitrate, _ = strconv.ParseInt(s.BitRate, 10, 64)
+		stream.SampleRate, _ = strconv.Atoi(s.SampleRate)
+		if stream.Type == "video" {
+			stream.FPS = parseFrameRate(s.AvgFrameRate)
+		}
+		info.Streams = append(info.Streams, stream)
+	}
+
+	for _, c := range raw.Chapters {
+		chapter := Chapter{Title: c.Tags["title"]}
+		chapter.StartTime, _ = strconv.ParseFloat(c.StartTime, 64)
+		chapter.EndTime, _ = strconv.ParseFloat(c.EndTime, 64)
+		info.Chapters = append(info.Chapters, chapter)
+	}
+	return info, nil
+}
+
+// ffprobeBinaryPath returns the local ffprobe binary if present, otherwise the system one
+func ffprobeBinaryPath() string {
+	ffprobePath := filepath.Join("./bin", "ffprobe"+getExecutableExtension())
+	if _, err := os.Stat(ffprobePath); os.IsNotExist(err) {
+		return "ffprobe"
+	}
+	return ffprobePath
+}
+
+// probeMedia inspects a media file with ffprobe
+func probeMedia(path string) (MediaInfo, error) {
+	cmd := exec.Command(ffprobeBinaryPath(), "-v", "error", "-print_format", "json",
+		"-show_format", "-show_streams", "-show_chapters", path)
+	setHideWindow(cmd)
+
+	output, err := cmd.Output()
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			err = fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitError.Stderr)))
+		}
+		return MediaInfo{}, fmt.Errorf("failed to probe %s: %w", path, err)
+	}
+	return parseFFprobeOutput(path, output)
+}
+
+// remuxArgs returns the FFmpeg arguments that convert the source into the
+// target format by copying its streams, or false when a stream would have to
+// be re-encoded. Subtitles the target cannot hold are left out.
+func remuxArgs(info MediaInfo, sourcePath, targetPath, targetFormat string) ([]string, bool) {
+	codecs, ok := remuxContainers[targetFormat]
+	if !ok || filepath.Clean(sourcePath) == filepath.Clean(targetPath) {
+		return nil, false
+	}
+
+	var video, audio, subtitles []MediaStream
+	for _, stream := range info.Streams {
+		switch {
+		case stream.Type == "video" && !stream.AttachedPic:
+			video = append(video, stream)
+		case stream.Type == "audio":
+			audio = append(audio, stream)
+		case stream.Type == "subtitle":
+			subtitles = append(subtitles, stream)
+		}
+	}
+
+	args := []string{"-i", sourcePath}
+	if codecs.audioOnly {
+		// Audio-only formats take the first audio track
+		if len(audio) == 0 || !codecs.holds("audio", audio[0].Codec) {
+			return nil, false
+		}
+		args = append(args, "-map", "0:a:0", "-vn")
+	} else {
+		if len(video) == 0 {
+			return nil, false
+		}
+		for _, stream := range slices.Concat(video, audio) {
+			if !codecs.holds(stream.Type, stream.Codec) {
+				return nil, false
+			}
+		}
+		// Uppercase V skips cover art, which not every container can hold
+		args = append(args, "-map", "0:V", "-map", "0:a?")
+		if len(subtitles) > 0 && !slices.ContainsFunc(subtitles, func(s MediaStream) bool { return !codecs.holds("subtitle", s.Codec) }) {
+			args = append(args, "-map", "0:s")
+		}
+		if codecs.faststart && slices.ContainsFunc(video, func(s MediaStream) bool { return s.Codec == "hevc" }) {
+			// Apple players only recognize HEVC in MP4 with the hvc1 tag
+			args = append(args, "-tag:v", "hvc1")
+		}
+	}
+
+	args = append(args, "-c", "copy")
+	if codecs.faststart {
+		args = append(args, "-movflags", "+faststart")
+	}
+	return append(args, "-map_metadata", "0", "-y", targetPath), true
+}
+
+// inspectSource probes the source of a conversion. When ffprobe is not
+// available the result is empty, which rules out remuxing.
+func (a *App) inspectSource(sourcePath string) MediaInfo {
+	info, err := probeMedia(sourcePath)
+	if err != nil {
+		a.logger.Warningf("Cannot inspect %s, converting without remux: %v", sourcePath, err)
+	}
+	return info
+}
+
+// probeMediaInternal returns the streams, chapters and tags of a media file as JSON
+func (a *App) probeMediaInternal(path string) (string, error) {
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("media file does not exist: %s", path)
+	}
+
+	info, err := probeMedia(path)
+	if err != nil {
+		a.logger.Errorf("Failed to probe media: %v", err)
+		return "", err
+	}
+
+	result, err := json.Marshal(info)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal media info: %w", err)
+	}
+	return string(result), nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// ffprobeMKV is ffprobe's output for an H.264/AAC Matroska file with SRT
+// subtitles, cover art and a chapter
+const ffprobeMKV = `{
+	"streams": [
+		{"index":0,"codec_name":"h264","codec_type":"video","profile":"High","width":1920,"height":1080,"avg_frame_rate":"30000/1001","disposition":{"attached_pic":0}},
+		{"index":1,"codec_name":"aac","codec_type":"audio","profile":"LC","bit_rate":"128000","sample_rate":"48000","channels":2,"tags":{"language":"eng"}},
+		{"index":2,"codec_name":"subrip","codec_type":"subtitle","tags":{"language":"eng"}},
+		{"index":3,"codec_name":"mjpeg","codec_type":"video","disposition":{"attached_pic":1}}
+	],
+	"chapters": [{"start_time":"0.000000","end_time":"61.500000","tags":{"title":"Intro"}}],
+	"format": {"format_name":"matroska,webm","duration":"120.500000","bit_rate":"1500000","size":"22593750","tags":{"title":"Clip"}}
+}`
+
+func TestParseFFprobeOutput(t *testing.T) {
+	info, err := parseFFprobeOutput("clip.mkv", []byte(ffprobeMKV))
+	if err != nil {
+		t.Fatalf("parseFFprobeOutput() error = %v", err)
+	}
+
+	if info.Format != "matroska,webm" || info.Duration != 120.5 || info.Bitrate != 1500000 || info.Size != 22593750 || info.Tags["title"] != "Clip" {
+		t.Errorf("unexpected format info: %+v", info)
+	}
+	if want := []Chapter{{StartTime: 0, EndTime: 61.5, Title: "Intro"}}; !reflect.DeepEqual(info.Chapters, want) {
+		t.Errorf("chapters = %+v, want %+v", info.Chapters, want)
+	}
+	if len(info.Streams) != 4 {
+		t.Fatalf("expected 4 streams, got %+v", info.Streams)
+	}
+	if video := info.Streams[0]; video.Codec != "h264" || video.Width != 1920 || video.FPS < 29.97 || video.FPS > 29.98 {
+		t.Errorf("unexpected video stream: %+v", video)
+	}
+	if audio := info.Streams[1]; audio.Bitrate != 128000 || audio.SampleRate != 48000 || audio.Channels != 2 || audio.Language != "eng" {
+		t.Errorf("unexpected audio stream: %+v", audio)
+	}
+	if !info.Streams[3].AttachedPic {
+		t.Error("cover art should be flagged as an attached picture")
+	}
+
+	if _, err := parseFFprobeOutput("clip.mkv", []byte("not json")); err == nil {
+		t.Error("expected an error for invalid output")
+	}
+}
+
+func TestRemuxArgs(t *testing.T) {
+	mkv, err := parseFFprobeOutput("in.mkv", []byte(ffprobeMKV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	streams := func(codecs ...string) MediaInfo {
+		var info MediaInfo
+		for i := 0; i+1 < len(codecs); i += 2 {
+			info.Streams = append(info.Streams, MediaStream{Type: codecs[i], Codec: codecs[i+1]})
+		}
+		return info
+	}
+
+	tests := []struct {
+		name   string
+		info   MediaInfo
+		format string
+		want   []string // nil when the source has to be re-encoded
+	}{
+		{
+			name: "MKVToMP4DropsSubRip", info: mkv, format: "mp4",
+			want: []string{"-i", "in.mkv", "-map", "0:V", "-map", "0:a?", "-c", "copy", "-movflags", "+faststart", "-map_metadata", "0", "-y", "out"},
+		},
+		{
+			name: "MKVKeepsSubtitles", info: mkv, format: "mkv",
+			want: []string{"-i", "in.mkv", "-map", "0:V", "-map", "0:a?", "-map", "0:s", "-c", "copy", "-map_metadata", "0", "-y", "out"},
+		},
+		{
+			name: "HEVCToMP4", info: streams("video", "hevc", "audio", "eac3"), format: "mov",
+			want: []string{"-i", "in.mkv", "-map", "0:V", "-map", "0:a?", "-tag:v", "hvc1", "-c", "copy", "-movflags", "+faststart", "-map_metadata", "0", "-y", "out"},
+		},
+		{
+			name: "AudioTrackToM4A", info: mkv, format: "m4a",
+			want: []string{"-i", "in.mkv", "-map", "0:a:0", "-vn", "-c", "copy", "-movflags", "+faststart", "-map_metadata", "0", "-y", "out"},
+		},
+		{name: "H264ToWebM", info: mkv, format: "webm"},
+		{name: "AACToMP3", info: mkv, format: "mp3"},
+		{name: "NoVideo", info: streams("audio", "aac"), format: "mp4"},
+		{name: "UnknownContainer", info: mkv, format: "avi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := remuxArgs(tt.info, "in.mkv", "out", tt.format)
+			if ok != (tt.want != nil) || !slices.Equal(got, tt.want) {
+				t.Errorf("remuxArgs() = %q, %v, want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertVideoRemuxes(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, sink := newTestApp(t)
+	if err := os.WriteFile("clip.mkv", []byte("video"), 0644); err != nil {
+		t.Fatalf("failed to create source file: %v", err)
+	}
+	tools.install("ffprobe"+getExecutableExtension(), fakeRun{Stdout: []string{ffprobeMKV}})
+	tools.install("ffmpeg"+getExecutableExtension(), fakeRun{Files: map[string]string{"clip.mp4": "remuxed"}})
+
+	if _, err := app.convertVideoInternal("clip.mkv", "mp4"); err != nil {
+		t.Fatalf("convertVideoInternal() error = %v", err)
+	}
+	sink.waitFor(t, "conversion-complete")
+
+	calls := tools.calls("ffmpeg" + getExecutableExtension())
+	if len(calls) != 1 || !slices.Contains(calls[0], "copy") || slices.Contains(calls[0], "libx264") {
+		t.Errorf("expected a stream copy, got %q", calls)
+	}
+}
+
+func TestProbeMediaInternal(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, _ := newTestApp(t)
+	tools.install("ffprobe"+getExecutableExtension(), fakeRun{Stderr: []string{"clip.mkv: Invalid data found when processing input"}, ExitCode: 1})
+
+	if _, err := app.probeMediaInternal("missing.mkv"); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+	if err := os.WriteFile("clip.mkv", []byte("video"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.probeMediaInternal("clip.mkv"); err == nil {
+		t.Error("expected an error when ffprobe fails")
+	}
+}
@@ -1,5 +1,10 @@
 package main
 
+import (
+	"os/exec"
+	"time"
+)
+
 // Settings struct to hold application settings
 type Settings struct {
 	ProxyMode           string `json:"proxy_mode"`             // "none", "system", "manual"
@@ -11,6 +16,236 @@ type Settings struct {
 	AutoRedirectToQueue bool   `json:"auto_redirect_to_queue"` // Automatically redirect to queue screen after adding download
 	UseJSRuntime        bool   `json:"use_js_runtime"`         // Use JavaScript runtime for YouTube and other sites that require it
 	JSRuntimeType       string `json:"js_runtime_type"`        // "deno" (recommended) or "node"
+
+	MaxConcurrentDownloads   int `json:"max_concurrent_downloads"`   // Number of queued downloads that may run in parallel
+	MaxConcurrentConversions int `json:"max_concurrent_conversions"` // Number of queued conversions that may run in parallel
+
+	// Defaults for what gets embedded into downloaded files, see EmbedOptions
+	EmbedMetadata  bool `json:"embed_metadata"`
+	EmbedThumbnail bool `json:"embed_thumbnail"`
+	EmbedChapters  bool `json:"embed_chapters"`
+	EmbedInfoJSON  bool `json:"embed_info_json"`
+
+	// yt-dlp output templates relative to the download directory, empty for the defaults
+	OutputTemplate         string `json:"output_template"`
+	PlaylistOutputTemplate string `json:"playlist_output_template"`
+	RestrictFilenames      bool   `json:"restrict_filenames"` // ASCII-only names without spaces (--restrict-filenames)
+	WindowsFilenames       bool   `json:"windows_filenames"`  // Names valid on Windows on every OS (--windows-filenames)
+
+	// Local HTTP API on the loopback interface for browser extensions and scripts
+	APIEnabled bool   `json:"api_enabled"`
+	APIPort    int    `json:"api_port"`  // 0 for the default port
+	APIToken   string `json:"api_token"` // Bearer token every request must carry
+
+	ClipboardWatch bool `json:"clipboard_watch"` // Report supported URLs copied to the clipboard
+
+	SubscriptionSyncMinutes int `json:"subscription_sync_minutes"` // How often subscriptions are checked for new items
+
+	// yt-dlp download archive of what was already downloaded, see DownloadArchive*
+	DownloadArchiveMode string `json:"download_archive_mode"`
+	ForceRedownload     bool   `json:"force_redownload"` // Ignore the archive and download everything again
+
+	ConversionPresets []ConversionPreset `json:"conversion_presets,omitempty"` // User-defined presets, the built-ins are not stored
+}
+
+// Download job statuses
+const (
+	JobStatusQueued      = "queued"
+	JobStatusRunning     = "running"
+	JobStatusPaused      = "paused"
+	JobStatusInterrupted = "interrupted" // Was queued or running when the app exited
+	JobStatusCompleted   = "completed"
+	JobStatusFailed      = "failed"
+	JobStatusCancelled   = "cancelled"
+)
+
+// DownloadJob represents a single download managed by the backend queue
+type DownloadJob struct {
+	ID              string          `json:"id"`
+	URL             string          `json:"url"`
+	Title           string          `json:"title,omitempty"`
+	FormatID        string          `json:"format_id"`
+	OutputPath      string          `json:"output_path"`
+	Playlist        bool            `json:"playlist"`             // Download the URL as a playlist
+	StartItem       int             `json:"start_item,omitempty"` // First playlist item (1-based), 0 for all
+	EndItem         int             `json:"end_item,omitempty"`   // Last playlist item, 0 for the end of the playlist
+	Items           []int           `json:"items,omitempty"`      // Playlist items (1-based) to download instead of the range
+	Filter          *PlaylistFilter `json:"filter,omitempty"`     // Which playlist items yt-dlp skips, nil for none
+	Options         DownloadOptions `json:"options"`
+	ArchivePath     string          `json:"archive_path,omitempty"`    // yt-dlp --download-archive file, empty for none
+	SubscriptionID  string          `json:"subscription_id,omitempty"` // Subscription that queued the job
+	Status          string          `json:"status"`                    // One of the JobStatus* constants
+	Progress        int             `json:"progress"`
+	DownloadedBytes int64           `json:"downloaded_bytes"`        // Bytes already on disk for the current file
+	PartialFiles    []string        `json:"partial_files,omitempty"` // .part files yt-dlp resumes from, cleared once completed
+	Error           string          `json:"error,omitempty"`
+	FilePath        string          `json:"file_path,omitempty"` // Final file (or folder for playlists) once completed
+	CreatedAt       time.Time       `json:"created_at"`
+	StartedAt       time.Time       `json:"started_at,omitempty"`
+
+	cmd               *exec.Cmd // Running yt-dlp process, nil when idle or superseded by a retry
+	stopReason        string    // "cancel" or "pause" when the job was stopped by the user
+	completionEmitted bool      // Whether a terminal event was already emitted for the current run
+	forcedArchive     string    // Archive a forced re-download merges its scratch archive into, see scratchArchivePath
+}
+
+// ConversionJob represents a single FFmpeg conversion managed by the backend queue
+type ConversionJob struct {
+	ID           string    `json:"id"`
+	SourcePath   string    `json:"source_path"`
+	TargetPath   string    `json:"target_path"`
+	TargetFormat string    `json:"target_format,omitempty"` // Format of a plain conversion, empty when Preset is set
+	Preset       string    `json:"preset,omitempty"`        // Conversion preset name, empty for a plain conversion
+	Status       string    `json:"status"`                  // One of the JobStatus* constants
+	Progress     int       `json:"progress"`
+	Error        string    `json:"error,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	StartedAt    time.Time `json:"started_at,omitempty"`
+
+	cmd               *exec.Cmd // Running FFmpeg process, nil when idle
+	cancelled         bool      // Whether the job was cancelled by the user
+	completionEmitted bool      // Whether a terminal event was already emitted
+}
+
+// DownloadOptions holds per-download settings passed alongside the format
+type DownloadOptions struct {
+	Audio     *AudioExtraction   `json:"audio,omitempty"`     // Extract audio only, nil to keep the video
+	Embed     *EmbedOptions      `json:"embed,omitempty"`     // What to embed into the file, nil to use the settings defaults
+	Subtitles *SubtitleSelection `json:"subtitles,omitempty"` // Subtitles to download, nil for none
+
+	ForceRedownload bool `json:"force_redownload,omitempty"` // Download even if the download archive lists the video
+}
+
+// PlaylistSelection chooses the entries of a playlist download
+type PlaylistSelection struct {
+	Items     []int           `json:"items,omitempty"`      // Playlist indices (1-based)
+	IDs       []string        `json:"ids,omitempty"`        // Entry IDs, looked up in the playlist
+	StartItem int             `json:"start_item,omitempty"` // Range used when neither items nor IDs are given
+	EndItem   int             `json:"end_item,omitempty"`
+	Filter    *PlaylistFilter `json:"filter,omitempty"`
+}
+
+// PlaylistFilter skips playlist entries that do not match (yt-dlp --match-filter)
+type PlaylistFilter struct {
+	MinDuration  int    `json:"min_duration,omitempty"`  // Seconds
+	MaxDuration  int    `json:"max_duration,omitempty"`  // Seconds
+	DateAfter    string `json:"date_after,omitempty"`    // Upload date YYYYMMDD, inclusive
+	DateBefore   string `json:"date_before,omitempty"`   // Upload date YYYYMMDD, inclusive
+	TitleRegex   string `json:"title_regex,omitempty"`   // Python regex the title must match, e.g. "(?i)live"
+	MaxDownloads int    `json:"max_downloads,omitempty"` // Stop after this many downloads, 0 for no limit
+}
+
+// AudioExtraction describes an audio-only download (yt-dlp -x)
+type AudioExtraction struct {
+	Codec        string `json:"codec"`         // "best", "mp3", "m4a", "aac", "opus", "vorbis", "flac", "alac" or "wav"
+	Quality      string `json:"quality"`       // VBR quality from "0" (best) to "10", or a bitrate like "192K"
+	KeepOriginal bool   `json:"keep_original"` // Keep the downloaded video next to the extracted audio
+}
+
+// EmbedOptions selects the metadata yt-dlp embeds into the downloaded file
+type EmbedOptions struct {
+	Metadata  bool `json:"metadata"`  // Title, artist, date, description and tags
+	Thumbnail bool `json:"thumbnail"` // Cover art
+	Chapters  bool `json:"chapters"`  // Chapter markers
+	InfoJSON  bool `json:"info_json"` // The full info JSON as an attachment (mkv/mka only)
+}
+
+// SubtitleSelection selects the subtitles yt-dlp downloads with a video
+type SubtitleSelection struct {
+	Languages []string `json:"languages"` // Language codes or regexes, e.g. "en", "de.*"; empty for yt-dlp's default
+	Auto      bool     `json:"auto"`      // Also use auto-generated captions
+	Format    string   `json:"format"`    // Convert to "srt", "vtt" or "ass", empty to keep the original format
+	Embed     bool     `json:"embed"`     // Embed into the video instead of writing sidecar files
+}
+
+// Subscription is a channel or playlist whose new entries are downloaded
+// automatically. Downloaded entries are recorded in a yt-dlp download archive.
+type Subscription struct {
+	ID           string          `json:"id"`
+	URL          string          `json:"url"`
+	FormatID     string          `json:"format_id"`  // Format preset, empty for yt-dlp's default
+	OutputDir    string          `json:"output_dir"` // Folder for the downloads, empty for the download directory
+	Options      DownloadOptions `json:"options"`
+	Enabled      bool            `json:"enabled"`
+	ArchivePath  string          `json:"archive_path"`  // yt-dlp --download-archive file of this subscription
+	SkipExisting bool            `json:"skip_existing"` // The first sync records the entries already published instead of downloading them
+	CreatedAt    time.Time       `json:"created_at"`
+	LastSync     time.Time       `json:"last_sync"` // Zero until the first sync
+	LastError    string          `json:"last_error,omitempty"`
+}
+
+// ConversionPreset is a named set of FFmpeg encoding settings. Leaving the
+// video codec empty makes an audio-only preset.
+type ConversionPreset struct {
+	Name         string  `json:"name"`
+	Container    string  `json:"container"`                // Output file extension, e.g. "mp4" or "flac"
+	VideoCodec   string  `json:"video_codec,omitempty"`    // FFmpeg encoder such as "libx264", "copy" to keep the stream
+	CRF          int     `json:"crf,omitempty"`            // Constant quality, used when no bitrate is set
+	VideoBitrate string  `json:"video_bitrate,omitempty"`  // e.g. "2500k"
+	Preset       string  `json:"preset,omitempty"`         // Encoder speed preset, e.g. "medium"
+	MaxHeight    int     `json:"max_height,omitempty"`     // Resolution cap in pixels, 0 to keep the source resolution
+	FPS          float64 `json:"fps,omitempty"`            // Frame rate, 0 to keep the source frame rate
+	AudioCodec   string  `json:"audio_codec,omitempty"`    // FFmpeg encoder, "none" to drop the audio
+	AudioBitrate string  `json:"audio_bitrate,omitempty"`  // e.g. "128k"
+	SampleRate   int     `json:"sample_rate,omitempty"`    // Hz, 0 to keep
+	Channels     int     `json:"channels,omitempty"`       // 0 to keep
+	VideoFilters string  `json:"video_filters,omitempty"`  // Extra -vf filters, applied after scaling
+	AudioFilters string  `json:"audio_filters,omitempty"`  // Extra -af filters
+	TargetSizeMB float64 `json:"target_size_mb,omitempty"` // Pick the video bitrate so the file stays below this size
+	BuiltIn      bool    `json:"built_in"`
+}
+
+// MediaInfo describes a local media file as reported by ffprobe
+type MediaInfo struct {
+	Path     string            `json:"path"`
+	Format   string            `json:"format"`   // Container, e.g. "matroska,webm"
+	Duration float64           `json:"duration"` // Seconds
+	Bitrate  int64             `json:"bitrate"`  // bit/s
+	Size     int64             `json:"size"`
+	Streams  []MediaStream     `json:"streams"`
+	Chapters []Chapter         `json:"chapters"`
+	Tags     map[string]string `json:"tags,omitempty"`
+}
+
+// MediaStream is one stream of a media file
+type MediaStream struct {
+	Index       int               `json:"index"`
+	Type        string            `json:"type"` // "video", "audio", "subtitle", "data" or "attachment"
+	Codec       string            `json:"codec"`
+	Profile     string            `json:"profile,omitempty"`
+	Bitrate     int64             `json:"bitrate,omitempty"`
+	Width       int               `json:"width,omitempty"`
+	Height      int               `json:"height,omitempty"`
+	FPS         float64           `json:"fps,omitempty"`
+	SampleRate  int               `json:"sample_rate,omitempty"`
+	Channels    int               `json:"channels,omitempty"`
+	Language    string            `json:"language,omitempty"`
+	AttachedPic bool              `json:"attached_pic,omitempty"` // Cover art stored as a video stream
+	Tags        map[string]string `json:"tags,omitempty"`
+}
+
+// HistoryItem represents a finished, failed or cancelled download
+type HistoryItem struct {
+	ID              string    `json:"id"`
+	JobID           string    `json:"job_id"`
+	URL             string    `json:"url"`
+	Title           string    `json:"title"`
+	FormatID        string    `json:"format_id"`
+	FilePath        string    `json:"file_path"`
+	FileSize        int64     `json:"file_size"`
+	DownloadSeconds float64   `json:"download_seconds"` // Time spent downloading, not the length of the media
+	Status          string    `json:"status"`           // "completed", "failed" or "cancelled"
+	Error           string    `json:"error,omitempty"`
+	FinishedAt      time.Time `json:"finished_at"`
+}
+
+// HistoryFilter narrows down the results of QueryHistory
+type HistoryFilter struct {
+	Text   string `json:"text"`   // Case-insensitive match against title, URL and file path
+	Status string `json:"status"` // Only items with this status, empty for all
+	From   string `json:"from"`   // Earliest finish date, "2006-01-02" or RFC 3339
+	To     string `json:"to"`     // Latest finish date (inclusive), "2006-01-02" or RFC 3339
+	Limit  int    `json:"limit"`  // Maximum number of items, 0 for no limit
 }
 
 // VideoInfo represents the video metadata from yt-dlp
@@ -24,6 +259,38 @@ type VideoInfo struct {
 	Description string      `json:"description"`
 	Uploader    string      `json:"uploader"`
 	ViewCount   interface{} `json:"view_count"` // Can be int or null
+	Channel     string      `json:"channel"`
+	UploadDate  string      `json:"upload_date"` // YYYYMMDD
+	Tags        []string    `json:"tags"`
+	Chapters    []Chapter   `json:"chapters"`
+
+	// Available subtitles keyed by language code
+	Subtitles         map[string][]SubtitleTrack `json:"subtitles"`
+	AutomaticCaptions map[string][]SubtitleTrack `json:"automatic_captions"`
+
+	// Summary of Subtitles and AutomaticCaptions added by the analyzer
+	AvailableSubtitles []SubtitleLanguage `json:"available_subtitles,omitempty"`
+}
+
+// SubtitleLanguage summarizes the subtitle formats available for one language
+type SubtitleLanguage struct {
+	Code    string   `json:"code"`
+	Name    string   `json:"name"`
+	Formats []string `json:"formats"`
+	Auto    bool     `json:"auto"` // Auto-generated captions
+}
+
+// SubtitleTrack is one available format of a subtitle language
+type SubtitleTrack struct {
+	Ext  string `json:"ext"`  // e.g. "vtt", "srv3", "json3"
+	Name string `json:"name"` // Human readable language name, may be empty
+}
+
+// Chapter represents a chapter marker of a video
+type Chapter struct {
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+	Title     string  `json:"title"`
 }
 
 // Format represents a downloadable format
@@ -49,11 +316,20 @@ type PlaylistInfo struct {
 	Entries     []PlaylistEntry `json:"entries"`
 }
 
-// PlaylistEntry represents a single item in a playlist
+// PlaylistEntry represents a single item in a playlist. A flat analysis often
+// leaves the metadata fields empty; a deep analysis fills them in.
 type PlaylistEntry struct {
-	ID        string  `json:"id"`
-	Title     string  `json:"title"`
-	Thumbnail string  `json:"thumbnail"`
-	URL       string  `json:"url"`
-	Duration  float64 `json:"duration"`
+	ID           string  `json:"id"`
+	Title        string  `json:"title"`
+	Thumbnail    string  `json:"thumbnail"`
+	URL          string  `json:"url"`
+	Duration     float64 `json:"duration"`
+	Uploader     string  `json:"uploader"`
+	UploadDate   string  `json:"upload_date"`  // YYYYMMDD
+	ViewCount    int64   `json:"view_count"`   // 0 when unknown
+	Availability string  `json:"availability"` // yt-dlp availability: "public", "unlisted", "private", "needs_auth", ...
+	Unavailable  bool    `json:"unavailable"`  // Private, deleted or otherwise not downloadable
+	Extractor    string  `json:"extractor"`    // yt-dlp extractor key, e.g. "Youtube"
+
+	AlreadyDownloaded bool `json:"already_downloaded"` // Listed in the download archive
 }
@@ -9,8 +9,6 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
-
-	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // isNodeAvailable checks if node.js is available in the system
@@ -124,7 +122,7 @@ func (a *App) downloadNode() error {
 		return fmt.Errorf("failed to create bin directory: %w", err)
 	}
 
-	wailsRuntime.LogInfof(a.ctx, "Downloading node.js from: %s", downloadURL)
+	a.logger.Infof("Downloading node.js from: %s", downloadURL)
 
 	// Download the archive
 	resp, err := http.Get(downloadURL)
@@ -266,61 +264,82 @@ func (a *App) getLatestNodeVersion() (string, error) {
 
 // installNode installs the latest version of node.js
 func (a *App) installNode() error {
-	wailsRuntime.LogInfo(a.ctx, "Starting node.js installation...")
+	a.logger.Info("Starting node.js installation...")
 
-	wailsRuntime.EventsEmit(a.ctx, "node-install-start", nil)
+	a.events.Emit("node-install-start", nil)
 
 	// Download and install node
 	err := a.downloadNode()
 	if err != nil {
-		wailsRuntime.LogErrorf(a.ctx, "Failed to install node.js: %v", err)
-		wailsRuntime.EventsEmit(a.ctx, "node-install-error", err.Error())
+		a.logger.Errorf("Failed to install node.js: %v", err)
+		a.events.Emit("node-install-error", err.Error())
 		return fmt.Errorf("failed to install node.js: %w", err)
 	}
 
-	wailsRuntime.LogInfo(a.ctx, "node.js installed successfully")
-	wailsRuntime.EventsEmit(a.ctx, "node-install-complete", nil)
+	a.logger.Info("node.js installed successfully")
+	a.events.Emit("node-install-complete", nil)
 	return nil
 }
 
 // updateNode updates node.js to the latest version
 func (a *App) updateNode() error {
-	wailsRuntime.LogInfo(a.ctx, "Starting node.js update...")
+	a.logger.Info("Starting node.js update...")
 
-	wailsRuntime.EventsEmit(a.ctx, "node-update-start", nil)
+	a.events.Emit("node-update-start", nil)
 
 	// Download and install latest node
 	err := a.downloadNode()
 	if err != nil {
-		wailsRuntime.LogErrorf(a.ctx, "Failed to update node.js: %v", err)
-		wailsRuntime.EventsEmit(a.ctx, "node-update-error", err.Error())
+		a.logger.Errorf("Failed to update node.js: %v", err)
+		a.events.Emit("node-update-error", err.Error())
 		return fmt.Errorf("failed to update node.js: %w", err)
 	}
 
-	wailsRuntime.LogInfo(a.ctx, "node.js updated successfully")
-	wailsRuntime.EventsEmit(a.ctx, "node-update-complete", nil)
+	a.logger.Info("node.js updated successfully")
+	a.events.Emit("node-update-complete", nil)
 	return nil
 }
 
-// getJSRuntimeCommand returns the appropriate JS runtime command based on settings
-func (a *App) getJSRuntimeCommand() string {
-	if !a.settings.UseJSRuntime {
+// jsRuntimeFor returns the --js-runtimes value needed for url, installing the
+// configured runtime if it is missing. It returns "" when no runtime is needed or available.
+func (a *App) jsRuntimeFor(url string) string {
+	settings := a.currentSettings()
+	if !settings.UseJSRuntime && !a.isYouTubeURL(url) {
 		return ""
 	}
 
-	if a.settings.JSRuntimeType == "node" {
-		// Check if node is available
+	denoRuntime := "deno:" + filepath.Join("./bin", a.getDenoBinaryName())
+
+	if settings.JSRuntimeType == "node" {
 		if a.isNodeAvailable() {
 			return "node"
 		}
+		a.logger.Infof("Node.js not found, attempting to download...")
+		err := a.installNode()
+		if err == nil {
+			return "node"
+		}
+		a.logger.Errorf("Failed to download node.js: %v", err)
 		// Fallback to deno if node is not available
-		return "deno:" + filepath.Join("./bin", a.getDenoBinaryName())
+		if a.isDenoAvailable() {
+			return denoRuntime
+		}
+		return ""
 	}
 
 	// Default to deno
 	if a.isDenoAvailable() {
-		return "deno:" + filepath.Join("./bin", a.getDenoBinaryName())
+		return denoRuntime
+	}
+	a.logger.Infof("Deno not found, attempting to download...")
+	err := a.downloadDenoWithProgress()
+	if err == nil {
+		return denoRuntime
+	}
+	a.logger.Errorf("Failed to download deno: %v", err)
+	// Fallback to node if deno is not available
+	if a.isNodeAvailable() {
+		return "node"
 	}
-
 	return ""
 }
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Default yt-dlp output templates, relative to the download directory
+const (
+	defaultOutputTemplate         = "%(title)s.%(ext)s"
+	defaultPlaylistOutputTemplate = "%(playlist_title)s/%(playlist_index)s - %(title)s.%(ext)s"
+)
+
+// outputTemplateNA is what yt-dlp writes for fields that are not available
+const outputTemplateNA = "NA"
+
+// outputTemplateField matches a yt-dlp template field such as %(title)s or
+// %(playlist_index)03d, and the %% escape
+var outputTemplateField = regexp.MustCompile(`%%|%\(([^)]*)\)([-#0+ ]*\d*(?:\.\d+)?)([diouxXeEfFgGcrsaqjlBUDS])`)
+
+// outputTemplate returns the configured template for single videos or playlists
+func (s Settings) outputTemplate(playlist bool) string {
+	if playlist {
+		if s.PlaylistOutputTemplate != "" {
+			return s.PlaylistOutputTemplate
+		}
+		return defaultPlaylistOutputTemplate
+	}
+	if s.OutputTemplate != "" {
+		return s.OutputTemplate
+	}
+	return defaultOutputTemplate
+}
+
+// filenameArgs returns the yt-dlp arguments that control filename sanitization
+func (s Settings) filenameArgs() []string {
+	var args []string
+	if s.RestrictFilenames {
+		args = append(args, "--restrict-filenames")
+	}
+	if s.WindowsFilenames {
+		args = append(args, "--windows-filenames")
+	}
+	return args
+}
+
+// validateOutputTemplate checks that a template stays inside the download
+// directory and produces files with an extension
+func validateOutputTemplate(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("output template cannot be empty")
+	}
+	if filepath.IsAbs(template) || strings.HasPrefix(template, "/") || strings.HasPrefix(template, "\\") {
+		return fmt.Errorf("output template must be relative to the download directory: %s", template)
+	}
+	for _, part := range strings.FieldsFunc(template, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == ".." {
+			return fmt.Errorf("output template must not leave the download directory: %s", template)
+		}
+	}
+	if !strings.Contains(template, "%(ext)s") {
+		return fmt.Errorf("output template must contain %%(ext)s: %s", template)
+	}
+	return nil
+}
+
+// playlistFolder returns the folder a playlist was downloaded to: the deepest
+// directory that holds every file yt-dlp reported, or the fixed part of the
+// output template when no file was reported
+func playlistFolder(files []string, outputPath string) string {
+	if len(files) == 0 {
+		return templateBaseDir(outputPath)
+	}
+
+	folder := filepath.Dir(filepath.Clean(files[0]))
+	for _, file := range files[1:] {
+		dir := filepath.Dir(filepath.Clean(file))
+		for !isWithinDir(folder, dir) {
+			parent := filepath.Dir(folder)
+			if parent == folder {
+				break
+			}
+			folder = parent
+		}
+	}
+	return folder
+}
+
+// isWithinDir reports whether path is dir or lies below it
+func isWithinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// renderOutputTemplate fills a yt-dlp output template from analyzed video info
+// the way yt-dlp would. It covers plain fields, alternatives (a,b), defaults
+// (a|x), object traversal (a.b) and date formatting (a>%Y), which is enough for
+// a preview; the actual file name is always chosen by yt-dlp.
+func renderOutputTemplate(template string, info map[string]interface{}, restrict, windows bool) string {
+	rendered := outputTemplateField.ReplaceAllStringFunc(template, func(match string) string {
+		if match == "%%" {
+			return "%"
+		}
+		groups := outputTemplateField.FindStringSubmatch(match)
+		key, flags, conversion := groups[1], groups[2], groups[3]
+
+		value, ok := lookupTemplateValue(info, key)
+		if !ok {
+			return sanitizeFilenameField(value, restrict)
+		}
+		return sanitizeFilenameField(formatTemplateValue(value, flags, conversion), restrict)
+	})
+
+	if windows {
+		parts := strings.Split(rendered, "/")
+		for i, part := range parts {
+			parts[i] = strings.TrimRight(part, ". ")
+		}
+		rendered = strings.Join(parts, "/")
+	}
+	return rendered
+}
+
+// lookupTemplateValue resolves the key of a template field. When no
+// alternative has a value it returns the default (or "NA") and false.
+func lookupTemplateValue(info map[string]interface{}, key string) (interface{}, bool) {
+	fallback := outputTemplateNA
+	if idx := strings.Index(key, "|"); idx >= 0 {
+		key, fallback = key[:idx], key[idx+1:]
+	}
+
+	for _, alternative := range strings.Split(key, ",") {
+		name, dateFormat := alternative, ""
+		if idx := strings.Index(alternative, ">"); idx >= 0 {
+			name, dateFormat = alternative[:idx], alternative[idx+1:]
+		}
+
+		var value interface{} = info
+		for _, field := range strings.Split(strings.TrimSpace(name), ".") {
+			object, isObject := value.(map[string]interface{})
+			if !isObject {
+				value = nil
+				break
+			}
+			value = object[field]
+		}
+		if value == nil || value == "" {
+			continue
+		}
+
+		if dateFormat != "" {
+			if date, ok := templateDate(value); ok {
+				return formatStrftime(date, dateFormat), true
+			}
+		}
+		return value, true
+	}
+	return fallback, false
+}
+
+// formatTemplateValue applies a printf-style conversion to a field value
+func formatTemplateValue(value interface{}, flags, conversion string) string {
+	number, isNumber := value.(float64)
+	if !isNumber {
+		if s, isString := value.(string); isString && strings.ContainsRune("dioxXeEfFgG", rune(conversion[0])) {
+			if parsed, err := strconv.ParseFloat(s, 64); err == nil {
+				number, isNumber = parsed, true
+			}
+		}
+	}
+
+	switch conversion {
+	case "d", "i", "u":
+		if isNumber {
+			return fmt.Sprintf("%"+flags+"d", int64(number))
+		}
+	case "o", "x", "X":
+		if isNumber {
+			return fmt.Sprintf("%"+flags+conversion, int64(number))
+		}
+	case "e", "E", "f", "F", "g", "G":
+		if isNumber {
+			return fmt.Sprintf("%"+flags+conversion, number)
+		}
+	}
+
+	var text string
+	switch v := value.(type) {
+	case string:
+		text = v
+	case float64:
+		text = strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		text = strconv.FormatBool(v)
+	default:
+		data, _ := json.Marshal(v)
+		text = string(data)
+	}
+	return fmt.Sprintf("%"+flags+"s", text)
+}
+
+// templateDate interprets a YYYYMMDD date or a Unix timestamp
+func templateDate(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case string:
+		date, err := time.Parse("20060102", v)
+		return date, err == nil
+	case float64:
+		return time.Unix(int64(v), 0).UTC(), true
+	}
+	return time.Time{}, false
+}
+
+// formatStrftime formats a date with the strftime directives commonly used in templates
+func formatStrftime(date time.Time, format string) string {
+	replacer := strings.NewReplacer(
+		"%Y", date.Format("2006"),
+		"%y", date.Format("06"),
+		"%m", date.Format("01"),
+		"%d", date.Format("02"),
+		"%H", date.Format("15"),
+		"%M", date.Format("04"),
+		"%S", date.Format("05"),
+		"%B", date.Format("January"),
+		"%b", date.Format("Jan"),
+		"%%", "%",
+	)
+	return replacer.Replace(format)
+}
+
+// sanitizeFilenameField makes a field value safe for use in a file name,
+// following yt-dlp's sanitize_filename
+func sanitizeFilenameField(value interface{}, restrict bool) string {
+	text := fmt.Sprint(value)
+
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r < 32 || r == 127:
+			continue
+		case restrict && (r == '?' || r == '"'):
+			continue
+		case restrict && r == ':':
+			b.WriteString("_-")
+		case restrict && (strings.ContainsRune(`\/|*<>!&'()[]{}$;`+"`"+`^,#`, r) || unicode.IsSpace(r) || r > 127):
+			b.WriteRune('_')
+		case !restrict && r == '/':
+			b.WriteRune('⧸')
+		case !restrict && r == '\\':
+			b.WriteRune('⧹')
+		case !restrict && strings.ContainsRune(`"*:<>?|`, r):
+			b.WriteRune(r + 0xfee0) // Fullwidth variant of the character
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	result := b.String()
+	if restrict {
+		for strings.Contains(result, "__") {
+			result = strings.ReplaceAll(result, "__", "_")
+		}
+		result = strings.Trim(result, "_")
+		if result == "" {
+			result = "_"
+		}
+	}
+	return result
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderOutputTemplate(t *testing.T) {
+	info := map[string]interface{}{
+		"id":             "dQw4w9WgXcQ",
+		"title":          "Song: Live / Acoustic?",
+		"uploader":       "Some Artist",
+		"upload_date":    "20240131",
+		"playlist_index": float64(7),
+		"duration":       float64(212.5),
+		"ext":            "webm",
+		"channel":        "",
+		"requested":      map[string]interface{}{"format_id": "251"},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		restrict bool
+		windows  bool
+		expected string
+	}{
+		{"Default", defaultOutputTemplate, false, false, "Song： Live ⧸ Acoustic？.webm"},
+		{"Folders", "%(uploader)s/%(upload_date)s - %(title)s [%(id)s].%(ext)s", false, false,
+			"Some Artist/20240131 - Song： Live ⧸ Acoustic？ [dQw4w9WgXcQ].webm"},
+		{"Restricted", "%(uploader)s/%(title)s.%(ext)s", true, false, "Some_Artist/Song_-_Live_Acoustic.webm"},
+		{"PaddedIndex", "%(playlist_index)03d - %(title)s.%(ext)s", true, false, "007 - Song_-_Live_Acoustic.webm"},
+		{"MissingField", "%(series)s.%(ext)s", false, false, "NA.webm"},
+		{"Default value", "%(series|Unknown)s.%(ext)s", false, false, "Unknown.webm"},
+		{"Alternatives skip empty values", "%(channel,uploader)s.%(ext)s", false, false, "Some Artist.webm"},
+		{"DateFormat", "%(upload_date>%Y-%m-%d)s.%(ext)s", false, false, "2024-01-31.webm"},
+		{"Traversal", "%(id)s.f%(requested.format_id)s.%(ext)s", false, false, "dQw4w9WgXcQ.f251.webm"},
+		{"Number as string", "%(duration)s %%.%(ext)s", false, false, "212.5 %.webm"},
+		{"WindowsTrimsTrailingDots", "%(uploader)s./%(id)s.%(ext)s", false, true, "Some Artist/dQw4w9WgXcQ.webm"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderOutputTemplate(tt.template, info, tt.restrict, tt.windows); got != tt.expected {
+				t.Errorf("renderOutputTemplate(%q) = %q, want %q", tt.template, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestValidateOutputTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		valid    bool
+	}{
+		{defaultOutputTemplate, true},
+		{defaultPlaylistOutputTemplate, true},
+		{"%(uploader)s/%(upload_date)s - %(title)s [%(id)s].%(ext)s", true},
+		{"", false},
+		{"%(title)s", false},
+		{"/tmp/%(title)s.%(ext)s", false},
+		{"..\\%(title)s.%(ext)s", false},
+		{"videos/../../%(title)s.%(ext)s", false},
+	}
+
+	for _, tt := range tests {
+		err := validateOutputTemplate(tt.template)
+		if (err == nil) != tt.valid {
+			t.Errorf("validateOutputTemplate(%q) error = %v, want valid %v", tt.template, err, tt.valid)
+		}
+	}
+}
+
+func TestPlaylistFolder(t *testing.T) {
+	template := filepath.Join("downloads", defaultPlaylistOutputTemplate)
+	tests := []struct {
+		name  string
+		files []string
+		want  string
+	}{
+		{
+			name:  "PlaylistFolder",
+			files: []string{filepath.Join("downloads", "Mix", "1 - a.mp4"), filepath.Join("downloads", "Mix", "2 - b.webm")},
+			want:  filepath.Join("downloads", "Mix"),
+		},
+		{
+			name:  "PerUploaderFolders",
+			files: []string{filepath.Join("downloads", "Alice", "a.mp4"), filepath.Join("downloads", "Bob", "Live", "b.mp4")},
+			want:  "downloads",
+		},
+		{name: "NothingReported", want: "downloads"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := playlistFolder(tt.files, template); got != tt.want {
+				t.Errorf("playlistFolder() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSettingsOutputTemplate(t *testing.T) {
+	var settings Settings
+	if got := settings.outputTemplate(false); got != defaultOutputTemplate {
+		t.Errorf("outputTemplate(false) = %q, want the default", got)
+	}
+	if got := settings.outputTemplate(true); got != defaultPlaylistOutputTemplate {
+		t.Errorf("outputTemplate(true) = %q, want the default", got)
+	}
+
+	settings.PlaylistOutputTemplate = "%(playlist)s/%(playlist_index)s.%(ext)s"
+	if got := settings.outputTemplate(true); got != settings.PlaylistOutputTemplate {
+		t.Errorf("outputTemplate(true) = %q, want %q", got, settings.PlaylistOutputTemplate)
+	}
+}
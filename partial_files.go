@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// partialFileRemoveAttempts and partialFileRemoveDelay bound how long removing
+// a partial file is retried, since a killed process may still hold it open on Windows
+const (
+	partialFileRemoveAttempts = 10
+	partialFileRemoveDelay    = 100 * time.Millisecond
+)
+
+// partialFileSet returns the files yt-dlp keeps for an unfinished download:
+// the .part file, its fragments and the .ytdl resume state of fragmented streams
+func partialFileSet(tmpFile string) []string {
+	files := []string{tmpFile, strings.TrimSuffix(tmpFile, ".part") + ".ytdl"}
+	if fragments, err := filepath.Glob(escapeGlob(tmpFile) + "-Frag*"); err == nil {
+		files = append(files, fragments...)
+	}
+	return files
+}
+
+// escapeGlob quotes the characters filepath.Glob treats as patterns
+func escapeGlob(path string) string {
+	return strings.NewReplacer(`*`, `[*]`, `?`, `[?]`, `[`, `[[]`).Replace(path)
+}
+
+// partialFileBytes returns how many bytes of the given unfinished downloads are on disk
+func partialFileBytes(tmpFiles []string) int64 {
+	var total int64
+	for _, tmpFile := range tmpFiles {
+		for _, path := range partialFileSet(tmpFile) {
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				total += info.Size()
+			}
+		}
+	}
+	return total
+}
+
+// removePartialFiles deletes the given unfinished downloads and returns how many
+// bytes were freed
+func removePartialFiles(tmpFiles []string) (int64, error) {
+	var freed int64
+	var errs []error
+	for _, tmpFile := range tmpFiles {
+		for _, path := range partialFileSet(tmpFile) {
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() {
+				continue
+			}
+			if err := removeWithRetry(path); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			freed += info.Size()
+		}
+	}
+	return freed, errors.Join(errs...)
+}
+
+// removeWithRetry removes a file, retrying briefly while it is still locked
+func removeWithRetry(path string) error {
+	var err error
+	for attempt := 0; attempt < partialFileRemoveAttempts; attempt++ {
+		if err = os.Remove(path); err == nil || errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		time.Sleep(partialFileRemoveDelay)
+	}
+	return fmt.Errorf("failed to remove partial file: %w", err)
+}
+
+// addJobPartialFile records a .part file yt-dlp is writing for a job
+func (a *App) addJobPartialFile(job *DownloadJob, tmpFile string) {
+	if absPath, err := filepath.Abs(tmpFile); err == nil {
+		tmpFile = absPath
+	}
+
+	a.queue.mu.Lock()
+	defer a.queue.mu.Unlock()
+	if !slices.Contains(job.PartialFiles, tmpFile) {
+		job.PartialFiles = append(job.PartialFiles, tmpFile)
+	}
+}
+
+// updateJobPartialBytes measures the partial files of a job, records the size
+// as its downloaded bytes and returns it
+func (a *App) updateJobPartialBytes(job *DownloadJob) int64 {
+	a.queue.mu.Lock()
+	files := slices.Clone(job.PartialFiles)
+	a.queue.mu.Unlock()
+
+	downloaded := partialFileBytes(files)
+
+	a.queue.mu.Lock()
+	job.DownloadedBytes = downloaded
+	a.queue.mu.Unlock()
+	return downloaded
+}
+
+// discardJobInternal cancels a job unless it already ended and deletes the
+// partial files it left behind
+func (a *App) discardJobInternal(id string) error {
+	a.queue.mu.Lock()
+	job, exists := a.queue.jobs[id]
+	if !exists {
+		a.queue.mu.Unlock()
+		return fmt.Errorf("%w: %s", errJobNotFound, id)
+	}
+	status := job.Status
+	a.queue.mu.Unlock()
+
+	switch status {
+	case JobStatusCompleted:
+		return fmt.Errorf("download job %s is already completed", id)
+	case JobStatusCancelled, JobStatusFailed:
+	default:
+		if err := a.stopJobInternal(id, "cancel"); err != nil {
+			return err
+		}
+	}
+
+	a.queue.mu.Lock()
+	files := slices.Clone(job.PartialFiles)
+	a.queue.mu.Unlock()
+
+	freed, err := removePartialFiles(files)
+	if err != nil {
+		a.logger.Errorf("Failed to discard partial files of download job %s: %v", id, err)
+		return err
+	}
+
+	a.queue.mu.Lock()
+	job.PartialFiles = nil
+	job.DownloadedBytes = 0
+	a.queue.mu.Unlock()
+	a.saveQueueState()
+
+	a.logger.Infof("Discarded partial files of download job %s (%d bytes)", id, freed)
+	a.events.Emit("download-discarded", map[string]interface{}{
+		"id":          id,
+		"freed_bytes": freed,
+	})
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// partialProgressLine reports downloads/My_Clip.mp4.part as the file being written
+const partialProgressLine = `[godlp-progress] {"progress":{"status":"downloading","downloaded_bytes":7,"total_bytes":1024,"tmpfilename":"downloads/My_Clip.mp4.part"},"playlist_index":null,"playlist_count":null}`
+
+// startPartialDownload starts a video job that hangs after writing seven bytes
+// of a .part file and returns its ID once progress was reported
+func startPartialDownload(t *testing.T, tools *fakeToolHarness, app *App, sink *recordingSink) string {
+	t.Helper()
+	tools.install(app.getYtDlpBinaryName(), fakeRun{
+		Files:  map[string]string{filepath.Join("downloads", "My_Clip.mp4.part"): "partial"},
+		Stdout: []string{partialProgressLine},
+		Hang:   true,
+	})
+
+	if _, err := app.downloadVideoInternal(fakeVideoURL, "best", filepath.Join("downloads", defaultOutputTemplate), ""); err != nil {
+		t.Fatalf("downloadVideoInternal() error = %v", err)
+	}
+	progress := sink.waitFor(t, "download-progress")
+	return progress.Data[0].(map[string]interface{})["id"].(string)
+}
+
+func TestPartialFileBytes(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"clip.mp4.part":       "12345",
+		"clip.mp4.part-Frag1": "123",
+		"clip.mp4.ytdl":       "12",
+		"other.mp4.part":      "ignored",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		files []string
+		want  int64
+	}{
+		{name: "WithFragmentsAndState", files: []string{filepath.Join(dir, "clip.mp4.part")}, want: 10},
+		{name: "Missing", files: []string{filepath.Join(dir, "missing.mp4.part")}, want: 0},
+		{name: "None", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := partialFileBytes(tt.files); got != tt.want {
+				t.Errorf("partialFileBytes() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPauseAndResumeDownload(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	useDownloadDir(t, "downloads")
+	app, sink := newTestApp(t)
+	id := startPartialDownload(t, tools, app, sink)
+
+	if err := app.stopJobInternal(id, "pause"); err != nil {
+		t.Fatalf("stopJobInternal() error = %v", err)
+	}
+	paused := sink.waitFor(t, "download-cancelled").Data[0].(map[string]interface{})
+	if paused["downloaded_bytes"] != int64(7) {
+		t.Errorf("downloaded_bytes on pause = %v, want 7", paused["downloaded_bytes"])
+	}
+	if _, err := os.Stat(filepath.Join("downloads", "My_Clip.mp4.part")); err != nil {
+		t.Errorf("pausing should keep the partial file: %v", err)
+	}
+
+	if err := app.resumeDownloadInternal(id); err != nil {
+		t.Fatalf("resumeDownloadInternal() error = %v", err)
+	}
+	resumed := sink.waitFor(t, "download-resumed").Data[0].(map[string]interface{})
+	if resumed["id"] != id || resumed["downloaded_bytes"] != int64(7) {
+		t.Errorf("unexpected download-resumed data: %v", resumed)
+	}
+
+	// The restarted process gets the same arguments, so --continue picks up the .part file
+	var calls [][]string
+	for deadline := time.Now().Add(5 * time.Second); len(calls) < 2 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		calls = tools.calls(app.getYtDlpBinaryName())
+	}
+	if len(calls) != 2 || !slices.Equal(calls[0], calls[1]) || !slices.Contains(calls[1], "--continue") {
+		t.Errorf("resumed download should repeat the original arguments, got %q", calls)
+	}
+	app.stopJobInternal(id, "cancel")
+}
+
+func TestDiscardJobRemovesPartialFiles(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	useDownloadDir(t, "downloads")
+	app, sink := newTestApp(t)
+	id := startPartialDownload(t, tools, app, sink)
+
+	if err := app.discardJobInternal(id); err != nil {
+		t.Fatalf("discardJobInternal() error = %v", err)
+	}
+	sink.waitFor(t, "download-cancelled")
+	discarded := sink.waitFor(t, "download-discarded").Data[0].(map[string]interface{})
+	if discarded["freed_bytes"] != int64(7) {
+		t.Errorf("freed_bytes = %v, want 7", discarded["freed_bytes"])
+	}
+	if _, err := os.Stat(filepath.Join("downloads", "My_Clip.mp4.part")); !os.IsNotExist(err) {
+		t.Errorf("the partial file should be removed, stat error = %v", err)
+	}
+	if got := jobStatus(app, id); got != JobStatusCancelled {
+		t.Errorf("job status = %s, want %s", got, JobStatusCancelled)
+	}
+
+	if err := app.discardJobInternal("missing"); err == nil {
+		t.Error("expected an error for an unknown job")
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// deepAnalysisConcurrency bounds how many playlist entries are analyzed at once
+const deepAnalysisConcurrency = 4
+
+// unavailableEntryTitles are the placeholder titles flat playlists give entries
+// that cannot be watched
+var unavailableEntryTitles = map[string]bool{
+	"[Private video]":     true,
+	"[Deleted video]":     true,
+	"[Unavailable video]": true,
+}
+
+// restrictedAvailability are the yt-dlp availability values that keep an entry
+// from being downloaded without extra access
+var restrictedAvailability = map[string]bool{
+	"private":         true,
+	"premium_only":    true,
+	"subscriber_only": true,
+	"needs_auth":      true,
+}
+
+// playlistEntryFromInfo builds a playlist entry from a yt-dlp info dict, either
+// a flat playlist entry or the full info of a single video
+func playlistEntryFromInfo(info map[string]interface{}) PlaylistEntry {
+	entry := PlaylistEntry{
+		ID:           getStringValue(info["id"]),
+		Title:        getStringValue(info["title"]),
+		Thumbnail:    getStringValue(info["thumbnail"]),
+		URL:          getStringValue(info["url"]),
+		Duration:     getFloatValue(info["duration"]),
+		Uploader:     getStringValue(info["uploader"]),
+		UploadDate:   getStringValue(info["upload_date"]),
+		ViewCount:    int64(getFloatValue(info["view_count"])),
+		Availability: getStringValue(info["availability"]),
+		Extractor:    getStringValue(info["extractor_key"]),
+	}
+
+	// Full video info has the page URL in webpage_url and "url" points at a format
+	if pageURL := getStringValue(info["webpage_url"]); pageURL != "" {
+		entry.URL = pageURL
+	}
+	if entry.Uploader == "" {
+		entry.Uploader = getStringValue(info["channel"])
+	}
+	// Flat entries name the extractor in ie_key
+	if entry.Extractor == "" {
+		entry.Extractor = getStringValue(info["ie_key"])
+	}
+	// Flat entries usually only list thumbnails, the best one last
+	if entry.Thumbnail == "" {
+		if thumbnails, ok := info["thumbnails"].([]interface{}); ok && len(thumbnails) > 0 {
+			if thumbnail, ok := thumbnails[len(thumbnails)-1].(map[string]interface{}); ok {
+				entry.Thumbnail = getStringValue(thumbnail["url"])
+			}
+		}
+	}
+
+	entry.Unavailable = unavailableEntryTitles[entry.Title] || restrictedAvailability[entry.Availability]
+	return entry
+}
+
+// analyzePlaylistEntry fetches the full metadata of a flat playlist entry.
+// On failure the entry is returned marked as unavailable.
+func (a *App) analyzePlaylistEntry(entry PlaylistEntry) (PlaylistEntry, error) {
+	if entry.URL == "" {
+		entry.Unavailable = true
+		return entry, fmt.Errorf("playlist entry %s has no URL", entry.ID)
+	}
+
+	inv := a.newYtDlpInvocation(entry.URL)
+	inv.Flags = []string{"--dump-json", "--no-playlist", "--skip-download", "--no-warnings"}
+
+	output, err := a.ytDlpCommand(inv).Output()
+	if err != nil {
+		if exitError, ok := err.(*exec.ExitError); ok {
+			stderrStr := string(exitError.Stderr)
+
+			// Try fallback without cookies if needed
+			if isCookiesRelatedError(stderrStr) && inv.UsesCookies() {
+				output, err = a.ytDlpCommand(inv.NoCookies()).Output()
+			} else {
+				err = fmt.Errorf("%w: %s", err, strings.TrimSpace(stderrStr))
+			}
+		}
+
+		if err != nil {
+			entry.Unavailable = true
+			return entry, fmt.Errorf("failed to analyze playlist entry %s: %w", entry.URL, err)
+		}
+	}
+
+	var info map[string]interface{}
+	if err := json.Unmarshal(output, &info); err != nil {
+		return entry, fmt.Errorf("failed to parse playlist entry info JSON: %w", err)
+	}
+
+	full := playlistEntryFromInfo(info)
+	if full.ID == "" {
+		full.ID = entry.ID
+	}
+	if full.URL == "" {
+		full.URL = entry.URL
+	}
+	full.AlreadyDownloaded = entry.AlreadyDownloaded
+	return full, nil
+}
+
+// analyzePlaylistEntries fetches the full metadata of every entry, at most
+// deepAnalysisConcurrency at a time, and emits a playlist-entry-analyzed event
+// for each. It stops starting new entries once stop is closed.
+func (a *App) analyzePlaylistEntries(playlist PlaylistInfo, stop <-chan struct{}) {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		analyzed int
+		failed   int
+	)
+	slots := make(chan struct{}, deepAnalysisConcurrency)
+	cancelled := false
+
+	for i, entry := range playlist.Entries {
+		select {
+		case <-stop:
+		case slots <- struct{}{}:
+		}
+		// Stopping wins even when a slot was free at the same time
+		select {
+		case <-stop:
+			cancelled = true
+		default:
+		}
+		if cancelled {
+			break
+		}
+
+		wg.Add(1)
+		go func(index int, entry PlaylistEntry) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			full, err := a.analyzePlaylistEntry(entry)
+			data := map[string]interface{}{
+				"playlist_id": playlist.ID,
+				"index":       index,
+				"entry":       full,
+			}
+
+			mu.Lock()
+			if err != nil {
+				a.logger.Warningf("Deep analysis of playlist entry %s failed: %v", entry.URL, err)
+				data["error"] = err.Error()
+				failed++
+			} else {
+				analyzed++
+			}
+			mu.Unlock()
+
+			a.events.Emit("playlist-entry-analyzed", data)
+		}(i, entry)
+	}
+	wg.Wait()
+
+	a.logger.Infof("Deep playlist analysis finished for %s: %d analyzed, %d failed", playlist.ID, analyzed, failed)
+	a.events.Emit("playlist-analysis-complete", map[string]interface{}{
+		"playlist_id": playlist.ID,
+		"analyzed":    analyzed,
+		"failed":      failed,
+		"cancelled":   cancelled,
+	})
+}
+
+// analyzePlaylistDeepInternal returns the flat playlist like analyzePlaylistInternal
+// and then fetches the full metadata of every entry in the background, streaming
+// the results as playlist-entry-analyzed events. A new deep analysis stops the
+// previous one.
+func (a *App) analyzePlaylistDeepInternal(url string) (string, error) {
+	result, err := a.analyzePlaylistInternal(url)
+	if err != nil {
+		return "", err
+	}
+
+	var playlist PlaylistInfo
+	if err := json.Unmarshal([]byte(result), &playlist); err != nil {
+		return "", fmt.Errorf("failed to parse playlist info: %w", err)
+	}
+
+	a.deepAnalysisMu.Lock()
+	if a.deepAnalysisStop != nil {
+		close(a.deepAnalysisStop)
+	}
+	stop := make(chan struct{})
+	a.deepAnalysisStop = stop
+	a.deepAnalysisMu.Unlock()
+
+	go a.analyzePlaylistEntries(playlist, stop)
+	return result, nil
+}
+
+// cancelPlaylistAnalysisInternal stops the running deep playlist analysis.
+// Entries already being analyzed still report their results.
+func (a *App) cancelPlaylistAnalysisInternal() {
+	a.deepAnalysisMu.Lock()
+	defer a.deepAnalysisMu.Unlock()
+	if a.deepAnalysisStop != nil {
+		close(a.deepAnalysisStop)
+		a.deepAnalysisStop = nil
+	}
+}
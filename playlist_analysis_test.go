@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPlaylistEntryFromInfo(t *testing.T) {
+	tests := []struct {
+		name string
+		info string
+		want PlaylistEntry
+	}{
+		{
+			name: "FlatEntry",
+			info: `{"id":"a1","title":"First","url":"https://example.com/watch?v=a1","channel":"Uploader","view_count":42,
+				"thumbnails":[{"url":"https://img/small.jpg"},{"url":"https://img/large.jpg"}]}`,
+			want: PlaylistEntry{ID: "a1", Title: "First", URL: "https://example.com/watch?v=a1", Uploader: "Uploader", ViewCount: 42, Thumbnail: "https://img/large.jpg"},
+		},
+		{
+			name: "FullInfo",
+			info: `{"id":"a1","title":"First","url":"https://cdn/video.mp4","webpage_url":"https://example.com/watch?v=a1","thumbnail":"https://img/a1.jpg",
+				"duration":61,"uploader":"Uploader","upload_date":"20240102","view_count":1000,"availability":"public"}`,
+			want: PlaylistEntry{ID: "a1", Title: "First", URL: "https://example.com/watch?v=a1", Thumbnail: "https://img/a1.jpg",
+				Duration: 61, Uploader: "Uploader", UploadDate: "20240102", ViewCount: 1000, Availability: "public"},
+		},
+		{
+			name: "PrivatePlaceholder",
+			info: `{"id":"p1","title":"[Private video]","url":"https://example.com/watch?v=p1"}`,
+			want: PlaylistEntry{ID: "p1", Title: "[Private video]", URL: "https://example.com/watch?v=p1", Unavailable: true},
+		},
+		{
+			name: "MembersOnly",
+			info: `{"id":"m1","title":"Members","url":"https://example.com/watch?v=m1","availability":"subscriber_only"}`,
+			want: PlaylistEntry{ID: "m1", Title: "Members", URL: "https://example.com/watch?v=m1", Availability: "subscriber_only", Unavailable: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var info map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.info), &info); err != nil {
+				t.Fatalf("invalid test JSON: %v", err)
+			}
+			if got := playlistEntryFromInfo(info); got != tt.want {
+				t.Errorf("playlistEntryFromInfo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnalyzePlaylistDeep(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, sink := newTestApp(t)
+	tools.install(app.getYtDlpBinaryName(),
+		fakeRun{When: []string{"--flat-playlist"}, Stdout: []string{`{"id":"pl","title":"List","playlist_count":2,"entries":[` +
+			`{"id":"a1","title":"First","url":"https://example.com/watch?v=a1"},` +
+			`{"id":"p1","title":"Second","url":"https://example.com/watch?v=p1"}]}`}},
+		fakeRun{When: []string{"--no-playlist", "https://example.com/watch?v=a1"},
+			Stdout: []string{`{"id":"a1","title":"First","webpage_url":"https://example.com/watch?v=a1","duration":61,"uploader":"Uploader","upload_date":"20240102","view_count":5}`}},
+		fakeRun{When: []string{"--no-playlist"}, Stderr: []string{"ERROR: [youtube] p1: Private video"}, ExitCode: 1},
+	)
+
+	result, err := app.analyzePlaylistDeepInternal("https://example.com/playlist")
+	if err != nil {
+		t.Fatalf("analyzePlaylistDeepInternal() error = %v", err)
+	}
+	var playlist PlaylistInfo
+	if err := json.Unmarshal([]byte(result), &playlist); err != nil || len(playlist.Entries) != 2 {
+		t.Fatalf("unexpected flat playlist %q, %v", result, err)
+	}
+
+	done := sink.waitFor(t, "playlist-analysis-complete")
+	if data := done.Data[0].(map[string]interface{}); data["analyzed"] != 1 || data["failed"] != 1 || data["cancelled"] != false {
+		t.Errorf("unexpected completion data: %v", data)
+	}
+
+	entries := make(map[int]PlaylistEntry)
+	for _, event := range sink.Events() {
+		if event.Name != "playlist-entry-analyzed" {
+			continue
+		}
+		data := event.Data[0].(map[string]interface{})
+		if data["playlist_id"] != "pl" {
+			t.Errorf("unexpected playlist_id in %v", data)
+		}
+		entries[data["index"].(int)] = data["entry"].(PlaylistEntry)
+	}
+	if first := entries[0]; first.Duration != 61 || first.Uploader != "Uploader" || first.UploadDate != "20240102" || first.ViewCount != 5 || first.Unavailable {
+		t.Errorf("first entry not enriched: %+v", first)
+	}
+	if second := entries[1]; second.ID != "p1" || !second.Unavailable {
+		t.Errorf("the failed entry should be marked unavailable: %+v", second)
+	}
+}
+
+func TestCancelPlaylistAnalysis(t *testing.T) {
+	app, sink := newTestApp(t)
+	stop := make(chan struct{})
+	close(stop)
+
+	app.analyzePlaylistEntries(PlaylistInfo{ID: "pl", Entries: []PlaylistEntry{{ID: "a1", URL: "https://example.com/watch?v=a1"}}}, stop)
+
+	data := sink.waitFor(t, "playlist-analysis-complete").Data[0].(map[string]interface{})
+	if data["analyzed"] != 0 || data["cancelled"] != true {
+		t.Errorf("a stopped analysis should not analyze entries, got %v", data)
+	}
+}
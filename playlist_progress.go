@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// playlistItemPattern matches the line yt-dlp prints before each playlist item,
+// counting only the selected items
+var playlistItemPattern = regexp.MustCompile(`\[download\] Downloading (?:item|video) (\d+) of (\d+)`)
+
+// itemErrorPattern splits an error line such as
+// "ERROR: [youtube] abc123: Video unavailable" into the video ID and message
+var itemErrorPattern = regexp.MustCompile(`^ERROR: (?:\[[^\]]+\] ([^:\s]+): )?(.*)$`)
+
+// archivedItemPattern extracts the video ID from the line yt-dlp prints for an
+// item the download archive already lists
+var archivedItemPattern = regexp.MustCompile(`\[download\] (\S+): ` + archivedLineMarker)
+
+// filterSkipMarker is what yt-dlp prints for entries --match-filter rejects
+const filterSkipMarker = "does not pass filter"
+
+// playlistItemError is a playlist item that failed while yt-dlp continued
+// with the rest (--ignore-errors)
+type playlistItemError struct {
+	ItemIndex int    `json:"item_index"` // 0 when the error was not tied to an item
+	VideoID   string `json:"video_id,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Error     string `json:"error"`
+}
+
+// playlistSummary counts how the items of a playlist download ended
+type playlistSummary struct {
+	Total     int                 `json:"total"`
+	Succeeded int                 `json:"succeeded"`
+	Failed    int                 `json:"failed"`
+	Skipped   int                 `json:"skipped"` // Already in the download archive or rejected by a filter
+	Errors    []playlistItemError `json:"errors"`
+}
+
+// playlistTracker follows which item of a playlist download is running and how
+// each item ended. The progress reporter's mutex guards it.
+type playlistTracker struct {
+	itemIndex   int // 1-based position among the selected items, 0 before the first
+	itemCount   int
+	itemTitle   string
+	itemVideoID string
+	itemEnded   bool // Whether the current item was already counted
+	overall     int  // Highest overall percentage reported, so it never goes backwards
+
+	summary playlistSummary
+}
+
+// startItem moves on to the next item, as announced by yt-dlp
+func (t *playlistTracker) startItem(index, count int) {
+	t.itemIndex = index
+	t.itemCount = count
+	t.itemTitle = ""
+	t.itemVideoID = ""
+	t.itemEnded = false
+}
+
+// endItem counts the current item once as succeeded, skipped or failed
+func (t *playlistTracker) endItem(counter *int) {
+	if t.itemEnded {
+		return
+	}
+	t.itemEnded = true
+	*counter++
+}
+
+// itemFailed records an error line. Errors arrive on stderr and items are
+// announced on stdout, so an error is only attributed to the current item when
+// its video ID does not contradict it; otherwise it is counted on its own.
+func (t *playlistTracker) itemFailed(line string) {
+	match := itemErrorPattern.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		return
+	}
+	videoID := match[1]
+
+	if t.itemIndex > 0 {
+		if !t.itemEnded && (videoID == "" || t.itemVideoID == "" || videoID == t.itemVideoID) {
+			if videoID == "" {
+				videoID = t.itemVideoID
+			}
+			t.itemVideoID = videoID
+			t.summary.Errors = append(t.summary.Errors, playlistItemError{
+				ItemIndex: t.itemIndex,
+				VideoID:   videoID,
+				Title:     t.itemTitle,
+				Error:     match[2],
+			})
+			t.endItem(&t.summary.Failed)
+			return
+		}
+		if t.itemEnded && (videoID == "" || videoID == t.itemVideoID) {
+			return // Follow-up error of an item that was already counted
+		}
+	}
+
+	// An item that was not announced yet, or the playlist itself when there is no video ID
+	t.summary.Errors = append(t.summary.Errors, playlistItemError{VideoID: videoID, Error: match[2]})
+	if videoID != "" {
+		t.summary.Failed++
+	}
+}
+
+// itemArchived counts the current item as skipped because the archive lists it
+func (t *playlistTracker) itemArchived(line string) {
+	if match := archivedItemPattern.FindStringSubmatch(line); match != nil {
+		t.itemVideoID = match[1]
+	}
+	t.endItem(&t.summary.Skipped)
+}
+
+// overallPercent combines the finished items and the current item's percentage
+func (t *playlistTracker) overallPercent(itemPercent float64) int {
+	if t.itemCount <= 0 || t.itemIndex <= 0 {
+		return int(itemPercent)
+	}
+	overall := int((float64(t.itemIndex-1)*100 + itemPercent) / float64(t.itemCount))
+	if overall > 100 {
+		overall = 100
+	}
+	if overall > t.overall {
+		t.overall = overall
+	}
+	return t.overall
+}
+
+// addEventData adds the current item and the counts to a download-progress payload
+func (t *playlistTracker) addEventData(data map[string]interface{}) {
+	data["item_index"] = t.itemIndex
+	data["item_count"] = t.itemCount
+	data["item_title"] = t.itemTitle
+	data["succeeded"] = t.summary.Succeeded
+	data["failed"] = t.summary.Failed
+	data["skipped"] = t.summary.Skipped
+}
+
+// result returns the summary of the download so far
+func (t *playlistTracker) result() playlistSummary {
+	summary := t.summary
+	summary.Errors = append([]playlistItemError{}, t.summary.Errors...)
+	summary.Total = t.itemCount
+	if counted := summary.Succeeded + summary.Failed + summary.Skipped; counted > summary.Total {
+		summary.Total = counted
+	}
+	return summary
+}
+
+// handleLine updates the tracker from a yt-dlp output line other than progress,
+// title and file path lines. It returns false for lines it does not consume;
+// error lines are recorded but not consumed so callers still see them.
+func (t *playlistTracker) handleLine(line string) bool {
+	if match := playlistItemPattern.FindStringSubmatch(line); match != nil {
+		index, _ := strconv.Atoi(match[1])
+		count, _ := strconv.Atoi(match[2])
+		t.startItem(index, count)
+		return true
+	}
+	if strings.Contains(line, filterSkipMarker) {
+		t.endItem(&t.summary.Skipped)
+		return true
+	}
+	if strings.HasPrefix(strings.TrimSpace(line), "ERROR:") {
+		t.itemFailed(line)
+	}
+	return false
+}
+
+// partialPlaylistFailure reports whether a playlist process failed only because
+// some items did. With --ignore-errors yt-dlp still exits with 1 if any item
+// failed, even though the others were downloaded.
+func partialPlaylistFailure(summary playlistSummary, err error) bool {
+	var exitError *exec.ExitError
+	return errors.As(err, &exitError) && exitError.ExitCode() == 1 &&
+		summary.Failed > 0 && summary.Succeeded+summary.Skipped > 0
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// playlistOutput is what yt-dlp prints for a three item playlist where the
+// first item downloads, the second fails and the third is in the archive
+var playlistOutput = []string{
+	"[download] Downloading item 1 of 3",
+	`[godlp-item-start] {"playlist_index":4,"video_id":"a1","title":"First"}`,
+	`[godlp-progress] {"progress":{"status":"downloading","downloaded_bytes":50,"total_bytes":100},"playlist_index":4,"playlist_count":9}`,
+	`[godlp-item-finish] {"playlist_index":4,"video_id":"a1","file_path":"downloads/First.mp4"}`,
+	"[download] Downloading item 2 of 3",
+	"ERROR: [youtube] b2: Video unavailable",
+	"[download] Downloading item 3 of 3",
+	"[download] c3: has already been recorded in the archive",
+}
+
+func TestProgressReporterTracksPlaylistItems(t *testing.T) {
+	app, sink := newTestApp(t)
+	job := &DownloadJob{ID: "job", Playlist: true}
+	progress := newProgressReporter(app, job)
+
+	for _, line := range playlistOutput {
+		progress.handleLine(line)
+	}
+
+	var update map[string]interface{}
+	for _, event := range sink.Events() {
+		if event.Name == "download-progress" {
+			update = event.Data[0].(map[string]interface{})
+		}
+	}
+	// Half of the first of three items
+	if update["progress"] != 16 || update["item_progress"] != 50 || update["item_index"] != 1 || update["item_count"] != 3 || update["item_title"] != "First" {
+		t.Errorf("unexpected progress event: %v", update)
+	}
+
+	summary := progress.playlistSummary()
+	want := playlistSummary{
+		Total: 3, Succeeded: 1, Failed: 1, Skipped: 1,
+		Errors: []playlistItemError{{ItemIndex: 2, VideoID: "b2", Error: "Video unavailable"}},
+	}
+	if !reflect.DeepEqual(summary, want) {
+		t.Errorf("playlistSummary() = %+v, want %+v", summary, want)
+	}
+
+	progress.resetPlaylist()
+	if summary := progress.playlistSummary(); summary.Total != 0 || len(summary.Errors) != 0 {
+		t.Errorf("summary after reset = %+v", summary)
+	}
+}
+
+func TestPlaylistTrackerErrorsOutOfOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  playlistSummary
+	}{
+		{
+			name:  "ErrorBeforeItemAnnounced",
+			lines: []string{"ERROR: [youtube] b2: Video unavailable", "[download] Downloading item 1 of 2"},
+			want:  playlistSummary{Total: 2, Failed: 1, Errors: []playlistItemError{{VideoID: "b2", Error: "Video unavailable"}}},
+		},
+		{
+			name: "ErrorOfNextItemWhileCurrentRuns",
+			lines: []string{"[download] Downloading item 1 of 2", `[godlp-item-start] {"video_id":"a1","title":"First"}`,
+				"ERROR: [youtube] b2: Video unavailable", `[godlp-item-finish] {"video_id":"a1"}`},
+			want: playlistSummary{Total: 2, Succeeded: 1, Failed: 1, Errors: []playlistItemError{{VideoID: "b2", Error: "Video unavailable"}}},
+		},
+		{
+			name:  "ErrorAfterArchivedItem",
+			lines: []string{"[download] Downloading item 1 of 2", "[download] a1: has already been recorded in the archive", "ERROR: [youtube] b2: Video unavailable"},
+			want:  playlistSummary{Total: 2, Failed: 1, Skipped: 1, Errors: []playlistItemError{{VideoID: "b2", Error: "Video unavailable"}}},
+		},
+		{
+			name:  "FollowUpError",
+			lines: []string{"[download] Downloading item 1 of 1", "ERROR: [youtube] a1: Private video", "ERROR: [youtube] a1: Private video"},
+			want:  playlistSummary{Total: 1, Failed: 1, Errors: []playlistItemError{{ItemIndex: 1, VideoID: "a1", Error: "Private video"}}},
+		},
+		{
+			name:  "PlaylistError",
+			lines: []string{"ERROR: Unable to download playlist page"},
+			want:  playlistSummary{Errors: []playlistItemError{{Error: "Unable to download playlist page"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, _ := newTestApp(t)
+			progress := newProgressReporter(app, &DownloadJob{ID: "job", Playlist: true})
+			for _, line := range tt.lines {
+				progress.handleLine(line)
+			}
+			if got := progress.playlistSummary(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("playlistSummary() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlaylistTrackerOverallNeverGoesBack(t *testing.T) {
+	tracker := &playlistTracker{}
+	tracker.startItem(2, 4)
+
+	if got := tracker.overallPercent(100); got != 50 {
+		t.Errorf("overallPercent(100) = %d, want 50", got)
+	}
+	// The audio stream of the same item starts from zero again
+	if got := tracker.overallPercent(0); got != 50 {
+		t.Errorf("overallPercent(0) = %d, want 50", got)
+	}
+}
+
+func TestPlaylistDownloadPartialFailure(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, sink := newTestApp(t)
+	tools.install(app.getYtDlpBinaryName(), fakeRun{
+		Stdout:   []string{playlistOutput[0], playlistOutput[1], playlistOutput[3], playlistOutput[4], playlistOutput[6], playlistOutput[7]},
+		Stderr:   []string{playlistOutput[5]},
+		ExitCode: 1,
+	})
+
+	if _, err := app.enqueuePlaylistInternal("https://example.com/playlist", "best", "downloads/%(title)s.%(ext)s", 0, 0, ""); err != nil {
+		t.Fatalf("enqueuePlaylistInternal() error = %v", err)
+	}
+
+	// The error arrives on stderr, so which item it lands on depends on timing;
+	// it must be counted exactly once either way
+	complete := sink.waitFor(t, "download-complete").Data[0].(map[string]interface{})
+	if complete["failed"] != 1 {
+		t.Errorf("unexpected download-complete data: %v", complete)
+	}
+	// The folder comes from the reported files, not the unexpanded template
+	if want, _ := filepath.Abs("downloads"); complete["file_path"] != want {
+		t.Errorf("file_path = %v, want %s", complete["file_path"], want)
+	}
+
+	summary := sink.waitFor(t, "playlist-download-summary").Data[0].(map[string]interface{})
+	errors := summary["errors"].([]playlistItemError)
+	if summary["total"] != 3 || len(errors) != 1 || errors[0].VideoID != "b2" || errors[0].Error != "Video unavailable" {
+		t.Errorf("unexpected summary: %v", summary)
+	}
+}
+
+func TestPlaylistDownloadAllItemsFailed(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, sink := newTestApp(t)
+	tools.install(app.getYtDlpBinaryName(), fakeRun{
+		Stdout:   []string{"[download] Downloading item 1 of 1"},
+		Stderr:   []string{"ERROR: [youtube] a1: Private video"},
+		ExitCode: 1,
+	})
+
+	if _, err := app.enqueuePlaylistInternal("https://example.com/playlist", "best", "downloads/%(title)s.%(ext)s", 0, 0, ""); err != nil {
+		t.Fatalf("enqueuePlaylistInternal() error = %v", err)
+	}
+
+	sink.waitFor(t, "download-error")
+	summary := sink.waitFor(t, "playlist-download-summary").Data[0].(map[string]interface{})
+	if summary["failed"] != 1 || summary["succeeded"] != 0 {
+		t.Errorf("unexpected summary: %v", summary)
+	}
+}
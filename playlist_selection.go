@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxDownloadsExitCode is the exit code of yt-dlp when --max-downloads was reached
+const maxDownloadsExitCode = 101
+
+// uploadDatePattern matches the YYYYMMDD dates yt-dlp uses
+var uploadDatePattern = regexp.MustCompile(`^\d{8}$`)
+
+// parsePlaylistSelection decodes and validates a JSON-encoded PlaylistSelection.
+// An empty string selects the whole playlist.
+func parsePlaylistSelection(selectionJSON string) (PlaylistSelection, error) {
+	var selection PlaylistSelection
+	if strings.TrimSpace(selectionJSON) == "" {
+		return selection, nil
+	}
+
+	if err := json.Unmarshal([]byte(selectionJSON), &selection); err != nil {
+		return selection, fmt.Errorf("failed to parse playlist selection: %w", err)
+	}
+	if err := selection.validate(); err != nil {
+		return selection, err
+	}
+	return selection, nil
+}
+
+// validate checks the selection for values yt-dlp would reject
+func (s *PlaylistSelection) validate() error {
+	for _, item := range s.Items {
+		if item < 1 {
+			return fmt.Errorf("invalid playlist item: %d", item)
+		}
+	}
+	if s.StartItem < 0 || s.EndItem < 0 || (s.EndItem > 0 && s.EndItem < s.StartItem) {
+		return fmt.Errorf("invalid playlist range: %d-%d", s.StartItem, s.EndItem)
+	}
+	if s.Filter != nil {
+		return s.Filter.validate()
+	}
+	return nil
+}
+
+// validate checks the filter for values yt-dlp would reject
+func (f *PlaylistFilter) validate() error {
+	if f.MinDuration < 0 || f.MaxDuration < 0 || (f.MaxDuration > 0 && f.MaxDuration < f.MinDuration) {
+		return fmt.Errorf("invalid duration filter: %d-%d", f.MinDuration, f.MaxDuration)
+	}
+	for _, date := range []string{f.DateAfter, f.DateBefore} {
+		if date != "" && !uploadDatePattern.MatchString(date) {
+			return fmt.Errorf("invalid upload date, expected YYYYMMDD: %s", date)
+		}
+	}
+	if f.MaxDownloads < 0 {
+		return fmt.Errorf("invalid max downloads: %d", f.MaxDownloads)
+	}
+	return nil
+}
+
+// matchFilter builds the yt-dlp --match-filter expression, "" for none.
+// Conditions are joined with "&", so a literal "&" in the title regex is escaped.
+func (f *PlaylistFilter) matchFilter() string {
+	var conditions []string
+	if f.MinDuration > 0 {
+		conditions = append(conditions, fmt.Sprintf("duration >= %d", f.MinDuration))
+	}
+	if f.MaxDuration > 0 {
+		conditions = append(conditions, fmt.Sprintf("duration <= %d", f.MaxDuration))
+	}
+	if f.DateAfter != "" {
+		conditions = append(conditions, "upload_date >= "+f.DateAfter)
+	}
+	if f.DateBefore != "" {
+		conditions = append(conditions, "upload_date <= "+f.DateBefore)
+	}
+	if f.TitleRegex != "" {
+		quoted := strings.NewReplacer("'", `\'`, "&", `\&`).Replace(f.TitleRegex)
+		conditions = append(conditions, "title ~= '"+quoted+"'")
+	}
+	return strings.Join(conditions, " & ")
+}
+
+// args returns the yt-dlp arguments for the filter
+func (f *PlaylistFilter) args() []string {
+	if f == nil {
+		return nil
+	}
+
+	var args []string
+	if filter := f.matchFilter(); filter != "" {
+		args = append(args, "--match-filter", filter)
+	}
+	if f.MaxDownloads > 0 {
+		args = append(args, "--max-downloads", strconv.Itoa(f.MaxDownloads))
+	}
+	return args
+}
+
+// playlistItemsList formats 1-based playlist indices for --playlist-items,
+// collapsing runs into ranges: 1, 3, 7, 8, 9 becomes "1,3,7-9"
+func playlistItemsList(items []int) string {
+	sorted := slices.Clone(items)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+
+	var parts []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[j]+1 {
+			j++
+		}
+		if j-i >= 2 {
+			parts = append(parts, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		} else {
+			for _, item := range sorted[i : j+1] {
+				parts = append(parts, strconv.Itoa(item))
+			}
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
+
+// maxDownloadsReached reports whether a playlist process ended because the
+// job's --max-downloads limit was hit, which yt-dlp signals with an error exit
+func maxDownloadsReached(job *DownloadJob, err error) bool {
+	var exitError *exec.ExitError
+	return job.Filter != nil && job.Filter.MaxDownloads > 0 &&
+		errors.As(err, &exitError) && exitError.ExitCode() == maxDownloadsExitCode
+}
+
+// resolvePlaylistItemIDs looks up the 1-based playlist indices of entry IDs
+func (a *App) resolvePlaylistItemIDs(url string, ids []string) ([]int, error) {
+	result, err := a.getPlaylistItemsInternal(url)
+	if err != nil {
+		return nil, err
+	}
+	var playlist struct {
+		Entries []PlaylistEntry `json:"entries"`
+	}
+	if err := json.Unmarshal([]byte(result), &playlist); err != nil {
+		return nil, fmt.Errorf("failed to parse playlist items: %w", err)
+	}
+
+	positions := make(map[string]int, len(playlist.Entries))
+	for i, entry := range playlist.Entries {
+		if _, ok := positions[entry.ID]; !ok {
+			positions[entry.ID] = i + 1
+		}
+	}
+
+	items := make([]int, 0, len(ids))
+	for _, id := range ids {
+		position, ok := positions[id]
+		if !ok {
+			return nil, fmt.Errorf("playlist has no entry with ID %s", id)
+		}
+		items = append(items, position)
+	}
+	return items, nil
+}
+
+// downloadPlaylistSelectionInternal queues a playlist download of the selected
+// entries and returns the job ID. Items and IDs may be combined; without either
+// the start and end range applies.
+func (a *App) downloadPlaylistSelectionInternal(url, formatID, outputPath, selectionJSON, optionsJSON string) (string, error) {
+	selection, err := parsePlaylistSelection(selectionJSON)
+	if err != nil {
+		return "", err
+	}
+	options, err := parseDownloadOptions(optionsJSON)
+	if err != nil {
+		return "", err
+	}
+
+	items := slices.Clone(selection.Items)
+	if len(selection.IDs) > 0 {
+		resolved, err := a.resolvePlaylistItemIDs(url, selection.IDs)
+		if err != nil {
+			return "", err
+		}
+		items = append(items, resolved...)
+	}
+
+	job := &DownloadJob{
+		URL:        url,
+		FormatID:   formatID,
+		OutputPath: outputPath,
+		Playlist:   true,
+		Items:      items,
+		Filter:     selection.Filter,
+		Options:    options,
+	}
+	if len(items) == 0 {
+		job.StartItem = selection.StartItem
+		job.EndItem = selection.EndItem
+	}
+	return a.enqueueJob(job), nil
+}
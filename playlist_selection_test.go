@@ -0,0 +1,149 @@
+package main
+
+import (
+	"os/exec"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestPlaylistItemsList(t *testing.T) {
+	tests := []struct {
+		items []int
+		want  string
+	}{
+		{nil, ""},
+		{[]int{4}, "4"},
+		{[]int{1, 3, 7, 8, 9}, "1,3,7-9"},
+		{[]int{9, 7, 8, 3, 1, 3}, "1,3,7-9"},
+		{[]int{1, 2, 5, 6}, "1,2,5,6"},
+		{[]int{1, 2, 3, 4, 10}, "1-4,10"},
+	}
+
+	for _, tt := range tests {
+		if got := playlistItemsList(tt.items); got != tt.want {
+			t.Errorf("playlistItemsList(%v) = %q, want %q", tt.items, got, tt.want)
+		}
+	}
+}
+
+func TestPlaylistFilterArgs(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter *PlaylistFilter
+		want   []string
+	}{
+		{"Nil", nil, nil},
+		{"Empty", &PlaylistFilter{}, nil},
+		{"Duration", &PlaylistFilter{MinDuration: 60, MaxDuration: 600}, []string{"--match-filter", "duration >= 60 & duration <= 600"}},
+		{"Dates", &PlaylistFilter{DateAfter: "20240101", DateBefore: "20241231"}, []string{"--match-filter", "upload_date >= 20240101 & upload_date <= 20241231"}},
+		{"TitleRegex", &PlaylistFilter{TitleRegex: `(?i)rock & roll's`}, []string{"--match-filter", `title ~= '(?i)rock \& roll\'s'`}},
+		{"MaxDownloads", &PlaylistFilter{MaxDownloads: 5}, []string{"--max-downloads", "5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.args(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("args() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePlaylistSelection(t *testing.T) {
+	selection, err := parsePlaylistSelection(`{"items":[1,3],"ids":["b2"],"filter":{"min_duration":60}}`)
+	if err != nil {
+		t.Fatalf("parsePlaylistSelection() error = %v", err)
+	}
+	if !reflect.DeepEqual(selection.Items, []int{1, 3}) || !reflect.DeepEqual(selection.IDs, []string{"b2"}) || selection.Filter.MinDuration != 60 {
+		t.Errorf("unexpected selection %+v", selection)
+	}
+
+	for _, invalid := range []string{
+		`{"items":[0]}`,
+		`{"start_item":5,"end_item":2}`,
+		`{"filter":{"min_duration":600,"max_duration":60}}`,
+		`{"filter":{"date_after":"2024-01-01"}}`,
+		`{"filter":{"max_downloads":-1}}`,
+		`not json`,
+	} {
+		if _, err := parsePlaylistSelection(invalid); err == nil {
+			t.Errorf("parsePlaylistSelection(%s) should fail", invalid)
+		}
+	}
+}
+
+func TestDownloadPlaylistSelection(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, sink := newTestApp(t)
+	tools.install(app.getYtDlpBinaryName(),
+		fakeRun{When: []string{"--flat-playlist"}, Stdout: fakeChannelEntries},
+		fakeRun{
+			When: []string{"--playlist-items"},
+			Stdout: []string{
+				`[godlp-item-start] {"playlist_index":1,"video_id":"a1","title":"First"}`,
+				`[godlp-item-finish] {"playlist_index":1,"video_id":"a1","file_path":"downloads/First.mp4"}`,
+				`[godlp-item-start] {"playlist_index":2,"video_id":"b2","title":"Second"}`,
+				`[godlp-item-finish] {"playlist_index":2,"video_id":"b2","file_path":"downloads/Second.mp4"}`,
+			},
+			ExitCode: maxDownloadsExitCode,
+		},
+	)
+
+	_, err := app.downloadPlaylistSelectionInternal("https://example.com/playlist", "best", "downloads/%(title)s.%(ext)s",
+		`{"items":[1],"ids":["b2"],"filter":{"title_regex":"^(First|Second)$","max_downloads":2}}`, "")
+	if err != nil {
+		t.Fatalf("downloadPlaylistSelectionInternal() error = %v", err)
+	}
+
+	sink.waitFor(t, "download-complete")
+	var started, finished []interface{}
+	for _, event := range sink.Events() {
+		data, _ := event.Data[0].(map[string]interface{})
+		switch event.Name {
+		case "playlist-item-start":
+			started = append(started, data["playlist_index"])
+		case "playlist-item-finish":
+			finished = append(finished, data["playlist_index"])
+		}
+	}
+	if !reflect.DeepEqual(started, []interface{}{1, 2}) || !reflect.DeepEqual(finished, []interface{}{1, 2}) {
+		t.Errorf("item events: started %v, finished %v", started, finished)
+	}
+
+	calls := tools.calls(app.getYtDlpBinaryName())
+	download := calls[len(calls)-1]
+	for _, pair := range [][2]string{{"--playlist-items", "1,2"}, {"--match-filter", "title ~= '^(First|Second)$'"}, {"--max-downloads", "2"}} {
+		if i := slices.Index(download, pair[0]); i < 0 || download[i+1] != pair[1] {
+			t.Errorf("expected %s %q in %q", pair[0], pair[1], download)
+		}
+	}
+}
+
+func TestDownloadPlaylistSelectionUnknownID(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, _ := newTestApp(t)
+	tools.install(app.getYtDlpBinaryName(), fakeRun{When: []string{"--flat-playlist"}, Stdout: fakeChannelEntries})
+
+	if _, err := app.downloadPlaylistSelectionInternal("https://example.com/playlist", "", "out", `{"ids":["zz"]}`, ""); err == nil {
+		t.Error("an ID that is not in the playlist should fail")
+	}
+	if len(app.queue.jobs) != 0 {
+		t.Error("nothing should be queued")
+	}
+}
+
+func TestMaxDownloadsReached(t *testing.T) {
+	exitErr := exec.Command("sh", "-c", "exit 101").Run()
+	limited := &DownloadJob{Filter: &PlaylistFilter{MaxDownloads: 2}}
+
+	if !maxDownloadsReached(limited, exitErr) {
+		t.Error("exit code 101 with a download limit should count as reaching it")
+	}
+	if maxDownloadsReached(&DownloadJob{}, exitErr) {
+		t.Error("without a download limit exit code 101 is an error")
+	}
+	if maxDownloadsReached(limited, exec.Command("sh", "-c", "exit 1").Run()) {
+		t.Error("other exit codes are errors")
+	}
+}
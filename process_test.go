@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const fakeVideoURL = "https://example.com/watch?v=abc"
+
+// fakeVideoJSON is a minimal yt-dlp info JSON for fakeVideoURL
+const fakeVideoJSON = `{"id":"abc","title":"Clip","duration":10,"formats":[]}`
+
+// jobStatus returns the status of a queued job
+func jobStatus(app *App, id string) string {
+	app.queue.mu.Lock()
+	defer app.queue.mu.Unlock()
+	return app.queue.jobs[id].Status
+}
+
+// useDownloadDir points the download directory at dir for the test
+func useDownloadDir(t *testing.T, dir string) {
+	t.Helper()
+	oldDir := defaultDownloadDir
+	defaultDownloadDir = dir
+	t.Cleanup(func() { defaultDownloadDir = oldDir })
+}
+
+func TestAnalyzeURLFallbacks(t *testing.T) {
+	tests := []struct {
+		name      string
+		cookies   bool
+		runs      []fakeRun
+		wantCalls int
+		check     func(t *testing.T, calls [][]string)
+	}{
+		{
+			name:    "retries without cookies",
+			cookies: true,
+			runs: []fakeRun{
+				{When: []string{"--cookies-from-browser"}, Stderr: []string{"ERROR: [youtube] abc: Sign in to confirm you're not a bot"}, ExitCode: 1},
+				{Stdout: []string{fakeVideoJSON}},
+			},
+			wantCalls: 2,
+			check: func(t *testing.T, calls [][]string) {
+				if !slices.Contains(calls[0], "--cookies-from-browser") || slices.Contains(calls[1], "--cookies-from-browser") {
+					t.Errorf("expected only the first attempt to use cookies, got %q", calls)
+				}
+			},
+		},
+		{
+			name: "retries with minimal arguments on format errors",
+			runs: []fakeRun{
+				{When: []string{"--no-warnings"}, Stderr: []string{"ERROR: Requested format is not available"}, ExitCode: 1},
+				{When: []string{"--print-json"}, Stdout: []string{fakeVideoJSON}},
+			},
+			wantCalls: 2,
+			check: func(t *testing.T, calls [][]string) {
+				if slices.Contains(calls[1], "--no-warnings") {
+					t.Errorf("minimal attempt should drop --no-warnings, got %q", calls[1])
+				}
+			},
+		},
+		{
+			name: "falls back to dump-single-json",
+			runs: []fakeRun{
+				{When: []string{"--print-json"}, Stderr: []string{"ERROR: Unsupported URL"}, ExitCode: 1},
+				{When: []string{"--dump-single-json"}, Stdout: []string{fakeVideoJSON}},
+			},
+			wantCalls: 2,
+			check: func(t *testing.T, calls [][]string) {
+				if !slices.Contains(calls[1], "--dump-single-json") {
+					t.Errorf("expected a --dump-single-json attempt, got %q", calls[1])
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tools := newFakeToolHarness(t)
+			app, _ := newTestApp(t)
+			if tt.cookies {
+				app.settings.CookiesMode = "browser"
+				app.settings.CookiesBrowser = "firefox"
+			}
+			tools.install(app.getYtDlpBinaryName(), tt.runs...)
+
+			output, err := app.analyzeURLInternal(fakeVideoURL)
+			if err != nil {
+				t.Fatalf("analyzeURLInternal() error = %v", err)
+			}
+			if !strings.Contains(output, `"title":"Clip"`) {
+				t.Errorf("unexpected output: %s", output)
+			}
+
+			calls := tools.calls(app.getYtDlpBinaryName())
+			if len(calls) != tt.wantCalls {
+				t.Fatalf("yt-dlp was called %d times, want %d: %q", len(calls), tt.wantCalls, calls)
+			}
+			tt.check(t, calls)
+		})
+	}
+}
+
+func TestAnalyzeURLFailure(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, _ := newTestApp(t)
+	tools.install(app.getYtDlpBinaryName(), fakeRun{Stderr: []string{"ERROR: Unsupported URL"}, ExitCode: 1})
+
+	_, err := app.analyzeURLInternal(fakeVideoURL)
+	if err == nil || !strings.Contains(err.Error(), "Unsupported URL") {
+		t.Fatalf("expected the yt-dlp error to be reported, got %v", err)
+	}
+}
+
+func TestVideoDownloadRetriesWithoutCookies(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, sink := newTestApp(t)
+	app.settings.CookiesMode = "browser"
+	app.settings.CookiesBrowser = "firefox"
+	output := filepath.Join("downloads", "Clip.mp4")
+	tools.install(app.getYtDlpBinaryName(),
+		fakeRun{When: []string{"--cookies-from-browser"}, Stderr: []string{"ERROR: [youtube] abc: Sign in to confirm you're not a bot"}, ExitCode: 1},
+		fakeRun{
+			Files:  map[string]string{output: "video"},
+			Stdout: []string{"[godlp-title] Clip", "[godlp-filepath] " + output},
+		},
+	)
+
+	id, err := app.downloadVideoInternal(fakeVideoURL, "best", filepath.Join("downloads", defaultOutputTemplate), "")
+	if err != nil {
+		t.Fatalf("downloadVideoInternal() error = %v", err)
+	}
+
+	event := sink.waitFor(t, "download-complete")
+	data := event.Data[0].(map[string]interface{})
+	if data["id"] != id {
+		t.Errorf("id = %v, want the returned job ID %s", data["id"], id)
+	}
+	want, _ := filepath.Abs(output)
+	if got := data["file_path"]; got != want {
+		t.Errorf("file_path = %v, want %s", got, want)
+	}
+	for _, name := range sink.Names() {
+		if name == "download-error" {
+			t.Errorf("the failed attempt with cookies should not be reported, got %v", sink.Names())
+		}
+	}
+
+	calls := tools.calls(app.getYtDlpBinaryName())
+	if len(calls) != 2 || slices.Contains(calls[1], "--cookies-from-browser") {
+		t.Errorf("expected a second attempt without cookies, got %q", calls)
+	}
+}
+
+func TestVideoDownloadRetryReportsError(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, sink := newTestApp(t)
+	app.settings.CookiesMode = "browser"
+	app.settings.CookiesBrowser = "firefox"
+	tools.install(app.getYtDlpBinaryName(),
+		fakeRun{When: []string{"--cookies-from-browser"}, Stderr: []string{"ERROR: [youtube] abc: Sign in to confirm you're not a bot"}, ExitCode: 1},
+		fakeRun{Stderr: []string{"ERROR: [youtube] abc: Video unavailable"}, ExitCode: 1},
+	)
+
+	if _, err := app.downloadVideoInternal(fakeVideoURL, "best", filepath.Join("downloads", defaultOutputTemplate), ""); err != nil {
+		t.Fatalf("downloadVideoInternal() error = %v", err)
+	}
+
+	event := sink.waitFor(t, "download-error")
+	if got := fmt.Sprint(event.Data[0].(map[string]interface{})["error"]); !strings.Contains(got, "(retry)") || !strings.Contains(got, "Video unavailable") {
+		t.Errorf("expected the retry's yt-dlp error, got %q", got)
+	}
+}
+
+func TestVideoDownloadStop(t *testing.T) {
+	for _, reason := range []string{"pause", "cancel"} {
+		t.Run(reason, func(t *testing.T) {
+			tools := newFakeToolHarness(t)
+			useDownloadDir(t, "downloads")
+			app, sink := newTestApp(t)
+			tools.install(app.getYtDlpBinaryName(), fakeRun{
+				Files: map[string]string{filepath.Join("downloads", "My_Clip.mp4.part"): "partial"},
+				Stdout: []string{
+					"[godlp-title] My Clip",
+					`[godlp-progress] {"progress":{"status":"downloading","downloaded_bytes":256,"total_bytes":1024,"tmpfilename":"downloads/My_Clip.mp4.part"},"playlist_index":null,"playlist_count":null}`,
+				},
+				Hang: true,
+			})
+
+			if _, err := app.downloadVideoInternal(fakeVideoURL, "best", filepath.Join("downloads", defaultOutputTemplate), ""); err != nil {
+				t.Fatalf("downloadVideoInternal() error = %v", err)
+			}
+			progress := sink.waitFor(t, "download-progress")
+			id := progress.Data[0].(map[string]interface{})["id"].(string)
+
+			if err := app.stopJobInternal(id, reason); err != nil {
+				t.Fatalf("stopJobInternal() error = %v", err)
+			}
+			event := sink.waitFor(t, "download-cancelled")
+			if got := event.Data[0].(map[string]interface{})["reason"]; got != reason {
+				t.Errorf("reason = %v, want %s", got, reason)
+			}
+
+			want := JobStatusPaused
+			if reason == "cancel" {
+				want = JobStatusCancelled
+			}
+			if got := jobStatus(app, id); got != want {
+				t.Errorf("job status = %s, want %s", got, want)
+			}
+
+			// The partial file yt-dlp left behind marks the download as incomplete
+			if _, err := app.getActualDownloadPathInternal("My Clip"); err == nil || !strings.Contains(err.Error(), "download incomplete") {
+				t.Errorf("expected an incomplete download error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestConversionProgress(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, sink := newTestApp(t)
+	source := "clip.mkv"
+	if err := os.WriteFile(source, []byte("video"), 0644); err != nil {
+		t.Fatalf("failed to create source file: %v", err)
+	}
+	tools.install("ffprobe"+getExecutableExtension(), fakeRun{
+		Stdout: []string{`{"streams":[{"codec_type":"video","codec_name":"vp8"}],"format":{"duration":"10.000000"}}`},
+	})
+	tools.install("ffmpeg"+getExecutableExtension(), fakeRun{
+		When: []string{"-i", source},
+		Stdout: []string{
+			"frame=120", "fps=30.00", "total_size=524288", "out_time_us=5000000", "out_time=00:00:05.000000", "speed=2.5x", "progress=continue",
+		},
+		Files: map[string]string{"clip.mp4": "converted"},
+	})
+
+	if _, err := app.convertVideoInternal(source, "mp4"); err != nil {
+		t.Fatalf("convertVideoInternal() error = %v", err)
+	}
+
+	complete := sink.waitFor(t, "conversion-complete")
+	if got := complete.Data[0].(map[string]interface{})["targetPath"]; got != "clip.mp4" {
+		t.Errorf("targetPath = %v, want clip.mp4", got)
+	}
+	progress := sink.waitFor(t, "conversion-progress").Data[0].(map[string]interface{})
+	if progress["progress"] != 50 || progress["speed"] != 2.5 || progress["fps"] != 30.0 || progress["total_size"] != int64(524288) || progress["eta_seconds"] != int64(2) {
+		t.Errorf("unexpected progress data: %v", progress)
+	}
+}
+
+func TestConversionFailure(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, sink := newTestApp(t)
+	if err := os.WriteFile("clip.mkv", []byte("video"), 0644); err != nil {
+		t.Fatalf("failed to create source file: %v", err)
+	}
+	tools.install("ffmpeg"+getExecutableExtension(), fakeRun{
+		Stderr:   []string{"clip.mkv: Invalid data found when processing input"},
+		ExitCode: 1,
+	})
+
+	if _, err := app.convertVideoInternal("clip.mkv", "mp3"); err != nil {
+		t.Fatalf("convertVideoInternal() error = %v", err)
+	}
+	sink.waitFor(t, "conversion-error")
+}
@@ -0,0 +1,496 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// progressLinePrefix marks the machine-readable progress lines printed by yt-dlp
+const progressLinePrefix = "[godlp-progress] "
+
+// progressTemplateBody is the JSON object yt-dlp prints for every progress update.
+// Missing playlist fields fall back to a literal null so the line stays valid JSON.
+const progressTemplateBody = `{"progress":%(progress)j,"playlist_index":%(info.playlist_index|null)j,"playlist_count":%(info.playlist_count|null)j}`
+
+// ytDlpProgressTemplate reports download progress as JSON lines
+const ytDlpProgressTemplate = "download:" + progressLinePrefix + progressTemplateBody
+
+// ytDlpPostprocessTemplate reports post-processing steps such as audio
+// extraction or merging as JSON lines
+const ytDlpPostprocessTemplate = "postprocess:" + progressLinePrefix + progressTemplateBody
+
+// Progress phases reported in download-progress events
+const (
+	progressPhaseDownload    = "download"
+	progressPhasePostprocess = "postprocess"
+)
+
+// filepathLinePrefix marks the final output paths printed by yt-dlp
+const filepathLinePrefix = "[godlp-filepath] "
+
+// ytDlpFilepathPrint makes yt-dlp print the final path of every file once it
+// has been post-processed and moved into place
+const ytDlpFilepathPrint = "after_move:" + filepathLinePrefix + "%(filepath)s"
+
+// titleLinePrefix marks the title printed by yt-dlp before downloading
+const titleLinePrefix = "[godlp-title] "
+
+// ytDlpTitlePrint makes yt-dlp print the video title, or the playlist title
+// for playlist items, before the download starts
+const ytDlpTitlePrint = "before_dl:" + titleLinePrefix + "%(playlist_title,title)s"
+
+// Prefixes of the lines yt-dlp prints when a playlist item starts and finishes
+const (
+	itemStartLinePrefix  = "[godlp-item-start] "
+	itemFinishLinePrefix = "[godlp-item-finish] "
+)
+
+// ytDlpItemStartPrint makes yt-dlp print a JSON line before each playlist item
+// downloads. playlist_index goes through the j conversion because with s yt-dlp
+// zero-pads it in playlists of 10 or more items, and 01 is not valid JSON.
+const ytDlpItemStartPrint = "before_dl:" + itemStartLinePrefix +
+	`{"playlist_index":%(playlist_index|null)j,"video_id":%(id|null)j,"title":%(title|null)j}`
+
+// ytDlpItemFinishPrint makes yt-dlp print a JSON line once each playlist item
+// has been downloaded and post-processed
+const ytDlpItemFinishPrint = "after_video:" + itemFinishLinePrefix +
+	`{"playlist_index":%(playlist_index|null)j,"video_id":%(id|null)j,"file_path":%(filepath|null)j}`
+
+// itemLine mirrors the JSON written by ytDlpItemStartPrint and ytDlpItemFinishPrint
+type itemLine struct {
+	PlaylistIndex int    `json:"playlist_index"`
+	VideoID       string `json:"video_id"`
+	Title         string `json:"title,omitempty"`
+	FilePath      string `json:"file_path,omitempty"`
+}
+
+// parseItemLine parses a line printed via ytDlpItemStartPrint or
+// ytDlpItemFinishPrint and returns the event it reports, or false for any
+// other output
+func parseItemLine(line string) (string, itemLine, bool) {
+	for prefix, event := range map[string]string{
+		itemStartLinePrefix:  "playlist-item-start",
+		itemFinishLinePrefix: "playlist-item-finish",
+	} {
+		idx := strings.Index(line, prefix)
+		if idx < 0 {
+			continue
+		}
+		var item itemLine
+		if err := json.Unmarshal([]byte(strings.TrimSpace(line[idx+len(prefix):])), &item); err != nil {
+			return "", itemLine{}, false
+		}
+		return event, item, true
+	}
+	return "", itemLine{}, false
+}
+
+// progressEmitInterval is how often an unchanged percentage is re-sent so
+// speed and ETA stay fresh in the UI
+const progressEmitInterval = 500 * time.Millisecond
+
+// progressUpdate is a single parsed progress line
+type progressUpdate struct {
+	Phase           string // progressPhaseDownload or progressPhasePostprocess
+	Postprocessor   string // Name of the running post-processor, e.g. "ExtractAudio"
+	Status          string
+	Percent         float64
+	DownloadedBytes int64
+	TotalBytes      int64
+	TotalEstimated  bool    // TotalBytes is yt-dlp's estimate, e.g. for fragmented streams
+	SpeedBps        float64 // 0 when unknown
+	EtaSeconds      int64   // -1 when unknown
+	FragmentIndex   int
+	FragmentCount   int
+	PlaylistIndex   int
+	PlaylistCount   int
+	TmpFilename     string // Partial file being written while downloading
+}
+
+// progressLine mirrors the JSON written by ytDlpProgressTemplate
+type progressLine struct {
+	Progress struct {
+		Status             string   `json:"status"`
+		Postprocessor      string   `json:"postprocessor"`
+		TmpFilename        string   `json:"tmpfilename"`
+		DownloadedBytes    float64  `json:"downloaded_bytes"`
+		TotalBytes         float64  `json:"total_bytes"`
+		TotalBytesEstimate float64  `json:"total_bytes_estimate"`
+		Speed              float64  `json:"speed"`
+		Eta                *float64 `json:"eta"`
+		FragmentIndex      float64  `json:"fragment_index"`
+		FragmentCount      float64  `json:"fragment_count"`
+	} `json:"progress"`
+	PlaylistIndex float64 `json:"playlist_index"`
+	PlaylistCount float64 `json:"playlist_count"`
+}
+
+// parseProgressLine parses a line printed via ytDlpProgressTemplate.
+// It returns false for any other output.
+func parseProgressLine(line string) (progressUpdate, bool) {
+	idx := strings.Index(line, progressLinePrefix)
+	if idx < 0 {
+		return progressUpdate{}, false
+	}
+
+	var raw progressLine
+	if err := json.Unmarshal([]byte(strings.TrimSpace(line[idx+len(progressLinePrefix):])), &raw); err != nil {
+		return progressUpdate{}, false
+	}
+
+	p := raw.Progress
+	update := progressUpdate{
+		Phase:           progressPhaseDownload,
+		Status:          p.Status,
+		DownloadedBytes: int64(p.DownloadedBytes),
+		TotalBytes:      int64(p.TotalBytes),
+		SpeedBps:        p.Speed,
+		EtaSeconds:      -1,
+		FragmentIndex:   int(p.FragmentIndex),
+		FragmentCount:   int(p.FragmentCount),
+		PlaylistIndex:   int(raw.PlaylistIndex),
+		PlaylistCount:   int(raw.PlaylistCount),
+	}
+	if p.Status == "downloading" {
+		update.TmpFilename = p.TmpFilename
+	}
+	if update.TotalBytes <= 0 && p.TotalBytesEstimate > 0 {
+		update.TotalBytes = int64(p.TotalBytesEstimate)
+		update.TotalEstimated = true
+	}
+	if p.Eta != nil && *p.Eta >= 0 {
+		update.EtaSeconds = int64(*p.Eta)
+	}
+
+	// Post-processors only report when they start and finish
+	if p.Postprocessor != "" {
+		update.Phase = progressPhasePostprocess
+		update.Postprocessor = p.Postprocessor
+		if update.Status == "finished" {
+			update.Percent = 100
+		}
+		return update, true
+	}
+
+	switch {
+	case update.Status == "finished":
+		update.Percent = 100
+	case update.TotalBytes > 0:
+		update.Percent = float64(update.DownloadedBytes) / float64(update.TotalBytes) * 100
+	case update.FragmentCount > 0:
+		update.Percent = float64(update.FragmentIndex) / float64(update.FragmentCount) * 100
+	}
+	if update.Percent > 100 {
+		update.Percent = 100
+	}
+	return update, true
+}
+
+// parseFilepathLine extracts the path from a line printed via ytDlpFilepathPrint
+func parseFilepathLine(line string) (string, bool) {
+	idx := strings.Index(line, filepathLinePrefix)
+	if idx < 0 {
+		return "", false
+	}
+	path := strings.TrimSpace(line[idx+len(filepathLinePrefix):])
+	if path == "" || path == "NA" {
+		return "", false
+	}
+	return path, true
+}
+
+// parseTitleLine extracts the title from a line printed via ytDlpTitlePrint
+func parseTitleLine(line string) (string, bool) {
+	idx := strings.Index(line, titleLinePrefix)
+	if idx < 0 {
+		return "", false
+	}
+	title := strings.TrimSpace(line[idx+len(titleLinePrefix):])
+	if title == "" || title == "NA" {
+		return "", false
+	}
+	return title, true
+}
+
+// sizeString formats the total size the way the UI shows it
+func (p progressUpdate) sizeString() string {
+	if p.TotalBytes <= 0 {
+		return "Calculating..."
+	}
+	if p.TotalEstimated {
+		return "~" + formatFileSizeHuman(float64(p.TotalBytes))
+	}
+	return formatFileSizeHuman(float64(p.TotalBytes))
+}
+
+// speedString formats the download speed the way the UI shows it
+func (p progressUpdate) speedString() string {
+	if p.SpeedBps <= 0 {
+		return "Calculating..."
+	}
+	return formatFileSizeHuman(p.SpeedBps) + "/s"
+}
+
+// etaString formats the remaining time the way the UI shows it
+func (p progressUpdate) etaString() string {
+	if p.EtaSeconds < 0 {
+		return "Calculating..."
+	}
+	return formatDuration(float64(p.EtaSeconds))
+}
+
+// eventData builds the download-progress payload with both formatted and numeric fields
+func (p progressUpdate) eventData() map[string]interface{} {
+	if p.Phase == progressPhasePostprocess {
+		return map[string]interface{}{
+			"progress":       int(p.Percent),
+			"phase":          p.Phase,
+			"phase_progress": int(p.Percent),
+			"postprocessor":  p.Postprocessor,
+			"status":         p.Status,
+			"playlist_index": p.PlaylistIndex,
+			"playlist_count": p.PlaylistCount,
+		}
+	}
+
+	return map[string]interface{}{
+		"progress":         int(p.Percent),
+		"phase":            p.Phase,
+		"phase_progress":   int(p.Percent),
+		"size":             p.sizeString(),
+		"speed":            p.speedString(),
+		"eta":              p.etaString(),
+		"downloaded_bytes": p.DownloadedBytes,
+		"total_bytes":      p.TotalBytes,
+		"speed_bps":        p.SpeedBps,
+		"eta_seconds":      p.EtaSeconds,
+		"fragment_index":   p.FragmentIndex,
+		"fragment_count":   p.FragmentCount,
+		"playlist_index":   p.PlaylistIndex,
+		"playlist_count":   p.PlaylistCount,
+	}
+}
+
+// progressReporter turns yt-dlp output lines into download-progress events for a
+// job and collects the output files yt-dlp reports
+type progressReporter struct {
+	app *App
+	job *DownloadJob
+
+	mu            sync.Mutex
+	lastPercent   float64
+	lastEmitted   int // Overall progress last sent
+	lastFileEmit  int // Progress of the current file last sent
+	lastEmitTime  time.Time
+	lastTotal     int64
+	playlistIndex int
+	files         []string
+	archived      int              // Videos skipped because the download archive lists them
+	playlist      *playlistTracker // Item tracking for playlist jobs, nil otherwise
+
+	lastPostprocess string // Postprocessor and status of the last post-processing event
+}
+
+// newProgressReporter creates a reporter that has not emitted anything yet
+func newProgressReporter(a *App, job *DownloadJob) *progressReporter {
+	r := &progressReporter{app: a, job: job, lastEmitted: -1, lastFileEmit: -1}
+	if job.Playlist {
+		r.playlist = &playlistTracker{}
+	}
+	return r
+}
+
+// resetPlaylist forgets the items seen so far, for when a playlist download
+// starts over, e.g. when it is retried without cookies
+func (r *progressReporter) resetPlaylist() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.playlist != nil {
+		r.playlist = &playlistTracker{}
+	}
+}
+
+// playlistSummary returns how the items of a playlist job ended so far
+func (r *progressReporter) playlistSummary() playlistSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.playlist == nil {
+		return playlistSummary{Errors: []playlistItemError{}}
+	}
+	return r.playlist.result()
+}
+
+// handleLine emits a progress event if the line carries new progress information
+// and records reported output files and playlist items. It returns false for any
+// other output, including error lines it recorded for a playlist item.
+func (r *progressReporter) handleLine(line string) bool {
+	if title, ok := parseTitleLine(line); ok {
+		r.app.setJobTitle(r.job, title)
+		return true
+	}
+	if path, ok := parseFilepathLine(line); ok {
+		r.mu.Lock()
+		r.files = append(r.files, path)
+		r.mu.Unlock()
+		return true
+	}
+	if event, item, ok := parseItemLine(line); ok {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		data := map[string]interface{}{
+			"playlist_index": item.PlaylistIndex,
+			"video_id":       item.VideoID,
+		}
+		if event == "playlist-item-start" {
+			data["title"] = item.Title
+		} else {
+			data["file_path"] = item.FilePath
+		}
+		if r.playlist != nil {
+			if event == "playlist-item-start" {
+				r.playlist.itemTitle = item.Title
+				r.playlist.itemVideoID = item.VideoID
+			} else {
+				r.playlist.endItem(&r.playlist.summary.Succeeded)
+			}
+			data["item_index"] = r.playlist.itemIndex
+			data["item_count"] = r.playlist.itemCount
+		}
+		r.app.emitDownloadEvent(r.job, event, data)
+		return true
+	}
+	if strings.Contains(line, archivedLineMarker) {
+		r.mu.Lock()
+		r.archived++
+		if r.playlist != nil {
+			r.playlist.itemArchived(line)
+		}
+		r.mu.Unlock()
+		return true
+	}
+	if r.playlist != nil {
+		r.mu.Lock()
+		handled := r.playlist.handleLine(line)
+		r.mu.Unlock()
+		if handled {
+			return true
+		}
+	}
+
+	update, ok := parseProgressLine(line)
+	if !ok {
+		return false
+	}
+
+	if update.TmpFilename != "" {
+		r.app.addJobPartialFile(r.job, update.TmpFilename)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if update.Phase == progressPhasePostprocess {
+		step := update.Postprocessor + ":" + update.Status
+		if step == r.lastPostprocess {
+			return true
+		}
+		r.lastPostprocess = step
+
+		// Keep the overall progress where the download left it
+		data := update.eventData()
+		data["progress"] = r.lastEmitted
+		if r.lastEmitted < 0 {
+			data["progress"] = 0
+		}
+		if r.playlist != nil {
+			r.playlist.addEventData(data)
+		}
+		r.app.emitDownloadEvent(r.job, "download-progress", data)
+		return true
+	}
+
+	// A new playlist item or stream (e.g. audio after video) starts from zero again
+	if update.PlaylistIndex != r.playlistIndex || (update.Percent < 1 && r.lastPercent > 90) {
+		r.playlistIndex = update.PlaylistIndex
+		r.lastPercent = 0
+	}
+	// Never let the progress bar jump backwards within one file
+	if update.Percent < r.lastPercent {
+		update.Percent = r.lastPercent
+	} else {
+		r.lastPercent = update.Percent
+	}
+
+	progress := int(update.Percent)
+	now := time.Now()
+	if progress == r.lastFileEmit && now.Sub(r.lastEmitTime) < progressEmitInterval {
+		return true
+	}
+	r.lastFileEmit = progress
+	r.lastEmitTime = now
+	r.lastTotal = update.TotalBytes
+
+	data := update.eventData()
+	// For playlists "progress" covers the whole download and item_progress the current item
+	if r.playlist != nil {
+		data["progress"] = r.playlist.overallPercent(update.Percent)
+		data["item_progress"] = progress
+		r.playlist.addEventData(data)
+	}
+	r.lastEmitted = data["progress"].(int)
+
+	r.app.emitDownloadEvent(r.job, "download-progress", data)
+	return true
+}
+
+// outputFiles returns the final paths yt-dlp reported, in the order they were written
+func (r *progressReporter) outputFiles() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.files...)
+}
+
+// archivedCount returns how many videos were skipped because the download archive lists them
+func (r *progressReporter) archivedCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.archived
+}
+
+// finish emits 100% progress unless it was already reported
+func (r *progressReporter) finish() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.lastEmitted == 100 {
+		return
+	}
+	r.lastEmitted = 100
+	r.app.emitDownloadEvent(r.job, "download-progress", map[string]interface{}{
+		"progress":         100,
+		"phase":            progressPhaseDownload,
+		"phase_progress":   100,
+		"size":             "Complete",
+		"speed":            "0",
+		"eta":              "00:00",
+		"downloaded_bytes": r.lastTotal,
+		"total_bytes":      r.lastTotal,
+		"speed_bps":        float64(0),
+		"eta_seconds":      int64(0),
+	})
+}
+
+// scanOutputLines calls handle for every line read from a yt-dlp output stream
+func scanOutputLines(r io.Reader, handle func(line string)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		handle(scanner.Text())
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseProgressLine(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want progressUpdate
+	}{
+		{
+			name: "Known size",
+			line: `[godlp-progress] {"progress":{"status":"downloading","downloaded_bytes":524288,"total_bytes":1048576,"speed":262144.5,"eta":2},"playlist_index":null,"playlist_count":null}`,
+			want: progressUpdate{Phase: progressPhaseDownload, Status: "downloading", Percent: 50, DownloadedBytes: 524288, TotalBytes: 1048576, SpeedBps: 262144.5, EtaSeconds: 2},
+		},
+		{
+			name: "Estimated size with fragments",
+			line: `[godlp-progress] {"progress":{"status":"downloading","downloaded_bytes":100,"total_bytes":null,"total_bytes_estimate":400,"speed":null,"eta":null,"fragment_index":8,"fragment_count":664},"playlist_index":null,"playlist_count":null}`,
+			want: progressUpdate{Phase: progressPhaseDownload, Status: "downloading", Percent: 25, DownloadedBytes: 100, TotalBytes: 400, TotalEstimated: true, EtaSeconds: -1, FragmentIndex: 8, FragmentCount: 664},
+		},
+		{
+			name: "Fragments only",
+			line: `[godlp-progress] {"progress":{"status":"downloading","downloaded_bytes":100,"fragment_index":1,"fragment_count":4},"playlist_index":3,"playlist_count":10}`,
+			want: progressUpdate{Phase: progressPhaseDownload, Status: "downloading", Percent: 25, DownloadedBytes: 100, EtaSeconds: -1, FragmentIndex: 1, FragmentCount: 4, PlaylistIndex: 3, PlaylistCount: 10},
+		},
+		{
+			name: "Partial file",
+			line: `[godlp-progress] {"progress":{"status":"downloading","downloaded_bytes":10,"total_bytes":40,"filename":"clip.mp4","tmpfilename":"clip.mp4.part"},"playlist_index":null,"playlist_count":null}`,
+			want: progressUpdate{Phase: progressPhaseDownload, Status: "downloading", Percent: 25, DownloadedBytes: 10, TotalBytes: 40, EtaSeconds: -1, TmpFilename: "clip.mp4.part"},
+		},
+		{
+			name: "Finished",
+			line: `[godlp-progress] {"progress":{"status":"finished","downloaded_bytes":2048,"total_bytes":2048},"playlist_index":null,"playlist_count":null}` + "\r",
+			want: progressUpdate{Phase: progressPhaseDownload, Status: "finished", Percent: 100, DownloadedBytes: 2048, TotalBytes: 2048, EtaSeconds: -1},
+		},
+		{
+			name: "Postprocessor started",
+			line: `[godlp-progress] {"progress":{"status":"started","postprocessor":"ExtractAudio"},"playlist_index":null,"playlist_count":null}`,
+			want: progressUpdate{Phase: progressPhasePostprocess, Postprocessor: "ExtractAudio", Status: "started", EtaSeconds: -1},
+		},
+		{
+			name: "Postprocessor finished",
+			line: `[godlp-progress] {"progress":{"status":"finished","postprocessor":"ExtractAudio"},"playlist_index":2,"playlist_count":5}`,
+			want: progressUpdate{Phase: progressPhasePostprocess, Postprocessor: "ExtractAudio", Status: "finished", Percent: 100, EtaSeconds: -1, PlaylistIndex: 2, PlaylistCount: 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseProgressLine(tt.line)
+			if !ok {
+				t.Fatalf("parseProgressLine(%q) did not recognize the line", tt.line)
+			}
+			if got != tt.want {
+				t.Errorf("parseProgressLine() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProgressLineIgnoresOtherOutput(t *testing.T) {
+	lines := []string{
+		"",
+		"[youtube] abc: Downloading webpage",
+		"[download]  12.3% of ~1.11GiB at 699.38KiB/s ETA 28:37",
+		"[godlp-progress] {not json",
+	}
+
+	for _, line := range lines {
+		if _, ok := parseProgressLine(line); ok {
+			t.Errorf("parseProgressLine(%q) should not be recognized", line)
+		}
+	}
+}
+
+func TestProgressUpdateEventData(t *testing.T) {
+	update := progressUpdate{Percent: 42.9, DownloadedBytes: 450, TotalBytes: 1024 * 1024, TotalEstimated: true, SpeedBps: 2048, EtaSeconds: 75, PlaylistIndex: 2}
+	data := update.eventData()
+
+	if data["progress"] != 42 {
+		t.Errorf("progress = %v, want 42", data["progress"])
+	}
+	if data["size"] != "~1.00 MB" {
+		t.Errorf("size = %v, want ~1.00 MB", data["size"])
+	}
+	if data["speed"] != "2.00 KB/s" {
+		t.Errorf("speed = %v, want 2.00 KB/s", data["speed"])
+	}
+	if data["eta"] != "1:15" {
+		t.Errorf("eta = %v, want 1:15", data["eta"])
+	}
+	if data["downloaded_bytes"] != int64(450) || data["eta_seconds"] != int64(75) || data["playlist_index"] != 2 {
+		t.Errorf("unexpected numeric fields: %v", data)
+	}
+
+	unknown := progressUpdate{EtaSeconds: -1}.eventData()
+	for _, key := range []string{"size", "speed", "eta"} {
+		if data := unknown[key].(string); !strings.HasPrefix(data, "Calculating") {
+			t.Errorf("%s = %q, want Calculating...", key, data)
+		}
+	}
+}
+
+func TestYtDlpProgressTemplate(t *testing.T) {
+	if !strings.HasPrefix(ytDlpProgressTemplate, "download:"+progressLinePrefix) {
+		t.Errorf("template must print the progress prefix, got %q", ytDlpProgressTemplate)
+	}
+	if !strings.HasPrefix(ytDlpPostprocessTemplate, "postprocess:"+progressLinePrefix) {
+		t.Errorf("postprocess template must print the progress prefix, got %q", ytDlpPostprocessTemplate)
+	}
+}
+
+func TestParseFilepathLine(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected string
+		ok       bool
+	}{
+		{"[godlp-filepath] /home/user/Downloads/My Video [abc].m4a", "/home/user/Downloads/My Video [abc].m4a", true},
+		{`[godlp-filepath] C:\Users\me\Downloads\clip.mkv` + "\r", `C:\Users\me\Downloads\clip.mkv`, true},
+		{"[godlp-filepath] NA", "", false},
+		{"[download] Destination: clip.mp4", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseFilepathLine(tt.line)
+		if got != tt.expected || ok != tt.ok {
+			t.Errorf("parseFilepathLine(%q) = %q, %v, want %q, %v", tt.line, got, ok, tt.expected, tt.ok)
+		}
+	}
+}
+
+func TestYtDlpFilepathPrint(t *testing.T) {
+	if !strings.HasPrefix(ytDlpFilepathPrint, "after_move:"+filepathLinePrefix) {
+		t.Errorf("print template must run after_move and print the prefix, got %q", ytDlpFilepathPrint)
+	}
+}
+
+func TestParseTitleLine(t *testing.T) {
+	if got, ok := parseTitleLine("[godlp-title] Song: Live / Acoustic\r"); !ok || got != "Song: Live / Acoustic" {
+		t.Errorf("parseTitleLine() = %q, %v", got, ok)
+	}
+	if _, ok := parseTitleLine("[godlp-title] NA"); ok {
+		t.Error("parseTitleLine should ignore a missing title")
+	}
+	if !strings.HasPrefix(ytDlpTitlePrint, "before_dl:"+titleLinePrefix) {
+		t.Errorf("title print must run before_dl and print the prefix, got %q", ytDlpTitlePrint)
+	}
+}
+
+func TestParseItemLine(t *testing.T) {
+	tests := []struct {
+		line  string
+		event string
+		item  itemLine
+		ok    bool
+	}{
+		{`[godlp-item-start] {"playlist_index":3,"video_id":"a1","title":"First"}`, "playlist-item-start", itemLine{PlaylistIndex: 3, VideoID: "a1", Title: "First"}, true},
+		{`[godlp-item-finish] {"playlist_index":3,"video_id":"a1","file_path":"dl/First.mp4"}` + "\r", "playlist-item-finish", itemLine{PlaylistIndex: 3, VideoID: "a1", FilePath: "dl/First.mp4"}, true},
+		{`[godlp-item-finish] {"playlist_index":null,"video_id":"a1","file_path":null}`, "playlist-item-finish", itemLine{VideoID: "a1"}, true},
+		// What the print templates used to produce for item 1 of 10 or more
+		{`[godlp-item-start] {"playlist_index":01,"video_id":"a1","title":"First"}`, "", itemLine{}, false},
+		{`[godlp-item-start] not json`, "", itemLine{}, false},
+		{`[download] Downloading item 3 of 10`, "", itemLine{}, false},
+	}
+
+	for _, tt := range tests {
+		event, item, ok := parseItemLine(tt.line)
+		if event != tt.event || item != tt.item || ok != tt.ok {
+			t.Errorf("parseItemLine(%q) = %q, %+v, %v, want %q, %+v, %v", tt.line, event, item, ok, tt.event, tt.item, tt.ok)
+		}
+	}
+}
+
+func TestYtDlpItemPrints(t *testing.T) {
+	for _, template := range []string{ytDlpItemStartPrint, ytDlpItemFinishPrint, progressTemplateBody} {
+		if strings.Contains(template, "playlist_index|null)s") {
+			t.Errorf("playlist_index must be printed as a JSON number, got %q", template)
+		}
+	}
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultMaxConcurrentDownloads is used when the setting is missing or invalid
+const defaultMaxConcurrentDownloads = 2
+
+// errJobNotFound is returned for operations on an unknown job ID
+var errJobNotFound = errors.New("download job not found")
+
+// downloadQueue holds all download jobs and tracks how many of them are running
+type downloadQueue struct {
+	mu      sync.Mutex
+	jobs    map[string]*DownloadJob
+	order   []string // Job IDs in the order they were enqueued
+	running int
+
+	saveMu    sync.Mutex // Serializes writes of the queue state file
+	statePath string     // Queue state file, empty to keep the queue in memory only
+}
+
+// newDownloadQueue creates an empty download queue
+func newDownloadQueue() *downloadQueue {
+	return &downloadQueue{
+		jobs: make(map[string]*DownloadJob),
+	}
+}
+
+// newJobID generates a random identifier for a download job
+func newJobID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	return hex.EncodeToString(buf)
+}
+
+// jobTitleFromOutputPath derives a display title from the output template,
+// falling back to the URL when the template has no usable file name
+func jobTitleFromOutputPath(outputPath, url string) string {
+	name := filepath.Base(strings.TrimSuffix(outputPath, ".%(ext)s"))
+	if name == "" || name == "." || strings.Contains(name, "%(") {
+		return url
+	}
+	return name
+}
+
+// maxConcurrentDownloads returns the configured number of parallel downloads
+func (a *App) maxConcurrentDownloads() int {
+	maxDownloads := a.currentSettings().MaxConcurrentDownloads
+	if maxDownloads < 1 {
+		return defaultMaxConcurrentDownloads
+	}
+	return maxDownloads
+}
+
+// enqueueJob adds a job to the queue and starts it if a slot is free
+func (a *App) enqueueJob(job *DownloadJob) string {
+	job.ID = newJobID()
+	job.Status = JobStatusQueued
+	job.CreatedAt = time.Now()
+	if job.Title == "" {
+		job.Title = jobTitleFromOutputPath(job.OutputPath, job.URL)
+	}
+
+	a.queue.mu.Lock()
+	a.queue.jobs[job.ID] = job
+	a.queue.order = append(a.queue.order, job.ID)
+	a.queue.mu.Unlock()
+	a.saveQueueState()
+
+	a.logger.Infof("Download job %s queued for URL: %s", job.ID, job.URL)
+	a.events.Emit("download-queued", map[string]interface{}{
+		"id":  job.ID,
+		"url": job.URL,
+	})
+
+	a.scheduleDownloads()
+	return job.ID
+}
+
+// scheduleDownloads starts queued jobs until the concurrency limit is reached
+func (a *App) scheduleDownloads() {
+	limit := a.maxConcurrentDownloads()
+
+	a.queue.mu.Lock()
+	var toStart []*DownloadJob
+	for _, id := range a.queue.order {
+		if a.queue.running >= limit {
+			break
+		}
+		job := a.queue.jobs[id]
+		if job.Status != JobStatusQueued {
+			continue
+		}
+		job.Status = JobStatusRunning
+		job.Error = ""
+		job.stopReason = ""
+		job.completionEmitted = false
+		job.StartedAt = time.Now()
+		a.queue.running++
+		toStart = append(toStart, job)
+	}
+	a.queue.mu.Unlock()
+
+	if len(toStart) == 0 {
+		return
+	}
+	a.saveQueueState()
+
+	for _, job := range toStart {
+		go a.startJob(job)
+	}
+}
+
+// startJob launches the yt-dlp process for a job that has been given a slot
+func (a *App) startJob(job *DownloadJob) {
+	var err error
+	if job.Playlist {
+		err = a.startPlaylistDownload(job)
+	} else {
+		err = a.startVideoDownload(job)
+	}
+
+	if err != nil {
+		a.logger.Errorf("Failed to start download job %s: %v", job.ID, err)
+		a.logDetailedError("StartDownloadJob", job.URL, job.FormatID, err)
+		a.emitDownloadEvent(job, "download-error", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
+
+// emitDownloadEvent emits a download event tagged with the job ID, ensuring only
+// one terminal (complete/error/cancelled) event is emitted per job run
+func (a *App) emitDownloadEvent(job *DownloadJob, eventType string, data map[string]interface{}) {
+	payload := map[string]interface{}{"id": job.ID}
+	for key, value := range data {
+		payload[key] = value
+	}
+
+	terminal := eventType == "download-complete" || eventType == "download-error" || eventType == "download-cancelled"
+	var finished DownloadJob
+
+	a.queue.mu.Lock()
+	if job.completionEmitted {
+		a.queue.mu.Unlock()
+		return // Already emitted a terminal event for this run
+	}
+
+	if terminal {
+		job.completionEmitted = true
+		if job.Status == JobStatusRunning {
+			a.queue.running--
+		}
+		job.cmd = nil
+
+		switch eventType {
+		case "download-complete":
+			job.Status = JobStatusCompleted
+			job.Progress = 100
+			job.PartialFiles = nil
+		case "download-error":
+			job.Status = JobStatusFailed
+			job.Error = fmt.Sprint(data["error"])
+		case "download-cancelled":
+			if job.stopReason == "pause" {
+				job.Status = JobStatusPaused
+			} else {
+				job.Status = JobStatusCancelled
+			}
+		}
+		finished = *job
+		finished.cmd = nil
+	} else {
+		if progress, ok := data["progress"].(int); ok {
+			job.Progress = progress
+		}
+		if downloaded, ok := data["downloaded_bytes"].(int64); ok {
+			job.DownloadedBytes = downloaded
+		}
+	}
+	a.queue.mu.Unlock()
+
+	if terminal {
+		a.mergeForcedArchive(finished)
+	}
+	a.events.Emit(eventType, payload)
+
+	if terminal {
+		if finished.Status != JobStatusPaused {
+			a.recordJobHistory(finished)
+		}
+		a.saveQueueState()
+		a.scheduleDownloads()
+	}
+}
+
+// setJobCommand records the process running a job. It returns false if the job
+// has been stopped in the meantime and the process should not be started.
+func (a *App) setJobCommand(job *DownloadJob, cmd *exec.Cmd) bool {
+	a.queue.mu.Lock()
+	defer a.queue.mu.Unlock()
+
+	if job.stopReason != "" || job.completionEmitted {
+		return false
+	}
+	job.cmd = cmd
+	return true
+}
+
+// jobCommandActive reports whether cmd is still the process driving the job.
+// It turns false once the job is stopped, finished or retried with a new process.
+func (a *App) jobCommandActive(job *DownloadJob, cmd *exec.Cmd) bool {
+	a.queue.mu.Lock()
+	defer a.queue.mu.Unlock()
+	return job.cmd == cmd
+}
+
+// listJobsInternal returns all jobs in queue order as JSON
+func (a *App) listJobsInternal() (string, error) {
+	a.queue.mu.Lock()
+	jobs := make([]DownloadJob, 0, len(a.queue.order))
+	for _, id := range a.queue.order {
+		job := *a.queue.jobs[id]
+		job.cmd = nil
+		jobs = append(jobs, job)
+	}
+	a.queue.mu.Unlock()
+
+	result, err := json.Marshal(jobs)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal jobs: %w", err)
+	}
+	return string(result), nil
+}
+
+// stopJobInternal cancels or pauses a job. Running jobs have their yt-dlp
+// process killed; queued jobs are simply taken out of the schedule.
+func (a *App) stopJobInternal(id, reason string) error {
+	a.queue.mu.Lock()
+	job, exists := a.queue.jobs[id]
+	if !exists {
+		a.queue.mu.Unlock()
+		return fmt.Errorf("%w: %s", errJobNotFound, id)
+	}
+	idle := job.Status == JobStatusPaused || job.Status == JobStatusInterrupted
+	if job.Status != JobStatusRunning && job.Status != JobStatusQueued && !(reason == "cancel" && idle) {
+		a.queue.mu.Unlock()
+		return fmt.Errorf("download job %s is not active (status: %s)", id, job.Status)
+	}
+
+	job.stopReason = reason
+	if idle {
+		// Paused jobs have no process; cancelling them is a plain status change
+		job.Status = JobStatusCancelled
+		finished := *job
+		finished.cmd = nil
+		a.queue.mu.Unlock()
+		a.recordJobHistory(finished)
+		a.saveQueueState()
+		a.events.Emit("download-cancelled", map[string]interface{}{
+			"id":     job.ID,
+			"reason": reason,
+		})
+		return nil
+	}
+	cmd := job.cmd
+	job.cmd = nil
+	a.queue.mu.Unlock()
+
+	if cmd != nil && cmd.Process != nil {
+		a.logger.Infof("Stopping download job %s...", id)
+		if err := cmd.Process.Kill(); err != nil {
+			a.logger.Errorf("Failed to stop download job %s: %v", id, err)
+			return fmt.Errorf("failed to stop download: %w", err)
+		}
+	}
+
+	a.logger.Infof("Download job %s stopped, reason: %s", id, reason)
+	data := map[string]interface{}{"reason": reason}
+	if reason == "pause" {
+		// The .part files stay on disk so resuming continues where the download stopped
+		data["downloaded_bytes"] = a.updateJobPartialBytes(job)
+	}
+	a.emitDownloadEvent(job, "download-cancelled", data)
+	return nil
+}
+
+// resumeJobInternal puts a paused or interrupted job back into the queue
+func (a *App) resumeJobInternal(id string) error {
+	a.queue.mu.Lock()
+	job, exists := a.queue.jobs[id]
+	if !exists {
+		a.queue.mu.Unlock()
+		return fmt.Errorf("%w: %s", errJobNotFound, id)
+	}
+	if job.Status != JobStatusPaused && job.Status != JobStatusInterrupted {
+		a.queue.mu.Unlock()
+		return fmt.Errorf("download job %s is not paused (status: %s)", id, job.Status)
+	}
+	job.Status = JobStatusQueued
+	job.stopReason = ""
+	job.completionEmitted = false
+	a.queue.mu.Unlock()
+
+	downloaded := a.updateJobPartialBytes(job)
+	a.logger.Infof("Download job %s resumed with %d bytes on disk", id, downloaded)
+	a.saveQueueState()
+	a.events.Emit("download-resumed", map[string]interface{}{
+		"id":               id,
+		"downloaded_bytes": downloaded,
+	})
+	a.scheduleDownloads()
+	return nil
+}
+
+// resumeDownloadInternal resumes one paused job, or every paused job when id
+// is empty, the counterpart of PauseDownload
+func (a *App) resumeDownloadInternal(id string) error {
+	if id == "" {
+		return a.resumeAllJobsInternal()
+	}
+	return a.resumeJobInternal(id)
+}
+
+// resumeAllJobsInternal puts every paused or interrupted job back into the queue
+func (a *App) resumeAllJobsInternal() error {
+	a.queue.mu.Lock()
+	var ids []string
+	for _, id := range a.queue.order {
+		status := a.queue.jobs[id].Status
+		if status == JobStatusPaused || status == JobStatusInterrupted {
+			ids = append(ids, id)
+		}
+	}
+	a.queue.mu.Unlock()
+
+	if len(ids) == 0 {
+		return fmt.Errorf("no paused downloads to resume")
+	}
+
+	for _, id := range ids {
+		if err := a.resumeJobInternal(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stopAllJobsInternal stops every running job, used by the legacy
+// CancelDownload/PauseDownload bindings that predate job IDs
+func (a *App) stopAllJobsInternal(reason string) error {
+	a.queue.mu.Lock()
+	var ids []string
+	for _, id := range a.queue.order {
+		if a.queue.jobs[id].Status == JobStatusRunning {
+			ids = append(ids, id)
+		}
+	}
+	a.queue.mu.Unlock()
+
+	if len(ids) == 0 {
+		a.logger.Infof("No active download to cancel")
+		return fmt.Errorf("no active download to cancel")
+	}
+
+	for _, id := range ids {
+		if err := a.stopJobInternal(id, reason); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// completedFilePathFor returns the file reported by yt-dlp for the newest completed
+// video job with the given title
+func (a *App) completedFilePathFor(title string) string {
+	if a.queue == nil {
+		return ""
+	}
+
+	a.queue.mu.Lock()
+	defer a.queue.mu.Unlock()
+
+	for i := len(a.queue.order) - 1; i >= 0; i-- {
+		job := a.queue.jobs[a.queue.order[i]]
+		if job.Status == JobStatusCompleted && !job.Playlist && job.FilePath != "" && job.Title == title {
+			return job.FilePath
+		}
+	}
+	return ""
+}
+
+// hasPartialFilesFor reports whether an unfinished video job with the given title
+// left partial files behind
+func (a *App) hasPartialFilesFor(title string) bool {
+	if a.queue == nil {
+		return false
+	}
+
+	a.queue.mu.Lock()
+	defer a.queue.mu.Unlock()
+
+	for _, job := range a.queue.jobs {
+		if job.Status != JobStatusCompleted && !job.Playlist && job.Title == title && len(job.PartialFiles) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// setJobTitle records the title yt-dlp reported for a job
+func (a *App) setJobTitle(job *DownloadJob, title string) {
+	a.queue.mu.Lock()
+	job.Title = title
+	a.queue.mu.Unlock()
+}
+
+// setJobFilePath records where a job's output ended up
+func (a *App) setJobFilePath(job *DownloadJob, path string) {
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
+	}
+
+	a.queue.mu.Lock()
+	job.FilePath = path
+	a.queue.mu.Unlock()
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// queueStateFile stores unfinished download jobs next to settings.json
+const queueStateFile = "./queue.json"
+
+// queueState is the on-disk representation of the download queue
+type queueState struct {
+	Jobs []DownloadJob `json:"jobs"`
+}
+
+// isResumableStatus reports whether a job in this status should survive a restart
+func isResumableStatus(status string) bool {
+	switch status {
+	case JobStatusQueued, JobStatusRunning, JobStatusPaused, JobStatusInterrupted:
+		return true
+	}
+	return false
+}
+
+// saveQueueState writes all unfinished jobs to the queue state file
+func (a *App) saveQueueState() {
+	if a.queue.statePath == "" {
+		return
+	}
+
+	// Concurrent saves share the temporary file, and a save must not replace a newer snapshot
+	a.queue.saveMu.Lock()
+	defer a.queue.saveMu.Unlock()
+
+	a.queue.mu.Lock()
+	state := queueState{Jobs: []DownloadJob{}}
+	for _, id := range a.queue.order {
+		job := a.queue.jobs[id]
+		if !isResumableStatus(job.Status) {
+			continue
+		}
+		snapshot := *job
+		snapshot.cmd = nil
+		state.Jobs = append(state.Jobs, snapshot)
+	}
+	a.queue.mu.Unlock()
+
+	if err := writeQueueStateFile(a.queue.statePath, state); err != nil {
+		a.logger.Errorf("Failed to save download queue: %v", err)
+	}
+}
+
+// writeQueueStateFile atomically replaces the queue state file
+func writeQueueStateFile(path string, state queueState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal queue state: %w", err)
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write queue state file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace queue state file: %w", err)
+	}
+	return nil
+}
+
+// readQueueStateFile reads the saved jobs. Jobs that were queued or running when
+// the app exited are marked as interrupted so they are only resumed on request.
+func readQueueStateFile(path string) ([]DownloadJob, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var state queueState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse queue state: %w", err)
+	}
+
+	jobs := make([]DownloadJob, 0, len(state.Jobs))
+	for _, job := range state.Jobs {
+		if job.ID == "" || job.URL == "" || !isResumableStatus(job.Status) {
+			continue
+		}
+		if job.Status == JobStatusQueued || job.Status == JobStatusRunning {
+			job.Status = JobStatusInterrupted
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// restoreQueueState loads unfinished jobs from the previous session into the queue.
+// It returns the number of jobs that can be resumed.
+func (a *App) restoreQueueState() int {
+	if a.queue.statePath == "" {
+		return 0
+	}
+
+	jobs, err := readQueueStateFile(a.queue.statePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			a.logger.Errorf("Failed to load download queue: %v", err)
+		}
+		return 0
+	}
+
+	a.queue.mu.Lock()
+	for i := range jobs {
+		job := jobs[i]
+		if _, exists := a.queue.jobs[job.ID]; exists {
+			continue
+		}
+		a.queue.jobs[job.ID] = &job
+		a.queue.order = append(a.queue.order, job.ID)
+	}
+	a.queue.mu.Unlock()
+
+	if len(jobs) > 0 {
+		a.logger.Infof("Restored %d unfinished downloads from previous session", len(jobs))
+	}
+	return len(jobs)
+}
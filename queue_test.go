@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMaxConcurrentDownloads_DefaultsWhenUnset(t *testing.T) {
+	app := &App{}
+	if got := app.maxConcurrentDownloads(); got != defaultMaxConcurrentDownloads {
+		t.Fatalf("expected default %d, got %d", defaultMaxConcurrentDownloads, got)
+	}
+
+	app.settings.MaxConcurrentDownloads = 4
+	if got := app.maxConcurrentDownloads(); got != 4 {
+		t.Fatalf("expected 4, got %d", got)
+	}
+}
+
+func TestListJobsInternal_PreservesQueueOrder(t *testing.T) {
+	app := &App{queue: newDownloadQueue()}
+	for _, id := range []string{"b", "a", "c"} {
+		app.queue.jobs[id] = &DownloadJob{ID: id, URL: "https://example.com/" + id, Status: JobStatusQueued}
+		app.queue.order = append(app.queue.order, id)
+	}
+
+	result, err := app.listJobsInternal()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var jobs []DownloadJob
+	if err := json.Unmarshal([]byte(result), &jobs); err != nil {
+		t.Fatalf("failed to parse jobs JSON: %v", err)
+	}
+	if len(jobs) != 3 || jobs[0].ID != "b" || jobs[1].ID != "a" || jobs[2].ID != "c" {
+		t.Fatalf("unexpected job order: %#v", jobs)
+	}
+}
+
+func TestStopJobInternal_RejectsFinishedJob(t *testing.T) {
+	app := &App{queue: newDownloadQueue()}
+	app.queue.jobs["done"] = &DownloadJob{ID: "done", Status: JobStatusCompleted}
+	app.queue.order = append(app.queue.order, "done")
+
+	if err := app.stopJobInternal("done", "cancel"); err == nil {
+		t.Fatal("expected error when cancelling a completed job")
+	}
+	if err := app.stopJobInternal("missing", "cancel"); err == nil {
+		t.Fatal("expected error for unknown job")
+	}
+	if err := app.resumeJobInternal("done"); err == nil {
+		t.Fatal("expected error when resuming a job that is not paused")
+	}
+}
+
+func TestNewJobID_IsUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		id := newJobID()
+		if id == "" || seen[id] {
+			t.Fatalf("expected unique non-empty job ID, got %q", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestQueueStateFile_RoundTripMarksInterruptedJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	state := queueState{Jobs: []DownloadJob{
+		{ID: "running", URL: "https://example.com/1", Status: JobStatusRunning, DownloadedBytes: 2048},
+		{ID: "paused", URL: "https://example.com/2", Status: JobStatusPaused, Playlist: true, StartItem: 2, EndItem: 5},
+		{ID: "queued", URL: "https://example.com/3", Status: JobStatusQueued},
+		{ID: "done", URL: "https://example.com/4", Status: JobStatusCompleted},
+	}}
+
+	if err := writeQueueStateFile(path, state); err != nil {
+		t.Fatalf("unexpected write error: %v", err)
+	}
+
+	jobs, err := readQueueStateFile(path)
+	if err != nil {
+		t.Fatalf("unexpected read error: %v", err)
+	}
+	if len(jobs) != 3 {
+		t.Fatalf("expected 3 resumable jobs, got %d: %#v", len(jobs), jobs)
+	}
+
+	want := map[string]string{
+		"running": JobStatusInterrupted,
+		"paused":  JobStatusPaused,
+		"queued":  JobStatusInterrupted,
+	}
+	for _, job := range jobs {
+		if job.Status != want[job.ID] {
+			t.Fatalf("job %s: expected status %q, got %q", job.ID, want[job.ID], job.Status)
+		}
+	}
+	if jobs[0].DownloadedBytes != 2048 {
+		t.Fatalf("expected downloaded bytes to be preserved, got %d", jobs[0].DownloadedBytes)
+	}
+	if !jobs[1].Playlist || jobs[1].StartItem != 2 || jobs[1].EndItem != 5 {
+		t.Fatalf("expected playlist range to be preserved, got %#v", jobs[1])
+	}
+}
+
+func TestSaveQueueState_ConcurrentSaves(t *testing.T) {
+	app, sink := newTestApp(t)
+	app.queue.statePath = filepath.Join(t.TempDir(), "queue.json")
+	for i := 0; i < 4; i++ {
+		job := &DownloadJob{ID: newJobID(), URL: "https://example.com/video", Status: JobStatusQueued}
+		app.queue.jobs[job.ID] = job
+		app.queue.order = append(app.queue.order, job.ID)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			app.queue.mu.Lock()
+			app.queue.jobs[app.queue.order[i%4]].DownloadedBytes = int64(i)
+			app.queue.mu.Unlock()
+			app.saveQueueState()
+		}(i)
+	}
+	wg.Wait()
+
+	for _, line := range sink.logs {
+		if strings.HasPrefix(line, "ERR ") {
+			t.Errorf("unexpected error while saving: %s", line)
+		}
+	}
+	jobs, err := readQueueStateFile(app.queue.statePath)
+	if err != nil || len(jobs) != 4 {
+		t.Fatalf("expected 4 saved jobs, got %d: %v", len(jobs), err)
+	}
+}
+
+func TestCompletedFilePathFor_ReturnsNewestMatch(t *testing.T) {
+	app := &App{queue: newDownloadQueue()}
+	jobs := []*DownloadJob{
+		{ID: "old", Title: "Clip", Status: JobStatusCompleted, FilePath: "/tmp/Clip.webm"},
+		{ID: "new", Title: "Clip", Status: JobStatusCompleted, FilePath: "/tmp/Clip.m4a"},
+		{ID: "failed", Title: "Clip", Status: JobStatusFailed, FilePath: "/tmp/Clip.mkv"},
+		{ID: "other", Title: "Other", Status: JobStatusCompleted, FilePath: "/tmp/Other.mp4"},
+	}
+	for _, job := range jobs {
+		app.queue.jobs[job.ID] = job
+		app.queue.order = append(app.queue.order, job.ID)
+	}
+
+	if got := app.completedFilePathFor("Clip"); got != "/tmp/Clip.m4a" {
+		t.Fatalf("expected newest completed file, got %q", got)
+	}
+	if got := app.completedFilePathFor("Missing"); got != "" {
+		t.Fatalf("expected no match, got %q", got)
+	}
+}
+
+func TestGetActualDownloadPathInternal_DoesNotGuess(t *testing.T) {
+	app := &App{queue: newDownloadQueue()}
+	jobs := []*DownloadJob{
+		{ID: "done", Title: "Clip", Status: JobStatusCompleted, FilePath: "/tmp/Clip.opus"},
+		{ID: "paused", Title: "Paused", Status: JobStatusPaused, PartialFiles: []string{"/tmp/Paused.mp4.part"}},
+	}
+	for _, job := range jobs {
+		app.queue.jobs[job.ID] = job
+		app.queue.order = append(app.queue.order, job.ID)
+	}
+
+	if got, err := app.getActualDownloadPathInternal("Clip"); err != nil || got != "/tmp/Clip.opus" {
+		t.Fatalf("expected the reported file, got %q, %v", got, err)
+	}
+	if _, err := app.getActualDownloadPathInternal("Paused"); err == nil || !strings.Contains(err.Error(), "download incomplete") {
+		t.Fatalf("expected an incomplete download error, got %v", err)
+	}
+	if got, err := app.getActualDownloadPathInternal("Missing"); err == nil || got != "" {
+		t.Fatalf("expected an error instead of a guessed path, got %q, %v", got, err)
+	}
+}
+
+func TestGetActualDownloadPathInternal_FallsBackToHistory(t *testing.T) {
+	dir := t.TempDir()
+	older := filepath.Join(dir, "Clip.webm")
+	newer := filepath.Join(dir, "Clip.mp4")
+	for _, path := range []string{older, newer} {
+		if err := os.WriteFile(path, []byte("video"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A fresh queue, as after a restart, with the downloads only in the history
+	app := &App{queue: newDownloadQueue(), history: newHistoryStore(filepath.Join(dir, "history.json"))}
+	items := []HistoryItem{
+		{ID: "1", Title: "Clip", Status: JobStatusCompleted, FilePath: older},
+		{ID: "2", Title: "Clip", Status: JobStatusCompleted, FilePath: newer},
+		{ID: "3", Title: "Clip", Status: JobStatusFailed},
+		{ID: "4", Title: "Gone", Status: JobStatusCompleted, FilePath: filepath.Join(dir, "Gone.mp4")},
+	}
+	for _, item := range items {
+		if err := app.history.add(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, err := app.getActualDownloadPathInternal("Clip"); err != nil || got != newer {
+		t.Fatalf("expected the newest recorded file %q, got %q, %v", newer, got, err)
+	}
+	if got, err := app.getActualDownloadPathInternal("Gone"); err == nil || got != "" {
+		t.Fatalf("expected an error for a file that is no longer on disk, got %q, %v", got, err)
+	}
+}
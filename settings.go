@@ -4,13 +4,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
-
-	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // loadSettings loads settings from a file or sets defaults
 func (a *App) loadSettings() {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+
 	settingsFile := "./settings.json"
 
 	// Set default values
@@ -22,6 +24,13 @@ func (a *App) loadSettings() {
 	a.settings.Language = "en"            // Default language
 	a.settings.AutoRedirectToQueue = true // Default: auto redirect to queue
 	a.settings.UseJSRuntime = false       // Default: don't use JS runtime
+	a.settings.MaxConcurrentDownloads = defaultMaxConcurrentDownloads
+	a.settings.MaxConcurrentConversions = defaultMaxConcurrentConversions
+	a.settings.OutputTemplate = defaultOutputTemplate
+	a.settings.PlaylistOutputTemplate = defaultPlaylistOutputTemplate
+	a.settings.WindowsFilenames = true // Default: names that also work on Windows
+	a.settings.SubscriptionSyncMinutes = defaultSubscriptionSyncMinutes
+	a.settings.DownloadArchiveMode = DownloadArchiveGlobal
 
 	// Try to read existing settings
 	data, err := os.ReadFile(settingsFile)
@@ -45,8 +54,37 @@ func (a *App) loadSettings() {
 	}
 }
 
+// currentSettings returns a copy of the settings. Code that may run outside
+// the binding changing the settings (queue workers, the scheduler, the local
+// API) reads them through it.
+func (a *App) currentSettings() Settings {
+	a.settingsMu.RLock()
+	defer a.settingsMu.RUnlock()
+
+	settings := a.settings
+	settings.ConversionPresets = slices.Clone(settings.ConversionPresets)
+	return settings
+}
+
+// updateSettings applies change to the settings and saves them
+func (a *App) updateSettings(change func(settings *Settings)) error {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+
+	change(&a.settings)
+	return a.writeSettingsFile()
+}
+
 // saveSettings saves settings to a file
 func (a *App) saveSettings() error {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+
+	return a.writeSettingsFile()
+}
+
+// writeSettingsFile writes the settings to disk. The caller holds settingsMu.
+func (a *App) writeSettingsFile() error {
 	settingsFile := "./settings.json"
 
 	data, err := json.MarshalIndent(a.settings, "", "  ")
@@ -62,6 +100,50 @@ func (a *App) saveSettings() error {
 	return nil
 }
 
+// updateEmbedSettingsInternal updates what gets embedded into downloads by default
+func (a *App) updateEmbedSettingsInternal(metadata, thumbnail, chapters, infoJSON bool) error {
+	err := a.updateSettings(func(settings *Settings) {
+		settings.EmbedMetadata = metadata
+		settings.EmbedThumbnail = thumbnail
+		settings.EmbedChapters = chapters
+		settings.EmbedInfoJSON = infoJSON
+	})
+	if err != nil {
+		a.logger.Errorf("Failed to save embed settings: %v", err)
+		return err
+	}
+	return nil
+}
+
+// updateOutputTemplatesInternal updates the output templates and filename sanitization.
+// Empty templates reset to the defaults.
+func (a *App) updateOutputTemplatesInternal(outputTemplate, playlistOutputTemplate string, restrictFilenames, windowsFilenames bool) error {
+	if outputTemplate == "" {
+		outputTemplate = defaultOutputTemplate
+	}
+	if playlistOutputTemplate == "" {
+		playlistOutputTemplate = defaultPlaylistOutputTemplate
+	}
+	if err := validateOutputTemplate(outputTemplate); err != nil {
+		return err
+	}
+	if err := validateOutputTemplate(playlistOutputTemplate); err != nil {
+		return err
+	}
+
+	err := a.updateSettings(func(settings *Settings) {
+		settings.OutputTemplate = outputTemplate
+		settings.PlaylistOutputTemplate = playlistOutputTemplate
+		settings.RestrictFilenames = restrictFilenames
+		settings.WindowsFilenames = windowsFilenames
+	})
+	if err != nil {
+		a.logger.Errorf("Failed to save output template settings: %v", err)
+		return err
+	}
+	return nil
+}
+
 // validateCookiesFileInternal validates if the cookies file exists and is accessible
 func (a *App) validateCookiesFileInternal(filePath string) (bool, error) {
 	if filePath == "" {
@@ -84,7 +166,7 @@ func (a *App) validateCookiesFileInternal(filePath string) (bool, error) {
 
 	// Basic validation - check if file has .txt or .cookies extension
 	if !(strings.HasSuffix(strings.ToLower(filePath), ".txt") || strings.HasSuffix(strings.ToLower(filePath), ".cookies")) {
-		wailsRuntime.LogInfof(a.ctx, "Warning: Cookies file does not have .txt or .cookies extension: %s", filePath)
+		a.logger.Infof("Warning: Cookies file does not have .txt or .cookies extension: %s", filePath)
 	}
 
 	return true, nil
@@ -92,7 +174,7 @@ func (a *App) validateCookiesFileInternal(filePath string) (bool, error) {
 
 // getSettingsAsJSON returns current application settings as JSON string
 func (a *App) getSettingsAsJSON() (string, error) {
-	settingsJSON, err := json.Marshal(a.settings)
+	settingsJSON, err := json.Marshal(a.currentSettings())
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal settings: %w", err)
 	}
@@ -101,15 +183,15 @@ func (a *App) getSettingsAsJSON() (string, error) {
 
 // updateSettingsWithCookiesFile updates application settings including cookies file
 func (a *App) updateSettingsWithCookiesFile(proxyMode, proxyAddress, cookiesMode, cookiesBrowser, cookiesFile string) error {
-	a.settings.ProxyMode = proxyMode
-	a.settings.ProxyAddress = proxyAddress
-	a.settings.CookiesMode = cookiesMode
-	a.settings.CookiesBrowser = cookiesBrowser
-	a.settings.CookiesFile = cookiesFile
-
-	err := a.saveSettings()
+	err := a.updateSettings(func(settings *Settings) {
+		settings.ProxyMode = proxyMode
+		settings.ProxyAddress = proxyAddress
+		settings.CookiesMode = cookiesMode
+		settings.CookiesBrowser = cookiesBrowser
+		settings.CookiesFile = cookiesFile
+	})
 	if err != nil {
-		wailsRuntime.LogErrorf(a.ctx, "Failed to save settings: %v", err)
+		a.logger.Errorf("Failed to save settings: %v", err)
 		return err
 	}
 
@@ -125,15 +207,15 @@ func (a *App) updateSettingsWithCookiesFile(proxyMode, proxyAddress, cookiesMode
 //
 //export UpdateLanguage
 func (a *App) UpdateLanguage(language string) error {
-	a.settings.Language = language
-
-	err := a.saveSettings()
+	err := a.updateSettings(func(settings *Settings) {
+		settings.Language = language
+	})
 	if err != nil {
-		wailsRuntime.LogErrorf(a.ctx, "Failed to save language: %v", err)
+		a.logger.Errorf("Failed to save language: %v", err)
 		return err
 	}
 
-	wailsRuntime.LogInfof(a.ctx, "Language updated to: %s", language)
+	a.logger.Infof("Language updated to: %s", language)
 	return nil
 }
 
@@ -142,14 +224,17 @@ func (a *App) AutoSaveSettings() {
 	// This function can be called periodically to autosave settings
 	err := a.saveSettings()
 	if err != nil {
-		wailsRuntime.LogErrorf(a.ctx, "Failed to autosave settings: %v", err)
+		a.logger.Errorf("Failed to autosave settings: %v", err)
 	} else {
-		wailsRuntime.LogInfof(a.ctx, "Settings autosaved successfully")
+		a.logger.Infof("Settings autosaved successfully")
 	}
 }
 
 // loadSettingsWithLogging loads settings and logs appropriately after context is initialized
 func (a *App) loadSettingsWithLogging() {
+	a.settingsMu.Lock()
+	defer a.settingsMu.Unlock()
+
 	settingsFile := "./settings.json"
 
 	// Set default values
@@ -161,19 +246,26 @@ func (a *App) loadSettingsWithLogging() {
 	a.settings.Language = "en"            // Default language
 	a.settings.AutoRedirectToQueue = true // Default: auto redirect to queue
 	a.settings.UseJSRuntime = false       // Default: don't use JS runtime
+	a.settings.MaxConcurrentDownloads = defaultMaxConcurrentDownloads
+	a.settings.MaxConcurrentConversions = defaultMaxConcurrentConversions
+	a.settings.OutputTemplate = defaultOutputTemplate
+	a.settings.PlaylistOutputTemplate = defaultPlaylistOutputTemplate
+	a.settings.WindowsFilenames = true // Default: names that also work on Windows
+	a.settings.SubscriptionSyncMinutes = defaultSubscriptionSyncMinutes
+	a.settings.DownloadArchiveMode = DownloadArchiveGlobal
 
 	// Try to read existing settings
 	data, err := os.ReadFile(settingsFile)
 	if err != nil {
 		// If file doesn't exist, we'll use defaults
-		wailsRuntime.LogInfo(a.ctx, "Settings file not found, using defaults")
+		a.logger.Info("Settings file not found, using defaults")
 		return
 	}
 
 	// Unmarshal the settings
 	err = json.Unmarshal(data, &a.settings)
 	if err != nil {
-		wailsRuntime.LogErrorf(a.ctx, "Failed to parse settings: %v", err)
+		a.logger.Errorf("Failed to parse settings: %v", err)
 		// Use defaults if parsing fails
 		a.settings.ProxyMode = "none"
 		a.settings.ProxyAddress = ""
@@ -182,6 +274,6 @@ func (a *App) loadSettingsWithLogging() {
 		a.settings.CookiesFile = ""
 		a.settings.UseJSRuntime = false
 	} else {
-		wailsRuntime.LogInfo(a.ctx, "Settings loaded successfully")
+		a.logger.Info("Settings loaded successfully")
 	}
 }
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// subscriptionsFile stores the subscriptions next to settings.json
+const subscriptionsFile = "./subscriptions.json"
+
+// subscriptionArchiveDir holds the download archive of every subscription
+const subscriptionArchiveDir = "./archives"
+
+// defaultSubscriptionSyncMinutes is used when the sync interval is not configured
+const defaultSubscriptionSyncMinutes = 60
+
+// subscriptionCheckInterval is how often the scheduler looks for subscriptions that are due
+const subscriptionCheckInterval = time.Minute
+
+// errSubscriptionNotFound is returned for operations on an unknown subscription ID
+var errSubscriptionNotFound = errors.New("subscription not found")
+
+// subscriptionStore keeps the subscriptions in memory and mirrors them to a JSON file
+type subscriptionStore struct {
+	mu     sync.Mutex
+	path   string
+	items  []Subscription
+	loaded bool
+
+	syncing map[string]bool // IDs of subscriptions being synced right now
+}
+
+// newSubscriptionStore creates a subscription store backed by the given file.
+// The file is read lazily on first use.
+func newSubscriptionStore(path string) *subscriptionStore {
+	return &subscriptionStore{path: path, syncing: make(map[string]bool)}
+}
+
+// load reads the subscriptions file once. Must be called with mu held.
+func (s *subscriptionStore) load() error {
+	if s.loaded {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.loaded = true
+			return nil
+		}
+		return fmt.Errorf("failed to read subscriptions file: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &s.items); err != nil {
+		return fmt.Errorf("failed to parse subscriptions file: %w", err)
+	}
+	s.loaded = true
+	return nil
+}
+
+// save writes the subscriptions file. Must be called with mu held.
+func (s *subscriptionStore) save() error {
+	data, err := json.MarshalIndent(s.items, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal subscriptions: %w", err)
+	}
+
+	tmpPath := s.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write subscriptions file: %w", err)
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		return fmt.Errorf("failed to replace subscriptions file: %w", err)
+	}
+	return nil
+}
+
+// list returns a copy of all subscriptions
+func (s *subscriptionStore) list() ([]Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return append([]Subscription{}, s.items...), nil
+}
+
+// get returns the subscription with the given ID
+func (s *subscriptionStore) get(id string) (Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return Subscription{}, err
+	}
+	for _, sub := range s.items {
+		if sub.ID == id {
+			return sub, nil
+		}
+	}
+	return Subscription{}, fmt.Errorf("%w: %s", errSubscriptionNotFound, id)
+}
+
+// add appends a subscription and persists the store
+func (s *subscriptionStore) add(sub Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	for _, existing := range s.items {
+		if existing.URL == sub.URL {
+			return fmt.Errorf("already subscribed to %s", sub.URL)
+		}
+	}
+	s.items = append(s.items, sub)
+	return s.save()
+}
+
+// update applies change to the subscription with the given ID and persists the store
+func (s *subscriptionStore) update(id string, change func(*Subscription)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	for i := range s.items {
+		if s.items[i].ID == id {
+			change(&s.items[i])
+			return s.save()
+		}
+	}
+	return fmt.Errorf("%w: %s", errSubscriptionNotFound, id)
+}
+
+// remove deletes the subscription with the given ID and returns it
+func (s *subscriptionStore) remove(id string) (Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.load(); err != nil {
+		return Subscription{}, err
+	}
+	for i, sub := range s.items {
+		if sub.ID == id {
+			s.items = append(s.items[:i], s.items[i+1:]...)
+			return sub, s.save()
+		}
+	}
+	return Subscription{}, fmt.Errorf("%w: %s", errSubscriptionNotFound, id)
+}
+
+// beginSync marks a subscription as syncing. It returns false if a sync is
+// already running for it.
+func (s *subscriptionStore) beginSync(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.syncing[id] {
+		return false
+	}
+	s.syncing[id] = true
+	return true
+}
+
+// endSync clears the syncing mark of a subscription
+func (s *subscriptionStore) endSync(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.syncing, id)
+}
+
+// subscriptionSyncInterval returns how often subscriptions are synced
+func (a *App) subscriptionSyncInterval() time.Duration {
+	minutes := a.currentSettings().SubscriptionSyncMinutes
+	if minutes < 1 {
+		minutes = defaultSubscriptionSyncMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// addSubscriptionInternal subscribes to a channel or playlist and returns the
+// subscription as JSON. Its current entries are queued by the first sync, or
+// recorded as downloaded when skipExisting is set.
+func (a *App) addSubscriptionInternal(url, formatID, outputDir, optionsJSON string, skipExisting bool) (string, error) {
+	if url == "" {
+		return "", fmt.Errorf("url cannot be empty")
+	}
+	options, err := parseDownloadOptions(optionsJSON)
+	if err != nil {
+		return "", err
+	}
+
+	id := newJobID()
+	sub := Subscription{
+		ID:           id,
+		URL:          url,
+		FormatID:     formatID,
+		OutputDir:    outputDir,
+		Options:      options,
+		Enabled:      true,
+		ArchivePath:  filepath.Join(subscriptionArchiveDir, id+".txt"),
+		SkipExisting: skipExisting,
+		CreatedAt:    time.Now(),
+	}
+	if err := a.subscriptions.add(sub); err != nil {
+		return "", err
+	}
+	a.logger.Infof("Subscribed to %s", url)
+
+	result, err := json.Marshal(sub)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal subscription: %w", err)
+	}
+	return string(result), nil
+}
+
+// listSubscriptionsInternal returns all subscriptions as JSON
+func (a *App) listSubscriptionsInternal() (string, error) {
+	subs, err := a.subscriptions.list()
+	if err != nil {
+		return "", err
+	}
+
+	result, err := json.Marshal(subs)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal subscriptions: %w", err)
+	}
+	return string(result), nil
+}
+
+// removeSubscriptionInternal unsubscribes and deletes the subscription's
+// download archive. Jobs it already queued are left alone.
+func (a *App) removeSubscriptionInternal(id string) error {
+	sub, err := a.subscriptions.remove(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(sub.ArchivePath); err != nil && !os.IsNotExist(err) {
+		a.logger.Warningf("Failed to delete download archive %s: %v", sub.ArchivePath, err)
+	}
+	a.logger.Infof("Unsubscribed from %s", sub.URL)
+	return nil
+}
+
+// setSubscriptionEnabledInternal pauses or resumes scheduled syncing of a subscription
+func (a *App) setSubscriptionEnabledInternal(id string, enabled bool) error {
+	return a.subscriptions.update(id, func(sub *Subscription) {
+		sub.Enabled = enabled
+	})
+}
+
+// updateSubscriptionSyncIntervalInternal sets how often subscriptions are synced
+func (a *App) updateSubscriptionSyncIntervalInternal(minutes int) error {
+	if minutes < 1 {
+		return fmt.Errorf("sync interval must be at least 1 minute")
+	}
+	err := a.updateSettings(func(settings *Settings) {
+		settings.SubscriptionSyncMinutes = minutes
+	})
+	if err != nil {
+		a.logger.Errorf("Failed to save subscription sync interval: %v", err)
+		return err
+	}
+	return nil
+}
+
+// syncSubscriptionInternal lists the subscription's entries and queues those
+// that are neither in its download archive nor already queued. It returns the
+// IDs of the queued jobs.
+func (a *App) syncSubscriptionInternal(id string) ([]string, error) {
+	sub, err := a.subscriptions.get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !a.subscriptions.beginSync(id) {
+		return nil, fmt.Errorf("subscription %s is already syncing", id)
+	}
+	defer a.subscriptions.endSync(id)
+
+	jobIDs, entries, skipped, syncErr := a.queueNewSubscriptionEntries(sub)
+
+	lastError := ""
+	if syncErr != nil {
+		lastError = syncErr.Error()
+	}
+	if err := a.subscriptions.update(id, func(s *Subscription) {
+		s.LastSync = time.Now()
+		s.LastError = lastError
+	}); err != nil {
+		a.logger.Errorf("Failed to save subscription %s: %v", id, err)
+	}
+
+	if syncErr != nil {
+		a.logger.Errorf("Failed to sync subscription %s: %v", sub.URL, syncErr)
+		a.events.Emit("subscription-sync-error", map[string]interface{}{
+			"id":    id,
+			"error": lastError,
+		})
+		return nil, syncErr
+	}
+
+	a.logger.Infof("Subscription %s synced, %d new items", sub.URL, len(jobIDs))
+	if len(jobIDs) > 0 || skipped > 0 {
+		a.events.Emit("subscription-new-items", map[string]interface{}{
+			"id":               id,
+			"url":              sub.URL,
+			"count":            len(jobIDs),
+			"items":            entries,
+			"job_ids":          jobIDs,
+			"skipped_existing": skipped,
+		})
+	}
+	return jobIDs, nil
+}
+
+// queueNewSubscriptionEntries queues a video job for every new entry of a
+// subscription and returns the job IDs and entries. A SkipExisting
+// subscription without a download archive yet has the archive seeded with the
+// entries instead; their number is returned as skipped.
+func (a *App) queueNewSubscriptionEntries(sub Subscription) ([]string, []PlaylistEntry, int, error) {
+	itemsJSON, err := a.getPlaylistItemsInternal(sub.URL)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	var items struct {
+		Entries []PlaylistEntry `json:"entries"`
+	}
+	if err := json.Unmarshal([]byte(itemsJSON), &items); err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to parse playlist items: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(sub.ArchivePath), 0755); err != nil {
+		return nil, nil, 0, fmt.Errorf("failed to create archive directory: %w", err)
+	}
+	if _, err := os.Stat(sub.ArchivePath); os.IsNotExist(err) && sub.SkipExisting {
+		if err := seedDownloadArchive(sub.ArchivePath, items.Entries); err != nil {
+			return nil, nil, 0, err
+		}
+		a.logger.Infof("Subscription %s: recorded %d existing items as downloaded", sub.URL, len(items.Entries))
+		return nil, nil, len(items.Entries), nil
+	}
+
+	archived, err := readDownloadArchive(sub.ArchivePath)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	pending := a.pendingSubscriptionURLs(sub.ID)
+
+	outputDir := sub.OutputDir
+	if outputDir == "" {
+		outputDir = a.getDownloadDirectoryInternal()
+	}
+	outputPath := filepath.Join(outputDir, a.currentSettings().outputTemplate(false))
+
+	var jobIDs []string
+	var entries []PlaylistEntry
+	for _, entry := range items.Entries {
+		if entry.URL == "" || archived[entry.ID] || pending[entry.URL] {
+			continue
+		}
+		jobIDs = append(jobIDs, a.enqueueJob(&DownloadJob{
+			URL:            entry.URL,
+			Title:          entry.Title,
+			FormatID:       sub.FormatID,
+			OutputPath:     outputPath,
+			Options:        sub.Options,
+			ArchivePath:    sub.ArchivePath,
+			SubscriptionID: sub.ID,
+		}))
+		entries = append(entries, entry)
+	}
+	return jobIDs, entries, 0, nil
+}
+
+// pendingSubscriptionURLs returns the URLs of the subscription's jobs that
+// have not finished yet, so a sync does not queue them twice
+func (a *App) pendingSubscriptionURLs(subscriptionID string) map[string]bool {
+	a.queue.mu.Lock()
+	defer a.queue.mu.Unlock()
+
+	pending := make(map[string]bool)
+	for _, job := range a.queue.jobs {
+		if job.SubscriptionID != subscriptionID {
+			continue
+		}
+		switch job.Status {
+		case JobStatusQueued, JobStatusRunning, JobStatusPaused, JobStatusInterrupted:
+			pending[job.URL] = true
+		}
+	}
+	return pending
+}
+
+// syncDueSubscriptions syncs every enabled subscription whose last sync is
+// older than the sync interval
+func (a *App) syncDueSubscriptions(now time.Time) {
+	subs, err := a.subscriptions.list()
+	if err != nil {
+		a.logger.Errorf("Failed to load subscriptions: %v", err)
+		return
+	}
+
+	interval := a.subscriptionSyncInterval()
+	for _, sub := range subs {
+		if !sub.Enabled || now.Sub(sub.LastSync) < interval {
+			continue
+		}
+		// Errors are reported by syncSubscriptionInternal itself
+		a.syncSubscriptionInternal(sub.ID)
+	}
+}
+
+// runSubscriptionScheduler syncs due subscriptions right away and then every
+// subscriptionCheckInterval, for the lifetime of the app
+func (a *App) runSubscriptionScheduler() {
+	a.syncDueSubscriptions(time.Now())
+
+	ticker := time.NewTicker(subscriptionCheckInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		a.syncDueSubscriptions(now)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeChannelEntries is the flat playlist yt-dlp lists for a test channel
+var fakeChannelEntries = []string{
+	`{"id":"a1","title":"First","url":"https://example.com/watch?v=a1","ie_key":"Example"}`,
+	`{"id":"b2","title":"Second","url":"https://example.com/watch?v=b2","ie_key":"Example"}`,
+	`{"id":"c3","title":"Third","url":"https://example.com/watch?v=c3","ie_key":"Example"}`,
+}
+
+// holdQueue keeps newly queued jobs from starting
+func holdQueue(app *App) {
+	app.settings.MaxConcurrentDownloads = 1
+	app.queue.running = 1
+}
+
+func TestReadDownloadArchive(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.txt")
+	if err := os.WriteFile(path, []byte("youtube a1\n\nvimeo 123\nbroken\n"), 0644); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+
+	ids, err := readDownloadArchive(path)
+	if err != nil {
+		t.Fatalf("readDownloadArchive() error = %v", err)
+	}
+	if want := map[string]bool{"a1": true, "123": true}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("readDownloadArchive() = %v, want %v", ids, want)
+	}
+
+	ids, err = readDownloadArchive(filepath.Join(t.TempDir(), "missing.txt"))
+	if err != nil || len(ids) != 0 {
+		t.Errorf("a missing archive should be empty, got %v, %v", ids, err)
+	}
+}
+
+func TestSubscriptionStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscriptions.json")
+	store := newSubscriptionStore(path)
+
+	if err := store.add(Subscription{ID: "s1", URL: "https://example.com/channel", Enabled: true}); err != nil {
+		t.Fatalf("add() error = %v", err)
+	}
+	if err := store.add(Subscription{ID: "s2", URL: "https://example.com/channel"}); err == nil {
+		t.Error("subscribing to the same URL twice should fail")
+	}
+	if err := store.update("s1", func(sub *Subscription) { sub.Enabled = false }); err != nil {
+		t.Fatalf("update() error = %v", err)
+	}
+
+	reloaded, err := newSubscriptionStore(path).get("s1")
+	if err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+	if reloaded.URL != "https://example.com/channel" || reloaded.Enabled {
+		t.Errorf("unexpected subscription after reload: %+v", reloaded)
+	}
+
+	if _, err := store.remove("s1"); err != nil {
+		t.Fatalf("remove() error = %v", err)
+	}
+	if _, err := store.get("s1"); !errors.Is(err, errSubscriptionNotFound) {
+		t.Errorf("get() after remove = %v, want errSubscriptionNotFound", err)
+	}
+}
+
+func TestSyncSubscriptionQueuesNewEntries(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, sink := newTestApp(t)
+	holdQueue(app)
+	tools.install(app.getYtDlpBinaryName(), fakeRun{When: []string{"--flat-playlist"}, Stdout: fakeChannelEntries})
+
+	subJSON, err := app.addSubscriptionInternal("https://example.com/channel", "best", "mirror", `{"embed":{"metadata":true}}`, false)
+	if err != nil {
+		t.Fatalf("addSubscriptionInternal() error = %v", err)
+	}
+	var sub Subscription
+	if err := json.Unmarshal([]byte(subJSON), &sub); err != nil {
+		t.Fatalf("failed to parse subscription: %v", err)
+	}
+
+	// a1 was downloaded before, b2 is still queued from an earlier sync
+	if err := os.MkdirAll(filepath.Dir(sub.ArchivePath), 0755); err != nil {
+		t.Fatalf("failed to create archive directory: %v", err)
+	}
+	if err := os.WriteFile(sub.ArchivePath, []byte("example a1\n"), 0644); err != nil {
+		t.Fatalf("failed to write archive: %v", err)
+	}
+	app.enqueueJob(&DownloadJob{URL: "https://example.com/watch?v=b2", SubscriptionID: sub.ID})
+
+	jobIDs, err := app.syncSubscriptionInternal(sub.ID)
+	if err != nil {
+		t.Fatalf("syncSubscriptionInternal() error = %v", err)
+	}
+	if len(jobIDs) != 1 {
+		t.Fatalf("expected one new job, got %v", jobIDs)
+	}
+
+	job := app.queue.jobs[jobIDs[0]]
+	want := DownloadJob{
+		URL:            "https://example.com/watch?v=c3",
+		Title:          "Third",
+		FormatID:       "best",
+		OutputPath:     filepath.Join("mirror", defaultOutputTemplate),
+		ArchivePath:    sub.ArchivePath,
+		SubscriptionID: sub.ID,
+	}
+	if job.URL != want.URL || job.Title != want.Title || job.FormatID != want.FormatID || job.OutputPath != want.OutputPath ||
+		job.ArchivePath != want.ArchivePath || job.SubscriptionID != want.SubscriptionID || job.Options.Embed == nil || !job.Options.Embed.Metadata {
+		t.Errorf("unexpected job %+v", job)
+	}
+
+	event := sink.waitFor(t, "subscription-new-items")
+	data := event.Data[0].(map[string]interface{})
+	if data["id"] != sub.ID || data["count"] != 1 || !reflect.DeepEqual(data["job_ids"], jobIDs) {
+		t.Errorf("unexpected event data: %v", data)
+	}
+
+	synced, _ := app.subscriptions.get(sub.ID)
+	if synced.LastSync.IsZero() || synced.LastError != "" {
+		t.Errorf("sync state not recorded: %+v", synced)
+	}
+
+	// Nothing new on the next sync, so no event either
+	if jobIDs, err := app.syncSubscriptionInternal(sub.ID); err != nil || len(jobIDs) != 0 {
+		t.Errorf("second sync queued %v, %v", jobIDs, err)
+	}
+}
+
+func TestSyncSubscriptionSkipExisting(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, sink := newTestApp(t)
+	holdQueue(app)
+	entries := append(slices.Clone(fakeChannelEntries), `{"id":"d4","title":"Fourth","url":"https://example.com/watch?v=d4"}`)
+	tools.install(app.getYtDlpBinaryName(), fakeRun{When: []string{"--flat-playlist"}, Stdout: entries})
+
+	subJSON, err := app.addSubscriptionInternal("https://example.com/channel", "", "", "", true)
+	if err != nil {
+		t.Fatalf("addSubscriptionInternal() error = %v", err)
+	}
+	var sub Subscription
+	json.Unmarshal([]byte(subJSON), &sub)
+
+	// The first sync records the back catalogue instead of downloading it
+	for range 2 {
+		if jobIDs, err := app.syncSubscriptionInternal(sub.ID); err != nil || len(jobIDs) != 0 {
+			t.Fatalf("sync queued %v, %v", jobIDs, err)
+		}
+	}
+	data, err := os.ReadFile(sub.ArchivePath)
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	if want := "example a1\nexample b2\nexample c3\ngeneric d4\n"; string(data) != want {
+		t.Errorf("archive = %q, want %q", data, want)
+	}
+	if len(app.queue.jobs) != 0 {
+		t.Errorf("nothing should be queued, got %d jobs", len(app.queue.jobs))
+	}
+	event := sink.waitFor(t, "subscription-new-items").Data[0].(map[string]interface{})
+	if event["count"] != 0 || event["skipped_existing"] != 4 {
+		t.Errorf("unexpected event data: %v", event)
+	}
+
+	// Without the option the first sync downloads everything
+	subJSON, err = app.addSubscriptionInternal("https://example.com/other", "", "", "", false)
+	if err != nil {
+		t.Fatalf("addSubscriptionInternal() error = %v", err)
+	}
+	json.Unmarshal([]byte(subJSON), &sub)
+	if jobIDs, err := app.syncSubscriptionInternal(sub.ID); err != nil || len(jobIDs) != 4 {
+		t.Errorf("first sync queued %v, %v, want all 4 entries", jobIDs, err)
+	}
+}
+
+func TestSyncSubscriptionReportsErrors(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, sink := newTestApp(t)
+	tools.install(app.getYtDlpBinaryName(), fakeRun{Stderr: []string{"ERROR: This channel does not exist"}, ExitCode: 1})
+
+	subJSON, err := app.addSubscriptionInternal("https://example.com/gone", "", "", "", false)
+	if err != nil {
+		t.Fatalf("addSubscriptionInternal() error = %v", err)
+	}
+	var sub Subscription
+	json.Unmarshal([]byte(subJSON), &sub)
+
+	if _, err := app.syncSubscriptionInternal(sub.ID); err == nil {
+		t.Fatal("expected a sync error")
+	}
+	sink.waitFor(t, "subscription-sync-error")
+	if synced, _ := app.subscriptions.get(sub.ID); synced.LastError == "" {
+		t.Error("the error should be recorded on the subscription")
+	}
+}
+
+func TestSyncDueSubscriptions(t *testing.T) {
+	tools := newFakeToolHarness(t)
+	app, _ := newTestApp(t)
+	holdQueue(app)
+	tools.install(app.getYtDlpBinaryName(), fakeRun{When: []string{"--flat-playlist"}, Stdout: fakeChannelEntries[:1]})
+
+	now := time.Now()
+	for _, sub := range []Subscription{
+		{ID: "due", URL: "https://example.com/due", Enabled: true, ArchivePath: "archives/due.txt", LastSync: now.Add(-2 * time.Hour)},
+		{ID: "recent", URL: "https://example.com/recent", Enabled: true, ArchivePath: "archives/recent.txt", LastSync: now.Add(-time.Minute)},
+		{ID: "disabled", URL: "https://example.com/disabled", ArchivePath: "archives/disabled.txt"},
+	} {
+		if err := app.subscriptions.add(sub); err != nil {
+			t.Fatalf("add() error = %v", err)
+		}
+	}
+
+	app.syncDueSubscriptions(now)
+
+	calls := tools.calls(app.getYtDlpBinaryName())
+	if len(calls) != 1 || calls[0][len(calls[0])-1] != "https://example.com/due" {
+		t.Errorf("expected only the due subscription to be synced, got %q", calls)
+	}
+}
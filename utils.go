@@ -13,8 +13,6 @@ import (
 	"strconv"
 	"strings"
 	"time"
-
-	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // formatFileSizeHuman converts bytes to a human-readable format (e.g., MB, GB)
@@ -46,7 +44,7 @@ func (a *App) logError(message string) {
 
 	file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
-		wailsRuntime.LogErrorf(a.ctx, "Failed to open log file: %v", err)
+		a.logger.Errorf("Failed to open log file: %v", err)
 		return
 	}
 	defer file.Close()
@@ -54,7 +52,7 @@ func (a *App) logError(message string) {
 	timestamp := time.Now().Format("2006-01-02 15:04:05")
 	_, err = file.WriteString(fmt.Sprintf("[%s] %s\n", timestamp, message))
 	if err != nil {
-		wailsRuntime.LogErrorf(a.ctx, "Failed to write to log file: %v", err)
+		a.logger.Errorf("Failed to write to log file: %v", err)
 	}
 }
 
@@ -64,7 +62,7 @@ func (a *App) logDetailedError(operation string, url string, formatID string, er
 
 	file, fileErr := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if fileErr != nil {
-		wailsRuntime.LogErrorf(a.ctx, "Failed to open log file: %v", fileErr)
+		a.logger.Errorf("Failed to open log file: %v", fileErr)
 		return
 	}
 	defer file.Close()
@@ -83,7 +81,7 @@ func (a *App) logDetailedError(operation string, url string, formatID string, er
 
 	_, writeErr := file.WriteString(logMessage + "\n")
 	if writeErr != nil {
-		wailsRuntime.LogErrorf(a.ctx, "Failed to write to log file: %v", writeErr)
+		a.logger.Errorf("Failed to write to log file: %v", writeErr)
 	}
 }
 
@@ -362,7 +360,7 @@ func (a *App) downloadDenoWithProgress() error {
 	}
 
 	// Emit start event
-	wailsRuntime.EventsEmit(a.ctx, "deno-download-start", nil)
+	a.events.Emit("deno-download-start", nil)
 
 	// Create HTTP request
 	req, err := http.NewRequest("GET", downloadURL, nil)
@@ -401,7 +399,7 @@ func (a *App) downloadDenoWithProgress() error {
 		if n > 0 {
 			_, writeErr := tempZipFile.Write(buf[:n])
 			if writeErr != nil {
-				wailsRuntime.EventsEmit(a.ctx, "deno-download-error", writeErr.Error())
+				a.events.Emit("deno-download-error", writeErr.Error())
 				return fmt.Errorf("failed to write to temp file: %w", writeErr)
 			}
 
@@ -412,7 +410,7 @@ func (a *App) downloadDenoWithProgress() error {
 				progress := int((downloaded * 100) / totalSize)
 
 				// Emit progress event to the frontend
-				wailsRuntime.EventsEmit(a.ctx, "deno-download-progress", map[string]interface{}{
+				a.events.Emit("deno-download-progress", map[string]interface{}{
 					"progress":   progress,
 					"downloaded": downloaded,
 					"total":      totalSize,
@@ -425,13 +423,13 @@ func (a *App) downloadDenoWithProgress() error {
 		}
 
 		if err != nil {
-			wailsRuntime.EventsEmit(a.ctx, "deno-download-error", err.Error())
+			a.events.Emit("deno-download-error", err.Error())
 			return fmt.Errorf("error during download: %w", err)
 		}
 	}
 
 	// Emit extraction start event
-	wailsRuntime.EventsEmit(a.ctx, "deno-download-progress", map[string]interface{}{
+	a.events.Emit("deno-download-progress", map[string]interface{}{
 		"progress":   100,
 		"downloaded": downloaded,
 		"total":      totalSize,
@@ -441,12 +439,12 @@ func (a *App) downloadDenoWithProgress() error {
 	// Extract the zip file to bin directory
 	err = a.extractDenoZip(tempZipFile.Name(), binDir)
 	if err != nil {
-		wailsRuntime.EventsEmit(a.ctx, "deno-download-error", err.Error())
+		a.events.Emit("deno-download-error", err.Error())
 		return fmt.Errorf("failed to extract deno: %w", err)
 	}
 
 	// Emit completion event
-	wailsRuntime.EventsEmit(a.ctx, "deno-download-complete", nil)
+	a.events.Emit("deno-download-complete", nil)
 
 	return nil
 }
@@ -525,15 +523,15 @@ func (a *App) updateDeno() error {
 		return fmt.Errorf("deno not found, please install first")
 	}
 
-	wailsRuntime.LogInfo(a.ctx, "Starting deno update...")
+	a.logger.Info("Starting deno update...")
 
 	// Download the latest version
 	err := a.downloadDenoWithProgress()
 	if err != nil {
-		wailsRuntime.LogErrorf(a.ctx, "Failed to update deno: %v", err)
+		a.logger.Errorf("Failed to update deno: %v", err)
 		return fmt.Errorf("failed to update deno: %w", err)
 	}
 
-	wailsRuntime.LogInfo(a.ctx, "Deno updated successfully")
+	a.logger.Info("Deno updated successfully")
 	return nil
 }
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// YtDlpInvocation describes a single yt-dlp run. It combines the user settings
+// with per-call options so every code path passes proxy, cookies and JS runtime
+// arguments the same way.
+type YtDlpInvocation struct {
+	Settings Settings
+	URL      string
+
+	Flags           []string // Mode-specific flags, e.g. "--dump-json", "--simulate"
+	FormatID        string   // Passed as -f when set
+	OutputPath      string   // Passed as -o when set
+	Download        bool     // Adds progress reporting, output path printing and resume flags
+	PlaylistItems   string   // Passed as --playlist-items when set
+	Options         DownloadOptions
+	DownloadArchive string // Passed as --download-archive when downloading
+	JSRuntime       string // Value for --js-runtimes, empty to omit
+	WithoutCookies  bool   // Skip cookies, used to retry after cookie-related errors
+}
+
+// Args returns the full yt-dlp argument list. The URL always comes last,
+// after "--", so it can never be mistaken for an option.
+func (inv YtDlpInvocation) Args() []string {
+	args := append([]string{}, inv.Flags...)
+
+	if inv.FormatID != "" {
+		args = append(args, "-f", inv.FormatID)
+	}
+	if inv.OutputPath != "" {
+		args = append(args, "-o", inv.OutputPath)
+	}
+	if inv.Download {
+		args = append(args, "--newline", "--progress", "--continue", "--part")
+		args = append(args, "--progress-template", ytDlpProgressTemplate, "--progress-template", ytDlpPostprocessTemplate)
+		args = append(args, "--print", ytDlpTitlePrint, "--print", ytDlpFilepathPrint)
+		args = append(args, inv.Settings.filenameArgs()...)
+	}
+	if inv.PlaylistItems != "" {
+		args = append(args, "--playlist-items", inv.PlaylistItems)
+	}
+	if inv.Download {
+		embed := inv.Settings.defaultEmbedOptions()
+		if inv.Options.Embed != nil {
+			embed = *inv.Options.Embed
+		}
+		args = append(args, audioExtractionArgs(inv.Options.Audio)...)
+		args = append(args, embedArgs(embed)...)
+		args = append(args, subtitleArgs(inv.Options.Subtitles)...)
+		if inv.DownloadArchive != "" {
+			args = append(args, "--download-archive", inv.DownloadArchive)
+		}
+	}
+	if inv.JSRuntime != "" {
+		args = append(args, "--js-runtimes", inv.JSRuntime)
+	}
+
+	args = append(args, inv.proxyArgs()...)
+	if !inv.WithoutCookies {
+		args = append(args, inv.cookiesArgs()...)
+	}
+
+	return append(args, "--", inv.URL)
+}
+
+// UsesCookies reports whether the invocation passes cookies to yt-dlp
+func (inv YtDlpInvocation) UsesCookies() bool {
+	return !inv.WithoutCookies && len(inv.cookiesArgs()) > 0
+}
+
+// NoCookies returns a copy of the invocation that runs without cookies
+func (inv YtDlpInvocation) NoCookies() YtDlpInvocation {
+	inv.WithoutCookies = true
+	return inv
+}
+
+// proxyArgs returns the proxy arguments. In "system" mode yt-dlp picks up the
+// proxy from the environment by itself, so nothing is passed.
+func (inv YtDlpInvocation) proxyArgs() []string {
+	address := strings.TrimSpace(inv.Settings.ProxyAddress)
+	if inv.Settings.ProxyMode == "manual" && address != "" {
+		return []string{"--proxy", address}
+	}
+	return nil
+}
+
+// cookiesArgs returns the cookie arguments. A missing cookies file is skipped.
+func (inv YtDlpInvocation) cookiesArgs() []string {
+	switch inv.Settings.CookiesMode {
+	case "browser":
+		if inv.Settings.CookiesBrowser != "" {
+			return []string{"--cookies-from-browser", inv.Settings.CookiesBrowser}
+		}
+	case "file":
+		if inv.Settings.CookiesFile != "" {
+			if _, err := os.Stat(inv.Settings.CookiesFile); err == nil {
+				return []string{"--cookies", inv.Settings.CookiesFile}
+			}
+		}
+	}
+	return nil
+}
+
+// playlistItemsRange formats a 1-based item range for --playlist-items.
+// An empty string means the whole playlist.
+func playlistItemsRange(startItem, endItem int) string {
+	if startItem <= 0 {
+		return ""
+	}
+	if endItem > 0 {
+		return fmt.Sprintf("%d-%d", startItem, endItem)
+	}
+	return fmt.Sprintf("%d-", startItem)
+}
+
+// isCookiesRelatedError reports whether yt-dlp output suggests that retrying
+// without cookies may help
+func isCookiesRelatedError(output string) bool {
+	return strings.Contains(output, "cookies") ||
+		strings.Contains(output, "Sign in to confirm") ||
+		strings.Contains(output, "authentication") ||
+		strings.Contains(output, "Requested format is not available")
+}
+
+// newYtDlpInvocation prepares an invocation for url with the current settings
+// and the JS runtime the URL needs
+func (a *App) newYtDlpInvocation(url string) YtDlpInvocation {
+	settings := a.currentSettings()
+	if settings.CookiesMode == "file" && settings.CookiesFile != "" {
+		if _, err := os.Stat(settings.CookiesFile); err != nil {
+			a.logger.Infof("Cookies file does not exist: %s, proceeding without cookies", settings.CookiesFile)
+		}
+	}
+
+	return YtDlpInvocation{
+		Settings:  settings,
+		URL:       url,
+		JSRuntime: a.jsRuntimeFor(url),
+	}
+}
+
+// ytDlpCommand creates the yt-dlp process for an invocation
+func (a *App) ytDlpCommand(inv YtDlpInvocation) *exec.Cmd {
+	ytDlpPath := filepath.Join("./bin", a.getYtDlpBinaryName())
+
+	// Hide console window on Windows
+	cmd := exec.Command(ytDlpPath, inv.Args()...)
+	setHideWindow(cmd)
+	return cmd
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestYtDlpInvocationSettingsCombinations(t *testing.T) {
+	cookiesFile := filepath.Join(t.TempDir(), "cookies.txt")
+	if err := os.WriteFile(cookiesFile, []byte("# Netscape HTTP Cookie File\n"), 0644); err != nil {
+		t.Fatalf("failed to create cookies file: %v", err)
+	}
+	missingFile := filepath.Join(t.TempDir(), "missing.txt")
+
+	proxyCases := []struct {
+		name     string
+		mode     string
+		address  string
+		expected []string
+	}{
+		{"NoProxy", "none", "", nil},
+		{"SystemProxy", "system", "", nil},
+		{"SystemProxyIgnoresAddress", "system", "http://127.0.0.1:8080", nil},
+		{"ManualProxy", "manual", "socks5://127.0.0.1:1080", []string{"--proxy", "socks5://127.0.0.1:1080"}},
+		{"ManualProxyTrimmed", "manual", "  http://proxy:3128 ", []string{"--proxy", "http://proxy:3128"}},
+		{"ManualProxyWithoutAddress", "manual", "", nil},
+	}
+
+	cookiesCases := []struct {
+		name     string
+		mode     string
+		browser  string
+		file     string
+		expected []string
+	}{
+		{"NoCookies", "none", "", "", nil},
+		{"BrowserCookies", "browser", "firefox", "", []string{"--cookies-from-browser", "firefox"}},
+		{"BrowserCookiesWithoutBrowser", "browser", "", "", nil},
+		{"FileCookies", "file", "", cookiesFile, []string{"--cookies", cookiesFile}},
+		{"FileCookiesMissingFile", "file", "", missingFile, nil},
+		{"FileCookiesWithoutFile", "file", "", "", nil},
+	}
+
+	jsRuntimeCases := []struct {
+		name     string
+		runtime  string
+		expected []string
+	}{
+		{"NoJSRuntime", "", nil},
+		{"Deno", "deno:bin/deno", []string{"--js-runtimes", "deno:bin/deno"}},
+		{"Node", "node", []string{"--js-runtimes", "node"}},
+	}
+
+	for _, proxy := range proxyCases {
+		for _, cookies := range cookiesCases {
+			for _, js := range jsRuntimeCases {
+				for _, withoutCookies := range []bool{false, true} {
+					name := proxy.name + "/" + cookies.name + "/" + js.name
+					if withoutCookies {
+						name += "/WithoutCookies"
+					}
+
+					t.Run(name, func(t *testing.T) {
+						inv := YtDlpInvocation{
+							Settings: Settings{
+								ProxyMode:      proxy.mode,
+								ProxyAddress:   proxy.address,
+								CookiesMode:    cookies.mode,
+								CookiesBrowser: cookies.browser,
+								CookiesFile:    cookies.file,
+							},
+							URL:            "https://example.com/watch?v=1",
+							Flags:          []string{"--dump-json"},
+							JSRuntime:      js.runtime,
+							WithoutCookies: withoutCookies,
+						}
+
+						expected := []string{"--dump-json"}
+						expected = append(expected, js.expected...)
+						expected = append(expected, proxy.expected...)
+						if !withoutCookies {
+							expected = append(expected, cookies.expected...)
+						}
+						expected = append(expected, "--", "https://example.com/watch?v=1")
+
+						if got := inv.Args(); !reflect.DeepEqual(got, expected) {
+							t.Errorf("Args() = %q, want %q", got, expected)
+						}
+
+						wantCookies := !withoutCookies && cookies.expected != nil
+						if got := inv.UsesCookies(); got != wantCookies {
+							t.Errorf("UsesCookies() = %v, want %v", got, wantCookies)
+						}
+					})
+				}
+			}
+		}
+	}
+}
+
+func TestYtDlpInvocationOptions(t *testing.T) {
+	settings := Settings{ProxyMode: "manual", ProxyAddress: "http://proxy:3128", CookiesMode: "browser", CookiesBrowser: "chrome"}
+
+	tests := []struct {
+		name     string
+		inv      YtDlpInvocation
+		expected []string
+	}{
+		{
+			name: "Analyze",
+			inv:  YtDlpInvocation{Settings: settings, URL: "u", Flags: []string{"--print-json", "--simulate", "--no-warnings"}},
+			expected: []string{"--print-json", "--simulate", "--no-warnings",
+				"--proxy", "http://proxy:3128", "--cookies-from-browser", "chrome", "--", "u"},
+		},
+		{
+			name: "VideoDownload",
+			inv:  YtDlpInvocation{Settings: settings, URL: "u", FormatID: "137+140", OutputPath: "out/%(title)s.%(ext)s", Download: true, JSRuntime: "node"},
+			expected: []string{"-f", "137+140", "-o", "out/%(title)s.%(ext)s",
+				"--newline", "--progress", "--continue", "--part",
+				"--progress-template", ytDlpProgressTemplate, "--progress-template", ytDlpPostprocessTemplate,
+				"--print", ytDlpTitlePrint, "--print", ytDlpFilepathPrint, "--js-runtimes", "node", "--proxy", "http://proxy:3128", "--cookies-from-browser", "chrome", "--", "u"},
+		},
+		{
+			name: "PlaylistDownloadWithoutCookies",
+			inv: YtDlpInvocation{Settings: settings, URL: "u", Flags: []string{"--ignore-errors"}, FormatID: "best", OutputPath: "o",
+				Download: true, PlaylistItems: "3-7"}.NoCookies(),
+			expected: []string{"--ignore-errors", "-f", "best", "-o", "o",
+				"--newline", "--progress", "--continue", "--part",
+				"--progress-template", ytDlpProgressTemplate, "--progress-template", ytDlpPostprocessTemplate,
+				"--print", ytDlpTitlePrint, "--print", ytDlpFilepathPrint, "--playlist-items", "3-7", "--proxy", "http://proxy:3128", "--", "u"},
+		},
+		{
+			name: "EmbedDefaultsFromSettings",
+			inv: YtDlpInvocation{Settings: Settings{EmbedMetadata: true, EmbedThumbnail: true}, URL: "u", Download: true,
+				Options: DownloadOptions{Audio: &AudioExtraction{Codec: "mp3", Quality: "0"}}},
+			expected: []string{"--newline", "--progress", "--continue", "--part",
+				"--progress-template", ytDlpProgressTemplate, "--progress-template", ytDlpPostprocessTemplate,
+				"--print", ytDlpTitlePrint, "--print", ytDlpFilepathPrint, "-x", "--audio-format", "mp3", "--audio-quality", "0",
+				"--embed-metadata", "--embed-thumbnail", "--", "u"},
+		},
+		{
+			name: "EmbedOverridesSettings",
+			inv: YtDlpInvocation{Settings: Settings{EmbedMetadata: true, EmbedThumbnail: true}, URL: "u", Download: true,
+				Options: DownloadOptions{Embed: &EmbedOptions{Chapters: true}}},
+			expected: []string{"--newline", "--progress", "--continue", "--part",
+				"--progress-template", ytDlpProgressTemplate, "--progress-template", ytDlpPostprocessTemplate,
+				"--print", ytDlpTitlePrint, "--print", ytDlpFilepathPrint, "--embed-chapters", "--", "u"},
+		},
+		{
+			name: "PlaylistWithSubtitles",
+			inv: YtDlpInvocation{URL: "u", Flags: []string{"--ignore-errors"}, Download: true, PlaylistItems: "1-",
+				Options: DownloadOptions{Subtitles: &SubtitleSelection{Languages: []string{"en"}, Format: "srt"}}},
+			expected: []string{"--ignore-errors", "--newline", "--progress", "--continue", "--part",
+				"--progress-template", ytDlpProgressTemplate, "--progress-template", ytDlpPostprocessTemplate,
+				"--print", ytDlpTitlePrint, "--print", ytDlpFilepathPrint, "--playlist-items", "1-",
+				"--write-subs", "--sub-langs", "en", "--convert-subs", "srt", "--", "u"},
+		},
+		{
+			name: "FilenameSanitization",
+			inv:  YtDlpInvocation{Settings: Settings{RestrictFilenames: true, WindowsFilenames: true}, URL: "u", OutputPath: "o", Download: true},
+			expected: []string{"-o", "o", "--newline", "--progress", "--continue", "--part",
+				"--progress-template", ytDlpProgressTemplate, "--progress-template", ytDlpPostprocessTemplate,
+				"--print", ytDlpTitlePrint, "--print", ytDlpFilepathPrint, "--restrict-filenames", "--windows-filenames", "--", "u"},
+		},
+		{
+			name: "DownloadArchive",
+			inv:  YtDlpInvocation{URL: "u", Download: true, DownloadArchive: "archives/sub.txt"},
+			expected: []string{"--newline", "--progress", "--continue", "--part",
+				"--progress-template", ytDlpProgressTemplate, "--progress-template", ytDlpPostprocessTemplate,
+				"--print", ytDlpTitlePrint, "--print", ytDlpFilepathPrint, "--download-archive", "archives/sub.txt", "--", "u"},
+		},
+		{
+			name:     "ArchiveIgnoredWithoutDownload",
+			inv:      YtDlpInvocation{URL: "u", Flags: []string{"--dump-json"}, DownloadArchive: "archives/sub.txt"},
+			expected: []string{"--dump-json", "--", "u"},
+		},
+		{
+			name:     "EmbedIgnoredWithoutDownload",
+			inv:      YtDlpInvocation{Settings: Settings{EmbedChapters: true}, URL: "u", Flags: []string{"--dump-json"}},
+			expected: []string{"--dump-json", "--", "u"},
+		},
+		{
+			name:     "URLLooksLikeOption",
+			inv:      YtDlpInvocation{URL: "--exec=rm"},
+			expected: []string{"--", "--exec=rm"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.inv.Args(); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Args() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestYtDlpInvocationArgsDoNotAliasFlags(t *testing.T) {
+	flags := make([]string, 1, 8)
+	flags[0] = "--dump-json"
+	inv := YtDlpInvocation{URL: "u", Flags: flags, FormatID: "best"}
+
+	inv.Args()
+	if got := flags[:cap(flags)][1]; got != "" {
+		t.Errorf("Args() wrote into the caller's Flags slice: %q", got)
+	}
+}
+
+func TestPlaylistItemsRange(t *testing.T) {
+	tests := []struct {
+		start, end int
+		expected   string
+	}{
+		{0, 0, ""},
+		{0, 5, ""},
+		{2, 0, "2-"},
+		{2, 5, "2-5"},
+	}
+
+	for _, tt := range tests {
+		if got := playlistItemsRange(tt.start, tt.end); got != tt.expected {
+			t.Errorf("playlistItemsRange(%d, %d) = %q, want %q", tt.start, tt.end, got, tt.expected)
+		}
+	}
+}
+
+func TestIsCookiesRelatedError(t *testing.T) {
+	tests := []struct {
+		output   string
+		expected bool
+	}{
+		{"ERROR: [youtube] abc: Sign in to confirm you're not a bot", true},
+		{"ERROR: could not find firefox cookies database", true},
+		{"ERROR: [youtube] abc: Requested format is not available", true},
+		{"ERROR: unable to download video data: HTTP Error 404", false},
+	}
+
+	for _, tt := range tests {
+		if got := isCookiesRelatedError(tt.output); got != tt.expected {
+			t.Errorf("isCookiesRelatedError(%q) = %v, want %v", tt.output, got, tt.expected)
+		}
+	}
+}